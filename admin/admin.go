@@ -5,12 +5,16 @@
 package admin
 
 import (
+	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/square/quotaservice/audit"
+	"github.com/square/quotaservice/config"
 	"github.com/square/quotaservice/logging"
 )
 
@@ -65,7 +69,7 @@ func ServeAdminConsole(a Administrable, mux *http.ServeMux, assetsDirectory stri
 		jsonResponseHandler(
 			apiVersionHandler(
 				a,
-				apiRequestHandler(namespacesHandler, bucketsHandler),
+				auditReadHandler(a, "read_config", apiRequestHandler(namespacesHandler, bucketsHandler)),
 			),
 		),
 	)
@@ -77,9 +81,34 @@ func ServeAdminConsole(a Administrable, mux *http.ServeMux, assetsDirectory stri
 	mux.Handle("/api/stats", statsHandler)
 	mux.Handle("/api/stats/", statsHandler)
 
-	configsHandler := loggingHandler(jsonResponseHandler(newConfigsAPIHandler(a)))
+	summaryHandler := loggingHandler(jsonResponseHandler(newSummaryAPIHandler(a)))
+	mux.Handle("/api/summary", summaryHandler)
+	mux.Handle("/api/summary/", summaryHandler)
+
+	configsHandler := loggingHandler(jsonResponseHandler(apiVersionHandler(a,
+		auditReadHandler(a, "read_configs", newConfigsAPIHandler(a)))))
 	mux.Handle("/api/configs", configsHandler)
 	mux.Handle("/api/configs/", configsHandler)
+
+	overridesHandler := loggingHandler(jsonResponseHandler(apiVersionHandler(a,
+		auditReadHandler(a, "read_overrides", newOverridesAPIHandler(a)))))
+	mux.Handle("/api/overrides", overridesHandler)
+	mux.Handle("/api/overrides/", overridesHandler)
+
+	alertsHandler := loggingHandler(jsonResponseHandler(apiVersionHandler(a,
+		auditReadHandler(a, "read_alerts", newAlertsAPIHandler(a)))))
+	mux.Handle("/api/alerts", alertsHandler)
+	mux.Handle("/api/alerts/", alertsHandler)
+
+	schedulesHandler := loggingHandler(jsonResponseHandler(newSchedulesAPIHandler(a)))
+	mux.Handle("/api/schedules", schedulesHandler)
+	mux.Handle("/api/schedules/", schedulesHandler)
+
+	mux.Handle("/api/reaper", loggingHandler(jsonResponseHandler(newReaperAPIHandler(a))))
+	mux.Handle("/api/events/stream", loggingHandler(auditReadHandler(a, "stream_events", newEventsStreamHandler(a))))
+
+	auditHandler := loggingHandler(jsonResponseHandler(auditReadHandler(a, "read_audit", newAuditAPIHandler(a))))
+	mux.Handle("/api/audit", auditHandler)
 }
 
 func (r *responseWrapper) Write(p []byte) (int, error) {
@@ -97,6 +126,11 @@ func (r *responseWrapper) WriteHeader(status int) {
 	r.ResponseWriter.WriteHeader(status)
 }
 
+// Unwrap returns the wrapped ResponseWriter, so that http.ResponseController can flush streamed responses.
+func (r *responseWrapper) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
 func (r *responseWrapper) log() {
 	timeFormatted := r.time.Format("02/Jan/2006 03:04:05")
 	requestLine := fmt.Sprintf("%s %s %s", r.method, r.uri, r.protocol)
@@ -112,6 +146,12 @@ func jsonResponseHandler(next http.Handler) http.Handler {
 	})
 }
 
+// versionKey is the key of the request context value holding the config version a request expects.
+type versionKey struct{}
+
+// apiVersionHandler parses the Version header, the version of the config a request is based on. Reads of another
+// version are rejected right away. Changes are rejected by the Administrable instead, which checks the version
+// atomically with making the change; handlers pass it on with expectedVersion.
 func apiVersionHandler(a Administrable, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		versionHeader := r.Header.Get("Version")
@@ -121,24 +161,35 @@ func apiVersionHandler(a Administrable, next http.Handler) http.Handler {
 			return
 		}
 
-		version, err := strconv.Atoi(versionHeader)
+		version, err := strconv.ParseInt(versionHeader, 10, 32)
 		if err != nil {
 			writeJSONError(w, &httpError{fmt.Sprintf("There was an error parsing the 'Version' header. Please verify version is provided and is properly formatted. %s", err.Error()), http.StatusBadRequest})
 			return
 		}
 
-		expectedVersion := int(a.Configs().Version)
-		if version != expectedVersion {
-			writeJSONError(w, &httpError{
-				fmt.Sprintf("The config version sent (%d) is different than the latest server version (%d). Please refresh and redo your changes.", version, expectedVersion),
-				http.StatusConflict,
-			})
-		} else {
-			next.ServeHTTP(w, r)
+		if r.Method == "GET" {
+			if currentVersion := a.Configs().Version; int32(version) != currentVersion {
+				writeAdministrableError(w, &config.ConfigConflictError{
+					ExpectedVersion: int32(version),
+					ActualVersion:   currentVersion}, http.StatusConflict)
+				return
+			}
 		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), versionKey{}, int32(version))))
 	})
 }
 
+// expectedVersion returns the config version a request expects to change, or config.AnyVersion if it didn't send
+// one.
+func expectedVersion(r *http.Request) int32 {
+	if version, ok := r.Context().Value(versionKey{}).(int32); ok {
+		return version
+	}
+
+	return config.AnyVersion
+}
+
 func apiRequestHandler(namespacesHandler, bucketsHandler http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		params := strings.SplitN(strings.Trim(r.URL.Path, "/"), "/", 3)
@@ -179,6 +230,37 @@ func loggingHandler(next http.Handler) http.Handler {
 	})
 }
 
+// auditReadHandler records GET requests in the audit trail, as reads of sensitive data, before serving them.
+func auditReadHandler(a Administrable, action string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			a.RecordAudit(&audit.Entry{Actor: getActor(r), Action: action, Target: r.URL.RequestURI()})
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// getActor identifies who made a request, for the audit trail. The user and X-Forwarded-For are taken from the
+// request's headers as-is, and are only as trustworthy as the proxy in front of the admin API.
+func getActor(r *http.Request) audit.Actor {
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+
+	source := audit.API
+	if strings.HasPrefix(r.UserAgent(), audit.CLIUserAgent) {
+		source = audit.CLI
+	}
+
+	return audit.Actor{
+		User:         getUsername(r),
+		IP:           ip,
+		ForwardedFor: r.Header.Get("X-Forwarded-For"),
+		Source:       source}
+}
+
 func getUsername(r *http.Request) string {
 	if username, exists := r.Header["X-Forwarded-User"]; exists {
 		return username[0]
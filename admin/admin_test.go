@@ -169,6 +169,14 @@ func establishTestServer(version int32) *httptest.Server {
 
 	apiHandler := apiVersionHandler(a, http.HandlerFunc(
 		func(w http.ResponseWriter, r *http.Request) {
+			// Changes are checked against the expected version by the Administrable.
+			if r.Method != http.MethodGet {
+				if err := a.UpdateConfig(a.Configs(), expectedVersion(r), getActor(r)); err != nil {
+					writeAdministrableError(w, err, http.StatusInternalServerError)
+					return
+				}
+			}
+
 			writeJSONOk(w)
 		}),
 	)
@@ -4,6 +4,10 @@
 package admin
 
 import (
+	"github.com/square/quotaservice/alerts"
+	"github.com/square/quotaservice/audit"
+	"github.com/square/quotaservice/config"
+	"github.com/square/quotaservice/events"
 	pb "github.com/square/quotaservice/protos/config"
 	"github.com/square/quotaservice/stats"
 )
@@ -12,18 +16,53 @@ import (
 type Administrable interface {
 	Configs() *pb.ServiceConfig
 	HistoricalConfigs() ([]*pb.ServiceConfig, error)
+	HistoricalConfigsPage(offset, limit int) ([]*pb.ServiceConfig, error)
+	// ConfigProvenance returns where each setting of the current config came from, or nil if configs aren't
+	// merged from several sources.
+	ConfigProvenance() *config.Provenance
 
-	UpdateConfig(*pb.ServiceConfig, string) error
+	// Changes to the config are made on behalf of an actor, and recorded in the audit trail. Each is made to the
+	// config with the version expected, failing with a *config.ConfigConflictError if another version has replaced
+	// it, unless config.AnyVersion is expected.
+	UpdateConfig(*pb.ServiceConfig, int32, audit.Actor) error
 
-	DeleteBucket(string, string, string) error
-	AddBucket(string, *pb.BucketConfig, string) error
-	UpdateBucket(string, *pb.BucketConfig, string) error
+	DeleteBucket(string, string, int32, audit.Actor) error
+	AddBucket(string, *pb.BucketConfig, int32, audit.Actor) error
+	UpdateBucket(string, *pb.BucketConfig, int32, audit.Actor) error
 
-	DeleteNamespace(string, string) error
-	AddNamespace(*pb.NamespaceConfig, string) error
-	UpdateNamespace(*pb.NamespaceConfig, string) error
+	DeleteNamespace(string, int32, audit.Actor) error
+	AddNamespace(*pb.NamespaceConfig, int32, audit.Actor) error
+	UpdateNamespace(*pb.NamespaceConfig, int32, audit.Actor) error
 
-	TopDynamicHits(string) []*stats.BucketScore
-	TopDynamicMisses(string) []*stats.BucketScore
-	DynamicBucketStats(string, string) *stats.BucketScores
+	// AddOverride temporarily overrides a bucket's config, replacing any existing override of the bucket.
+	AddOverride(*pb.BucketOverride, int32, audit.Actor) error
+	DeleteOverride(string, string, int32, audit.Actor) error
+
+	// AddAlertRule adds a rule alerting webhooks when requests are being rejected, replacing any rule of the same
+	// name.
+	AddAlertRule(*pb.AlertRule, int32, audit.Actor) error
+	DeleteAlertRule(string, int32, audit.Actor) error
+	// Alerts returns the alerts that are firing.
+	Alerts() []*alerts.Alert
+
+	// TopBuckets, BucketStats and NamespaceStats return nil if there is no stats listener.
+	TopBuckets(string, stats.Query) []*stats.BucketScore
+	BucketStats(string, string, stats.Query) *stats.BucketScores
+	NamespaceStats(string, stats.Query) *stats.NamespaceSummary
+
+	ReaperStats() *stats.ReaperStats
+	// DynamicBucketCounts returns the number of dynamic buckets currently in each namespace.
+	DynamicBucketCounts() map[string]int
+	// SubscribeEvents subscribes to events as they happen, buffering up to bufSize of those passing filter.
+	SubscribeEvents(bufSize int, filter func(events.Event) bool) (*events.Subscription, error)
+	// DroppedEvents returns the number of events dropped because a listener's queue was full, keyed on listener.
+	DroppedEvents() map[string]uint64
+	// BucketSchedules describes the schedule in effect for each bucket with schedules.
+	BucketSchedules() []config.ScheduleState
+
+	// RecordAudit appends an entry to the audit trail, if there is one.
+	RecordAudit(*audit.Entry)
+	// AuditTrail returns the entries of the audit trail matching a query, newest first, or audit.ErrNoSink if
+	// there is no audit trail.
+	AuditTrail(audit.Query) ([]*audit.Entry, error)
 }
@@ -0,0 +1,67 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package admin
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/square/quotaservice/alerts"
+	pb "github.com/square/quotaservice/protos/config"
+)
+
+type alertsAPIHandler struct {
+	a Administrable
+}
+
+// alertsResponse lists the alert rules of the config, and the alerts they've raised that are firing.
+type alertsResponse struct {
+	Rules  []*pb.AlertRule `json:"rules"`
+	Firing []*alerts.Alert `json:"firing"`
+}
+
+func newAlertsAPIHandler(admin Administrable) (a *alertsAPIHandler) {
+	return &alertsAPIHandler{a: admin}
+}
+
+func (a *alertsAPIHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// [api, alerts, {name}]
+	params := strings.SplitN(strings.Trim(r.URL.Path, "/"), "/", 3)
+	actor, version := getActor(r), expectedVersion(r)
+
+	switch {
+	case r.Method == "GET" && len(params) == 2:
+		rules := a.a.Configs().AlertRules
+		if rules == nil {
+			rules = make([]*pb.AlertRule, 0)
+		}
+
+		writeJSON(w, &alertsResponse{rules, a.a.Alerts()})
+	case r.Method == "POST" && len(params) == 2:
+		rule := &pb.AlertRule{}
+		if err := unmarshalJSON(r.Body, rule); err != nil {
+			writeJSONError(w, &httpError{err.Error(), http.StatusBadRequest})
+			return
+		}
+
+		if rule.Name == "" || rule.Namespace == "" {
+			writeJSONError(w, &httpError{"An alert rule needs a name and namespace", http.StatusBadRequest})
+			return
+		}
+
+		if err := a.a.AddAlertRule(rule, version, actor); err != nil {
+			writeAdministrableError(w, err, http.StatusBadRequest)
+		} else {
+			writeJSONOk(w)
+		}
+	case r.Method == "DELETE" && len(params) == 3:
+		if err := a.a.DeleteAlertRule(params[2], version, actor); err != nil {
+			writeAdministrableError(w, err, http.StatusBadRequest)
+		} else {
+			writeJSONOk(w)
+		}
+	default:
+		writeJSONError(w, &httpError{"Unknown method " + r.Method + " for " + r.URL.Path, http.StatusBadRequest})
+	}
+}
@@ -0,0 +1,116 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package admin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/square/quotaservice/audit"
+	pb "github.com/square/quotaservice/protos/config"
+)
+
+// alertsAdministrable records the alert rules added and deleted through it.
+type alertsAdministrable struct {
+	*MockAdministrable
+	added   *pb.AlertRule
+	deleted string
+}
+
+func (a *alertsAdministrable) AddAlertRule(r *pb.AlertRule, version int32, actor audit.Actor) error {
+	a.added = r
+	return a.MockAdministrable.AddAlertRule(r, version, actor)
+}
+
+func (a *alertsAdministrable) DeleteAlertRule(name string, version int32, actor audit.Actor) error {
+	a.deleted = name
+	return a.MockAdministrable.DeleteAlertRule(name, version, actor)
+}
+
+func TestAlertsGet(t *testing.T) {
+	a := NewMockAdministrable()
+	a.Configs().AlertRules = []*pb.AlertRule{{Name: "timeouts", Namespace: "ns", ThresholdPercent: 10}}
+
+	response := &alertsResponse{}
+	doAlertsRequest(t, a, response, "GET", "/api/alerts", "")
+
+	if len(response.Rules) != 1 || response.Rules[0].ThresholdPercent != 10 ||
+		len(response.Firing) != 1 || response.Firing[0].Rule != "timeouts" {
+		t.Errorf("Received unexpected alerts %+v", response)
+	}
+}
+
+func TestAlertsPost(t *testing.T) {
+	a := &alertsAdministrable{MockAdministrable: NewMockAdministrable()}
+
+	jsonResponse := make(map[string]string)
+	doAlertsRequest(t, a, &jsonResponse, "POST", "/api/alerts",
+		`{"name": "rejections", "namespace": "ns", "condition": "REJECTIONS", "threshold_percent": 25,
+		  "window_millis": 300000, "webhooks": ["https://example.com/hook"]}`)
+
+	if len(jsonResponse) != 0 {
+		t.Fatalf("Received non-empty response \"%+v\"", jsonResponse)
+	}
+
+	if r := a.added; r.Name != "rejections" || r.Condition != pb.AlertRule_REJECTIONS || r.ThresholdPercent != 25 ||
+		r.WindowMillis != 300000 || len(r.Webhooks) != 1 {
+		t.Errorf("Received unexpected alert rule %+v", r)
+	}
+
+	doAlertsRequest(t, a, &jsonResponse, "POST", "/api/alerts", `{"namespace": "ns"}`)
+
+	if jsonResponse["description"] != "An alert rule needs a name and namespace" {
+		t.Errorf("Received unexpected response %+v", jsonResponse)
+	}
+}
+
+func TestAlertsPostError(t *testing.T) {
+	jsonResponse := make(map[string]string)
+	doAlertsRequest(t, NewMockErrorAdministrable(), &jsonResponse, "POST", "/api/alerts",
+		`{"name": "timeouts", "namespace": "ns"}`)
+
+	if jsonResponse["description"] != "AddAlertRule" {
+		t.Errorf("Received \"%s\" from %+v instead of AddAlertRule", jsonResponse["description"], jsonResponse)
+	}
+}
+
+func TestAlertsDelete(t *testing.T) {
+	a := &alertsAdministrable{MockAdministrable: NewMockAdministrable()}
+
+	jsonResponse := make(map[string]string)
+	doAlertsRequest(t, a, &jsonResponse, "DELETE", "/api/alerts/timeouts", "")
+
+	if len(jsonResponse) != 0 || a.deleted != "timeouts" {
+		t.Errorf("Expected the timeouts rule to be deleted, got %q with response %+v", a.deleted, jsonResponse)
+	}
+
+	doAlertsRequest(t, a, &jsonResponse, "DELETE", "/api/alerts", "")
+
+	if jsonResponse["description"] != "Unknown method DELETE for /api/alerts" {
+		t.Errorf("Received unexpected response %+v", jsonResponse)
+	}
+}
+
+func doAlertsRequest(t *testing.T, a Administrable, object interface{}, method, path, body string) {
+	t.Helper()
+
+	ts := httptest.NewServer(newAlertsAPIHandler(a))
+	defer ts.Close()
+
+	request, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = unmarshalJSON(res.Body, object); err != nil {
+		t.Fatal(err)
+	}
+}
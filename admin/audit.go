@@ -0,0 +1,95 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/square/quotaservice/audit"
+)
+
+type auditAPIHandler struct {
+	a Administrable
+}
+
+type auditResponse struct {
+	Entries []*audit.Entry `json:"entries"`
+}
+
+func newAuditAPIHandler(admin Administrable) (a *auditAPIHandler) {
+	return &auditAPIHandler{a: admin}
+}
+
+func (a *auditAPIHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeJSONError(w, &httpError{"Unknown method " + r.Method, http.StatusBadRequest})
+		return
+	}
+
+	query, httpErr := parseAuditQuery(r.URL.Query())
+	if httpErr != nil {
+		writeJSONError(w, httpErr)
+		return
+	}
+
+	entries, err := a.a.AuditTrail(query)
+	switch {
+	case errors.Is(err, audit.ErrNoSink):
+		writeJSONError(w, &httpError{err.Error(), http.StatusBadRequest})
+	case err != nil:
+		writeJSONError(w, &httpError{"Error reading audit trail " + err.Error(), http.StatusInternalServerError})
+	default:
+		if entries == nil {
+			entries = make([]*audit.Entry, 0)
+		}
+
+		writeJSON(w, &auditResponse{entries})
+	}
+}
+
+// parseAuditQuery parses the user, action, since, until, offset and limit query parameters. Times are RFC 3339.
+func parseAuditQuery(params url.Values) (audit.Query, *httpError) {
+	query := audit.Query{User: params.Get("user"), Action: params.Get("action")}
+
+	var httpErr *httpError
+	if query.Since, httpErr = parseTimeParam(params.Get("since"), "since"); httpErr != nil {
+		return query, httpErr
+	}
+
+	if query.Until, httpErr = parseTimeParam(params.Get("until"), "until"); httpErr != nil {
+		return query, httpErr
+	}
+
+	if query.Offset, httpErr = parseIntParam(params.Get("offset"), "offset"); httpErr != nil {
+		return query, httpErr
+	}
+
+	if query.Limit, httpErr = parseIntParam(params.Get("limit"), "limit"); httpErr != nil {
+		return query, httpErr
+	}
+
+	if query.Limit > audit.MaxLimit {
+		return query, &httpError{"limit must be at most " + strconv.Itoa(audit.MaxLimit), http.StatusBadRequest}
+	}
+
+	return query, nil
+}
+
+// parseTimeParam parses an optional RFC 3339 query parameter.
+func parseTimeParam(value, name string) (time.Time, *httpError) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, &httpError{"Invalid " + name + " " + value + "; expected RFC 3339", http.StatusBadRequest}
+	}
+
+	return t, nil
+}
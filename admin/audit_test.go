@@ -0,0 +1,135 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package admin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/square/quotaservice/audit"
+)
+
+func TestAuditGetRecordsReads(t *testing.T) {
+	mux := http.NewServeMux()
+	ServeAdminConsole(NewMockAdministrable(), mux, "", false)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	request, err := http.NewRequest("GET", ts.URL+"/api/configs", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request.Header.Set("User-Agent", audit.CLIUserAgent+"/1.0")
+	request.Header.Set("X-Forwarded-User", "alice")
+	request.Header.Set("X-Forwarded-For", "10.0.0.1")
+
+	res, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+
+	response := &auditResponse{}
+	doAuditRequest(t, ts.URL+"/api/audit", response)
+
+	// Reading the trail is itself recorded, before the trail is read.
+	if len(response.Entries) != 2 || response.Entries[0].Action != "read_audit" {
+		t.Fatalf("Expected reads of the configs and the trail, got %+v", response.Entries)
+	}
+
+	e := response.Entries[1]
+	if e.Action != "read_configs" || e.Target != "/api/configs" || e.User != "alice" || e.Source != audit.CLI ||
+		e.IP != "127.0.0.1" || e.ForwardedFor != "10.0.0.1" {
+		t.Errorf("Received unexpected entry %+v", e)
+	}
+
+	response = &auditResponse{}
+	doAuditRequest(t, ts.URL+"/api/audit?user=alice&since=2000-01-01T00:00:00Z&limit=1", response)
+
+	if len(response.Entries) != 1 || response.Entries[0].Action != "read_configs" {
+		t.Errorf("Expected alice's read of the configs, got %+v", response.Entries)
+	}
+}
+
+func TestAuditGetRecordsConfigReads(t *testing.T) {
+	for _, path := range []string{"/api/", "/api/test", "/api/test/bucket"} {
+		assertReadAudited(t, path, "read_config")
+	}
+}
+
+func TestAuditGetRecordsOverrideReads(t *testing.T) {
+	assertReadAudited(t, "/api/overrides", "read_overrides")
+}
+
+func TestAuditGetRecordsAlertReads(t *testing.T) {
+	assertReadAudited(t, "/api/alerts", "read_alerts")
+}
+
+// assertReadAudited checks that getting path records a read with the action in the audit trail.
+func assertReadAudited(t *testing.T, path, action string) {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	ServeAdminConsole(NewMockAdministrable(), mux, "", false)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+
+	response := &auditResponse{}
+	doAuditRequest(t, ts.URL+"/api/audit?action="+action, response)
+
+	if len(response.Entries) != 1 || response.Entries[0].Target != path {
+		t.Errorf("Expected a %v entry for %v, got %+v", action, path, response.Entries)
+	}
+}
+
+func TestAuditGetInvalidQuery(t *testing.T) {
+	ts := httptest.NewServer(newAuditAPIHandler(NewMockAdministrable()))
+	defer ts.Close()
+
+	for query, expected := range map[string]string{
+		"?since=yesterday": "Invalid since yesterday; expected RFC 3339",
+		"?limit=-1":        "Invalid limit -1",
+		"?limit=1001":      "limit must be at most 1000",
+	} {
+		jsonResponse := make(map[string]string)
+		doAuditRequest(t, ts.URL+"/api/audit"+query, &jsonResponse)
+
+		if jsonResponse["description"] != expected {
+			t.Errorf("Expected %q for %v, got %+v", expected, query, jsonResponse)
+		}
+	}
+}
+
+func TestAuditGetNoSink(t *testing.T) {
+	ts := httptest.NewServer(newAuditAPIHandler(NewMockErrorAdministrable()))
+	defer ts.Close()
+
+	jsonResponse := make(map[string]string)
+	doAuditRequest(t, ts.URL+"/api/audit", &jsonResponse)
+
+	if jsonResponse["description"] != audit.ErrNoSink.Error() {
+		t.Errorf("Received unexpected response %+v", jsonResponse)
+	}
+}
+
+func doAuditRequest(t *testing.T, url string, object interface{}) {
+	t.Helper()
+
+	res, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = unmarshalJSON(res.Body, object); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -23,7 +23,7 @@ func newBucketsAPIHandler(admin Administrable) (a *bucketsAPIHandler) {
 func (a *bucketsAPIHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	params := strings.SplitN(strings.Trim(r.URL.Path, "/"), "/", 3)
 	namespace, bucket := params[1], params[2]
-	user := getUsername(r)
+	actor, version := getActor(r), expectedVersion(r)
 
 	switch r.Method {
 	case "GET":
@@ -33,20 +33,20 @@ func (a *bucketsAPIHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			writeJSONError(w, err)
 		}
 	case "DELETE":
-		err := a.a.DeleteBucket(namespace, bucket, user)
+		err := a.a.DeleteBucket(namespace, bucket, version, actor)
 
 		if err != nil {
-			writeJSONError(w, &httpError{err.Error(), http.StatusBadRequest})
+			writeAdministrableError(w, err, http.StatusBadRequest)
 		} else {
 			writeJSONOk(w)
 		}
 	case "PUT":
 		changeBucket(w, r, bucket, func(c *pb.BucketConfig) error {
-			return a.a.UpdateBucket(namespace, c, user)
+			return a.a.UpdateBucket(namespace, c, version, actor)
 		})
 	case "POST":
 		changeBucket(w, r, bucket, func(c *pb.BucketConfig) error {
-			return a.a.AddBucket(namespace, c, user)
+			return a.a.AddBucket(namespace, c, version, actor)
 		})
 	default:
 		writeJSONError(w, &httpError{"Unknown method " + r.Method, http.StatusBadRequest})
@@ -68,7 +68,7 @@ func changeBucket(w http.ResponseWriter, r *http.Request, bucket string, updater
 	e = updater(c)
 
 	if e != nil {
-		writeJSONError(w, &httpError{e.Error(), http.StatusInternalServerError})
+		writeAdministrableError(w, e, http.StatusInternalServerError)
 	} else {
 		writeJSONOk(w)
 	}
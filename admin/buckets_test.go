@@ -6,9 +6,11 @@ package admin
 import (
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
 
+	"github.com/square/quotaservice/audit"
 	"github.com/square/quotaservice/config"
 	pb "github.com/square/quotaservice/protos/config"
 )
@@ -48,7 +50,7 @@ func TestBucketsGet(t *testing.T) {
 	configResponse := &pb.BucketConfig{}
 	doBucketsRequest(t, a, configResponse, "GET", "/api/test/bucket", "")
 
-	if *bucket != *configResponse {
+	if !reflect.DeepEqual(bucket, configResponse) {
 		t.Errorf("Received \"%+v\" but was expecting \"%+v\"", configResponse, bucket)
 	}
 }
@@ -107,6 +109,28 @@ func TestBucketsDelete(t *testing.T) {
 	}
 }
 
+// invalidatingAdministrable rejects all bucket changes as invalid.
+type invalidatingAdministrable struct {
+	*MockAdministrable
+}
+
+func (i *invalidatingAdministrable) AddBucket(namespace string, b *pb.BucketConfig, version int32, actor audit.Actor) error {
+	return config.ValidationErrors{{Path: "namespaces.test.buckets.test.size", Message: "must be positive; was -1"}}
+}
+
+func TestBucketsPostInvalid(t *testing.T) {
+	response := &validationErrorResponse{}
+	doBucketsRequest(t, &invalidatingAdministrable{NewMockAdministrable()}, response, "POST", "/api/test/test", "")
+
+	if response.Error != http.StatusText(http.StatusUnprocessableEntity) {
+		t.Fatalf("Expected 422 Unprocessable Entity, but received %+v", response)
+	}
+
+	if len(response.Errors) != 1 || response.Errors[0].Path != "namespaces.test.buckets.test.size" {
+		t.Fatalf("Expected error for namespaces.test.buckets.test.size, but received %+v", response.Errors)
+	}
+}
+
 func doBucketsRequest(t *testing.T, a Administrable, object interface{}, method, path, body string) {
 	t.Helper()
 
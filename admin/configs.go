@@ -4,8 +4,12 @@
 package admin
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
+	"github.com/square/quotaservice/config"
 	pb "github.com/square/quotaservice/protos/config"
 )
 
@@ -21,13 +25,71 @@ type configsResponse struct {
 	Configs []*pb.ServiceConfig `json:"configs"`
 }
 
+type configDiffResponse struct {
+	From int32              `json:"from"`
+	To   int32              `json:"to"`
+	Diff *config.ConfigDiff `json:"diff"`
+}
+
+type rollbackResponse struct {
+	// Version is the historical version rolled back to.
+	Version int32 `json:"version"`
+	// CurrentVersion is the version of the config that was replaced, or would be if this was a dry run.
+	CurrentVersion int32              `json:"current_version"`
+	DryRun         bool               `json:"dry_run"`
+	Diff           *config.ConfigDiff `json:"diff"`
+}
+
 func (a *configsAPIHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// [diff], [provenance] or [{version}, rollback]
+	params := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/configs"), "/"), "/")
+
+	if len(params) == 1 && params[0] == "diff" {
+		a.diff(w, r)
+		return
+	}
+
+	if len(params) == 1 && params[0] == "provenance" {
+		a.provenance(w, r)
+		return
+	}
+
+	if len(params) == 2 && params[1] == "rollback" {
+		a.rollback(w, r, params[0])
+		return
+	}
+
+	if params[0] != "" {
+		writeJSONError(w, &httpError{"", http.StatusNotFound})
+		return
+	}
+
 	if r.Method != "GET" {
 		writeJSONError(w, &httpError{"Unknown method " + r.Method, http.StatusBadRequest})
 		return
 	}
 
-	configs, err := a.a.HistoricalConfigs()
+	var configs []*pb.ServiceConfig
+	var err error
+
+	query := r.URL.Query()
+	if query.Get("offset") != "" || query.Get("limit") != "" {
+		offset, httpErr := parseIntParam(query.Get("offset"), "offset")
+		if httpErr != nil {
+			writeJSONError(w, httpErr)
+			return
+		}
+
+		limit, httpErr := parseIntParam(query.Get("limit"), "limit")
+		if httpErr != nil {
+			writeJSONError(w, httpErr)
+			return
+		}
+
+		configs, err = a.a.HistoricalConfigsPage(offset, limit)
+	} else {
+		configs, err = a.a.HistoricalConfigs()
+	}
 
 	if err != nil {
 		writeJSONError(w, &httpError{"Error reading configs " + err.Error(), http.StatusInternalServerError})
@@ -35,3 +97,157 @@ func (a *configsAPIHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, &configsResponse{configs})
 	}
 }
+
+// parseIntParam parses an optional, non-negative integer query parameter.
+func parseIntParam(value, name string) (int, *httpError) {
+	if value == "" {
+		return 0, nil
+	}
+
+	i, err := strconv.Atoi(value)
+	if err != nil || i < 0 {
+		return 0, &httpError{fmt.Sprintf("Invalid %v %v", name, value), http.StatusBadRequest}
+	}
+
+	return i, nil
+}
+
+// provenance lists the layer each setting of the current config came from, when using a
+// CompositeConfigPersister.
+func (a *configsAPIHandler) provenance(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeJSONError(w, &httpError{"Unknown method " + r.Method, http.StatusBadRequest})
+		return
+	}
+
+	p := a.a.ConfigProvenance()
+	if p == nil {
+		writeJSONError(w, &httpError{"Config provenance is only available for layered configs", http.StatusNotFound})
+		return
+	}
+
+	writeJSON(w, p)
+}
+
+// diff describes the changes made between two versions, given by the from and to parameters. If to is
+// omitted, the running config is compared against.
+func (a *configsAPIHandler) diff(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeJSONError(w, &httpError{"Unknown method " + r.Method, http.StatusBadRequest})
+		return
+	}
+
+	query := r.URL.Query()
+	if query.Get("from") == "" {
+		writeJSONError(w, &httpError{"Missing from parameter", http.StatusBadRequest})
+		return
+	}
+
+	fromVersion, httpErr := parseVersion(query.Get("from"))
+	if httpErr != nil {
+		writeJSONError(w, httpErr)
+		return
+	}
+
+	from, httpErr := a.findConfig(fromVersion)
+	if httpErr != nil {
+		writeJSONError(w, httpErr)
+		return
+	}
+
+	to := a.a.Configs()
+	if query.Get("to") != "" {
+		toVersion, httpErr := parseVersion(query.Get("to"))
+		if httpErr != nil {
+			writeJSONError(w, httpErr)
+			return
+		}
+
+		if to, httpErr = a.findConfig(toVersion); httpErr != nil {
+			writeJSONError(w, httpErr)
+			return
+		}
+	}
+
+	writeJSON(w, &configDiffResponse{
+		From: from.Version,
+		To:   to.Version,
+		Diff: config.DiffConfigs(from, to)})
+}
+
+// rollback persists a historical config as a new version, replacing the current config. If the dry_run
+// parameter is true, the changes that would be made are returned without persisting anything.
+func (a *configsAPIHandler) rollback(w http.ResponseWriter, r *http.Request, versionParam string) {
+	if r.Method != "POST" {
+		writeJSONError(w, &httpError{"Unknown method " + r.Method, http.StatusBadRequest})
+		return
+	}
+
+	version, httpErr := parseVersion(versionParam)
+	if httpErr != nil {
+		writeJSONError(w, httpErr)
+		return
+	}
+
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+
+	historical, httpErr := a.findConfig(version)
+	if httpErr != nil {
+		writeJSONError(w, httpErr)
+		return
+	}
+
+	current := a.a.Configs()
+	response := &rollbackResponse{
+		Version:        version,
+		CurrentVersion: current.Version,
+		DryRun:         dryRun,
+		Diff:           config.DiffConfigs(current, historical)}
+
+	if !dryRun {
+		// The diff is against the current config, so only roll back that config, even if the request doesn't say
+		// which version it is based on.
+		expected := expectedVersion(r)
+		if expected == config.AnyVersion {
+			expected = current.Version
+		}
+
+		// UpdateConfig persists the historical config as version current+1, by the requesting user.
+		if err := a.a.UpdateConfig(historical, expected, getActor(r)); err != nil {
+			writeAdministrableError(w, err, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	writeJSON(w, response)
+}
+
+// findConfig returns the most recently persisted config with the given version.
+func (a *configsAPIHandler) findConfig(version int32) (*pb.ServiceConfig, *httpError) {
+	configs, err := a.a.HistoricalConfigs()
+	if err != nil {
+		return nil, &httpError{"Error reading configs " + err.Error(), http.StatusInternalServerError}
+	}
+
+	var found *pb.ServiceConfig
+	for _, c := range configs {
+		if c != nil && c.Version == version && (found == nil || c.Date > found.Date) {
+			found = c
+		}
+	}
+
+	if found == nil {
+		return nil, &httpError{fmt.Sprintf("Unable to locate config version %v", version), http.StatusNotFound}
+	}
+
+	return config.CloneConfig(found), nil
+}
+
+func parseVersion(version string) (int32, *httpError) {
+	v, err := strconv.ParseInt(version, 10, 32)
+	if err != nil {
+		return 0, &httpError{"Invalid version " + version, http.StatusBadRequest}
+	}
+
+	return int32(v), nil
+}
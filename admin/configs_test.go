@@ -6,8 +6,14 @@ package admin
 import (
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
+
+	"github.com/square/quotaservice/audit"
+	"github.com/square/quotaservice/config"
+	pb "github.com/square/quotaservice/protos/config"
+	"github.com/square/quotaservice/test/helpers"
 )
 
 func TestConfigsGet(t *testing.T) {
@@ -33,6 +39,35 @@ func TestConfigsGetError(t *testing.T) {
 	}
 }
 
+// pagingAdministrable records the page of configs requested.
+type pagingAdministrable struct {
+	*MockAdministrable
+	offset, limit int
+}
+
+func (p *pagingAdministrable) HistoricalConfigsPage(offset, limit int) ([]*pb.ServiceConfig, error) {
+	p.offset, p.limit = offset, limit
+	return []*pb.ServiceConfig{{Version: 3}, {Version: 2}}, nil
+}
+
+func TestConfigsGetPage(t *testing.T) {
+	a := &pagingAdministrable{MockAdministrable: NewMockAdministrable()}
+
+	configResponse := &configsResponse{}
+	doConfigsRequest(t, a, configResponse, "GET", "/api/configs?offset=10&limit=2", "")
+
+	if len(configResponse.Configs) != 2 || a.offset != 10 || a.limit != 2 {
+		t.Errorf("Expected a page of 2 configs at offset 10; got %+v, offset %v, limit %v", configResponse, a.offset, a.limit)
+	}
+
+	jsonResponse := make(map[string]string)
+	doConfigsRequest(t, a, &jsonResponse, "GET", "/api/configs?limit=-1", "")
+
+	if jsonResponse["description"] != "Invalid limit -1" {
+		t.Errorf("Received \"%s\" from %+v instead of \"Invalid limit -1\"", jsonResponse["description"], jsonResponse)
+	}
+}
+
 func TestConfigsPut(t *testing.T) {
 	a := NewMockAdministrable()
 
@@ -45,6 +80,188 @@ func TestConfigsPut(t *testing.T) {
 	}
 }
 
+// historicalAdministrable has a history of two configs, and records the configs it is updated with.
+type historicalAdministrable struct {
+	*MockAdministrable
+	history  []*pb.ServiceConfig
+	updated  *pb.ServiceConfig
+	expected int32
+	user     string
+}
+
+func newHistoricalAdministrable() *historicalAdministrable {
+	v1 := config.NewDefaultServiceConfig()
+	v1.Version = 1
+	v1.Date = 100
+	helpers.PanicError(config.AddNamespace(v1, config.NewDefaultNamespaceConfig("old")))
+
+	v2 := config.NewDefaultServiceConfig()
+	v2.Version = 2
+	v2.Date = 200
+	helpers.PanicError(config.AddNamespace(v2, config.NewDefaultNamespaceConfig("new")))
+
+	a := &historicalAdministrable{MockAdministrable: NewMockAdministrable(), history: []*pb.ServiceConfig{v2, v1}}
+	a.cfg = v2
+	return a
+}
+
+func (h *historicalAdministrable) HistoricalConfigs() ([]*pb.ServiceConfig, error) {
+	return h.history, nil
+}
+
+func (h *historicalAdministrable) UpdateConfig(c *pb.ServiceConfig, version int32, actor audit.Actor) error {
+	h.updated = c
+	h.expected = version
+	h.user = actor.User
+	return nil
+}
+
+func TestConfigsRollback(t *testing.T) {
+	a := newHistoricalAdministrable()
+
+	response := &rollbackResponse{}
+	doConfigsRequest(t, a, response, "POST", "/api/configs/1/rollback", "")
+
+	if response.Version != 1 || response.CurrentVersion != 2 || response.DryRun {
+		t.Fatalf("Unexpected rollback response %+v", response)
+	}
+
+	if len(response.Diff.Namespaces) != 2 {
+		t.Fatalf("Expected namespace new to be removed and old to be added; got %+v", response.Diff)
+	}
+
+	if a.updated == nil || a.updated.Namespaces["old"] == nil || a.user != "quotaservice" {
+		t.Fatalf("Expected version 1 to be persisted by quotaservice; got %+v by %v", a.updated, a.user)
+	}
+
+	// Without a Version header, only the config diffed against is rolled back.
+	if a.expected != 2 {
+		t.Fatalf("Expected the rollback to be based on version 2; got %v", a.expected)
+	}
+}
+
+func TestConfigsRollbackDryRun(t *testing.T) {
+	a := newHistoricalAdministrable()
+
+	response := &rollbackResponse{}
+	doConfigsRequest(t, a, response, "POST", "/api/configs/1/rollback?dry_run=true", "")
+
+	if !response.DryRun || response.Diff == nil || response.Diff.Empty() {
+		t.Fatalf("Expected a dry run with changes; got %+v", response)
+	}
+
+	if a.updated != nil {
+		t.Fatalf("Dry run should not update the config; got %+v", a.updated)
+	}
+}
+
+func TestConfigsRollbackErrors(t *testing.T) {
+	tests := []struct {
+		method, path, description string
+	}{
+		{"POST", "/api/configs/3/rollback", "Unable to locate config version 3"},
+		{"POST", "/api/configs/x/rollback", "Invalid version x"},
+		{"GET", "/api/configs/1/rollback", "Unknown method GET"},
+	}
+
+	for _, test := range tests {
+		a := newHistoricalAdministrable()
+
+		jsonResponse := make(map[string]string)
+		doConfigsRequest(t, a, &jsonResponse, test.method, test.path, "")
+
+		if jsonResponse["description"] != test.description {
+			t.Errorf("Received \"%s\" from %v %v instead of \"%s\"",
+				jsonResponse["description"], test.method, test.path, test.description)
+		}
+
+		if a.updated != nil {
+			t.Errorf("%v %v should not update the config", test.method, test.path)
+		}
+	}
+}
+
+func TestConfigsDiff(t *testing.T) {
+	a := newHistoricalAdministrable()
+	changed := config.CloneConfig(a.cfg)
+	changed.Version = 3
+	changed.Date = 300
+	changed.Namespaces["new"].MaxDynamicBuckets = 10
+	a.history = append(a.history, changed)
+
+	response := &configDiffResponse{}
+	doConfigsRequest(t, a, response, "GET", "/api/configs/diff?from=2&to=3", "")
+
+	expected := []config.NamespaceDiff{{
+		Name:   "new",
+		Change: config.Changed,
+		Fields: []config.FieldChange{{Field: "max_dynamic_buckets", From: float64(0), To: float64(10)}}}}
+
+	if response.From != 2 || response.To != 3 || !reflect.DeepEqual(response.Diff.Namespaces, expected) {
+		t.Fatalf("Expected diff %+v from 2 to 3; got %+v", expected, response)
+	}
+
+	// Without to, compares against the running config, version 2.
+	response = &configDiffResponse{}
+	doConfigsRequest(t, a, response, "GET", "/api/configs/diff?from=1", "")
+
+	if response.From != 1 || response.To != 2 || len(response.Diff.Namespaces) != 2 {
+		t.Fatalf("Expected namespaces to be added and removed from 1 to 2; got %+v", response)
+	}
+}
+
+func TestConfigsDiffErrors(t *testing.T) {
+	tests := []struct {
+		method, path, description string
+	}{
+		{"GET", "/api/configs/diff", "Missing from parameter"},
+		{"GET", "/api/configs/diff?from=x", "Invalid version x"},
+		{"GET", "/api/configs/diff?from=1&to=7", "Unable to locate config version 7"},
+		{"POST", "/api/configs/diff?from=1", "Unknown method POST"},
+	}
+
+	for _, test := range tests {
+		jsonResponse := make(map[string]string)
+		doConfigsRequest(t, newHistoricalAdministrable(), &jsonResponse, test.method, test.path, "")
+
+		if jsonResponse["description"] != test.description {
+			t.Errorf("Received \"%s\" from %v %v instead of \"%s\"",
+				jsonResponse["description"], test.method, test.path, test.description)
+		}
+	}
+}
+
+// provenanceAdministrable serves layered configs.
+type provenanceAdministrable struct {
+	*MockAdministrable
+}
+
+func (p *provenanceAdministrable) ConfigProvenance() *config.Provenance {
+	return &config.Provenance{
+		Namespaces: map[string]map[string]string{"ns": {"max_dynamic_buckets": "base"}},
+		Buckets:    map[string]map[string]string{"ns:b": {"size": "base", "fill_rate": "overrides"}}}
+}
+
+func TestConfigsProvenance(t *testing.T) {
+	a := &provenanceAdministrable{NewMockAdministrable()}
+
+	response := &config.Provenance{}
+	doConfigsRequest(t, a, response, "GET", "/api/configs/provenance", "")
+
+	if !reflect.DeepEqual(a.ConfigProvenance(), response) {
+		t.Errorf("Expected %+v, got %+v", a.ConfigProvenance(), response)
+	}
+}
+
+func TestConfigsProvenanceUnavailable(t *testing.T) {
+	jsonResponse := make(map[string]string)
+	doConfigsRequest(t, NewMockAdministrable(), &jsonResponse, "GET", "/api/configs/provenance", "")
+
+	if jsonResponse["description"] != "Config provenance is only available for layered configs" {
+		t.Errorf("Received unexpected response %+v", jsonResponse)
+	}
+}
+
 func doConfigsRequest(t *testing.T, a Administrable, object interface{}, method, path, body string) {
 	t.Helper()
 
@@ -0,0 +1,229 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/square/quotaservice/events"
+	"github.com/square/quotaservice/logging"
+)
+
+// Limits on event streams, so that watching events can't overwhelm the server or the client.
+const (
+	// defaultStreamRate is the number of events per second streamed when no rate is specified.
+	defaultStreamRate = 50
+	maxStreamRate     = 1000
+	// streamBufSize is the number of events buffered for a stream that hasn't caught up with its events.
+	streamBufSize = 100
+	// streamStatusInterval is how often streams are told how many events they've missed.
+	streamStatusInterval = 5 * time.Second
+)
+
+type eventsStreamHandler struct {
+	a Administrable
+}
+
+// streamedEvent is an events.Event as streamed to clients.
+type streamedEvent struct {
+	Type       string `json:"type"`
+	Namespace  string `json:"namespace"`
+	Bucket     string `json:"bucket"`
+	Dynamic    bool   `json:"dynamic"`
+	NumTokens  int64  `json:"numTokens"`
+	WaitMillis int64  `json:"waitMillis"`
+}
+
+// streamStatus tells clients how many matching events weren't streamed, because of sampling, the stream's rate
+// limit or because it fell behind.
+type streamStatus struct {
+	Sampled int64 `json:"sampled"`
+	Limited int64 `json:"limited"`
+	Dropped int64 `json:"dropped"`
+}
+
+// eventFilter selects the events to stream.
+type eventFilter struct {
+	namespace, bucket string
+	types             map[events.EventType]bool
+	sample            float64
+	limiter           *rate.Limiter
+	sampled, limited  int64
+}
+
+func newEventsStreamHandler(admin Administrable) *eventsStreamHandler {
+	return &eventsStreamHandler{a: admin}
+}
+
+func (h *eventsStreamHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeStreamError(w, &httpError{"Unknown method " + r.Method, http.StatusBadRequest})
+		return
+	}
+
+	filter, err := parseEventFilter(r.URL.Query())
+
+	if err != nil {
+		writeStreamError(w, err)
+		return
+	}
+
+	subscription, e := h.a.SubscribeEvents(streamBufSize, filter.matches)
+
+	if e == events.ErrTooManySubscribers {
+		writeStreamError(w, &httpError{"Too many event streams", http.StatusServiceUnavailable})
+		return
+	} else if e != nil {
+		writeStreamError(w, &httpError{e.Error(), http.StatusInternalServerError})
+		return
+	}
+
+	defer subscription.Close()
+
+	rc := http.NewResponseController(w)
+	// Streams outlive any write timeout set for ordinary requests.
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	if e := rc.Flush(); e != nil {
+		logging.Error("Cannot stream events", "error", e)
+		return
+	}
+
+	ticker := time.NewTicker(streamStatusInterval)
+	defer ticker.Stop()
+
+	for {
+		var e error
+
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-subscription.Events():
+			if !ok {
+				return
+			}
+
+			e = writeServerSentEvent(w, event.EventType().String(), &streamedEvent{
+				Type:       event.EventType().String(),
+				Namespace:  event.Namespace(),
+				Bucket:     event.BucketName(),
+				Dynamic:    event.Dynamic(),
+				NumTokens:  event.NumTokens(),
+				WaitMillis: event.WaitTime().Milliseconds()})
+		case <-ticker.C:
+			e = writeServerSentEvent(w, "status", &streamStatus{
+				Sampled: atomic.LoadInt64(&filter.sampled),
+				Limited: atomic.LoadInt64(&filter.limited),
+				Dropped: int64(subscription.Dropped())})
+		}
+
+		if e == nil {
+			e = rc.Flush()
+		}
+
+		if e != nil {
+			// The client has gone away.
+			return
+		}
+	}
+}
+
+// parseEventFilter parses the namespace, bucket, type, sample and rate query parameters.
+func parseEventFilter(params url.Values) (*eventFilter, *httpError) {
+	filter := &eventFilter{
+		namespace: params.Get("namespace"),
+		bucket:    params.Get("bucket"),
+		sample:    1,
+		limiter:   rate.NewLimiter(defaultStreamRate, defaultStreamRate)}
+
+	if param := params.Get("type"); param != "" {
+		filter.types = make(map[events.EventType]bool)
+
+		for _, name := range strings.Split(param, ",") {
+			eventType, e := events.ParseEventType(strings.TrimSpace(name))
+			if e != nil {
+				return nil, &httpError{e.Error(), http.StatusBadRequest}
+			}
+
+			filter.types[eventType] = true
+		}
+	}
+
+	if param := params.Get("sample"); param != "" {
+		sample, e := strconv.ParseFloat(param, 64)
+		if e != nil || sample <= 0 || sample > 1 {
+			return nil, &httpError{"sample must be greater than 0 and at most 1", http.StatusBadRequest}
+		}
+
+		filter.sample = sample
+	}
+
+	if param := params.Get("rate"); param != "" {
+		eventsPerSecond, e := strconv.Atoi(param)
+		if e != nil || eventsPerSecond < 1 || eventsPerSecond > maxStreamRate {
+			return nil, &httpError{"rate must be between 1 and " + strconv.Itoa(maxStreamRate), http.StatusBadRequest}
+		}
+
+		filter.limiter = rate.NewLimiter(rate.Limit(eventsPerSecond), eventsPerSecond)
+	}
+
+	return filter, nil
+}
+
+// matches tells whether to stream an event. It's called from the listener passing events on to streams.
+func (f *eventFilter) matches(e events.Event) bool {
+	if f.namespace != "" && e.Namespace() != f.namespace {
+		return false
+	}
+
+	if f.bucket != "" && e.BucketName() != f.bucket {
+		return false
+	}
+
+	if f.types != nil && !f.types[e.EventType()] {
+		return false
+	}
+
+	if f.sample < 1 && rand.Float64() >= f.sample {
+		atomic.AddInt64(&f.sampled, 1)
+		return false
+	}
+
+	if !f.limiter.Allow() {
+		atomic.AddInt64(&f.limited, 1)
+		return false
+	}
+
+	return true
+}
+
+func writeServerSentEvent(w http.ResponseWriter, name string, data interface{}) error {
+	b, e := json.Marshal(data)
+	if e != nil {
+		return e
+	}
+
+	_, e = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, b)
+	return e
+}
+
+// writeStreamError writes an error as JSON, like the rest of the API, since the stream hasn't started.
+func writeStreamError(w http.ResponseWriter, err *httpError) {
+	w.Header().Set("Content-Type", "application/json")
+	writeJSONError(w, err)
+}
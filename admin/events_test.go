@@ -0,0 +1,113 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package admin
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/square/quotaservice/events"
+)
+
+func TestEventsStream(t *testing.T) {
+	a := NewMockAdministrable()
+	ts := httptest.NewServer(loggingHandler(newEventsStreamHandler(a)))
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/api/events/stream?namespace=ns&type=tokens_served,bucket_miss")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	if res.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %v", res.Header.Get("Content-Type"))
+	}
+
+	// Only one stream is allowed by the mock.
+	jsonResponse := make(map[string]string)
+	second, err := http.Get(ts.URL + "/api/events/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := unmarshalJSON(second.Body, &jsonResponse); err != nil || second.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("Expected 503 Service Unavailable, got %v %+v", second.StatusCode, jsonResponse)
+	}
+
+	a.broadcaster.HandleEvent(events.NewTokensServedEvent("other", "b", false, 1, 0))
+	a.broadcaster.HandleEvent(events.NewBucketCreatedEvent("ns", "b", true))
+	a.broadcaster.HandleEvent(events.NewTokensServedEvent("ns", "b", true, 2, 5*time.Millisecond))
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(res.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	expected := []string{
+		"event: EVENT_TOKENS_SERVED",
+		`data: {"type":"EVENT_TOKENS_SERVED","namespace":"ns","bucket":"b","dynamic":true,"numTokens":2,"waitMillis":5}`,
+		""}
+
+	for _, e := range expected {
+		select {
+		case line := <-lines:
+			if line != e {
+				t.Fatalf("Expected %q, got %q", e, line)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for %q", e)
+		}
+	}
+}
+
+func TestEventsStreamErrors(t *testing.T) {
+	for _, query := range []string{"type=unknown", "sample=0", "sample=2", "rate=0", "rate=1001"} {
+		params, _ := url.ParseQuery(query)
+		if _, err := parseEventFilter(params); err == nil || err.status != http.StatusBadRequest {
+			t.Errorf("Expected a bad request parsing %v, got %+v", query, err)
+		}
+	}
+
+	ts := httptest.NewServer(newEventsStreamHandler(NewMockErrorAdministrable()))
+	defer ts.Close()
+
+	res, err := http.Post(ts.URL+"/api/events/stream", "text/plain", strings.NewReader(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jsonResponse := make(map[string]string)
+	if err := unmarshalJSON(res.Body, &jsonResponse); err != nil || jsonResponse["description"] != "Unknown method POST" {
+		t.Errorf("Expected an unknown method error, got %+v", jsonResponse)
+	}
+}
+
+func TestEventFilterRateLimit(t *testing.T) {
+	params, _ := url.ParseQuery("rate=2&sample=1")
+	filter, err := parseEventFilter(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	matched := 0
+	for i := 0; i < 10; i++ {
+		if filter.matches(events.NewBucketMissedEvent("ns", "b", true)) {
+			matched++
+		}
+	}
+
+	if matched != 2 || filter.limited != 8 {
+		t.Errorf("Expected 2 events to match and 8 to be limited, got %v and %v", matched, filter.limited)
+	}
+}
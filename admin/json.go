@@ -9,20 +9,11 @@ import (
 	"io/ioutil"
 	"net/http"
 
+	"github.com/square/quotaservice/config"
 	"github.com/square/quotaservice/logging"
 )
 
-var emptyJSONResponse []byte
-
-func init() {
-	b, e := json.Marshal(make(map[string]string))
-
-	if e != nil {
-		logging.Fatalf("Error setting up empty JSON response! %+v", e)
-	}
-
-	emptyJSONResponse = b
-}
+var emptyJSONResponse = []byte("{}")
 
 func writeJSONError(w http.ResponseWriter, err *httpError) {
 	response := make(map[string]string)
@@ -35,6 +26,32 @@ func writeJSONError(w http.ResponseWriter, err *httpError) {
 	writeJSON(w, response)
 }
 
+type validationErrorResponse struct {
+	Error       string                   `json:"error"`
+	Description string                   `json:"description"`
+	Errors      []config.ValidationError `json:"errors"`
+}
+
+// writeAdministrableError writes an error returned by an Administrable, using the given status unless the
+// error is a config conflict, reported as a 409, or an invalid config, reported as a 422 listing each problem.
+func writeAdministrableError(w http.ResponseWriter, err error, status int) {
+	if v, ok := config.AsValidationErrors(err); ok {
+		logging.Printf("Response error: %v", err)
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		writeJSON(w, &validationErrorResponse{
+			Error:       http.StatusText(http.StatusUnprocessableEntity),
+			Description: err.Error(),
+			Errors:      v})
+		return
+	}
+
+	if config.IsConfigConflict(err) {
+		status = http.StatusConflict
+	}
+
+	writeJSONError(w, &httpError{err.Error(), status})
+}
+
 func writeJSONOk(w http.ResponseWriter) {
 	if _, e := w.Write(emptyJSONResponse); e != nil {
 		logging.Printf("Error writing JSON! %+v", e)
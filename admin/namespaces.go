@@ -22,7 +22,7 @@ func newNamespacesAPIHandler(admin Administrable) (a *namespacesAPIHandler) {
 
 func (a *namespacesAPIHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	ns := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api"), "/")
-	user := getUsername(r)
+	actor, version := getActor(r), expectedVersion(r)
 
 	switch r.Method {
 	case "GET":
@@ -37,10 +37,10 @@ func (a *namespacesAPIHandler) ServeHTTP(w http.ResponseWriter, r *http.Request)
 			return
 		}
 
-		err := a.a.DeleteNamespace(ns, user)
+		err := a.a.DeleteNamespace(ns, version, actor)
 
 		if err != nil {
-			writeJSONError(w, &httpError{err.Error(), http.StatusBadRequest})
+			writeAdministrableError(w, err, http.StatusBadRequest)
 		} else {
 			writeJSONOk(w)
 		}
@@ -51,14 +51,14 @@ func (a *namespacesAPIHandler) ServeHTTP(w http.ResponseWriter, r *http.Request)
 		}
 
 		changeNamespace(w, r, ns, func(c *pb.NamespaceConfig) error {
-			return a.a.UpdateNamespace(c, user)
+			return a.a.UpdateNamespace(c, version, actor)
 		})
 	case "POST":
 		if ns == "" {
 			updateConfig(a, w, r)
 		} else {
 			changeNamespace(w, r, ns, func(c *pb.NamespaceConfig) error {
-				return a.a.AddNamespace(c, user)
+				return a.a.AddNamespace(c, version, actor)
 			})
 		}
 	default:
@@ -93,10 +93,10 @@ func updateConfig(a *namespacesAPIHandler, w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	e = a.a.UpdateConfig(c, getUsername(r))
+	e = a.a.UpdateConfig(c, expectedVersion(r), getActor(r))
 
 	if e != nil {
-		writeJSONError(w, &httpError{e.Error(), http.StatusInternalServerError})
+		writeAdministrableError(w, e, http.StatusInternalServerError)
 	} else {
 		writeJSONOk(w)
 	}
@@ -117,7 +117,7 @@ func changeNamespace(w http.ResponseWriter, r *http.Request, namespace string, u
 	e = updater(c)
 
 	if e != nil {
-		writeJSONError(w, &httpError{e.Error(), http.StatusInternalServerError})
+		writeAdministrableError(w, e, http.StatusInternalServerError)
 	} else {
 		writeJSONOk(w)
 	}
@@ -9,6 +9,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/square/quotaservice/audit"
 	"github.com/square/quotaservice/config"
 	pb "github.com/square/quotaservice/protos/config"
 )
@@ -98,6 +99,32 @@ func TestNamespacesDelete(t *testing.T) {
 	}
 }
 
+// conflictingAdministrable fails all config changes as if they were made concurrently.
+type conflictingAdministrable struct {
+	*MockAdministrable
+}
+
+func (c *conflictingAdministrable) UpdateConfig(*pb.ServiceConfig, int32, audit.Actor) error {
+	return &config.ConfigConflictError{ExpectedHash: "old", ActualHash: "new"}
+}
+
+func (c *conflictingAdministrable) AddNamespace(*pb.NamespaceConfig, int32, audit.Actor) error {
+	return &config.ConfigConflictError{ExpectedHash: "old", ActualHash: "new"}
+}
+
+func TestNamespacesConflict(t *testing.T) {
+	a := &conflictingAdministrable{NewMockAdministrable()}
+
+	for _, path := range []string{"/api/", "/api/test"} {
+		jsonResponse := make(map[string]string)
+		doNamespacesRequest(t, a, &jsonResponse, "POST", path, "")
+
+		if jsonResponse["error"] != http.StatusText(http.StatusConflict) {
+			t.Errorf("Expected 409 Conflict from %v, but received \"%+v\"", path, jsonResponse)
+		}
+	}
+}
+
 func doNamespacesRequest(t *testing.T, a Administrable, object interface{}, method, path, body string) {
 	t.Helper()
 
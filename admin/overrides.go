@@ -0,0 +1,91 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package admin
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	pb "github.com/square/quotaservice/protos/config"
+)
+
+type overridesAPIHandler struct {
+	a Administrable
+}
+
+// overrideRequest is the body of a request to add an override. The override expires either at expires_at or
+// after duration, e.g. "2h".
+type overrideRequest struct {
+	pb.BucketOverride
+	Duration string `json:"duration"`
+}
+
+func newOverridesAPIHandler(admin Administrable) (a *overridesAPIHandler) {
+	return &overridesAPIHandler{a: admin}
+}
+
+func (a *overridesAPIHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// [api, overrides, {namespace}, {bucket}]
+	params := strings.SplitN(strings.Trim(r.URL.Path, "/"), "/", 4)
+	actor, version := getActor(r), expectedVersion(r)
+
+	switch {
+	case r.Method == "GET" && len(params) == 2:
+		overrides := a.a.Configs().Overrides
+		if overrides == nil {
+			overrides = make([]*pb.BucketOverride, 0)
+		}
+
+		writeJSON(w, overrides)
+	case r.Method == "POST" && len(params) == 2:
+		o, httpErr := getOverride(r)
+		if httpErr != nil {
+			writeJSONError(w, httpErr)
+			return
+		}
+
+		if err := a.a.AddOverride(o, version, actor); err != nil {
+			writeAdministrableError(w, err, http.StatusBadRequest)
+		} else {
+			writeJSONOk(w)
+		}
+	case r.Method == "DELETE" && len(params) == 4:
+		if err := a.a.DeleteOverride(params[2], params[3], version, actor); err != nil {
+			writeAdministrableError(w, err, http.StatusBadRequest)
+		} else {
+			writeJSONOk(w)
+		}
+	default:
+		writeJSONError(w, &httpError{"Unknown method " + r.Method + " for " + r.URL.Path, http.StatusBadRequest})
+	}
+}
+
+func getOverride(r *http.Request) (*pb.BucketOverride, *httpError) {
+	req := &overrideRequest{}
+	if err := unmarshalJSON(r.Body, req); err != nil {
+		return nil, &httpError{err.Error(), http.StatusBadRequest}
+	}
+
+	o := &req.BucketOverride
+
+	if req.Duration != "" {
+		if o.ExpiresAt != 0 {
+			return nil, &httpError{"Only one of expires_at and duration may be given", http.StatusBadRequest}
+		}
+
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil {
+			return nil, &httpError{"Unable to parse duration: " + err.Error(), http.StatusBadRequest}
+		}
+
+		o.ExpiresAt = time.Now().Add(d).Unix()
+	}
+
+	if o.Namespace == "" || o.Bucket == "" {
+		return nil, &httpError{"An override needs a namespace and bucket", http.StatusBadRequest}
+	}
+
+	return o, nil
+}
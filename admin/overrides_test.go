@@ -0,0 +1,127 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package admin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/square/quotaservice/audit"
+	pb "github.com/square/quotaservice/protos/config"
+)
+
+// overridesAdministrable records the overrides added and deleted through it.
+type overridesAdministrable struct {
+	*MockAdministrable
+	added   *pb.BucketOverride
+	deleted []string
+}
+
+func (o *overridesAdministrable) AddOverride(override *pb.BucketOverride, version int32, actor audit.Actor) error {
+	o.added = override
+	return o.MockAdministrable.AddOverride(override, version, actor)
+}
+
+func (o *overridesAdministrable) DeleteOverride(namespace, bucket string, version int32, actor audit.Actor) error {
+	o.deleted = []string{namespace, bucket}
+	return o.MockAdministrable.DeleteOverride(namespace, bucket, version, actor)
+}
+
+func TestOverridesGet(t *testing.T) {
+	a := NewMockAdministrable()
+
+	var overrides []*pb.BucketOverride
+	doOverridesRequest(t, a, &overrides, "GET", "/api/overrides", "")
+
+	if overrides == nil || len(overrides) != 0 {
+		t.Errorf("Expected an empty list, got %+v", overrides)
+	}
+
+	a.Configs().Overrides = []*pb.BucketOverride{
+		{Namespace: "ns", Bucket: "b", Config: &pb.BucketConfig{Size: 10}, ExpiresAt: 100}}
+	doOverridesRequest(t, a, &overrides, "GET", "/api/overrides", "")
+
+	if len(overrides) != 1 || overrides[0].Config.Size != 10 || overrides[0].ExpiresAt != 100 {
+		t.Errorf("Received unexpected overrides %+v", overrides)
+	}
+}
+
+func TestOverridesPost(t *testing.T) {
+	a := &overridesAdministrable{MockAdministrable: NewMockAdministrable()}
+
+	jsonResponse := make(map[string]string)
+	doOverridesRequest(t, a, &jsonResponse, "POST", "/api/overrides",
+		`{"namespace": "ns", "bucket": "b", "config": {"size": 10}, "duration": "1h", "reason": "incident"}`)
+
+	if len(jsonResponse) != 0 {
+		t.Fatalf("Received non-empty response \"%+v\"", jsonResponse)
+	}
+
+	expiresAt := time.Now().Add(time.Hour).Unix()
+	if o := a.added; o.Namespace != "ns" || o.Bucket != "b" || o.Config.Size != 10 || o.Reason != "incident" ||
+		o.ExpiresAt < expiresAt-5 || o.ExpiresAt > expiresAt {
+		t.Errorf("Received unexpected override %+v", o)
+	}
+
+	doOverridesRequest(t, a, &jsonResponse, "POST", "/api/overrides",
+		`{"namespace": "ns", "bucket": "b", "config": {"size": 10}, "duration": "1h", "expires_at": 100}`)
+
+	if jsonResponse["description"] != "Only one of expires_at and duration may be given" {
+		t.Errorf("Received unexpected response %+v", jsonResponse)
+	}
+}
+
+func TestOverridesPostError(t *testing.T) {
+	jsonResponse := make(map[string]string)
+	doOverridesRequest(t, NewMockErrorAdministrable(), &jsonResponse, "POST", "/api/overrides",
+		`{"namespace": "ns", "bucket": "b", "expires_at": 100}`)
+
+	if jsonResponse["description"] != "AddOverride" {
+		t.Errorf("Received \"%s\" from %+v instead of AddOverride", jsonResponse["description"], jsonResponse)
+	}
+}
+
+func TestOverridesDelete(t *testing.T) {
+	a := &overridesAdministrable{MockAdministrable: NewMockAdministrable()}
+
+	jsonResponse := make(map[string]string)
+	doOverridesRequest(t, a, &jsonResponse, "DELETE", "/api/overrides/ns/b", "")
+
+	if len(jsonResponse) != 0 || len(a.deleted) != 2 || a.deleted[0] != "ns" || a.deleted[1] != "b" {
+		t.Errorf("Expected override of ns:b to be deleted, got %v with response %+v", a.deleted, jsonResponse)
+	}
+
+	doOverridesRequest(t, a, &jsonResponse, "DELETE", "/api/overrides", "")
+
+	if jsonResponse["description"] != "Unknown method DELETE for /api/overrides" {
+		t.Errorf("Received unexpected response %+v", jsonResponse)
+	}
+}
+
+func doOverridesRequest(t *testing.T, a Administrable, object interface{}, method, path, body string) {
+	t.Helper()
+
+	apiHandler := newOverridesAPIHandler(a)
+	ts := httptest.NewServer(apiHandler)
+	defer ts.Close()
+
+	client := &http.Client{}
+	request, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := client.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = unmarshalJSON(res.Body, &object)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
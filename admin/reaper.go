@@ -0,0 +1,32 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package admin
+
+import (
+	"net/http"
+)
+
+type reaperAPIHandler struct {
+	a Administrable
+}
+
+func newReaperAPIHandler(admin Administrable) (a *reaperAPIHandler) {
+	return &reaperAPIHandler{a: admin}
+}
+
+func (a *reaperAPIHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeJSONError(w, &httpError{"Unknown method " + r.Method, http.StatusBadRequest})
+		return
+	}
+
+	reaperStats := a.a.ReaperStats()
+
+	if reaperStats == nil {
+		writeJSONError(w, &httpError{"Reaper not running", http.StatusServiceUnavailable})
+		return
+	}
+
+	writeJSON(w, reaperStats)
+}
@@ -0,0 +1,65 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package admin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/square/quotaservice/stats"
+)
+
+func TestReaperGet(t *testing.T) {
+	a := NewMockAdministrable()
+
+	reaperStats := &stats.ReaperStats{}
+	doReaperRequest(t, a, reaperStats, "GET", "/api/reaper", "")
+
+	if reaperStats.Watchers != 1 || reaperStats.Reaped["test"] != 2 {
+		t.Errorf("Received %+v instead of [Watchers=1, Reaped=[test:2]]", reaperStats)
+	}
+
+	jsonResponse := make(map[string]string)
+	doReaperRequest(t, a, &jsonResponse, "POST", "/api/reaper", "")
+
+	if jsonResponse["description"] != "Unknown method POST" {
+		t.Errorf("Received \"%s\" from %+v instead of \"Unknown method POST\"",
+			jsonResponse["description"], jsonResponse)
+	}
+
+	a = NewMockErrorAdministrable()
+	jsonResponse = make(map[string]string)
+	doReaperRequest(t, a, &jsonResponse, "GET", "/api/reaper", "")
+
+	if jsonResponse["description"] != "Reaper not running" {
+		t.Errorf("Received \"%s\" from %+v instead of \"Reaper not running\"",
+			jsonResponse["description"], jsonResponse)
+	}
+}
+
+func doReaperRequest(t *testing.T, a Administrable, object interface{}, method, path, body string) {
+	t.Helper()
+
+	apiHandler := newReaperAPIHandler(a)
+	ts := httptest.NewServer(apiHandler)
+	defer ts.Close()
+
+	client := &http.Client{}
+	request, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := client.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = unmarshalJSON(res.Body, &object)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
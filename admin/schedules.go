@@ -0,0 +1,45 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package admin
+
+import (
+	"net/http"
+	"strings"
+)
+
+type schedulesAPIHandler struct {
+	a Administrable
+}
+
+func newSchedulesAPIHandler(admin Administrable) (a *schedulesAPIHandler) {
+	return &schedulesAPIHandler{a: admin}
+}
+
+func (a *schedulesAPIHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeJSONError(w, &httpError{"Unknown method " + r.Method, http.StatusBadRequest})
+		return
+	}
+
+	// [api, schedules, {namespace}, {bucket}]
+	params := strings.SplitN(strings.Trim(r.URL.Path, "/"), "/", 4)
+	states := a.a.BucketSchedules()
+
+	switch len(params) {
+	case 2:
+		writeJSON(w, states)
+	case 4:
+		for _, state := range states {
+			if state.Namespace == params[2] && state.Bucket == params[3] {
+				writeJSON(w, state)
+				return
+			}
+		}
+
+		writeJSONError(w, &httpError{"No schedules for bucket " + params[3] + " in namespace " + params[2],
+			http.StatusNotFound})
+	default:
+		writeJSONError(w, &httpError{"Unknown path " + r.URL.Path, http.StatusNotFound})
+	}
+}
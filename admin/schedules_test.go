@@ -0,0 +1,79 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package admin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/square/quotaservice/config"
+	pb "github.com/square/quotaservice/protos/config"
+)
+
+func schedulesAdministrable() *MockAdministrable {
+	a := NewMockAdministrable()
+	ns := config.NewDefaultNamespaceConfig("ns")
+	ns.Buckets["b"] = &pb.BucketConfig{Name: "b", Size: 10, FillRate: 10, Schedules: []*pb.BucketSchedule{
+		{Name: "always", Start: "* * * * *", DurationMillis: (2 * time.Minute).Milliseconds(), FillRate: 20}}}
+	a.Configs().Namespaces["ns"] = ns
+	return a
+}
+
+func TestSchedulesGet(t *testing.T) {
+	var states []config.ScheduleState
+	doSchedulesRequest(t, schedulesAdministrable(), &states, "GET", "/api/schedules")
+
+	if len(states) != 1 || states[0].Namespace != "ns" || states[0].Bucket != "b" || states[0].Active != "always" {
+		t.Errorf("Received unexpected states %+v", states)
+	}
+
+	if !states[0].NextChange.After(time.Now()) {
+		t.Errorf("Expected the next change to be in the future, got %v", states[0].NextChange)
+	}
+}
+
+func TestSchedulesGetBucket(t *testing.T) {
+	a := schedulesAdministrable()
+
+	state := &config.ScheduleState{}
+	doSchedulesRequest(t, a, state, "GET", "/api/schedules/ns/b")
+
+	if state.Active != "always" {
+		t.Errorf("Received unexpected state %+v", state)
+	}
+
+	jsonResponse := make(map[string]string)
+	doSchedulesRequest(t, a, &jsonResponse, "GET", "/api/schedules/ns/other")
+
+	if jsonResponse["description"] != "No schedules for bucket other in namespace ns" {
+		t.Errorf("Received unexpected response %+v", jsonResponse)
+	}
+}
+
+func doSchedulesRequest(t *testing.T, a Administrable, object interface{}, method, path string) {
+	t.Helper()
+
+	apiHandler := newSchedulesAPIHandler(a)
+	ts := httptest.NewServer(apiHandler)
+	defer ts.Close()
+
+	client := &http.Client{}
+	request, err := http.NewRequest(method, ts.URL+path, strings.NewReader(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := client.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = unmarshalJSON(res.Body, &object)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
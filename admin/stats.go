@@ -5,21 +5,40 @@ package admin
 
 import (
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 
 	"github.com/square/quotaservice/stats"
 )
 
+// maxStatsLimit is the largest number of buckets that can be listed at once.
+const maxStatsLimit = 1000
+
 type statsAPIHandler struct {
 	a Administrable
 }
 
 type bucketStats struct {
 	Ns     string               `json:"namespace"`
+	Window string               `json:"window"`
 	Hits   []*stats.BucketScore `json:"topHits"`
 	Misses []*stats.BucketScore `json:"topMisses"`
 }
 
+type topBuckets struct {
+	Ns     string               `json:"namespace"`
+	Window string               `json:"window"`
+	Metric string               `json:"metric"`
+	Top    []*stats.BucketScore `json:"top"`
+}
+
+type namespaceSummary struct {
+	Ns     string `json:"namespace"`
+	Window string `json:"window"`
+	*stats.NamespaceSummary
+}
+
 func newStatsAPIHandler(admin Administrable) (a *statsAPIHandler) {
 	return &statsAPIHandler{a: admin}
 }
@@ -37,14 +56,50 @@ func (a *statsAPIHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := writeStats(a, w, ns)
+	query, err := parseStatsQuery(r.URL.Query())
+
+	if err == nil {
+		err = writeStats(a, w, ns, query, r.URL.Query().Get("metric") != "")
+	}
 
 	if err != nil {
 		writeJSONError(w, err)
 	}
 }
 
-func writeStats(a *statsAPIHandler, w http.ResponseWriter, path string) *httpError {
+// parseStatsQuery parses the window, metric, limit and static query parameters.
+func parseStatsQuery(params url.Values) (stats.Query, *httpError) {
+	query := stats.Query{Window: stats.DefaultWindow, Limit: stats.DefaultLimit}
+	var e error
+
+	if param := params.Get("window"); param != "" {
+		if query.Window, e = stats.ParseWindow(param); e != nil {
+			return query, &httpError{e.Error(), http.StatusBadRequest}
+		}
+	}
+
+	if param := params.Get("metric"); param != "" {
+		if query.Metric, e = stats.ParseMetric(param); e != nil {
+			return query, &httpError{e.Error(), http.StatusBadRequest}
+		}
+	}
+
+	if param := params.Get("limit"); param != "" {
+		if query.Limit, e = strconv.Atoi(param); e != nil || query.Limit < 1 || query.Limit > maxStatsLimit {
+			return query, &httpError{"limit must be between 1 and " + strconv.Itoa(maxStatsLimit), http.StatusBadRequest}
+		}
+	}
+
+	if param := params.Get("static"); param != "" {
+		if query.IncludeStatic, e = strconv.ParseBool(param); e != nil {
+			return query, &httpError{"static must be true or false", http.StatusBadRequest}
+		}
+	}
+
+	return query, nil
+}
+
+func writeStats(a *statsAPIHandler, w http.ResponseWriter, path string, query stats.Query, byMetric bool) *httpError {
 	params := strings.SplitN(path, "/", 2)
 	namespace := params[0]
 
@@ -55,7 +110,7 @@ func writeStats(a *statsAPIHandler, w http.ResponseWriter, path string) *httpErr
 	}
 
 	if len(params) == 2 {
-		stat := a.a.DynamicBucketStats(namespace, params[1])
+		stat := a.a.BucketStats(namespace, params[1], query)
 
 		if stat == nil {
 			return &httpError{"No stats listener configured", http.StatusBadRequest}
@@ -67,19 +122,75 @@ func writeStats(a *statsAPIHandler, w http.ResponseWriter, path string) *httpErr
 		return nil
 	}
 
-	hits := a.a.TopDynamicHits(namespace)
+	if byMetric {
+		top := a.a.TopBuckets(namespace, query)
+
+		if top == nil {
+			return &httpError{"No stats listener configured", http.StatusBadRequest}
+		}
+
+		writeJSON(w, &topBuckets{namespace, query.Window.String(), query.Metric.String(), top})
+		return nil
+	}
+
+	query.Metric = stats.HITS
+	hits := a.a.TopBuckets(namespace, query)
 
 	if hits == nil {
 		return &httpError{"No stats listener configured", http.StatusBadRequest}
 	}
 
-	misses := a.a.TopDynamicMisses(namespace)
+	query.Metric = stats.MISSES
+	misses := a.a.TopBuckets(namespace, query)
 
 	if misses == nil {
 		return &httpError{"No stats listener configured", http.StatusBadRequest}
 	}
 
-	writeJSON(w, &bucketStats{namespace, hits, misses})
+	writeJSON(w, &bucketStats{namespace, query.Window.String(), hits, misses})
 
 	return nil
 }
+
+type summaryAPIHandler struct {
+	a Administrable
+}
+
+func newSummaryAPIHandler(admin Administrable) (a *summaryAPIHandler) {
+	return &summaryAPIHandler{a: admin}
+}
+
+func (a *summaryAPIHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	namespace := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/summary"), "/")
+
+	if r.Method != "GET" {
+		writeJSONError(w, &httpError{"Unknown method " + r.Method, http.StatusBadRequest})
+		return
+	}
+
+	if namespace == "" {
+		writeJSONError(w, &httpError{"No namespace specified", http.StatusBadRequest})
+		return
+	}
+
+	if _, exists := a.a.Configs().Namespaces[namespace]; !exists {
+		writeJSONError(w, &httpError{"Unable to locate namespace " + namespace, http.StatusNotFound})
+		return
+	}
+
+	query, err := parseStatsQuery(r.URL.Query())
+
+	if err != nil {
+		writeJSONError(w, err)
+		return
+	}
+
+	summary := a.a.NamespaceStats(namespace, query)
+
+	if summary == nil {
+		writeJSONError(w, &httpError{"No stats listener configured", http.StatusBadRequest})
+		return
+	}
+
+	writeJSON(w, &namespaceSummary{namespace, query.Window.String(), summary})
+}
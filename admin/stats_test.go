@@ -6,8 +6,10 @@ package admin
 import (
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/square/quotaservice/config"
 	"github.com/square/quotaservice/stats"
@@ -31,6 +33,13 @@ func TestStatsErrors(t *testing.T) {
 			jsonResponse["description"], jsonResponse)
 	}
 
+	doStatsRequest(t, a, &jsonResponse, "GET", "/api/stats/test?window=2m", "")
+
+	if !strings.HasPrefix(jsonResponse["description"], "unsupported window 2m0s") {
+		t.Errorf("Received \"%s\" from %+v instead of \"unsupported window 2m0s\"",
+			jsonResponse["description"], jsonResponse)
+	}
+
 	doStatsRequest(t, a, &jsonResponse, "GET", "/api/stats/unknown", "")
 
 	if jsonResponse["description"] != "Unable to locate namespace unknown" {
@@ -48,8 +57,14 @@ func TestStatsGet(t *testing.T) {
 	nsResponse := &bucketStats{}
 	doStatsRequest(t, a, nsResponse, "GET", "/api/stats/test", "")
 
-	if nsResponse.Ns != "test" || len(nsResponse.Hits) != 0 || len(nsResponse.Misses) != 0 {
-		t.Errorf("Received %+v instead of [Ns=test, Hits=[], Misses=[]]", nsResponse)
+	if nsResponse.Ns != "test" || nsResponse.Window != "5m0s" || len(nsResponse.Hits) != 0 || len(nsResponse.Misses) != 0 {
+		t.Errorf("Received %+v instead of [Ns=test, Window=5m0s, Hits=[], Misses=[]]", nsResponse)
+	}
+
+	doStatsRequest(t, a, nsResponse, "GET", "/api/stats/test?window=1h", "")
+
+	if nsResponse.Window != "1h0m0s" {
+		t.Errorf("Received %+v instead of [Window=1h0m0s]", nsResponse)
 	}
 
 	a = NewMockErrorAdministrable()
@@ -115,3 +130,87 @@ func doStatsRequest(t *testing.T, a Administrable, object interface{}, method, p
 		t.Fatal(err)
 	}
 }
+
+func TestStatsGetByMetric(t *testing.T) {
+	a := NewMockAdministrable()
+	a.Configs().Namespaces["test"] = config.NewDefaultNamespaceConfig("test")
+
+	response := &topBuckets{}
+	doStatsRequest(t, a, response, "GET", "/api/stats/test?metric=timeouts&limit=5&static=true", "")
+
+	if response.Ns != "test" || response.Metric != "timeouts" || response.Window != "5m0s" || response.Top == nil {
+		t.Errorf("Received %+v instead of [Ns=test, Window=5m0s, Metric=timeouts, Top=[]]", response)
+	}
+}
+
+func TestParseStatsQuery(t *testing.T) {
+	params, _ := url.ParseQuery("window=1m&metric=waitMillis&limit=20&static=true")
+	query, err := parseStatsQuery(params)
+	expected := stats.Query{Window: time.Minute, Metric: stats.WAIT_MILLIS, Limit: 20, IncludeStatic: true}
+
+	if err != nil || query != expected {
+		t.Errorf("Parsed %+v, %v instead of %+v", query, err, expected)
+	}
+
+	query, err = parseStatsQuery(url.Values{})
+	expected = stats.Query{Window: stats.DefaultWindow, Limit: stats.DefaultLimit}
+
+	if err != nil || query != expected {
+		t.Errorf("Parsed %+v, %v instead of %+v", query, err, expected)
+	}
+
+	for _, invalid := range []string{"metric=latency", "limit=0", "limit=1001", "limit=x", "static=maybe", "window=2m"} {
+		params, _ := url.ParseQuery(invalid)
+		if _, err := parseStatsQuery(params); err == nil || err.status != http.StatusBadRequest {
+			t.Errorf("Expected a bad request parsing %v, got %+v", invalid, err)
+		}
+	}
+}
+
+func TestSummaryGet(t *testing.T) {
+	a := NewMockAdministrable()
+	a.Configs().Namespaces["test"] = config.NewDefaultNamespaceConfig("test")
+
+	response := &namespaceSummary{}
+	doSummaryRequest(t, a, response, "/api/summary/test?window=1h")
+
+	if response.Ns != "test" || response.Window != "1h0m0s" || response.Buckets != 1 || response.Hits != 2 ||
+		response.Tokens != 3 {
+		t.Errorf("Received %+v instead of [Ns=test, Window=1h0m0s, Buckets=1, Hits=2, Tokens=3]", response)
+	}
+
+	jsonResponse := make(map[string]string)
+	doSummaryRequest(t, a, &jsonResponse, "/api/summary/unknown")
+
+	if jsonResponse["description"] != "Unable to locate namespace unknown" {
+		t.Errorf("Received \"%s\" from %+v instead of \"Unable to locate namespace unknown\"",
+			jsonResponse["description"], jsonResponse)
+	}
+
+	a = NewMockErrorAdministrable()
+	a.Configs().Namespaces["test"] = config.NewDefaultNamespaceConfig("test")
+	doSummaryRequest(t, a, &jsonResponse, "/api/summary/test")
+
+	if jsonResponse["description"] != "No stats listener configured" {
+		t.Errorf("Received \"%s\" from %+v instead of \"No stats listener configured\"",
+			jsonResponse["description"], jsonResponse)
+	}
+}
+
+func doSummaryRequest(t *testing.T, a Administrable, object interface{}, path string) {
+	t.Helper()
+
+	ts := httptest.NewServer(newSummaryAPIHandler(a))
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = unmarshalJSON(res.Body, &object)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
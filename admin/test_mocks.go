@@ -5,94 +5,142 @@ package admin
 
 import (
 	"errors"
+	"time"
 
+	"github.com/square/quotaservice/alerts"
+	"github.com/square/quotaservice/audit"
 	"github.com/square/quotaservice/config"
+	"github.com/square/quotaservice/events"
 	pb "github.com/square/quotaservice/protos/config"
 	"github.com/square/quotaservice/stats"
 )
 
 type MockAdministrable struct {
-	cfg    *pb.ServiceConfig
-	errors bool
+	cfg         *pb.ServiceConfig
+	errors      bool
+	broadcaster *events.Broadcaster
+	auditSink   *audit.MemorySink
 }
 
 func NewMockErrorAdministrable() *MockAdministrable {
-	return &MockAdministrable{config.NewDefaultServiceConfig(), true}
+	return &MockAdministrable{cfg: config.NewDefaultServiceConfig(), errors: true}
 }
 
 func NewMockAdministrable() *MockAdministrable {
-	return &MockAdministrable{config.NewDefaultServiceConfig(), false}
+	return &MockAdministrable{cfg: config.NewDefaultServiceConfig(), broadcaster: events.NewBroadcaster(1),
+		auditSink: audit.NewMemorySink(audit.MaxLimit)}
 }
 
 func (m *MockAdministrable) Configs() *pb.ServiceConfig {
 	return m.cfg
 }
 
-func (m *MockAdministrable) UpdateConfig(config *pb.ServiceConfig, user string) error {
+// checkVersion fails changes expected to be made to another version of the config, as the server does.
+func (m *MockAdministrable) checkVersion(version int32) error {
+	if version != config.AnyVersion && version != m.cfg.Version {
+		return &config.ConfigConflictError{ExpectedVersion: version, ActualVersion: m.cfg.Version}
+	}
+
+	return nil
+}
+
+func (m *MockAdministrable) UpdateConfig(c *pb.ServiceConfig, version int32, actor audit.Actor) error {
 	if m.errors {
 		return errors.New("UpdateConfig")
 	}
 
-	return nil
+	return m.checkVersion(version)
 }
 
-func (m *MockAdministrable) DeleteBucket(namespace, name, user string) error {
+func (m *MockAdministrable) DeleteBucket(namespace, name string, version int32, actor audit.Actor) error {
 	if m.errors {
 		return errors.New("DeleteBucket")
 	}
 
-	return nil
+	return m.checkVersion(version)
 }
 
-func (m *MockAdministrable) AddBucket(namespace string, b *pb.BucketConfig, user string) error {
+func (m *MockAdministrable) AddBucket(namespace string, b *pb.BucketConfig, version int32, actor audit.Actor) error {
 	if m.errors {
 		return errors.New("AddBucket")
 	}
 
-	return nil
+	return m.checkVersion(version)
 }
 
-func (m *MockAdministrable) UpdateBucket(namespace string, b *pb.BucketConfig, user string) error {
+func (m *MockAdministrable) UpdateBucket(namespace string, b *pb.BucketConfig, version int32, actor audit.Actor) error {
 	if m.errors {
 		return errors.New("UpdateBucket")
 	}
 
-	return nil
+	return m.checkVersion(version)
 }
 
-func (m *MockAdministrable) DeleteNamespace(namespace, user string) error {
+func (m *MockAdministrable) DeleteNamespace(namespace string, version int32, actor audit.Actor) error {
 	if m.errors {
 		return errors.New("DeleteNamespace")
 	}
 
-	return nil
+	return m.checkVersion(version)
 }
 
-func (m *MockAdministrable) AddNamespace(n *pb.NamespaceConfig, user string) error {
+func (m *MockAdministrable) AddNamespace(n *pb.NamespaceConfig, version int32, actor audit.Actor) error {
 	if m.errors {
 		return errors.New("AddNamespace")
 	}
 
-	return nil
+	return m.checkVersion(version)
 }
 
-func (m *MockAdministrable) UpdateNamespace(n *pb.NamespaceConfig, user string) error {
+func (m *MockAdministrable) UpdateNamespace(n *pb.NamespaceConfig, version int32, actor audit.Actor) error {
 	if m.errors {
 		return errors.New("UpdateNamespace")
 	}
 
-	return nil
+	return m.checkVersion(version)
 }
 
-func (m *MockAdministrable) TopDynamicHits(namespace string) []*stats.BucketScore {
+func (m *MockAdministrable) AddOverride(o *pb.BucketOverride, version int32, actor audit.Actor) error {
 	if m.errors {
-		return nil
+		return errors.New("AddOverride")
 	}
 
-	return make([]*stats.BucketScore, 0)
+	return m.checkVersion(version)
+}
+
+func (m *MockAdministrable) DeleteOverride(namespace, bucket string, version int32, actor audit.Actor) error {
+	if m.errors {
+		return errors.New("DeleteOverride")
+	}
+
+	return m.checkVersion(version)
 }
 
-func (m *MockAdministrable) TopDynamicMisses(namespace string) []*stats.BucketScore {
+func (m *MockAdministrable) AddAlertRule(r *pb.AlertRule, version int32, actor audit.Actor) error {
+	if m.errors {
+		return errors.New("AddAlertRule")
+	}
+
+	return m.checkVersion(version)
+}
+
+func (m *MockAdministrable) DeleteAlertRule(name string, version int32, actor audit.Actor) error {
+	if m.errors {
+		return errors.New("DeleteAlertRule")
+	}
+
+	return m.checkVersion(version)
+}
+
+func (m *MockAdministrable) Alerts() []*alerts.Alert {
+	if m.errors {
+		return make([]*alerts.Alert, 0)
+	}
+
+	return []*alerts.Alert{{Rule: "timeouts", Status: alerts.Firing, Namespace: "ns"}}
+}
+
+func (m *MockAdministrable) TopBuckets(namespace string, query stats.Query) []*stats.BucketScore {
 	if m.errors {
 		return nil
 	}
@@ -100,7 +148,7 @@ func (m *MockAdministrable) TopDynamicMisses(namespace string) []*stats.BucketSc
 	return make([]*stats.BucketScore, 0)
 }
 
-func (m *MockAdministrable) DynamicBucketStats(namespace, bucket string) *stats.BucketScores {
+func (m *MockAdministrable) BucketStats(namespace, bucket string, query stats.Query) *stats.BucketScores {
 	if m.errors {
 		return nil
 	}
@@ -108,6 +156,46 @@ func (m *MockAdministrable) DynamicBucketStats(namespace, bucket string) *stats.
 	return &stats.BucketScores{Hits: 0, Misses: 0}
 }
 
+func (m *MockAdministrable) NamespaceStats(namespace string, query stats.Query) *stats.NamespaceSummary {
+	if m.errors {
+		return nil
+	}
+
+	return &stats.NamespaceSummary{BucketScores: stats.BucketScores{Hits: 2, Tokens: 3}, Buckets: 1}
+}
+
+func (m *MockAdministrable) ReaperStats() *stats.ReaperStats {
+	if m.errors {
+		return nil
+	}
+
+	return &stats.ReaperStats{
+		Watchers:          1,
+		Sweeps:            4,
+		Reaped:            map[string]uint64{"test": 2},
+		LastSweepDuration: 250 * time.Millisecond}
+}
+
+func (m *MockAdministrable) DynamicBucketCounts() map[string]int {
+	return map[string]int{"test": 3}
+}
+
+func (m *MockAdministrable) DroppedEvents() map[string]uint64 {
+	return map[string]uint64{"default": 0}
+}
+
+func (m *MockAdministrable) SubscribeEvents(bufSize int, filter func(events.Event) bool) (*events.Subscription, error) {
+	if m.errors {
+		return nil, errors.New("SubscribeEvents")
+	}
+
+	return m.broadcaster.Subscribe(bufSize, filter)
+}
+
+func (m *MockAdministrable) BucketSchedules() []config.ScheduleState {
+	return config.ScheduleStates(m.cfg, time.Now())
+}
+
 func (m *MockAdministrable) HistoricalConfigs() ([]*pb.ServiceConfig, error) {
 	if m.errors {
 		return nil, errors.New("HistoricalConfigs")
@@ -115,3 +203,30 @@ func (m *MockAdministrable) HistoricalConfigs() ([]*pb.ServiceConfig, error) {
 
 	return make([]*pb.ServiceConfig, 1), nil
 }
+
+func (m *MockAdministrable) ConfigProvenance() *config.Provenance {
+	return nil
+}
+
+func (m *MockAdministrable) HistoricalConfigsPage(offset, limit int) ([]*pb.ServiceConfig, error) {
+	if m.errors {
+		return nil, errors.New("HistoricalConfigsPage")
+	}
+
+	return make([]*pb.ServiceConfig, 0), nil
+}
+
+func (m *MockAdministrable) RecordAudit(e *audit.Entry) {
+	if m.auditSink != nil {
+		e.Time = time.Now()
+		_ = m.auditSink.Record(e)
+	}
+}
+
+func (m *MockAdministrable) AuditTrail(q audit.Query) ([]*audit.Entry, error) {
+	if m.errors {
+		return nil, audit.ErrNoSink
+	}
+
+	return m.auditSink.Query(q)
+}
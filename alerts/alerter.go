@@ -0,0 +1,316 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+// Package alerts raises alerts when too many of the requests to a namespace or bucket are rejected, as
+// described by the alert rules of the service config, and notifies webhooks when they fire and resolve.
+package alerts
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/square/quotaservice/events"
+	pb "github.com/square/quotaservice/protos/config"
+)
+
+// EvaluationInterval is how often an Alerter checks its rules once started.
+const EvaluationInterval = 10 * time.Second
+
+// Status tells whether an alert is firing or has resolved.
+type Status string
+
+const (
+	Firing   Status = "firing"
+	Resolved Status = "resolved"
+)
+
+// Alert describes a rule that fired. It's the JSON payload POSTed to the rule's webhooks, both when the rule fires
+// and when it resolves; the rule name and FiredAt together identify the alert.
+type Alert struct {
+	Rule             string  `json:"rule"`
+	Status           Status  `json:"status"`
+	Namespace        string  `json:"namespace"`
+	Bucket           string  `json:"bucket,omitempty"`
+	Condition        string  `json:"condition"`
+	ThresholdPercent float64 `json:"thresholdPercent"`
+	WindowMillis     int64   `json:"windowMillis"`
+	// Requests is the number of requests in the window, Matching those matching the condition and Percent the
+	// share of requests they make up. They're as of the last evaluation of the rule.
+	Requests   int64      `json:"requests"`
+	Matching   int64      `json:"matching"`
+	Percent    float64    `json:"percent"`
+	FiredAt    time.Time  `json:"firedAt"`
+	ResolvedAt *time.Time `json:"resolvedAt,omitempty"`
+}
+
+// Notifier delivers alerts to webhooks. Notify is called without any locks held, but should return quickly.
+type Notifier interface {
+	Notify(webhooks []string, alert *Alert)
+}
+
+// Alerter is an events.Listener counting the requests each alert rule applies to, which evaluates the rules every
+// EvaluationInterval once started. A webhook is notified once when a rule fires, and once when it resolves.
+type Alerter struct {
+	// rules are keyed by namespace, then by name.
+	rules      map[string]map[string]*rule
+	notifier   Notifier
+	now        func() time.Time
+	stop       chan struct{}
+	sync.Mutex // Embedded mutex guards rules and stop
+}
+
+type rule struct {
+	cfg                *pb.AlertRule
+	requests, matching counter
+	// firing is the alert raised by the rule, or nil if it isn't firing.
+	firing *Alert
+}
+
+type notification struct {
+	webhooks []string
+	alert    *Alert
+}
+
+// NewAlerter creates an Alerter without any rules, which notifies webhooks through notifier.
+func NewAlerter(notifier Notifier) *Alerter {
+	return &Alerter{rules: make(map[string]map[string]*rule), notifier: notifier, now: time.Now}
+}
+
+// SetRules replaces the rules. Rules that are unchanged keep counting requests and remain firing; rules that are
+// changed or removed start over, and webhooks are told that any alert they'd raised has resolved.
+func (a *Alerter) SetRules(cfgs []*pb.AlertRule) {
+	a.Lock()
+
+	rules := make(map[string]map[string]*rule)
+	for _, cfg := range cfgs {
+		if cfg == nil {
+			continue
+		}
+
+		if rules[cfg.Namespace] == nil {
+			rules[cfg.Namespace] = make(map[string]*rule)
+		}
+
+		r := a.findLocked(cfg.Name)
+		if r == nil || !proto.Equal(r.cfg, cfg) {
+			r = &rule{cfg: proto.Clone(cfg).(*pb.AlertRule)}
+		}
+
+		rules[cfg.Namespace][cfg.Name] = r
+	}
+
+	var resolved []notification
+	now := a.now()
+
+	for namespace, byName := range a.rules {
+		for name, r := range byName {
+			if r.firing != nil && rules[namespace][name] != r {
+				resolved = append(resolved, r.resolve(now))
+			}
+		}
+	}
+
+	a.rules = rules
+	a.Unlock()
+
+	a.notify(resolved)
+}
+
+func (a *Alerter) findLocked(name string) *rule {
+	for _, byName := range a.rules {
+		if r, ok := byName[name]; ok {
+			return r
+		}
+	}
+
+	return nil
+}
+
+// HandleEvent counts a request against the rules that apply to it. It's an events.Listener.
+func (a *Alerter) HandleEvent(e events.Event) {
+	isRequest, rejected, timedOut := classify(e)
+	if !isRequest {
+		return
+	}
+
+	a.Lock()
+	defer a.Unlock()
+
+	byName := a.rules[e.Namespace()]
+	if len(byName) == 0 {
+		return
+	}
+
+	now := a.now()
+
+	for _, r := range byName {
+		if r.cfg.Bucket != "" && r.cfg.Bucket != e.BucketName() {
+			continue
+		}
+
+		slot := r.slotAt(now)
+		r.requests.add(slot, 1)
+
+		if timedOut || (rejected && r.cfg.Condition == pb.AlertRule_REJECTIONS) {
+			r.matching.add(slot, 1)
+		}
+	}
+}
+
+// classify tells whether an event is a request, and if so whether it was rejected and whether it timed out.
+func classify(e events.Event) (isRequest, rejected, timedOut bool) {
+	switch e.EventType() {
+	case events.EVENT_TOKENS_SERVED:
+		return true, false, false
+	case events.EVENT_TIMEOUT_SERVING_TOKENS:
+		return true, true, true
+	case events.EVENT_TOO_MANY_TOKENS_REQUESTED, events.EVENT_BUCKET_MISS:
+		return true, true, false
+	default:
+		return false, false, false
+	}
+}
+
+// Evaluate checks each rule, notifying webhooks of rules that have fired or resolved since the last evaluation.
+func (a *Alerter) Evaluate() {
+	a.Lock()
+
+	var changed []notification
+	now := a.now()
+
+	for _, byName := range a.rules {
+		for _, r := range byName {
+			if n, ok := r.evaluate(now); ok {
+				changed = append(changed, n)
+			}
+		}
+	}
+
+	a.Unlock()
+
+	a.notify(changed)
+}
+
+// Alerts returns the alerts that are firing, ordered by rule name.
+func (a *Alerter) Alerts() []*Alert {
+	a.Lock()
+	defer a.Unlock()
+
+	alerts := make([]*Alert, 0)
+	for _, byName := range a.rules {
+		for _, r := range byName {
+			if r.firing != nil {
+				alert := *r.firing
+				alerts = append(alerts, &alert)
+			}
+		}
+	}
+
+	sort.Slice(alerts, func(i, j int) bool { return alerts[i].Rule < alerts[j].Rule })
+	return alerts
+}
+
+// Start evaluates the rules every EvaluationInterval, until Stop is called. Starting an Alerter that has already
+// started does nothing.
+func (a *Alerter) Start() {
+	a.Lock()
+	defer a.Unlock()
+
+	if a.stop != nil {
+		return
+	}
+
+	a.stop = make(chan struct{})
+	go a.evaluateEvery(EvaluationInterval, a.stop)
+}
+
+// Stop stops evaluating the rules.
+func (a *Alerter) Stop() {
+	a.Lock()
+	defer a.Unlock()
+
+	if a.stop != nil {
+		close(a.stop)
+		a.stop = nil
+	}
+}
+
+func (a *Alerter) evaluateEvery(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			a.Evaluate()
+		}
+	}
+}
+
+func (a *Alerter) notify(notifications []notification) {
+	for _, n := range notifications {
+		a.notifier.Notify(n.webhooks, n.alert)
+	}
+}
+
+// evaluate checks whether the rule should fire or resolve, returning the notification to send if so.
+func (r *rule) evaluate(now time.Time) (notification, bool) {
+	slot := r.slotAt(now)
+	requests, matching := r.requests.sum(slot), r.matching.sum(slot)
+
+	var percent float64
+	if requests > 0 {
+		percent = float64(matching) * 100 / float64(requests)
+	}
+
+	exceeded := requests > 0 && requests >= r.cfg.MinRequests && percent > r.cfg.ThresholdPercent
+
+	switch {
+	case exceeded && r.firing == nil:
+		r.firing = &Alert{
+			Rule:             r.cfg.Name,
+			Status:           Firing,
+			Namespace:        r.cfg.Namespace,
+			Bucket:           r.cfg.Bucket,
+			Condition:        r.cfg.Condition.String(),
+			ThresholdPercent: r.cfg.ThresholdPercent,
+			WindowMillis:     r.cfg.WindowMillis,
+			FiredAt:          now}
+		r.firing.Requests, r.firing.Matching, r.firing.Percent = requests, matching, percent
+
+		alert := *r.firing
+		return notification{r.cfg.Webhooks, &alert}, true
+	case exceeded:
+		r.firing.Requests, r.firing.Matching, r.firing.Percent = requests, matching, percent
+	case r.firing != nil:
+		r.firing.Requests, r.firing.Matching, r.firing.Percent = requests, matching, percent
+		return r.resolve(now), true
+	}
+
+	return notification{}, false
+}
+
+// resolve marks the rule as no longer firing, returning the notification to send.
+func (r *rule) resolve(now time.Time) notification {
+	alert := *r.firing
+	alert.Status = Resolved
+	alert.ResolvedAt = &now
+	r.firing = nil
+
+	return notification{r.cfg.Webhooks, &alert}
+}
+
+// slotAt returns the slot of the rule's window that t falls in, as the number of slots since the epoch.
+func (r *rule) slotAt(t time.Time) int64 {
+	width := time.Duration(r.cfg.WindowMillis) * time.Millisecond / slotsPerWindow
+	if width <= 0 {
+		width = 1
+	}
+
+	return t.UnixNano() / int64(width)
+}
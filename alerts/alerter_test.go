@@ -0,0 +1,195 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package alerts
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/square/quotaservice/events"
+	pb "github.com/square/quotaservice/protos/config"
+)
+
+type recordingNotifier struct {
+	alerts []*Alert
+	sync.Mutex
+}
+
+func (n *recordingNotifier) Notify(webhooks []string, alert *Alert) {
+	n.Lock()
+	defer n.Unlock()
+	n.alerts = append(n.alerts, alert)
+}
+
+func (n *recordingNotifier) take() []*Alert {
+	n.Lock()
+	defer n.Unlock()
+	alerts := n.alerts
+	n.alerts = nil
+	return alerts
+}
+
+func newTestAlerter(now *time.Time) (*Alerter, *recordingNotifier) {
+	notifier := &recordingNotifier{}
+	a := NewAlerter(notifier)
+	a.now = func() time.Time { return *now }
+	return a, notifier
+}
+
+func timeoutsRule() *pb.AlertRule {
+	return &pb.AlertRule{
+		Name:             "timeouts",
+		Namespace:        "ns",
+		Bucket:           "b",
+		ThresholdPercent: 50,
+		WindowMillis:     time.Minute.Milliseconds(),
+		MinRequests:      4,
+		Webhooks:         []string{"http://example.com"}}
+}
+
+func TestAlertFiresAndResolves(t *testing.T) {
+	now := time.Unix(1000, 0)
+	a, notifier := newTestAlerter(&now)
+	a.SetRules([]*pb.AlertRule{timeoutsRule()})
+
+	// Too few requests for the rule to fire.
+	for i := 0; i < 3; i++ {
+		a.HandleEvent(events.NewTimedOutEvent("ns", "b", false, 1))
+	}
+
+	a.Evaluate()
+	if alerts := notifier.take(); len(alerts) != 0 {
+		t.Fatalf("Expected no alerts below min_requests, got %+v", alerts)
+	}
+
+	// Requests to other buckets, and rejections that aren't timeouts, don't count.
+	a.HandleEvent(events.NewTimedOutEvent("ns", "other", false, 1))
+	a.HandleEvent(events.NewTimedOutEvent("other", "b", false, 1))
+	a.HandleEvent(events.NewBucketMissedEvent("ns", "b", false))
+	a.HandleEvent(events.NewTokensServedEvent("ns", "b", false, 1, 0))
+
+	a.Evaluate()
+	alerts := notifier.take()
+	if len(alerts) != 1 || alerts[0].Status != Firing || alerts[0].Requests != 5 || alerts[0].Matching != 3 ||
+		alerts[0].Percent != 60 || !alerts[0].FiredAt.Equal(now) {
+		t.Fatalf("Expected the rule to fire with 3 of 5 requests timing out, got %+v", alerts)
+	}
+
+	// Alerts are only sent when the rule fires or resolves.
+	a.Evaluate()
+	if alerts := notifier.take(); len(alerts) != 0 {
+		t.Fatalf("Expected no more alerts while firing, got %+v", alerts)
+	}
+
+	if firing := a.Alerts(); len(firing) != 1 || firing[0].Rule != "timeouts" {
+		t.Fatalf("Expected the timeouts alert to be firing, got %+v", firing)
+	}
+
+	// The timeouts roll out of the window.
+	now = now.Add(time.Minute)
+	a.Evaluate()
+	alerts = notifier.take()
+	if len(alerts) != 1 || alerts[0].Status != Resolved || alerts[0].ResolvedAt == nil ||
+		!alerts[0].FiredAt.Equal(time.Unix(1000, 0)) {
+		t.Fatalf("Expected the alert to resolve, got %+v", alerts)
+	}
+
+	if firing := a.Alerts(); len(firing) != 0 {
+		t.Fatalf("Expected no alerts to be firing, got %+v", firing)
+	}
+}
+
+func TestRejectionsAcrossNamespace(t *testing.T) {
+	now := time.Unix(1000, 0)
+	a, notifier := newTestAlerter(&now)
+
+	rule := timeoutsRule()
+	rule.Bucket = ""
+	rule.Condition = pb.AlertRule_REJECTIONS
+	rule.MinRequests = 0
+	a.SetRules([]*pb.AlertRule{rule})
+
+	a.HandleEvent(events.NewBucketMissedEvent("ns", "a", true))
+	a.HandleEvent(events.NewTooManyTokensRequestedEvent("ns", "b", false, 100))
+	a.HandleEvent(events.NewTokensServedEvent("ns", "c", false, 1, 0))
+
+	a.Evaluate()
+	if alerts := notifier.take(); len(alerts) != 1 || alerts[0].Matching != 2 || alerts[0].Requests != 3 {
+		t.Fatalf("Expected the rule to fire with 2 of 3 requests rejected, got %+v", alerts)
+	}
+}
+
+func TestSetRules(t *testing.T) {
+	now := time.Unix(1000, 0)
+	a, notifier := newTestAlerter(&now)
+	rule := timeoutsRule()
+	rule.MinRequests = 0
+	a.SetRules([]*pb.AlertRule{rule})
+
+	a.HandleEvent(events.NewTimedOutEvent("ns", "b", false, 1))
+	a.Evaluate()
+	notifier.take()
+
+	// Changing a rule starts it over.
+	a.SetRules([]*pb.AlertRule{timeoutsRule()})
+	if alerts := notifier.take(); len(alerts) != 1 || alerts[0].Status != Resolved {
+		t.Fatalf("Expected changing the rule to resolve its alert, got %+v", alerts)
+	}
+
+	a.HandleEvent(events.NewTimedOutEvent("ns", "b", false, 1))
+	a.HandleEvent(events.NewTimedOutEvent("ns", "b", false, 1))
+	a.HandleEvent(events.NewTimedOutEvent("ns", "b", false, 1))
+	a.HandleEvent(events.NewTimedOutEvent("ns", "b", false, 1))
+	a.Evaluate()
+	notifier.take()
+
+	// An unchanged rule keeps firing without notifying again.
+	a.SetRules([]*pb.AlertRule{timeoutsRule()})
+	if alerts := notifier.take(); len(alerts) != 0 || len(a.Alerts()) != 1 {
+		t.Fatalf("Expected the unchanged rule to keep firing, got %+v", alerts)
+	}
+
+	a.SetRules(nil)
+	if alerts := notifier.take(); len(alerts) != 1 || alerts[0].Status != Resolved {
+		t.Fatalf("Expected removing the rule to resolve its alert, got %+v", alerts)
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	received := make(chan *Alert, 2)
+	attempts := 0
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Fail the first attempt, which should be retried.
+		if attempts++; attempts == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+
+		alert := &Alert{}
+		if err := json.NewDecoder(r.Body).Decode(alert); err != nil {
+			t.Error(err)
+		}
+
+		received <- alert
+	}))
+	defer ts.Close()
+
+	n := NewWebhookNotifier(time.Second)
+	n.backoff = time.Millisecond
+	n.Notify([]string{ts.URL}, &Alert{Rule: "r", Status: Firing})
+
+	select {
+	case alert := <-received:
+		if alert.Rule != "r" || alert.Status != Firing || attempts != 2 {
+			t.Fatalf("Expected the alert after 2 attempts, got %+v after %v", alert, attempts)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the webhook to be notified")
+	}
+}
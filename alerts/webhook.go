@@ -0,0 +1,112 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package alerts
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/square/quotaservice/logging"
+)
+
+// Defaults for webhook notifications.
+const (
+	DefaultWebhookTimeout = 5 * time.Second
+	// webhookQueueSize is the number of notifications that can be waiting to be sent before more are dropped.
+	webhookQueueSize = 100
+	// webhookAttempts is the number of times a notification is sent before giving up, backing off from
+	// webhookRetryBackoff between attempts.
+	webhookAttempts     = 3
+	webhookRetryBackoff = time.Second
+)
+
+// WebhookNotifier is a Notifier POSTing alerts as JSON. Alerts are sent in order, one at a time, by a goroutine
+// started with the first notification; alerts that can't be queued are dropped.
+type WebhookNotifier struct {
+	client  *http.Client
+	queue   chan notification
+	once    sync.Once
+	dropped uint64
+	backoff time.Duration
+}
+
+// NewWebhookNotifier creates a WebhookNotifier whose requests time out after timeout.
+func NewWebhookNotifier(timeout time.Duration) *WebhookNotifier {
+	return &WebhookNotifier{
+		client:  &http.Client{Timeout: timeout},
+		queue:   make(chan notification, webhookQueueSize),
+		backoff: webhookRetryBackoff}
+}
+
+// Notify queues an alert to be POSTed to each of webhooks.
+func (n *WebhookNotifier) Notify(webhooks []string, alert *Alert) {
+	n.once.Do(func() { go n.send() })
+
+	select {
+	case n.queue <- notification{webhooks, alert}:
+	default:
+		atomic.AddUint64(&n.dropped, 1)
+		logging.Warn("Dropping alert notification; too many are waiting to be sent",
+			"rule", alert.Rule, "status", alert.Status)
+	}
+}
+
+// Dropped returns the number of notifications dropped because too many were waiting to be sent.
+func (n *WebhookNotifier) Dropped() uint64 {
+	return atomic.LoadUint64(&n.dropped)
+}
+
+func (n *WebhookNotifier) send() {
+	for notification := range n.queue {
+		body, err := json.Marshal(notification.alert)
+		if err != nil {
+			logging.Error("Unable to marshal alert", "rule", notification.alert.Rule, "error", err)
+			continue
+		}
+
+		for _, url := range notification.webhooks {
+			if err := n.post(url, body); err != nil {
+				logging.Error("Unable to notify webhook", "rule", notification.alert.Rule,
+					"status", notification.alert.Status, "url", url, "error", err)
+			}
+		}
+	}
+}
+
+// post POSTs body to url, retrying on errors and 5xx responses.
+func (n *WebhookNotifier) post(url string, body []byte) error {
+	var err error
+	backoff := n.backoff
+
+	for attempt := 1; ; attempt++ {
+		var res *http.Response
+		res, err = n.client.Post(url, "application/json", bytes.NewReader(body))
+
+		if err == nil {
+			res.Body.Close()
+
+			switch {
+			case res.StatusCode < 300:
+				return nil
+			case res.StatusCode < 500:
+				// The webhook won't accept the alert however many times it's sent.
+				return fmt.Errorf("webhook responded with %v", res.Status)
+			}
+
+			err = fmt.Errorf("webhook responded with %v", res.Status)
+		}
+
+		if attempt == webhookAttempts {
+			return err
+		}
+
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
@@ -0,0 +1,50 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package alerts
+
+// slotsPerWindow is the number of slots each rule's window is divided into. Requests roll out of the window a slot
+// at a time, so a rule is evaluated over between (slotsPerWindow-1)/slotsPerWindow of its window and all of it.
+const slotsPerWindow = 12
+
+// counter counts requests in a rolling window, with a count per slot.
+type counter struct {
+	slots [slotsPerWindow]int64
+	// newest is the most recent slot counted.
+	newest int64
+}
+
+func (c *counter) add(slot, n int64) {
+	if slot <= c.newest-slotsPerWindow {
+		// Too old to be in the window.
+		return
+	}
+
+	if slot-c.newest >= slotsPerWindow {
+		c.slots = [slotsPerWindow]int64{}
+	} else {
+		// Clear slots that have rolled out of the window since the last count.
+		for s := c.newest + 1; s <= slot; s++ {
+			c.slots[s%slotsPerWindow] = 0
+		}
+	}
+
+	if slot > c.newest {
+		c.newest = slot
+	}
+
+	c.slots[slot%slotsPerWindow] += n
+}
+
+// sum returns the count of the window ending with slot.
+func (c *counter) sum(slot int64) int64 {
+	var total int64
+
+	for s := slot - slotsPerWindow + 1; s <= slot; s++ {
+		if s <= c.newest && s > c.newest-slotsPerWindow {
+			total += c.slots[s%slotsPerWindow]
+		}
+	}
+
+	return total
+}
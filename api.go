@@ -7,6 +7,8 @@ import (
 	"net/http"
 
 	"github.com/square/quotaservice/admin"
+	"github.com/square/quotaservice/alerts"
+	"github.com/square/quotaservice/audit"
 	"github.com/square/quotaservice/config"
 	"github.com/square/quotaservice/events"
 	"github.com/square/quotaservice/logging"
@@ -18,9 +20,18 @@ type Server interface {
 	Start() (bool, error)
 	Stop() (bool, error)
 	SetLogger(logger logging.Logger)
+	SetStructuredLogger(logger logging.StructuredLogger)
 	ServeAdminConsole(*http.ServeMux, string, bool)
+	// SetMetricsHandler sets a handler for ServeAdminConsole to serve on /metrics, such as a Prometheus exporter's.
+	SetMetricsHandler(http.Handler)
 	SetListener(listener events.Listener, eventQueueBufSize int)
+	// AddListener adds a named listener, with its own queue of up to bufSize events, to which dropPolicy
+	// applies when full. Unlike SetListener, listeners may be added after the server has started.
+	AddListener(name string, listener events.Listener, bufSize int, dropPolicy events.DropPolicy) error
 	SetStatsListener(listener stats.Listener)
+	// SetAuditSink sets where the audit trail of config changes and reads of sensitive data is kept. Without one,
+	// nothing is recorded.
+	SetAuditSink(sink audit.Sink)
 	GetServerAdministrable() admin.Administrable
 }
 
@@ -44,6 +55,8 @@ func New(bucketFactory BucketFactory, persister config.ConfigPersister, reaperCo
 		bucketFactory:   bucketFactory,
 		rpcEndpoints:    rpcEndpoints,
 		maxJitterMillis: maxCfgReloadJitterMs,
-		reaperConfig:    reaperConfig}
+		reaperConfig:    reaperConfig,
+		broadcaster:     events.NewBroadcaster(maxEventStreams),
+		alerter:         alerts.NewAlerter(alerts.NewWebhookNotifier(alerts.DefaultWebhookTimeout))}
 	return s
 }
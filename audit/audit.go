@@ -0,0 +1,137 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+// Package audit keeps an append-only trail of changes to the config, and of reads of sensitive data, recording
+// who did what, when, from where and through which interface.
+package audit
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// CLIUserAgent is the User-Agent sent by quotaservice-cli, by which requests from the CLI are told apart from
+// other requests to the admin API.
+const CLIUserAgent = "quotaservice-cli"
+
+// Limits on the number of entries returned by a query.
+const (
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+// ErrNoSink is returned when querying the trail of a server without an audit sink.
+var ErrNoSink = errors.New("no audit sink configured")
+
+// Source is the interface through which an action was taken.
+type Source int
+
+const (
+	// API is the admin API, including the admin console.
+	API Source = iota
+	// CLI is quotaservice-cli.
+	CLI
+	// SERVER is the server itself, e.g. removing expired overrides.
+	SERVER
+)
+
+var sourceNames = []string{
+	API:    "API",
+	CLI:    "CLI",
+	SERVER: "SERVER",
+}
+
+func (s Source) String() string {
+	if s < 0 || int(s) >= len(sourceNames) {
+		return fmt.Sprintf("Source(%d)", int(s))
+	}
+
+	return sourceNames[s]
+}
+
+// MarshalText implements encoding.TextMarshaler, so that sources are named in JSON.
+func (s Source) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *Source) UnmarshalText(text []byte) error {
+	for i, name := range sourceNames {
+		if name == string(text) {
+			*s = Source(i)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown source %q", text)
+}
+
+// Actor identifies who took an action.
+type Actor struct {
+	// User is the user as claimed by the request, e.g. through X-Forwarded-User, which isn't verified.
+	User string `json:"user"`
+	// IP is the address the request came from, which is that of the proxy, if any.
+	IP string `json:"ip,omitempty"`
+	// ForwardedFor is the X-Forwarded-For header of the request, if any.
+	ForwardedFor string `json:"forwarded_for,omitempty"`
+	Source       Source `json:"source"`
+}
+
+// Entry records an action.
+type Entry struct {
+	Time time.Time `json:"time"`
+	Actor
+	// Action names what was done, e.g. add_bucket or read_configs.
+	Action string `json:"action"`
+	// Target is what the action was taken on, e.g. a bucket's fully qualified name, if anything in particular.
+	Target string `json:"target,omitempty"`
+	// Version is the config version created by a change.
+	Version int32 `json:"version,omitempty"`
+	// Changes describes what a change to the config changed.
+	Changes *Changes `json:"changes,omitempty"`
+	// Error is set if the action failed.
+	Error string `json:"error,omitempty"`
+}
+
+// Query selects entries of the trail. Zero values match everything.
+type Query struct {
+	User   string
+	Action string
+	// Since and Until bound the time of entries, inclusively.
+	Since, Until time.Time
+	// Offset skips that many of the newest matching entries, and Limit returns at most that many.
+	Offset, Limit int
+}
+
+// WithDefaults returns the query with DefaultLimit in place of a limit that isn't positive, and MaxLimit in place
+// of one that's larger.
+func (q Query) WithDefaults() Query {
+	if q.Limit <= 0 {
+		q.Limit = DefaultLimit
+	} else if q.Limit > MaxLimit {
+		q.Limit = MaxLimit
+	}
+
+	if q.Offset < 0 {
+		q.Offset = 0
+	}
+
+	return q
+}
+
+// Matches tells whether an entry is selected by the query, ignoring its offset and limit.
+func (q Query) Matches(e *Entry) bool {
+	return (q.User == "" || e.User == q.User) &&
+		(q.Action == "" || e.Action == q.Action) &&
+		(q.Since.IsZero() || !e.Time.Before(q.Since)) &&
+		(q.Until.IsZero() || !e.Time.After(q.Until))
+}
+
+// Sink stores the trail. Entries are only ever appended.
+type Sink interface {
+	// Record appends an entry to the trail.
+	Record(e *Entry) error
+	// Query returns the entries matching a query, newest first.
+	Query(q Query) ([]*Entry, error)
+}
@@ -0,0 +1,142 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package audit
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/square/quotaservice/config"
+	pb "github.com/square/quotaservice/protos/config"
+	"github.com/square/quotaservice/test/helpers"
+)
+
+func TestDiff(t *testing.T) {
+	from := config.NewDefaultServiceConfig()
+	helpers.CheckError(t, config.AddNamespace(from, config.NewDefaultNamespaceConfig("ns")))
+	from.Overrides = []*pb.BucketOverride{{Namespace: "ns", Bucket: "a", ExpiresAt: 1}}
+	from.AlertRules = []*pb.AlertRule{{Name: "kept"}, {Name: "removed"}}
+
+	to := config.CloneConfig(from)
+	helpers.CheckError(t, config.CreateBucket(to, "ns", config.NewDefaultBucketConfig("b")))
+	to.Overrides[0].ExpiresAt = 2
+	to.AlertRules = []*pb.AlertRule{{Name: "kept"}, {Name: "added"}}
+
+	changes := Diff(from, to)
+
+	if len(changes.Namespaces) != 1 || changes.Namespaces[0].Name != "ns" ||
+		len(changes.Namespaces[0].Buckets) != 1 || changes.Namespaces[0].Buckets[0].Name != "b" {
+		t.Errorf("Expecting bucket ns:b to be added, got %+v", changes.ConfigDiff)
+	}
+
+	if expected := []ItemChange{{"ns:a", config.Changed}}; !reflect.DeepEqual(changes.Overrides, expected) {
+		t.Errorf("Expecting overrides %+v, got %+v", expected, changes.Overrides)
+	}
+
+	expected := []ItemChange{{"added", config.Added}, {"removed", config.Removed}}
+	if !reflect.DeepEqual(changes.AlertRules, expected) {
+		t.Errorf("Expecting alert rules %+v, got %+v", expected, changes.AlertRules)
+	}
+
+	if changes.Empty() || !Diff(from, from).Empty() {
+		t.Error("Expecting only a config compared with itself to be unchanged")
+	}
+}
+
+func TestSourceJSON(t *testing.T) {
+	b, err := json.Marshal(&Actor{User: "u", Source: CLI})
+	helpers.CheckError(t, err)
+
+	if string(b) != `{"user":"u","source":"CLI"}` {
+		t.Fatalf("Unexpected JSON %s", b)
+	}
+
+	actor := &Actor{}
+	helpers.CheckError(t, json.Unmarshal(b, actor))
+	if actor.Source != CLI {
+		t.Fatalf("Expecting source CLI, got %v", actor.Source)
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	sink, err := NewFileSink(path)
+	helpers.CheckError(t, err)
+
+	recordEntries(t, sink)
+	helpers.CheckError(t, sink.Close())
+
+	// Entries survive reopening the file.
+	sink, err = NewFileSink(path)
+	helpers.CheckError(t, err)
+	defer func() { _ = sink.Close() }()
+
+	checkQueries(t, sink)
+}
+
+func TestMemorySink(t *testing.T) {
+	sink := NewMemorySink(5)
+	recordEntries(t, sink)
+	checkQueries(t, sink)
+
+	helpers.CheckError(t, sink.Record(&Entry{Action: "newest"}))
+	if entries, _ := sink.Query(Query{Action: "add_bucket"}); len(entries) != 2 {
+		t.Errorf("Expecting the oldest entry to be dropped, got %+v", entries)
+	}
+}
+
+var start = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// recordEntries records 5 entries a minute apart, 3 of them adding buckets.
+func recordEntries(t *testing.T, sink Sink) {
+	for i, action := range []string{"add_bucket", "read_configs", "add_bucket", "read_configs", "add_bucket"} {
+		helpers.CheckError(t, sink.Record(&Entry{
+			Time:    start.Add(time.Duration(i) * time.Minute),
+			Actor:   Actor{User: "u", IP: "10.0.0.1", Source: API},
+			Action:  action,
+			Version: int32(i),
+			Changes: &Changes{Overrides: []ItemChange{{"ns:b", config.Added}}}}))
+	}
+}
+
+func checkQueries(t *testing.T, sink Sink) {
+	t.Helper()
+
+	versions := func(q Query) []int32 {
+		t.Helper()
+		entries, err := sink.Query(q)
+		helpers.CheckError(t, err)
+
+		versions := make([]int32, 0, len(entries))
+		for _, e := range entries {
+			versions = append(versions, e.Version)
+		}
+
+		return versions
+	}
+
+	for _, c := range []struct {
+		q        Query
+		expected []int32
+	}{
+		{Query{}, []int32{4, 3, 2, 1, 0}},
+		{Query{Action: "add_bucket"}, []int32{4, 2, 0}},
+		{Query{Action: "add_bucket", Offset: 1, Limit: 1}, []int32{2}},
+		{Query{Since: start.Add(time.Minute), Until: start.Add(3 * time.Minute)}, []int32{3, 2, 1}},
+		{Query{User: "someone else"}, []int32{}},
+	} {
+		if actual := versions(c.q); !reflect.DeepEqual(actual, c.expected) {
+			t.Errorf("Expecting %+v to return versions %v, got %v", c.q, c.expected, actual)
+		}
+	}
+
+	entries, err := sink.Query(Query{Limit: 1})
+	helpers.CheckError(t, err)
+	if e := entries[0]; e.IP != "10.0.0.1" || len(e.Changes.Overrides) != 1 || !e.Time.Equal(start.Add(4*time.Minute)) {
+		t.Errorf("Expecting the entry to be recorded in full, got %+v", e)
+	}
+}
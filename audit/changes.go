@@ -0,0 +1,88 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package audit
+
+import (
+	"sort"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/square/quotaservice/config"
+	pb "github.com/square/quotaservice/protos/config"
+)
+
+// Changes describes what a change to the config changed: the namespaces and buckets, as compared by
+// config.DiffConfigs, along with the overrides and alert rules, which it doesn't compare.
+type Changes struct {
+	*config.ConfigDiff
+	Overrides  []ItemChange `json:"overrides,omitempty"`
+	AlertRules []ItemChange `json:"alert_rules,omitempty"`
+}
+
+// ItemChange describes an override, named by the fully qualified name of its bucket, or an alert rule that was
+// added, removed or changed.
+type ItemChange struct {
+	Name   string            `json:"name"`
+	Change config.ChangeType `json:"change"`
+}
+
+// Diff compares two configs, returning what changed going from one to the other.
+func Diff(from, to *pb.ServiceConfig) *Changes {
+	if from == nil {
+		from = &pb.ServiceConfig{}
+	}
+
+	if to == nil {
+		to = &pb.ServiceConfig{}
+	}
+
+	overrides := func(cfg *pb.ServiceConfig) map[string]proto.Message {
+		m := make(map[string]proto.Message, len(cfg.Overrides))
+		for _, o := range cfg.Overrides {
+			m[config.FullyQualifiedName(o.Namespace, o.Bucket)] = o
+		}
+
+		return m
+	}
+
+	alertRules := func(cfg *pb.ServiceConfig) map[string]proto.Message {
+		m := make(map[string]proto.Message, len(cfg.AlertRules))
+		for _, r := range cfg.AlertRules {
+			m[r.Name] = r
+		}
+
+		return m
+	}
+
+	return &Changes{
+		ConfigDiff: config.DiffConfigs(from, to),
+		Overrides:  diffItems(overrides(from), overrides(to)),
+		AlertRules: diffItems(alertRules(from), alertRules(to))}
+}
+
+// Empty returns true if nothing changed.
+func (c *Changes) Empty() bool {
+	return c.ConfigDiff.Empty() && len(c.Overrides) == 0 && len(c.AlertRules) == 0
+}
+
+func diffItems(from, to map[string]proto.Message) []ItemChange {
+	var changes []ItemChange
+
+	for name, m := range from {
+		if other, ok := to[name]; !ok {
+			changes = append(changes, ItemChange{name, config.Removed})
+		} else if !proto.Equal(m, other) {
+			changes = append(changes, ItemChange{name, config.Changed})
+		}
+	}
+
+	for name := range to {
+		if _, ok := from[name]; !ok {
+			changes = append(changes, ItemChange{name, config.Added})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	return changes
+}
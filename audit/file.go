@@ -0,0 +1,101 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+// maxEntrySize is the largest entry a FileSink can read back, as a change to a large config can have a large diff.
+const maxEntrySize = 16 * 1024 * 1024
+
+// FileSink is a Sink appending entries to a file as lines of JSON. Each entry is synced to disk before Record
+// returns. Queries read the whole file, so the file should be rotated by renaming it while the server is stopped
+// if it grows too large.
+type FileSink struct {
+	path       string
+	f          *os.File
+	sync.Mutex // Embedded mutex guards f
+}
+
+// NewFileSink opens the file at path for appending, creating it if it doesn't exist.
+func NewFileSink(path string) (*FileSink, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileSink{path: path, f: f}, nil
+}
+
+// Record appends an entry to the file.
+func (s *FileSink) Record(e *Entry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if _, err := s.f.Write(append(b, '\n')); err != nil {
+		return err
+	}
+
+	return s.f.Sync()
+}
+
+// Query reads the entries matching a query from the file, newest first.
+func (s *FileSink) Query(q Query) ([]*Entry, error) {
+	q = q.WithDefaults()
+
+	f, err := os.Open(s.path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var matching []*Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), maxEntrySize)
+
+	for scanner.Scan() {
+		e := &Entry{}
+		if err := json.Unmarshal(scanner.Bytes(), e); err != nil {
+			return nil, err
+		}
+
+		if q.Matches(e) {
+			matching = append(matching, e)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return page(matching, q), nil
+}
+
+// Close closes the file.
+func (s *FileSink) Close() error {
+	s.Lock()
+	defer s.Unlock()
+	return s.f.Close()
+}
+
+// page returns the page of entries selected by a query's offset and limit, newest first, given matching entries
+// oldest first.
+func page(oldestFirst []*Entry, q Query) []*Entry {
+	entries := make([]*Entry, 0, q.Limit)
+
+	for i := len(oldestFirst) - 1 - q.Offset; i >= 0 && len(entries) < q.Limit; i-- {
+		entries = append(entries, oldestFirst[i])
+	}
+
+	return entries
+}
@@ -0,0 +1,49 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package audit
+
+import "sync"
+
+// MemorySink is a Sink keeping the most recent entries in memory, for development and tests. Entries are lost
+// when the server stops.
+type MemorySink struct {
+	entries    []*Entry
+	maxEntries int
+	sync.Mutex // Embedded mutex guards entries
+}
+
+// NewMemorySink creates a MemorySink keeping up to maxEntries entries, dropping the oldest beyond that.
+func NewMemorySink(maxEntries int) *MemorySink {
+	return &MemorySink{maxEntries: maxEntries}
+}
+
+// Record appends an entry.
+func (s *MemorySink) Record(e *Entry) error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.entries = append(s.entries, e)
+	if len(s.entries) > s.maxEntries {
+		s.entries = s.entries[len(s.entries)-s.maxEntries:]
+	}
+
+	return nil
+}
+
+// Query returns the entries matching a query, newest first.
+func (s *MemorySink) Query(q Query) ([]*Entry, error) {
+	q = q.WithDefaults()
+
+	s.Lock()
+	defer s.Unlock()
+
+	var matching []*Entry
+	for _, e := range s.entries {
+		if q.Matches(e) {
+			matching = append(matching, e)
+		}
+	}
+
+	return page(matching, q), nil
+}
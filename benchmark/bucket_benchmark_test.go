@@ -5,6 +5,7 @@
 package benchmark
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
@@ -35,12 +36,12 @@ var benchmarkContainer, _, _ = quotaservice.NewBucketContainerWithMocks(benchmar
 func BenchmarkDynamicBucket(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		bucket := fmt.Sprintf("new.%d", i)
-		_, _ = benchmarkContainer.FindBucket("y", bucket)
+		_, _ = benchmarkContainer.FindBucket(context.Background(), "y", bucket)
 	}
 }
 
 func BenchmarkFindBucket(b *testing.B) {
 	for i := 0; i < b.N; i++ {
-		_, _ = benchmarkContainer.FindBucket("y", "y")
+		_, _ = benchmarkContainer.FindBucket(context.Background(), "y", "y")
 	}
 }
@@ -5,6 +5,7 @@ package quotaservice
 
 import (
 	"bytes"
+	"container/list"
 	"context"
 	"errors"
 	"fmt"
@@ -17,6 +18,8 @@ import (
 	"github.com/square/quotaservice/logging"
 
 	pbconfig "github.com/square/quotaservice/protos/config"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // bucketContainer is a holder for configurations and bucket factories.
@@ -37,7 +40,9 @@ type namespace struct {
 	buckets            map[string]Bucket
 	dynamicBucketCount int32
 	defaultBucket      Bucket
-	sync.RWMutex       // Embedded mutex
+	// Dynamic buckets by most recent activity, if the namespace evicts the least recently used
+	activity     *activityList
+	sync.RWMutex // Embedded mutex
 }
 
 type notifier interface {
@@ -77,6 +82,10 @@ func (ns *namespace) removeBucket(bucketName string) {
 	// Remove this bucket.
 	ns.Lock()
 	defer ns.Unlock()
+	ns.removeBucketLocked(bucketName)
+}
+
+func (ns *namespace) removeBucketLocked(bucketName string) {
 	bucket := ns.buckets[bucketName]
 	if bucket != nil {
 		delete(ns.buckets, bucketName)
@@ -88,6 +97,74 @@ func (ns *namespace) removeBucket(bucketName string) {
 	}
 }
 
+// activityList orders buckets by their most recent activity, most recent first, so that the least recently used
+// can be found without scanning every bucket. Buckets move to the front as they report activity, which happens
+// without holding their namespace's lock, so the list has its own.
+type activityList struct {
+	l *list.List
+	sync.Mutex
+}
+
+func newActivityList() *activityList {
+	return &activityList{l: list.New()}
+}
+
+// add adds a bucket to the front of the list, returning its element.
+func (a *activityList) add(bucketName string) *list.Element {
+	a.Lock()
+	defer a.Unlock()
+	return a.l.PushFront(bucketName)
+}
+
+// touch moves a bucket's element to the front of the list. Elements already removed are ignored.
+func (a *activityList) touch(e *list.Element) {
+	a.Lock()
+	defer a.Unlock()
+	a.l.MoveToFront(e)
+}
+
+func (a *activityList) remove(e *list.Element) {
+	a.Lock()
+	defer a.Unlock()
+	a.l.Remove(e)
+}
+
+// leastRecent returns the name of the least recently used bucket, or false if the list is empty.
+func (a *activityList) leastRecent() (string, bool) {
+	a.Lock()
+	defer a.Unlock()
+
+	if e := a.l.Back(); e != nil {
+		return e.Value.(string), true
+	}
+
+	return "", false
+}
+
+// evictLeastRecentlyUsedLocked removes the dynamic bucket that has gone the longest without reporting
+// activity, to make room for a new dynamic bucket. Returns false if there was nothing to evict.
+// Callers must hold the namespace's write lock.
+func (ns *namespace) evictLeastRecentlyUsedLocked() bool {
+	if ns.activity == nil {
+		return false
+	}
+
+	lruName, ok := ns.activity.leastRecent()
+	if !ok {
+		return false
+	}
+
+	var lruActivity time.Time
+	if rb, ok := ns.buckets[lruName].(*reapableBucket); ok {
+		lruActivity = rb.lastActive()
+	}
+
+	logging.Printf("Evicting bucket %v:%v, last active at %v, to make room for a new dynamic bucket.",
+		ns.name, lruName, lruActivity)
+	ns.removeBucketLocked(lruName)
+	return true
+}
+
 // destroy calls Destroy() on all buckets in this namespace
 func (ns *namespace) destroy() {
 	ns.Lock()
@@ -108,10 +185,55 @@ func (ns *namespace) swapCfg(newCfg *pbconfig.NamespaceConfig) {
 	ns.cfg = newCfg
 }
 
+// replaceChangedBucketsLocked points a namespace at a new config that only differs in the settings of its buckets,
+// as when a schedule starts or ends, replacing just the buckets whose config changed. If the dynamic bucket
+// template changed, dynamic buckets are removed, to be re-created from the new template when next used.
+func (bc *bucketContainer) replaceChangedBucketsLocked(ns *namespace, newCfg *pbconfig.NamespaceConfig) {
+	ns.Lock()
+	defer ns.Unlock()
+
+	oldCfg := ns.cfg
+	ns.cfg = newCfg
+
+	if config.DifferentBucketConfigs(oldCfg.DefaultBucket, newCfg.DefaultBucket) {
+		if ns.defaultBucket != nil {
+			ns.defaultBucket.Destroy()
+			ns.defaultBucket = nil
+		}
+
+		if newCfg.DefaultBucket != nil {
+			ns.defaultBucket = bc.bf.NewBucket(ns.name, config.DefaultBucketName, newCfg.DefaultBucket, false)
+		}
+	}
+
+	templateChanged := config.DifferentBucketConfigs(oldCfg.DynamicBucketTemplate, newCfg.DynamicBucketTemplate)
+
+	var changed []string
+	for name, bucket := range ns.buckets {
+		if bucket.Dynamic() {
+			if templateChanged {
+				changed = append(changed, name)
+			}
+		} else if config.DifferentBucketConfigs(oldCfg.Buckets[name], newCfg.Buckets[name]) {
+			changed = append(changed, name)
+		}
+	}
+
+	for _, name := range changed {
+		ns.removeBucketLocked(name)
+
+		// Static buckets that aren't re-created here are re-created by FindBucket when next used.
+		if bucketCfg := newCfg.Buckets[name]; bucketCfg != nil {
+			bc.createNewNamedBucketFromCfg(ns.name, name, ns, bucketCfg, false)
+		}
+	}
+}
+
 // BucketFactory creates buckets.
 type BucketFactory interface {
-	// Init initializes the bucket factory.
-	Init(cfg *pbconfig.ServiceConfig)
+	// Init initializes the bucket factory with a new config. If it fails, the server keeps using the
+	// previous config.
+	Init(cfg *pbconfig.ServiceConfig) error
 
 	// NewBucket creates a new bucket.
 	NewBucket(namespace, bucketName string, cfg *pbconfig.BucketConfig, dyn bool) Bucket
@@ -132,22 +254,24 @@ func NewBucketContainer(bf BucketFactory, n notifier, r config.ReaperConfig) (bc
 	return
 }
 
-func (bc *bucketContainer) Init(cfg *pbconfig.ServiceConfig) {
+func (bc *bucketContainer) Init(cfg *pbconfig.ServiceConfig) error {
 	bc.Lock()
 	defer bc.Unlock()
 
-	bc.initLocked(cfg)
+	return bc.initLocked(cfg)
 }
 
-func (bc *bucketContainer) initLocked(cfg *pbconfig.ServiceConfig) {
+func (bc *bucketContainer) initLocked(cfg *pbconfig.ServiceConfig) error {
 	if bc.cfg != nil {
-		logging.Fatal("BucketContainer already has a config; cannot be re-initialized")
+		return errors.New("BucketContainer already has a config; cannot be re-initialized")
 	}
+
+	if cfg.GlobalDefaultBucket != nil && bc.defaultBucket != nil {
+		return errors.New("Global default bucket already exists when initializing")
+	}
+
 	bc.cfg = cfg
 	if cfg.GlobalDefaultBucket != nil {
-		if bc.defaultBucket != nil {
-			logging.Fatal("Global default bucket already exists when initializing")
-		}
 		bc.createGlobalDefaultBucketLocked(cfg.GlobalDefaultBucket)
 	}
 
@@ -158,6 +282,8 @@ func (bc *bucketContainer) initLocked(cfg *pbconfig.ServiceConfig) {
 
 		bc.createNamespaceLocked(nsCfg)
 	}
+
+	return nil
 }
 
 func (bc *bucketContainer) createNamespaceLocked(nsCfg *pbconfig.NamespaceConfig) {
@@ -186,13 +312,27 @@ func (bc *bucketContainer) createGlobalDefaultBucketLocked(cfg *pbconfig.BucketC
 // a dynamic bucket is created if enabled (and space for more dynamic buckets is available). If all
 // fails, this function returns nil. This function is thread-safe, and may lazily create dynamic
 // buckets or re-create statically defined buckets that have been invalidated.
-func (bc *bucketContainer) FindBucket(namespace string, bucketName string) (Bucket, error) {
+func (bc *bucketContainer) FindBucket(ctx context.Context, namespace string, bucketName string) (bucket Bucket, err error) {
+	ctx, span := tracer.Start(ctx, "bucketContainer.FindBucket", trace.WithAttributes(
+		NamespaceAttribute.String(namespace), BucketAttribute.String(bucketName)))
+	defer func() {
+		if bucket != nil {
+			span.SetAttributes(DynamicAttribute.Bool(bucket.Dynamic()))
+		}
+
+		if err != nil {
+			span.SetAttributes(OutcomeAttribute.String(OutcomeTooManyBuckets))
+		} else if bucket == nil {
+			span.SetAttributes(OutcomeAttribute.String(OutcomeNoBucket))
+		}
+
+		span.End()
+	}()
+
 	bc.RLock()
 	ns := bc.namespaces[namespace]
 	bc.RUnlock()
 
-	var bucket Bucket
-	var err error
 	reportActivity := true
 
 	if ns == nil {
@@ -205,7 +345,7 @@ func (bc *bucketContainer) FindBucket(namespace string, bucketName string) (Buck
 		ns.RUnlock()
 
 		if bucket == nil {
-			if ns.cfg.DynamicBucketTemplate != nil {
+			if ns.cfg.DynamicBucketTemplate != nil || ns.cfg.Buckets[bucketName] != nil {
 				// Double-checked locking is safe in Golang, since acquiring locks (read or write)
 				// have the same effect as volatile in Java, causing a memory fence being crossed.
 				ns.Lock()
@@ -214,8 +354,8 @@ func (bc *bucketContainer) FindBucket(namespace string, bucketName string) (Buck
 				bucket = ns.buckets[bucketName]
 				if bucket == nil {
 					reportActivity = false // createNewNamedBucket will report activity
-					bucket = bc.createNewNamedBucket(namespace, bucketName, ns)
-					if bucket == nil {
+					bucket = bc.createNewNamedBucket(ctx, namespace, bucketName, ns)
+					if bucket == nil && ns.cfg.Buckets[bucketName] == nil {
 						err = errors.New("Cannot create dynamic bucket")
 					}
 				}
@@ -234,16 +374,28 @@ func (bc *bucketContainer) FindBucket(namespace string, bucketName string) (Buck
 }
 
 // createNewNamedBucket creates a new, named bucket. May return nil if the named bucket is dynamic,
-// and the namespace has already reached its maxDynamicBuckets setting.
-func (bc *bucketContainer) createNewNamedBucket(namespace, bucketName string, ns *namespace) Bucket {
+// and the namespace has already reached its maxDynamicBuckets setting, unless the namespace's eviction
+// policy allows for an existing dynamic bucket to be evicted to make room.
+func (bc *bucketContainer) createNewNamedBucket(ctx context.Context, namespace, bucketName string, ns *namespace) Bucket {
 	bCfg := ns.cfg.Buckets[bucketName]
 	dyn := false
 	if bCfg == nil {
 		// Dynamic.
+		_, span := tracer.Start(ctx, "bucketContainer.createDynamicBucket", trace.WithAttributes(
+			NamespaceAttribute.String(namespace), BucketAttribute.String(bucketName)))
+		defer span.End()
+
 		if ns.dynamicBucketCount >= ns.cfg.MaxDynamicBuckets && ns.cfg.MaxDynamicBuckets > 0 {
-			logging.Printf("Bucket %v:%v numDynamicBuckets=%v maxDynamicBuckets=%v. Not creating more dynamic buckets.",
-				namespace, bucketName, ns.dynamicBucketCount, ns.cfg.MaxDynamicBuckets)
-			return nil
+			evicted := ns.cfg.DynamicBucketEvictionPolicy == pbconfig.NamespaceConfig_EVICT_LRU &&
+				ns.evictLeastRecentlyUsedLocked()
+
+			span.SetAttributes(attribute.Bool("quotaservice.evicted", evicted))
+			if !evicted {
+				logging.Printf("Bucket %v:%v numDynamicBuckets=%v maxDynamicBuckets=%v. Not creating more dynamic buckets.",
+					namespace, bucketName, ns.dynamicBucketCount, ns.cfg.MaxDynamicBuckets)
+				span.SetAttributes(OutcomeAttribute.String(OutcomeTooManyBuckets))
+				return nil
+			}
 		}
 
 		dyn = true
@@ -276,13 +428,23 @@ func (bc *bucketContainer) createNewNamedBucketFromCfg(namespace, bucketName str
 		return nil
 	}
 
-	if dyn {
-		// Apply a watcher if a bucket is dynamic. We don't expire
-		// static buckets since FindBucket won't create a new bucket
-		// for static buckets. Also, removing idle static buckets
-		// won't help much since the number of static buckets is
-		// small.
+	if dyn || bc.r.cfg.ReapsStaticBuckets(namespace) {
+		// Apply a watcher if a bucket is dynamic. Static buckets are
+		// only expired if the reaper is configured to do so, since
+		// the number of static buckets is usually small. FindBucket
+		// re-creates reaped static buckets when they are next used.
 		bucket, _ = bc.r.applyWatch(bucket, namespace, bucketName, bCfg)
+	}
+
+	if dyn {
+		if ns.cfg.DynamicBucketEvictionPolicy == pbconfig.NamespaceConfig_EVICT_LRU {
+			// Eviction needs to know which dynamic bucket was least recently used.
+			if ns.activity == nil {
+				ns.activity = newActivityList()
+			}
+
+			bucket = trackActivity(bucket, bucketName, ns.activity)
+		}
 		ns.dynamicBucketCount++
 	}
 	ns.buckets[bucketName] = bucket
@@ -5,8 +5,11 @@
 package quotaservice
 
 import (
+	"container/list"
+	"context"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/square/quotaservice/config"
 	"github.com/square/quotaservice/test/helpers"
@@ -41,13 +44,21 @@ var cfg = func() *pbconfig.ServiceConfig {
 	helpers.PanicError(config.AddBucket(ns, config.NewDefaultBucketConfig("c")))
 	helpers.PanicError(config.AddNamespace(c, ns))
 
+	// Namespace "lru"
+	ns = config.NewDefaultNamespaceConfig("lru")
+	ns.DynamicBucketTemplate = config.NewDefaultBucketConfig(config.DefaultBucketName)
+	ns.MaxDynamicBuckets = 2
+	ns.DynamicBucketEvictionPolicy = pbconfig.NamespaceConfig_EVICT_LRU
+	helpers.PanicError(config.AddBucket(ns, config.NewDefaultBucketConfig("static")))
+	helpers.PanicError(config.AddNamespace(c, ns))
+
 	return c
 }()
 
 var container, _, _ = NewBucketContainerWithMocks(cfg)
 
 func TestFallbackToGlobalDefaultBucket(t *testing.T) {
-	b, _ := container.FindBucket("nonexistent_namespace", "nonexistent_bucket")
+	b, _ := container.FindBucket(context.Background(), "nonexistent_namespace", "nonexistent_bucket")
 
 	if b == nil {
 		t.Fatal("Should fall back to default bucket.")
@@ -59,7 +70,7 @@ func TestFallbackToGlobalDefaultBucket(t *testing.T) {
 }
 
 func TestFallbackToDefaultBucket(t *testing.T) {
-	b, _ := container.FindBucket("x", "nonexistent_bucket")
+	b, _ := container.FindBucket(context.Background(), "x", "nonexistent_bucket")
 	if b == nil {
 		t.Fatal("Should fall back to default bucket.")
 	}
@@ -71,7 +82,7 @@ func TestFallbackToDefaultBucket(t *testing.T) {
 
 func TestDynamicBucket(t *testing.T) {
 	initGoroutineCount := runtime.NumGoroutine()
-	b, _ := container.FindBucket("y", "new")
+	b, _ := container.FindBucket(context.Background(), "y", "new")
 	if b == nil {
 		t.Fatal("Should create new bucket.")
 	}
@@ -80,7 +91,7 @@ func TestDynamicBucket(t *testing.T) {
 		t.Fatal("Should create new bucket.")
 	}
 
-	b2, _ := container.FindBucket("y", "new")
+	b2, _ := container.FindBucket(context.Background(), "y", "new")
 	if b == nil {
 		t.Fatal("Should return a bucket.")
 	}
@@ -96,7 +107,7 @@ func TestDynamicBucket(t *testing.T) {
 }
 
 func TestBucketNamespaces(t *testing.T) {
-	bx, _ := container.FindBucket("x", "a")
+	bx, _ := container.FindBucket(context.Background(), "x", "a")
 	if bx == nil {
 		t.Fatal("Should create new bucket.")
 	}
@@ -105,7 +116,7 @@ func TestBucketNamespaces(t *testing.T) {
 		t.Fatal("Should create new bucket.")
 	}
 
-	by, _ := container.FindBucket("y", "a")
+	by, _ := container.FindBucket(context.Background(), "y", "a")
 	if by == nil {
 		t.Fatal("Should create new bucket.")
 	}
@@ -126,7 +137,7 @@ func TestMaxDynamic(t *testing.T) {
 	}
 
 	for i := 0; i < 5; i++ {
-		container.createNewNamedBucket("z", strconv.Itoa(i), container.namespaces["z"])
+		container.createNewNamedBucket(context.Background(), "z", strconv.Itoa(i), container.namespaces["z"])
 	}
 
 	c = container.countDynamicBuckets("z")
@@ -134,8 +145,67 @@ func TestMaxDynamic(t *testing.T) {
 		t.Fatalf("Should have 5 dynamic buckets. Instead was %v", c)
 	}
 
-	b := container.createNewNamedBucket("z", "should_fail", container.namespaces["z"])
+	b := container.createNewNamedBucket(context.Background(), "z", "should_fail", container.namespaces["z"])
 	if b != nil {
 		t.Fatal("Should not have created dynamic bucket z:should_fail")
 	}
 }
+
+func TestMaxDynamicWithEviction(t *testing.T) {
+	a, _ := container.FindBucket(context.Background(), "lru", "a")
+	time.Sleep(time.Millisecond)
+	b, _ := container.FindBucket(context.Background(), "lru", "b")
+	time.Sleep(time.Millisecond)
+
+	// Use "a" again, so "b" becomes the least recently used.
+	if b2, _ := container.FindBucket(context.Background(), "lru", "a"); b2 != a {
+		t.Fatal("Should not create a new bucket.")
+	}
+
+	c, err := container.FindBucket(context.Background(), "lru", "c")
+	if c == nil || err != nil {
+		t.Fatalf("Should have evicted a bucket to make room for lru:c. Error: %v", err)
+	}
+
+	if container.Exists("lru", "b") {
+		t.Fatal("lru:b should have been evicted")
+	}
+
+	if !container.Exists("lru", "a") || !container.Exists("lru", "static") {
+		t.Fatal("Only lru:b should have been evicted")
+	}
+
+	if n := container.countDynamicBuckets("lru"); n != 2 {
+		t.Fatalf("Should have 2 dynamic buckets. Instead was %v", n)
+	}
+
+	if b3, _ := container.FindBucket(context.Background(), "lru", "b"); b3 == b {
+		t.Fatal("lru:b should have been re-created")
+	}
+}
+
+func TestActivityList(t *testing.T) {
+	a := newActivityList()
+	elements := make(map[string]*list.Element)
+	for _, name := range []string{"a", "b", "c"} {
+		elements[name] = a.add(name)
+	}
+
+	a.touch(elements["a"])
+	if lru, _ := a.leastRecent(); lru != "b" {
+		t.Fatalf("Expected b to be the least recently used, got %v", lru)
+	}
+
+	a.remove(elements["b"])
+	// Touching a removed element is ignored.
+	a.touch(elements["b"])
+	if lru, _ := a.leastRecent(); lru != "c" {
+		t.Fatalf("Expected c to be the least recently used, got %v", lru)
+	}
+
+	a.remove(elements["a"])
+	a.remove(elements["c"])
+	if _, ok := a.leastRecent(); ok {
+		t.Fatal("Expected the list to be empty")
+	}
+}
@@ -76,13 +76,13 @@ func TestGC(t *testing.T, factory quotaservice.BucketFactory, impl string) {
 
 	eventsEmitter := &quotaservice.MockEmitter{Events: make(chan events.Event, 100)}
 	container := quotaservice.NewBucketContainer(factory, eventsEmitter, quotaservice.NewReaperConfigForTests())
-	container.Init(cfg)
+	helpers.CheckError(t, container.Init(cfg))
 
 	// No GC should happen here as long as we are in use.
 	for i := 0; i < 10; i++ {
 		for j := 0; j < 10; j++ {
 			bName := strconv.Itoa(j)
-			b, _ := container.FindBucket("n", bName)
+			b, _ := container.FindBucket(context.Background(), "n", bName)
 			if b == nil {
 				t.Fatalf("Failed looking for bucket %v on impl %v", bName, impl)
 			}
@@ -17,14 +17,19 @@ import (
 	"github.com/square/quotaservice/logging"
 
 	pbconfig "github.com/square/quotaservice/protos/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("github.com/square/quotaservice/buckets/memory")
+
 type bucketFactory struct {
 	cfg *pbconfig.ServiceConfig
 }
 
-func (bf *bucketFactory) Init(cfg *pbconfig.ServiceConfig) {
+func (bf *bucketFactory) Init(cfg *pbconfig.ServiceConfig) error {
 	bf.cfg = cfg
+	return nil
 }
 
 func (bf *bucketFactory) Client() interface{} {
@@ -39,6 +44,8 @@ func (bf *bucketFactory) NewBucket(namespace, bucketName string, cfg *pbconfig.B
 		nanosBetweenTokens: 1e9 / cfg.FillRate,
 		accumulatedTokens:  cfg.Size, // Start full
 		fullName:           config.FullyQualifiedName(namespace, bucketName),
+		namespace:          namespace,
+		bucketName:         bucketName,
 		waitTimer:          make(chan *waitTimeReq),
 		closer:             make(chan struct{})}
 
@@ -65,6 +72,7 @@ type tokenBucket struct {
 	tokensNextAvailableNanos   int64
 	accumulatedTokens          int64
 	fullName                   string
+	namespace, bucketName      string
 	waitTimer                  chan *waitTimeReq
 	closer                     chan struct{}
 	quotaservice.DefaultBucket // Extension for default methods on interface
@@ -77,7 +85,13 @@ type waitTimeReq struct {
 	response                    chan int64
 }
 
-func (b *tokenBucket) Take(_ context.Context, numTokens int64, maxWaitTime time.Duration) (time.Duration, bool, error) {
+func (b *tokenBucket) Take(ctx context.Context, numTokens int64, maxWaitTime time.Duration) (wait time.Duration, success bool, err error) {
+	_, span := tracer.Start(ctx, "memory.Take", trace.WithAttributes(
+		quotaservice.NamespaceAttribute.String(b.namespace),
+		quotaservice.BucketAttribute.String(b.bucketName),
+		quotaservice.TokensAttribute.Int64(numTokens)))
+	defer func() { quotaservice.EndTakeSpan(span, wait, success, err) }()
+
 	rsp := make(chan int64, 1)
 	b.waitTimer <- &waitTimeReq{numTokens, maxWaitTime.Nanoseconds(), rsp}
 	waitTimeNanos := <-rsp
@@ -9,6 +9,7 @@ import (
 
 	"github.com/square/quotaservice/buckets"
 	"github.com/square/quotaservice/config"
+	"github.com/square/quotaservice/test/helpers"
 )
 
 var factory = NewBucketFactory()
@@ -20,7 +21,7 @@ func TestMain(m *testing.M) {
 }
 
 func setUp() {
-	factory.Init(config.NewDefaultServiceConfig())
+	helpers.PanicError(factory.Init(config.NewDefaultServiceConfig()))
 }
 
 func TestTokenAcquisition(t *testing.T) {
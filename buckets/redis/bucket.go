@@ -10,14 +10,17 @@ import (
 	"strconv"
 	"time"
 
-	"github.com/opentracing/opentracing-go"
 	"github.com/pkg/errors"
 	"github.com/redis/go-redis/v9"
 	"github.com/square/quotaservice"
 	"github.com/square/quotaservice/logging"
 	pbconfig "github.com/square/quotaservice/protos/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("github.com/square/quotaservice/buckets/redis")
+
 // configAttributes represents certain values from a pbconfig.BucketConfig, represented as strings, for easy use as
 // parameters to a Redis call.
 type configAttributes struct {
@@ -31,16 +34,23 @@ type configAttributes struct {
 // abstractBucket contains attributes common to both static and dynamic buckets.
 type abstractBucket struct {
 	*configAttributes
-	cfg     *pbconfig.BucketConfig
-	factory *bucketFactory
-	keys    []string
+	cfg                   *pbconfig.BucketConfig
+	factory               *bucketFactory
+	keys                  []string
+	namespace, bucketName string
 }
 
 func (a *abstractBucket) Config() *pbconfig.BucketConfig {
 	return a.cfg
 }
 
-func (a *abstractBucket) Take(ctx context.Context, requested int64, maxWaitTime time.Duration) (time.Duration, bool, error) {
+func (a *abstractBucket) Take(ctx context.Context, requested int64, maxWaitTime time.Duration) (wait time.Duration, success bool, err error) {
+	ctx, span := tracer.Start(ctx, "redis.Take", trace.WithAttributes(
+		quotaservice.NamespaceAttribute.String(a.namespace),
+		quotaservice.BucketAttribute.String(a.bucketName),
+		quotaservice.TokensAttribute.Int64(requested)))
+	defer func() { quotaservice.EndTakeSpan(span, wait, success, err) }()
+
 	maxIdleTimeMillis := a.maxIdleTimeMillis
 	if a.maxIdleTimeMillis == "0" {
 		// bucket MaxIdleMillis was not set; fall back to factory setting
@@ -77,8 +87,8 @@ func (a *abstractBucket) Take(ctx context.Context, requested int64, maxWaitTime
 }
 
 func (a *abstractBucket) takeFromRedis(ctx context.Context, client redis.UniversalClient, args []interface{}) *redis.Cmd {
-	span, ctx := opentracing.StartSpanFromContext(ctx, "script.Run")
-	defer span.Finish()
+	ctx, span := tracer.Start(ctx, "script.Run")
+	defer span.End()
 	return a.factory.script.Run(ctx, client, a.keys, args...)
 }
 
@@ -111,7 +121,11 @@ func (d *dynamicBucket) Destroy() {
 	d.factory.refcounts[d.cfg.Namespace]--
 
 	if d.factory.refcounts[d.cfg.Namespace] < 0 {
-		logging.Fatalf("Ref counts for %v went negative! refcounts=%+v sharedAttributes=%+v", d.cfg.Namespace, d.factory.refcounts, d.factory.sharedAttributes)
+		// Most likely a bucket destroyed twice. Reset the count rather than exiting, but make it visible.
+		logging.Error("Ref counts went negative; was a dynamic bucket destroyed twice?",
+			"namespace", d.cfg.Namespace, "bucket", d.cfg.Name, "refcounts", d.factory.refcounts,
+			"sharedAttributes", d.factory.sharedAttributes)
+		d.factory.refcounts[d.cfg.Namespace] = 0
 	}
 
 	// If ref-count hits 0, remove common bucket fields
@@ -110,6 +110,7 @@ type bucketFactory struct {
 	redisClusterOpts *redis.ClusterOptions
 
 	script                    *redis.Script
+	hooks                     []redis.Hook
 	connectionRetries         int
 	connectionNeedsResolution bool
 	numTimesConnResolved      int // For testing and debugging purposes
@@ -162,41 +163,49 @@ func NewClusterBucketFactory(redisClusterOpts *redis.ClusterOptions, connectionR
 }
 
 // Init initializes a bucketFactory for use, implementing Init() on the quotaservice.BucketFactory interface
-func (bf *bucketFactory) Init(cfg *pbconfig.ServiceConfig) {
+func (bf *bucketFactory) Init(cfg *pbconfig.ServiceConfig) error {
 	start := time.Now()
 	logging.Printf("Initializing redis.bucketFactory for config version %v", cfg.Version)
 	bf.Lock()
 	defer bf.Unlock()
 
-	bf.cfg = cfg
-
 	if bf.client == nil {
 		connStart := time.Now()
-		bf.connectToRedisLocked()
+		if err := bf.connectToRedisLocked(); err != nil {
+			return err
+		}
 		logging.Printf("Re-established Redis connections in %v", time.Since(connStart))
 	}
 
+	bf.cfg = cfg
 	bf.script = redis.NewScript(luaScript)
 
 	logging.Printf("Initialized redis.BucketFactory in %v", time.Since(start))
+	return nil
 }
 
-func (bf *bucketFactory) connectToRedisLocked() {
+func (bf *bucketFactory) connectToRedisLocked() error {
 	// Set up connection to Redis
 	if bf.redisOpts != nil {
 		bf.client = redis.NewClient(bf.redisOpts)
 	} else if bf.redisClusterOpts != nil {
 		bf.client = redis.NewClusterClient(bf.redisClusterOpts)
 	} else {
-		logging.Fatal("Cannot connect to Redis because no connection options have been provided.")
+		return errors.New("Cannot connect to Redis because no connection options have been provided.")
+	}
+
+	for _, hook := range bf.hooks {
+		bf.client.AddHook(hook)
 	}
 
 	_, err := bf.client.Touch(context.TODO(), "areYouAlive?").Result()
 	if err != nil {
-		logging.Printf("Cannot connect to Redis. TOUCH returned %v", err)
+		logging.Warn("Cannot connect to Redis", "command", "TOUCH", "error", err)
 	} else {
 		logging.Printf("Connection established")
 	}
+
+	return nil
 }
 
 func (bf *bucketFactory) reconnectToRedis(oldClient redis.UniversalClient) {
@@ -209,7 +218,9 @@ func (bf *bucketFactory) reconnectToRedis(oldClient redis.UniversalClient) {
 	}
 
 	if oldClient == bf.client {
-		bf.connectToRedisLocked()
+		if err := bf.connectToRedisLocked(); err != nil {
+			logging.Error("Cannot reconnect to Redis", "error", err)
+		}
 	}
 }
 
@@ -270,6 +281,24 @@ func (bf *bucketFactory) establishNewConnectionToRedis(oldClient redis.Universal
 	logging.Printf("Handler has resolved %v connection(s) so far", bf.numTimesConnResolved)
 }
 
+// AddHook instruments the Redis calls of a bucket factory created by this package, e.g. to measure how long token
+// buckets take to run their script. The hook is added to every Redis client the factory uses, including clients
+// created when reconnecting.
+func AddHook(factory quotaservice.BucketFactory, hook redis.Hook) {
+	bf, ok := factory.(*bucketFactory)
+	if !ok {
+		panic(fmt.Sprintf("Cannot add a Redis hook to bucket factory %T", factory))
+	}
+
+	bf.Lock()
+	defer bf.Unlock()
+
+	bf.hooks = append(bf.hooks, hook)
+	if bf.client != nil {
+		bf.client.AddHook(hook)
+	}
+}
+
 func (bf *bucketFactory) getNumTimesConnResolved() int {
 	bf.Lock()
 	defer bf.Unlock()
@@ -318,6 +347,8 @@ func (bf *bucketFactory) NewBucket(namespace, bucketName string, cfg *pbconfig.B
 				cfg:              cfg,
 				factory:          bf,
 				keys:             keys,
+				namespace:        namespace,
+				bucketName:       bucketName,
 			}}
 	} else {
 		// Create a staticBucket with its own non-shared configAttributes
@@ -327,6 +358,8 @@ func (bf *bucketFactory) NewBucket(namespace, bucketName string, cfg *pbconfig.B
 				cfg:              cfg,
 				factory:          bf,
 				keys:             keys,
+				namespace:        namespace,
+				bucketName:       bucketName,
 			}}
 	}
 }
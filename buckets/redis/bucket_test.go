@@ -14,6 +14,7 @@ import (
 	"github.com/square/quotaservice/buckets"
 	"github.com/square/quotaservice/config"
 	quotaservice_configs "github.com/square/quotaservice/protos/config"
+	"github.com/square/quotaservice/test/helpers"
 )
 
 const (
@@ -41,7 +42,7 @@ func setUp() {
 	config.AddNamespace(cfg, dynNs)
 
 	factory = NewBucketFactory(&redis.Options{Addr: "localhost:6379"}, 2, 0).(*bucketFactory)
-	factory.Init(cfg)
+	helpers.PanicError(factory.Init(cfg))
 	bucket = factory.NewBucket("redis", "redis", config.NewDefaultBucketConfig(""), false).(*staticBucket)
 }
 
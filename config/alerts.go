@@ -0,0 +1,50 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package config
+
+import (
+	"errors"
+
+	pb "github.com/square/quotaservice/protos/config"
+)
+
+// minAlertWindowMillis is the shortest window an alert rule may be evaluated over, which is long enough to span
+// several evaluations.
+const minAlertWindowMillis = 60 * 1000
+
+func findAlertRule(cfg *pb.ServiceConfig, name string) int {
+	for i, r := range cfg.AlertRules {
+		if r != nil && r.Name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// AddAlertRule adds an alert rule to a config, replacing any existing rule of the same name.
+func AddAlertRule(clonedCfg *pb.ServiceConfig, r *pb.AlertRule) error {
+	if clonedCfg.Namespaces[r.Namespace] == nil {
+		return errors.New("No such namespace " + r.Namespace + ".")
+	}
+
+	if i := findAlertRule(clonedCfg, r.Name); i >= 0 {
+		clonedCfg.AlertRules[i] = r
+	} else {
+		clonedCfg.AlertRules = append(clonedCfg.AlertRules, r)
+	}
+
+	return nil
+}
+
+// RemoveAlertRule removes an alert rule from a config.
+func RemoveAlertRule(clonedCfg *pb.ServiceConfig, name string) error {
+	i := findAlertRule(clonedCfg, name)
+	if i < 0 {
+		return errors.New("No such alert rule " + name + ".")
+	}
+
+	clonedCfg.AlertRules = append(clonedCfg.AlertRules[:i], clonedCfg.AlertRules[i+1:]...)
+	return nil
+}
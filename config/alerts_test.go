@@ -0,0 +1,56 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package config
+
+import (
+	"testing"
+
+	pb "github.com/square/quotaservice/protos/config"
+	"github.com/square/quotaservice/test/helpers"
+)
+
+func TestAddAndRemoveAlertRule(t *testing.T) {
+	cfg := overridesTestConfig()
+
+	if err := AddAlertRule(cfg, &pb.AlertRule{Name: "r", Namespace: "missing"}); err == nil {
+		t.Error("Expected an error adding a rule for a missing namespace")
+	}
+
+	rule := func(name string, threshold float64) *pb.AlertRule {
+		return &pb.AlertRule{Name: name, Namespace: "ns", ThresholdPercent: threshold, WindowMillis: 60000,
+			Webhooks: []string{"https://example.com/hook"}}
+	}
+
+	helpers.CheckError(t, AddAlertRule(cfg, rule("timeouts", 10)))
+	helpers.CheckError(t, AddAlertRule(cfg, rule("rejections", 20)))
+	helpers.CheckError(t, AddAlertRule(cfg, rule("timeouts", 30)))
+
+	if len(cfg.AlertRules) != 2 || cfg.AlertRules[0].ThresholdPercent != 30 {
+		t.Fatalf("Expected the second timeouts rule to replace the first, got %v", cfg.AlertRules)
+	}
+
+	if errs := Validate(cfg); len(errs) != 0 {
+		t.Fatalf("Expected config to be valid; got %v", errs)
+	}
+
+	helpers.CheckError(t, RemoveAlertRule(cfg, "timeouts"))
+
+	if err := RemoveAlertRule(cfg, "timeouts"); err == nil {
+		t.Error("Expected an error removing a missing rule")
+	}
+
+	if len(cfg.AlertRules) != 1 || cfg.AlertRules[0].Name != "rejections" {
+		t.Fatalf("Expected only the rejections rule to remain, got %v", cfg.AlertRules)
+	}
+
+	pruned := Reconcile(cfg, overridesTestConfig(), true)
+	if len(pruned.AlertRules) != 1 {
+		t.Errorf("Expected reconciling to keep the alert rule, got %v", pruned.AlertRules)
+	}
+
+	delete(pruned.Namespaces, "ns")
+	if pruned = Reconcile(cfg, pruned, true); len(pruned.AlertRules) != 0 {
+		t.Errorf("Expected reconciling to drop the rule of a removed namespace, got %v", pruned.AlertRules)
+	}
+}
@@ -19,13 +19,14 @@
 package google
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"cloud.google.com/go/datastore"
-	"github.com/golang/protobuf/proto"
 	"golang.org/x/net/context"
 	"google.golang.org/api/option"
 
@@ -35,6 +36,8 @@ import (
 	pb "github.com/square/quotaservice/protos/config"
 )
 
+var errNoConfigs = errors.New("no configurations stored")
+
 // storedEntity stores the configuration as a serialized protobuf, and some metadata about the configuration.
 // storedEntities use a named key, of the format "version:{version_int}", to make it efficient to retrieve a
 // specific record based on version.
@@ -55,21 +58,38 @@ type DatastoreConfigPersister struct {
 	*internal.Notifier
 	version     int
 	newVersions chan int
+
+	retentionLock sync.Mutex
+	retention     config.RetentionPolicy
+	pruner        *internal.Pruner
 }
 
+// PersistAndNotify persists a configuration passed in. If oldHash is not empty, it is compared against the hash of
+// the most recent configuration. Since entities are keyed on version, a concurrent writer that persisted the same
+// version first is also reported as a conflict.
 func (p *DatastoreConfigPersister) PersistAndNotify(oldHash string, cfg *pb.ServiceConfig) error {
-	// TODO(manik) Optimistic version check with oldHash
-
-	b, e := proto.Marshal(cfg)
+	b, e := config.MarshalBytes(cfg)
 	if e != nil {
 		return e
 	}
+
+	if oldHash != "" {
+		currentHash, e := p.latestHash()
+		if e != nil {
+			return e
+		}
+
+		if e = config.CheckConfigHash(oldHash, currentHash); e != nil {
+			return e
+		}
+	}
+
 	// Persist...
 	s := &storedEntity{Contents: b,
 		Version: cfg.Version,
 		Date:    time.Unix(cfg.Date, 0),
 		User:    cfg.User,
-		Hash:    config.HashConfigBytes(b)}
+		Hash:    config.HashConfig(cfg)}
 
 	// TODO(manik) datastore key should be the hash, not version?
 	k := datastore.NameKey(p.entity, fmt.Sprintf("version:%v", cfg.Version), nil)
@@ -84,8 +104,9 @@ func (p *DatastoreConfigPersister) PersistAndNotify(oldHash string, cfg *pb.Serv
 
 		if e == nil {
 			if existing.Hash != s.Hash {
-				// Hashes don't match, likely to be a bug.
-				return fmt.Errorf("Attempting to write configuration with version %v and hash %v. Datastore already contains a configuration with the same version, with hash %v.", cfg.Version, s.Hash, existing.Hash)
+				// Someone else persisted this version first.
+				logging.Printf("Attempting to write configuration with version %v and hash %v. Datastore already contains a configuration with the same version, with hash %v.", cfg.Version, s.Hash, existing.Hash)
+				return &config.ConfigConflictError{ExpectedHash: oldHash, ActualHash: existing.Hash}
 			}
 
 			// This version already exists. Do not overwrite.
@@ -117,6 +138,7 @@ func (p *DatastoreConfigPersister) ConfigChangedWatcher() <-chan struct{} {
 
 // Close closes the notification channel.
 func (p *DatastoreConfigPersister) Close() {
+	p.SetRetentionPolicy(config.RetentionPolicy{})
 	close(p.Notifier.Watcher)
 }
 
@@ -148,6 +170,10 @@ func (p *DatastoreConfigPersister) getLatest(keyOnly bool) (*datastore.Key, *sto
 		return nil, nil, e
 	}
 
+	if len(keys) == 0 {
+		return nil, nil, errNoConfigs
+	}
+
 	if len(keys) != 1 {
 		return nil, nil, fmt.Errorf("expected 1 result, got %v result(s)", len(keys))
 	}
@@ -155,6 +181,26 @@ func (p *DatastoreConfigPersister) getLatest(keyOnly bool) (*datastore.Key, *sto
 	return keys[0], entities[0], nil
 }
 
+// latestHash returns the hash of the most recent configuration, or an empty string if there is none.
+func (p *DatastoreConfigPersister) latestHash() (string, error) {
+	_, s, e := p.getLatest(false)
+	if e == errNoConfigs {
+		return "", nil
+	}
+
+	if e != nil {
+		return "", e
+	}
+
+	// Hashes stored by older versions weren't computed deterministically, so recompute.
+	cfg, e := config.UnmarshalBytes(s.Contents)
+	if e != nil {
+		return "", e
+	}
+
+	return config.HashConfig(cfg), nil
+}
+
 func (p *DatastoreConfigPersister) ReadHistoricalConfigs() ([]*pb.ServiceConfig, error) {
 	var entities []*storedEntity
 	var e error
@@ -179,6 +225,80 @@ func (p *DatastoreConfigPersister) ReadHistoricalConfigs() ([]*pb.ServiceConfig,
 	return res, nil
 }
 
+// ReadHistoricalConfigsPage returns a page of previously persisted configs, newest first.
+func (p *DatastoreConfigPersister) ReadHistoricalConfigsPage(offset, limit int) ([]*pb.ServiceConfig, error) {
+	q := datastore.NewQuery(p.entity).
+		Namespace(p.namespace).
+		Order("-Version").
+		Offset(offset)
+
+	if limit > 0 {
+		q = q.Limit(limit)
+	}
+
+	var entities []*storedEntity
+	if _, e := p.client.GetAll(context.Background(), q, &entities); e != nil {
+		return nil, e
+	}
+
+	res := make([]*pb.ServiceConfig, len(entities))
+
+	for i, t := range entities {
+		var e error
+		if res[i], e = config.UnmarshalBytes(t.Contents); e != nil {
+			return nil, e
+		}
+	}
+
+	return res, nil
+}
+
+// SetRetentionPolicy sets the policy used to prune historical configs, pruning in the background if the
+// policy is enabled.
+func (p *DatastoreConfigPersister) SetRetentionPolicy(policy config.RetentionPolicy) {
+	p.retentionLock.Lock()
+	old := p.pruner
+	p.retention = policy
+	p.pruner = nil
+	if policy.Enabled() {
+		p.pruner = internal.NewPruner(policy.Interval(), p.Prune)
+	}
+	p.retentionLock.Unlock()
+
+	old.Stop()
+}
+
+// Prune deletes historical configs that fall outside the retention policy from Datastore. The latest version
+// is never deleted.
+func (p *DatastoreConfigPersister) Prune() error {
+	p.retentionLock.Lock()
+	policy := p.retention
+	p.retentionLock.Unlock()
+
+	var entities []*storedEntity
+	keys, e := p.client.GetAll(context.Background(),
+		datastore.NewQuery(p.entity).
+			Namespace(p.namespace).
+			Order("-Version"),
+		&entities)
+	if e != nil || len(keys) == 0 {
+		return e
+	}
+
+	configs := make(map[*datastore.Key]*pb.ServiceConfig, len(keys))
+	for i, k := range keys {
+		configs[k] = &pb.ServiceConfig{Version: entities[i].Version, Date: entities[i].Date.Unix()}
+	}
+
+	expired := config.ExpiredConfigs(policy, configs, keys[0], time.Now())
+	if len(expired) == 0 {
+		return nil
+	}
+
+	logging.Printf("Pruning %v configurations", len(expired))
+	return p.client.DeleteMulti(context.Background(), expired)
+}
+
 func (p *DatastoreConfigPersister) poll(pollingDuration time.Duration) {
 	t := time.NewTicker(pollingDuration)
 	for {
@@ -0,0 +1,634 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package config
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/square/quotaservice/config/internal"
+	"github.com/square/quotaservice/logging"
+	pb "github.com/square/quotaservice/protos/config"
+)
+
+// ConfigLayer is one of the persisters merged by a CompositeConfigPersister.
+type ConfigLayer struct {
+	// Name identifies the layer in provenance, e.g. "base" or "overrides".
+	Name      string
+	Persister ConfigPersister
+	// TTL, if positive, is how long a config persisted to this layer remains in effect, counted from the
+	// config's Date. Expired configs are ignored.
+	TTL time.Duration
+}
+
+func (l *ConfigLayer) expiry(cfg *pb.ServiceConfig) time.Time {
+	if l.TTL <= 0 || cfg == nil {
+		return time.Time{}
+	}
+
+	return time.Unix(cfg.Date, 0).Add(l.TTL)
+}
+
+func (l *ConfigLayer) expired(cfg *pb.ServiceConfig, now time.Time) bool {
+	e := l.expiry(cfg)
+	return !e.IsZero() && !now.Before(e)
+}
+
+// Provenance records which layer each setting of a merged config came from. Fields are named as in the YAML
+// representation of the config.
+type Provenance struct {
+	// Namespace name -> field -> layer name
+	Namespaces map[string]map[string]string `json:"namespaces"`
+	// Fully qualified bucket name, as returned by FQN -> field -> layer name
+	Buckets map[string]map[string]string `json:"buckets"`
+}
+
+func newProvenance() *Provenance {
+	return &Provenance{
+		Namespaces: make(map[string]map[string]string),
+		Buckets:    make(map[string]map[string]string)}
+}
+
+// Bucket returns the layer each of a bucket's fields came from. Use DefaultBucketName and
+// DynamicBucketTemplateName for a namespace's default bucket and dynamic bucket template, and GlobalNamespace
+// for the global default bucket.
+func (p *Provenance) Bucket(namespace, name string) map[string]string {
+	return p.Buckets[FullyQualifiedName(namespace, name)]
+}
+
+func (p *Provenance) clone() *Provenance {
+	c := newProvenance()
+	for k, v := range p.Namespaces {
+		c.Namespaces[k] = cloneFields(v)
+	}
+
+	for k, v := range p.Buckets {
+		c.Buckets[k] = cloneFields(v)
+	}
+
+	return c
+}
+
+func cloneFields(fields map[string]string) map[string]string {
+	c := make(map[string]string, len(fields))
+	for k, v := range fields {
+		c[k] = v
+	}
+
+	return c
+}
+
+func setProvenance(m map[string]map[string]string, key, field, layer string) {
+	if m[key] == nil {
+		m[key] = make(map[string]string)
+	}
+
+	m[key][field] = layer
+}
+
+// The bucket fields that can be overridden, by the layers of a CompositeConfigPersister or by a BucketOverride,
+// named as in YAML.
+var overridableBucketFields = []struct {
+	name  string
+	field func(*pb.BucketConfig) *int64
+}{
+	{"size", func(b *pb.BucketConfig) *int64 { return &b.Size }},
+	{"fill_rate", func(b *pb.BucketConfig) *int64 { return &b.FillRate }},
+	{"wait_timeout_millis", func(b *pb.BucketConfig) *int64 { return &b.WaitTimeoutMillis }},
+	{"max_idle_millis", func(b *pb.BucketConfig) *int64 { return &b.MaxIdleMillis }},
+	{"max_debt_millis", func(b *pb.BucketConfig) *int64 { return &b.MaxDebtMillis }},
+	{"max_tokens_per_request", func(b *pb.BucketConfig) *int64 { return &b.MaxTokensPerRequest }},
+}
+
+// CompositeConfigPersister merges the configs of an ordered list of layers into one effective config. Later
+// layers take precedence: namespaces and buckets they define are added, and the fields they set (to
+// non-zero values) override those of earlier layers.
+//
+// Configs are persisted to the last layer, which only stores what differs from the layers below it. This
+// allows, for example, emergency overrides made through the admin console to be layered on top of a base
+// config kept in version control, expiring after the override layer's TTL. Namespaces and buckets defined by
+// lower layers can't be removed through the last layer, and fields can't be overridden with zero values.
+type CompositeConfigPersister struct {
+	layers []ConfigLayer
+
+	// The most recent config read from each layer
+	configs []*pb.ServiceConfig
+	// The hash of the most recent config read from the last layer, so that configs are only persisted to it if
+	// no one else has since. Empty if nothing was read, in which case nothing is checked.
+	topHash string
+
+	merged     *pb.ServiceConfig
+	provenance *Provenance
+
+	expiryTimer *time.Timer
+	closed      bool
+
+	notifier *internal.Notifier
+	wg       sync.WaitGroup
+	sync.RWMutex
+}
+
+// NewCompositeConfigPersister merges layers, lowest precedence first. Configs are persisted to the last
+// layer. The CompositeConfigPersister takes ownership of the layers' persisters, closing them when closed.
+func NewCompositeConfigPersister(layers ...ConfigLayer) (*CompositeConfigPersister, error) {
+	if len(layers) == 0 {
+		return nil, errors.New("a CompositeConfigPersister needs at least one layer")
+	}
+
+	c := &CompositeConfigPersister{
+		layers:   layers,
+		configs:  make([]*pb.ServiceConfig, len(layers)),
+		notifier: internal.NewNotifier()}
+
+	c.Lock()
+	for i := range layers {
+		c.readLayerLocked(i)
+	}
+	c.remergeLocked()
+	c.Unlock()
+
+	c.notifier.Notify()
+
+	for i := range layers {
+		c.wg.Add(1)
+		go c.watchLayer(i)
+	}
+
+	return c, nil
+}
+
+// watchLayer re-merges whenever a layer's config changes, until the layer is closed.
+func (c *CompositeConfigPersister) watchLayer(i int) {
+	defer c.wg.Done()
+
+	for range c.layers[i].Persister.ConfigChangedWatcher() {
+		c.Lock()
+		c.readLayerLocked(i)
+		changed := c.remergeLocked()
+		c.Unlock()
+
+		if changed {
+			logging.Printf("Config layer %v changed", c.layers[i].Name)
+			c.notifier.Notify()
+		}
+	}
+}
+
+// readLayerLocked reads a layer's config. If it can't be read, the previous config read is kept.
+func (c *CompositeConfigPersister) readLayerLocked(i int) {
+	cfg, err := c.layers[i].Persister.ReadPersistedConfig()
+	if err != nil {
+		logging.Printf("Unable to read config layer %v: %v", c.layers[i].Name, err)
+		return
+	}
+
+	c.configs[i] = cfg
+	if i == len(c.layers)-1 {
+		c.topHash = ""
+		if cfg != nil {
+			c.topHash = HashConfig(cfg)
+		}
+	}
+}
+
+// expire re-merges once a layer's config has expired.
+func (c *CompositeConfigPersister) expire() {
+	c.Lock()
+	if c.closed {
+		c.Unlock()
+		return
+	}
+
+	changed := c.remergeLocked()
+	c.Unlock()
+
+	if changed {
+		logging.Print("Config layer expired")
+		c.notifier.Notify()
+	}
+}
+
+// remergeLocked merges the layers' configs, returning true if the merged config changed. The merged config's
+// version is incremented whenever it changes, as a layer expiring changes the merged config without
+// changing any layer's version.
+func (c *CompositeConfigPersister) remergeLocked() bool {
+	now := time.Now()
+	merged, provenance := c.mergeLocked(len(c.layers), now)
+
+	version := int32(0)
+	for _, cfg := range c.configs {
+		if cfg != nil && cfg.Version > version {
+			version = cfg.Version
+		}
+	}
+
+	if c.merged != nil {
+		merged.Version = c.merged.Version
+		if HashConfig(merged) != HashConfig(c.merged) {
+			version = max(version, c.merged.Version+1)
+		} else {
+			version = max(version, c.merged.Version)
+		}
+	}
+
+	merged.Version = version
+	changed := c.merged == nil || HashConfig(merged) != HashConfig(c.merged)
+	c.merged = merged
+	c.provenance = provenance
+
+	c.scheduleExpiryLocked(now)
+
+	return changed
+}
+
+// mergeLocked merges the first n layers that haven't expired.
+func (c *CompositeConfigPersister) mergeLocked(n int, now time.Time) (*pb.ServiceConfig, *Provenance) {
+	merged := &pb.ServiceConfig{Namespaces: make(map[string]*pb.NamespaceConfig)}
+	provenance := newProvenance()
+
+	for i := 0; i < n; i++ {
+		cfg := c.configs[i]
+		if cfg == nil || c.layers[i].expired(cfg, now) {
+			continue
+		}
+
+		mergeConfig(merged, cfg, c.layers[i].Name, provenance)
+		merged.User = cfg.User
+		merged.Date = cfg.Date
+	}
+
+	// Expired overrides can only be removed from the layer that defines them, so leave them out.
+	RemoveExpiredOverrides(merged, now)
+	ApplyDefaults(merged)
+	return merged, provenance
+}
+
+func (c *CompositeConfigPersister) scheduleExpiryLocked(now time.Time) {
+	if c.expiryTimer != nil {
+		c.expiryTimer.Stop()
+		c.expiryTimer = nil
+	}
+
+	var next time.Time
+	for i, cfg := range c.configs {
+		e := c.layers[i].expiry(cfg)
+		if e.After(now) && (next.IsZero() || e.Before(next)) {
+			next = e
+		}
+	}
+
+	if !next.IsZero() {
+		c.expiryTimer = time.AfterFunc(next.Sub(now), c.expire)
+	}
+}
+
+func mergeConfig(dst, src *pb.ServiceConfig, layer string, provenance *Provenance) {
+	mergeBucket(&dst.GlobalDefaultBucket, src.GlobalDefaultBucket,
+		FullyQualifiedName(GlobalNamespace, DefaultBucketName), layer, provenance)
+
+	for name, ns := range src.Namespaces {
+		if ns == nil {
+			continue
+		}
+
+		dstNs := dst.Namespaces[name]
+		added := dstNs == nil
+		if added {
+			dstNs = NewDefaultNamespaceConfig(name)
+			dst.Namespaces[name] = dstNs
+		}
+
+		if added || ns.MaxDynamicBuckets != 0 {
+			dstNs.MaxDynamicBuckets = ns.MaxDynamicBuckets
+			setProvenance(provenance.Namespaces, name, "max_dynamic_buckets", layer)
+		}
+
+		if added || ns.DynamicBucketEvictionPolicy != 0 {
+			dstNs.DynamicBucketEvictionPolicy = ns.DynamicBucketEvictionPolicy
+			setProvenance(provenance.Namespaces, name, "dynamic_bucket_eviction_policy", layer)
+		}
+
+		mergeBucket(&dstNs.DefaultBucket, ns.DefaultBucket,
+			FullyQualifiedName(name, DefaultBucketName), layer, provenance)
+		mergeBucket(&dstNs.DynamicBucketTemplate, ns.DynamicBucketTemplate,
+			FullyQualifiedName(name, DynamicBucketTemplateName), layer, provenance)
+
+		for bucketName, b := range ns.Buckets {
+			dstBucket := dstNs.Buckets[bucketName]
+			mergeBucket(&dstBucket, b, FullyQualifiedName(name, bucketName), layer, provenance)
+			if dstBucket != nil {
+				dstNs.Buckets[bucketName] = dstBucket
+			}
+		}
+	}
+
+	// An override replaces any override of the same bucket by a lower layer.
+	for _, o := range src.Overrides {
+		if o != nil {
+			_ = AddOverride(dst, proto.Clone(o).(*pb.BucketOverride))
+		}
+	}
+
+	// As does an alert rule of the same name.
+	for _, r := range src.AlertRules {
+		if r != nil {
+			_ = AddAlertRule(dst, proto.Clone(r).(*pb.AlertRule))
+		}
+	}
+}
+
+func mergeBucket(dst **pb.BucketConfig, src *pb.BucketConfig, fqn, layer string, provenance *Provenance) {
+	if src == nil {
+		return
+	}
+
+	added := *dst == nil
+	if added {
+		*dst = &pb.BucketConfig{}
+	}
+
+	for _, f := range overridableBucketFields {
+		if v := *f.field(src); added || v != 0 {
+			*f.field(*dst) = v
+			setProvenance(provenance.Buckets, fqn, f.name, layer)
+		}
+	}
+
+	if added || len(src.Schedules) > 0 {
+		(*dst).Schedules = cloneSchedules(src.Schedules)
+		setProvenance(provenance.Buckets, fqn, "schedules", layer)
+	}
+}
+
+func cloneSchedules(schedules []*pb.BucketSchedule) []*pb.BucketSchedule {
+	var cloned []*pb.BucketSchedule
+	for _, s := range schedules {
+		cloned = append(cloned, proto.Clone(s).(*pb.BucketSchedule))
+	}
+
+	return cloned
+}
+
+// override returns the config to persist to the last layer so that merging it over lower produces desired.
+func override(lower, desired *pb.ServiceConfig) (*pb.ServiceConfig, error) {
+	var errs ValidationErrors
+	o := &pb.ServiceConfig{
+		Namespaces: make(map[string]*pb.NamespaceConfig),
+		Version:    desired.Version,
+		User:       desired.User,
+		Date:       desired.Date}
+
+	o.GlobalDefaultBucket = overrideBucket(lower.GlobalDefaultBucket, desired.GlobalDefaultBucket,
+		"global_default_bucket", &errs)
+
+	for _, name := range sortedKeys(lower.Namespaces) {
+		if desired.Namespaces[name] == nil {
+			errs = append(errs, ValidationError{"namespaces." + name, "is defined by a lower layer, so can't be removed"})
+		}
+	}
+
+	for _, name := range sortedKeys(desired.Namespaces) {
+		ns, lowerNs := desired.Namespaces[name], lower.Namespaces[name]
+		if ns == nil {
+			continue
+		}
+
+		if lowerNs == nil {
+			o.Namespaces[name] = proto.Clone(ns).(*pb.NamespaceConfig)
+			continue
+		}
+
+		path := "namespaces." + name
+		oNs := NewDefaultNamespaceConfig(name)
+		changed := false
+
+		if ns.MaxDynamicBuckets != lowerNs.MaxDynamicBuckets {
+			oNs.MaxDynamicBuckets = ns.MaxDynamicBuckets
+			changed = true
+			if ns.MaxDynamicBuckets == 0 {
+				errs = append(errs, ValidationError{path + ".max_dynamic_buckets", "can't be overridden with a zero value"})
+			}
+		}
+
+		if ns.DynamicBucketEvictionPolicy != lowerNs.DynamicBucketEvictionPolicy {
+			oNs.DynamicBucketEvictionPolicy = ns.DynamicBucketEvictionPolicy
+			changed = true
+			if ns.DynamicBucketEvictionPolicy == 0 {
+				errs = append(errs, ValidationError{path + ".dynamic_bucket_eviction_policy", "can't be overridden with a zero value"})
+			}
+		}
+
+		oNs.DefaultBucket = overrideBucket(lowerNs.DefaultBucket, ns.DefaultBucket, path+".default_bucket", &errs)
+		oNs.DynamicBucketTemplate = overrideBucket(lowerNs.DynamicBucketTemplate, ns.DynamicBucketTemplate,
+			path+".dynamic_bucket_template", &errs)
+		changed = changed || oNs.DefaultBucket != nil || oNs.DynamicBucketTemplate != nil
+
+		for _, bucketName := range mergedKeys(lowerNs.Buckets, ns.Buckets) {
+			b := overrideBucket(lowerNs.Buckets[bucketName], ns.Buckets[bucketName],
+				path+".buckets."+bucketName, &errs)
+			if b != nil {
+				oNs.Buckets[bucketName] = b
+				changed = true
+			}
+		}
+
+		if changed {
+			o.Namespaces[name] = oNs
+		}
+	}
+
+	for _, lowerO := range lower.Overrides {
+		if findOverride(desired, lowerO.Namespace, lowerO.Bucket) < 0 {
+			errs = append(errs, ValidationError{"overrides", "override of " +
+				FullyQualifiedName(lowerO.Namespace, lowerO.Bucket) + " is defined by a lower layer, so can't be removed"})
+		}
+	}
+
+	for _, desiredO := range desired.Overrides {
+		if i := findOverride(lower, desiredO.Namespace, desiredO.Bucket); i < 0 || !proto.Equal(lower.Overrides[i], desiredO) {
+			o.Overrides = append(o.Overrides, proto.Clone(desiredO).(*pb.BucketOverride))
+		}
+	}
+
+	for _, lowerR := range lower.AlertRules {
+		if findAlertRule(desired, lowerR.Name) < 0 {
+			errs = append(errs, ValidationError{"alert_rules", "alert rule " + lowerR.Name +
+				" is defined by a lower layer, so can't be removed"})
+		}
+	}
+
+	for _, desiredR := range desired.AlertRules {
+		if i := findAlertRule(lower, desiredR.Name); i < 0 || !proto.Equal(lower.AlertRules[i], desiredR) {
+			o.AlertRules = append(o.AlertRules, proto.Clone(desiredR).(*pb.AlertRule))
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return o, nil
+}
+
+func overrideBucket(lower, desired *pb.BucketConfig, path string, errs *ValidationErrors) *pb.BucketConfig {
+	if desired == nil {
+		if lower != nil {
+			*errs = append(*errs, ValidationError{path, "is defined by a lower layer, so can't be removed"})
+		}
+
+		return nil
+	}
+
+	if lower == nil {
+		return proto.Clone(desired).(*pb.BucketConfig)
+	}
+
+	var o *pb.BucketConfig
+	for _, f := range overridableBucketFields {
+		v := *f.field(desired)
+		if v == *f.field(lower) {
+			continue
+		}
+
+		if v == 0 {
+			*errs = append(*errs, ValidationError{path + "." + f.name, "can't be overridden with a zero value"})
+			continue
+		}
+
+		if o == nil {
+			o = &pb.BucketConfig{}
+		}
+
+		*f.field(o) = v
+	}
+
+	// Schedules are overridden as a whole.
+	if formatSchedules(desired.Schedules) != formatSchedules(lower.Schedules) {
+		if len(desired.Schedules) == 0 {
+			*errs = append(*errs, ValidationError{path + ".schedules", "can't be overridden with a zero value"})
+		} else {
+			if o == nil {
+				o = &pb.BucketConfig{}
+			}
+
+			o.Schedules = cloneSchedules(desired.Schedules)
+		}
+	}
+
+	return o
+}
+
+// PersistAndNotify persists the difference between cfg and the merged config of the lower layers to the last
+// layer. Returns ValidationErrors if cfg can't be expressed as an override of the lower layers, and a
+// ConfigConflictError if the last layer has changed since it was last read, as when it is shared with others.
+func (c *CompositeConfigPersister) PersistAndNotify(oldHash string, cfg *pb.ServiceConfig) error {
+	c.Lock()
+	defer c.Unlock()
+
+	if err := CheckConfigHash(oldHash, HashConfig(c.merged)); err != nil {
+		return err
+	}
+
+	lower, _ := c.mergeLocked(len(c.layers)-1, time.Now())
+	o, err := override(lower, cfg)
+	if err != nil {
+		return err
+	}
+
+	top := c.layers[len(c.layers)-1]
+	logging.Printf("Persisting config version %v to layer %v", cfg.Version, top.Name)
+
+	// There is no notification, that happens when the layer notifies its watcher
+	return top.Persister.PersistAndNotify(c.topHash, o)
+}
+
+// ConfigChangedWatcher returns a channel that is notified whenever the merged config changes.
+func (c *CompositeConfigPersister) ConfigChangedWatcher() <-chan struct{} {
+	return c.notifier.Watcher
+}
+
+// ReadPersistedConfig provides the merged config.
+func (c *CompositeConfigPersister) ReadPersistedConfig() (*pb.ServiceConfig, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	return CloneConfig(c.merged), nil
+}
+
+// Provenance returns the layer each setting of the merged config came from.
+func (c *CompositeConfigPersister) Provenance() *Provenance {
+	c.RLock()
+	defer c.RUnlock()
+
+	return c.provenance.clone()
+}
+
+// ReadHistoricalConfigs returns the configs previously persisted to the last layer, each merged over the
+// current configs of the lower layers.
+func (c *CompositeConfigPersister) ReadHistoricalConfigs() ([]*pb.ServiceConfig, error) {
+	history, err := c.layers[len(c.layers)-1].Persister.ReadHistoricalConfigs()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.mergeHistory(history), nil
+}
+
+// ReadHistoricalConfigsPage returns a page of the configs previously persisted to the last layer, newest first,
+// each merged over the current configs of the lower layers.
+func (c *CompositeConfigPersister) ReadHistoricalConfigsPage(offset, limit int) ([]*pb.ServiceConfig, error) {
+	history, err := c.layers[len(c.layers)-1].Persister.ReadHistoricalConfigsPage(offset, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.mergeHistory(history), nil
+}
+
+// mergeHistory merges configs persisted to the last layer over the current configs of the lower layers, in place.
+func (c *CompositeConfigPersister) mergeHistory(history []*pb.ServiceConfig) []*pb.ServiceConfig {
+	top := len(c.layers) - 1
+
+	c.RLock()
+	defer c.RUnlock()
+
+	lower, _ := c.mergeLocked(top, time.Now())
+	for i, h := range history {
+		merged := CloneConfig(lower)
+		mergeConfig(merged, h, c.layers[top].Name, newProvenance())
+		ApplyDefaults(merged)
+		merged.Version = h.Version
+		merged.User = h.User
+		merged.Date = h.Date
+		history[i] = merged
+	}
+
+	return history
+}
+
+// SetRetentionPolicy sets the retention policy of the last layer, if its persister supports one.
+func (c *CompositeConfigPersister) SetRetentionPolicy(policy RetentionPolicy) {
+	if p, ok := c.layers[len(c.layers)-1].Persister.(interface{ SetRetentionPolicy(RetentionPolicy) }); ok {
+		p.SetRetentionPolicy(policy)
+	}
+}
+
+// Close closes all of the layers' persisters and the notification channel.
+func (c *CompositeConfigPersister) Close() {
+	c.Lock()
+	c.closed = true
+	if c.expiryTimer != nil {
+		c.expiryTimer.Stop()
+	}
+	c.Unlock()
+
+	for _, l := range c.layers {
+		l.Persister.Close()
+	}
+
+	c.wg.Wait()
+	close(c.notifier.Watcher)
+}
@@ -0,0 +1,252 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package config
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/square/quotaservice/protos/config"
+	"github.com/square/quotaservice/test/helpers"
+)
+
+func compositeTestConfigs() (base, overrides *pb.ServiceConfig) {
+	base = NewDefaultServiceConfig()
+	base.Version = 1
+	ns := NewDefaultNamespaceConfig("ns")
+	helpers.PanicError(AddBucket(ns, &pb.BucketConfig{Name: "b", Size: 300, FillRate: 40}))
+	helpers.PanicError(AddNamespace(base, ns))
+	ApplyDefaults(base)
+
+	overrides = NewDefaultServiceConfig()
+	overrides.Version = 2
+	ns = NewDefaultNamespaceConfig("ns")
+	ns.Buckets["b"] = &pb.BucketConfig{FillRate: 10}
+	helpers.PanicError(AddNamespace(overrides, ns))
+	helpers.PanicError(AddNamespace(overrides, NewDefaultNamespaceConfig("other")))
+
+	return base, overrides
+}
+
+func waitForCompositeNotification(t *testing.T, c *CompositeConfigPersister, timeout time.Duration) {
+	select {
+	case <-c.ConfigChangedWatcher():
+	case <-time.After(timeout):
+		t.Fatal("No notification received")
+	}
+}
+
+func TestCompositeMerge(t *testing.T) {
+	base, overrides := compositeTestConfigs()
+	c, err := NewCompositeConfigPersister(
+		ConfigLayer{Name: "base", Persister: NewMemoryConfig(base)},
+		ConfigLayer{Name: "overrides", Persister: NewMemoryConfig(overrides)})
+	helpers.CheckError(t, err)
+	defer c.Close()
+
+	waitForCompositeNotification(t, c, time.Second)
+
+	merged, err := c.ReadPersistedConfig()
+	helpers.CheckError(t, err)
+
+	if merged.Version != 2 {
+		t.Errorf("Expected version 2, got %v", merged.Version)
+	}
+
+	b := merged.Namespaces["ns"].Buckets["b"]
+	if b.Size != 300 || b.FillRate != 10 {
+		t.Errorf("Expected size from base and fill rate from overrides, got %+v", b)
+	}
+
+	if merged.Namespaces["other"] == nil {
+		t.Errorf("Expected namespace added by overrides, got %+v", merged.Namespaces)
+	}
+
+	provenance := c.Provenance().Bucket("ns", "b")
+	if provenance["size"] != "base" || provenance["fill_rate"] != "overrides" {
+		t.Errorf("Unexpected provenance %+v", provenance)
+	}
+
+	if p := c.Provenance().Namespaces["other"]["max_dynamic_buckets"]; p != "overrides" {
+		t.Errorf("Expected namespace provenance overrides, got %v", p)
+	}
+}
+
+func TestCompositePersistAndNotify(t *testing.T) {
+	base, _ := compositeTestConfigs()
+	top := NewMemoryConfigPersister()
+	c, err := NewCompositeConfigPersister(
+		ConfigLayer{Name: "base", Persister: NewMemoryConfig(base)},
+		ConfigLayer{Name: "overrides", Persister: top})
+	helpers.CheckError(t, err)
+	defer c.Close()
+
+	waitForCompositeNotification(t, c, time.Second)
+
+	current, err := c.ReadPersistedConfig()
+	helpers.CheckError(t, err)
+
+	desired := CloneConfig(current)
+	desired.Version++
+	desired.Namespaces["ns"].Buckets["b"].Size = 500
+	helpers.CheckError(t, c.PersistAndNotify(HashConfig(current), desired))
+	waitForCompositeNotification(t, c, time.Second)
+
+	// Only the change should have been persisted to the top layer.
+	persisted, err := top.ReadPersistedConfig()
+	helpers.CheckError(t, err)
+
+	expected := map[string]*pb.BucketConfig{"b": {Size: 500}}
+	if !reflect.DeepEqual(expected, persisted.Namespaces["ns"].Buckets) {
+		t.Errorf("Expected %+v, got %+v", expected, persisted.Namespaces["ns"].Buckets)
+	}
+
+	merged, err := c.ReadPersistedConfig()
+	helpers.CheckError(t, err)
+
+	if merged.Namespaces["ns"].Buckets["b"].Size != 500 || merged.Version != desired.Version {
+		t.Errorf("Expected merged config to reflect the change, got %+v", merged)
+	}
+
+	if p := c.Provenance().Bucket("ns", "b")["size"]; p != "overrides" {
+		t.Errorf("Expected size from overrides, got %v", p)
+	}
+
+	history, err := c.ReadHistoricalConfigs()
+	helpers.CheckError(t, err)
+
+	if len(history) != 1 || history[0].Namespaces["ns"].Buckets["b"].FillRate != 40 {
+		t.Errorf("Expected history merged over the base layer, got %+v", history)
+	}
+
+	history, err = c.ReadHistoricalConfigsPage(0, 1)
+	helpers.CheckError(t, err)
+
+	if len(history) != 1 || history[0].Namespaces["ns"].Buckets["b"].FillRate != 40 {
+		t.Errorf("Expected a page of history merged over the base layer, got %+v", history)
+	}
+
+	// Namespaces defined by lower layers can't be removed.
+	desired = CloneConfig(merged)
+	desired.Version++
+	delete(desired.Namespaces, "ns")
+	err = c.PersistAndNotify(HashConfig(merged), desired)
+	if _, ok := AsValidationErrors(err); !ok {
+		t.Errorf("Expected validation errors, got %v", err)
+	}
+
+	// Overrides are persisted to the last layer, but those of lower layers can't be removed.
+	desired = CloneConfig(merged)
+	desired.Version++
+	helpers.CheckError(t, AddOverride(desired, &pb.BucketOverride{
+		Namespace: "ns", Bucket: "b", Config: &pb.BucketConfig{Size: 600}, ExpiresAt: time.Now().Unix() + 60}))
+	helpers.CheckError(t, c.PersistAndNotify(HashConfig(merged), desired))
+	waitForCompositeNotification(t, c, time.Second)
+
+	persisted, err = top.ReadPersistedConfig()
+	helpers.CheckError(t, err)
+
+	if len(persisted.Overrides) != 1 || persisted.Overrides[0].Config.Size != 600 {
+		t.Errorf("Expected the override to be persisted to the top layer, got %+v", persisted.Overrides)
+	}
+
+	merged, err = c.ReadPersistedConfig()
+	helpers.CheckError(t, err)
+
+	if len(merged.Overrides) != 1 {
+		t.Errorf("Expected the override to be merged, got %+v", merged.Overrides)
+	}
+
+	// Nor can changes be made based on a stale config.
+	if err = c.PersistAndNotify(HashConfig(current), merged); !IsConfigConflict(err) {
+		t.Errorf("Expected a conflict, got %v", err)
+	}
+}
+
+func TestCompositeExpiry(t *testing.T) {
+	base, overrides := compositeTestConfigs()
+
+	// Dates have a resolution of a second, so backdate the overrides to expire shortly.
+	overrides.Date = time.Now().Unix() - 10
+	c, err := NewCompositeConfigPersister(
+		ConfigLayer{Name: "base", Persister: NewMemoryConfig(base)},
+		ConfigLayer{Name: "overrides", Persister: NewMemoryConfig(overrides), TTL: 11 * time.Second})
+	helpers.CheckError(t, err)
+	defer c.Close()
+
+	waitForCompositeNotification(t, c, time.Second)
+
+	merged, err := c.ReadPersistedConfig()
+	helpers.CheckError(t, err)
+
+	if merged.Namespaces["ns"].Buckets["b"].FillRate != 10 {
+		t.Fatalf("Expected overrides to be in effect, got %+v", merged)
+	}
+
+	waitForCompositeNotification(t, c, 3*time.Second)
+
+	expired, err := c.ReadPersistedConfig()
+	helpers.CheckError(t, err)
+
+	if expired.Namespaces["ns"].Buckets["b"].FillRate != 40 || expired.Namespaces["other"] != nil {
+		t.Errorf("Expected overrides to have expired, got %+v", expired)
+	}
+
+	if expired.Version <= merged.Version {
+		t.Errorf("Expected version to increase from %v, got %v", merged.Version, expired.Version)
+	}
+}
+
+func TestCompositeSharedTopLayer(t *testing.T) {
+	base, overrides := compositeTestConfigs()
+	top := &sharedPersister{ConfigPersister: NewMemoryConfig(overrides)}
+
+	newComposite := func() *CompositeConfigPersister {
+		c, err := NewCompositeConfigPersister(
+			ConfigLayer{Name: "base", Persister: NewMemoryConfig(base)},
+			ConfigLayer{Name: "overrides", Persister: top})
+		helpers.CheckError(t, err)
+		waitForCompositeNotification(t, c, time.Second)
+		return c
+	}
+
+	c1, c2 := newComposite(), newComposite()
+	defer c1.Close()
+	defer c2.Close()
+
+	current, err := c1.ReadPersistedConfig()
+	helpers.CheckError(t, err)
+
+	desired := CloneConfig(current)
+	desired.Version++
+	desired.Namespaces["ns"].Buckets["b"].Size = 500
+	helpers.CheckError(t, c1.PersistAndNotify(HashConfig(current), desired))
+
+	// c2 hasn't read c1's change yet, so only its local check against its own merged config passes.
+	desired = CloneConfig(current)
+	desired.Version++
+	desired.Namespaces["ns"].Buckets["b"].Size = 600
+	if err = c2.PersistAndNotify(HashConfig(current), desired); !IsConfigConflict(err) {
+		t.Errorf("Expected a conflict, got %v", err)
+	}
+
+	persisted, err := top.ReadPersistedConfig()
+	helpers.CheckError(t, err)
+
+	if size := persisted.Namespaces["ns"].Buckets["b"].Size; size != 500 {
+		t.Errorf("Expected c1's change to be kept, got size %v", size)
+	}
+}
+
+// sharedPersister only closes the persister it wraps once, so that it can be shared.
+type sharedPersister struct {
+	ConfigPersister
+	once sync.Once
+}
+
+func (s *sharedPersister) Close() {
+	s.once.Do(s.ConfigPersister.Close)
+}
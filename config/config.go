@@ -15,7 +15,6 @@ import (
 	"time"
 
 	"github.com/golang/protobuf/proto"
-	"github.com/square/quotaservice/logging"
 	pb "github.com/square/quotaservice/protos/config"
 	"gopkg.in/yaml.v2"
 )
@@ -25,7 +24,6 @@ const (
 	DefaultBucketName         = "___DEFAULT_BUCKET___"
 	DynamicBucketTemplateName = "___DYNAMIC_BUCKET_TPL___"
 	initialVersion            = 0
-	initialHash               = "___INITIAL_HASH___"
 )
 
 func ApplyDefaults(sc *pb.ServiceConfig) {
@@ -35,11 +33,13 @@ func ApplyDefaults(sc *pb.ServiceConfig) {
 	}
 
 	for name, ns := range sc.Namespaces {
-		ns.Name = name
-		if ns.DefaultBucket != nil && ns.DynamicBucketTemplate != nil {
-			panic(fmt.Sprintf("Namespace %v is not allowed to have a default bucket as well as allow dynamic buckets.", name))
+		if ns == nil {
+			// Reported by Validate.
+			continue
 		}
 
+		ns.Name = name
+
 		// Ensure the namespace's bucket map exists.
 		if ns.Buckets == nil {
 			ns.Buckets = make(map[string]*pb.BucketConfig)
@@ -58,6 +58,11 @@ func ApplyDefaults(sc *pb.ServiceConfig) {
 		}
 
 		for n, b := range ns.Buckets {
+			if b == nil {
+				// Reported by Validate.
+				continue
+			}
+
 			ApplyBucketDefaults(b)
 			b.Name = n
 			b.Namespace = ns.Name
@@ -113,33 +118,48 @@ func FQN(b *pb.BucketConfig) string {
 	return FullyQualifiedName(b.Namespace, b.Name)
 }
 
-func ReadConfigFromFile(filename string) *pb.ServiceConfig {
+// ReadConfigFromFile reads a YAML config from a file, and applies defaults. The config isn't validated; use
+// Validate for that.
+func ReadConfigFromFile(filename string) (*pb.ServiceConfig, error) {
 	bytes, err := ioutil.ReadFile(filename)
 	if err != nil {
-		panic(fmt.Sprintf("Unable to open file %v. Error: %v", filename, err))
+		return nil, fmt.Errorf("unable to open file %v: %w", filename, err)
 	}
 
-	return readConfigFromBytes(bytes)
+	return readConfigFromBytes(bytes, yaml.Unmarshal)
 }
 
-func ReadConfig(yamlStream io.Reader) *pb.ServiceConfig {
+// ReadConfig reads a YAML config from a stream, and applies defaults. The config isn't validated; use Validate
+// for that.
+func ReadConfig(yamlStream io.Reader) (*pb.ServiceConfig, error) {
 	bytes, err := ioutil.ReadAll(yamlStream)
 	if err != nil {
-		panic(fmt.Sprintf("Unable to open reader. Error: %v", err))
+		return nil, fmt.Errorf("unable to read config: %w", err)
 	}
 
-	return readConfigFromBytes(bytes)
+	return readConfigFromBytes(bytes, yaml.Unmarshal)
 }
 
-func readConfigFromBytes(bytes []byte) *pb.ServiceConfig {
+// ReadConfigFromFileStrict behaves like ReadConfigFromFile, but also returns an error if the file contains
+// fields that aren't part of a config, such as misspelled field names.
+func ReadConfigFromFileStrict(filename string) (*pb.ServiceConfig, error) {
+	bytes, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open file %v: %w", filename, err)
+	}
+
+	return readConfigFromBytes(bytes, yaml.UnmarshalStrict)
+}
+
+func readConfigFromBytes(bytes []byte, unmarshal func([]byte, interface{}) error) (*pb.ServiceConfig, error) {
 	cfg := NewDefaultServiceConfig()
 	cfg.GlobalDefaultBucket = nil
-	if err := yaml.Unmarshal(bytes, cfg); err != nil {
-		panic(fmt.Sprintf("Unable to read YAML. Error: %v", err))
+	if err := unmarshal(bytes, cfg); err != nil {
+		return nil, fmt.Errorf("unable to read YAML: %w", err)
 	}
 
 	ApplyDefaults(cfg)
-	return cfg
+	return cfg, nil
 }
 
 func NewDefaultServiceConfig() *pb.ServiceConfig {
@@ -193,15 +213,16 @@ func FullyQualifiedName(namespace, bucketName string) string {
 
 func NewMemoryConfig(p *pb.ServiceConfig) ConfigPersister {
 	persister := NewMemoryConfigPersister()
-	if err := persister.PersistAndNotify(initialHash, p); err != nil {
-		logging.Fatalf("Unable to persist initial configuration: %v", err)
+	if err := persister.PersistAndNotify("", p); err != nil {
+		// A new persister has no config for this one to conflict with.
+		panic(fmt.Sprintf("Unable to persist initial configuration: %v", err))
 	}
 
 	return persister
 }
 
 func Marshal(p *pb.ServiceConfig) (io.Reader, error) {
-	b, e := proto.Marshal(p)
+	b, e := MarshalBytes(p)
 	if e != nil {
 		return nil, e
 	}
@@ -209,6 +230,18 @@ func Marshal(p *pb.ServiceConfig) (io.Reader, error) {
 	return bytes.NewReader(b), nil
 }
 
+// MarshalBytes serializes a config to its protobuf representation. Serialization is deterministic, so equal
+// configs produce the same bytes, and hence the same hash.
+func MarshalBytes(p *pb.ServiceConfig) ([]byte, error) {
+	b := proto.NewBuffer(nil)
+	b.SetDeterministic(true)
+	if e := b.Marshal(p); e != nil {
+		return nil, e
+	}
+
+	return b.Bytes(), nil
+}
+
 func Unmarshal(r io.Reader) (*pb.ServiceConfig, error) {
 	b, e := ioutil.ReadAll(r)
 	if e != nil {
@@ -264,19 +297,11 @@ func DifferentBucketConfigs(c1, c2 *pb.BucketConfig) bool {
 		return true
 	}
 
-	return c1.Name != c2.Name ||
-		c1.Namespace != c2.Namespace ||
-		c1.Size != c2.Size ||
-		c1.FillRate != c2.FillRate ||
-		c1.WaitTimeoutMillis != c2.WaitTimeoutMillis ||
-		c1.MaxIdleMillis != c2.MaxIdleMillis ||
-		c1.MaxDebtMillis != c2.MaxDebtMillis ||
-		c1.MaxTokensPerRequest != c2.MaxTokensPerRequest
+	return len(BucketConfigChanges(c1, c2)) > 0
 }
 
 func DifferentNamespaceConfigs(c1, c2 *pb.NamespaceConfig) bool {
-	different := c1.Name != c2.Name ||
-		c1.MaxDynamicBuckets != c2.MaxDynamicBuckets ||
+	different := len(NamespaceConfigChanges(c1, c2)) > 0 ||
 		DifferentBucketConfigs(c1.DefaultBucket, c2.DefaultBucket) ||
 		DifferentBucketConfigs(c1.DynamicBucketTemplate, c2.DynamicBucketTemplate) ||
 		len(c1.Buckets) != len(c2.Buckets)
@@ -4,6 +4,7 @@
 package config
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/square/quotaservice/test/helpers"
@@ -21,6 +22,7 @@ const cfgYaml = `namespaces:
         wait_timeout_millis: 9999
         max_idle_millis: 20000
         max_debt_millis: 30000
+        max_tokens_per_request: 100
       with_defaults:
         size: 100
   only_dynamic:
@@ -35,10 +37,12 @@ const cfgYaml = `namespaces:
       fill_rate: 800
       wait_timeout_millis: 7777
       max_idle_millis: 40000
+      max_tokens_per_request: 100
 `
 
 func TestConfig(t *testing.T) {
-	cfg := ReadConfig(strings.NewReader(cfgYaml))
+	cfg, err := ReadConfig(strings.NewReader(cfgYaml))
+	helpers.CheckError(t, err)
 
 	if cfg.GlobalDefaultBucket != nil {
 		t.Fatal("Did not configure a global default bucket")
@@ -52,7 +56,7 @@ func TestConfig(t *testing.T) {
 	ns := cfg.Namespaces[namespace]
 
 	assertNamespace(t, namespace, ns, 2, false, false, 0)
-	assertBucket(t, "one", namespace, ns.Buckets["one"], 100, 321, 9999, 20000, 30000, 321)
+	assertBucket(t, "one", namespace, ns.Buckets["one"], 100, 321, 9999, 20000, 30000, 100)
 	assertBucket(t, "with_defaults", namespace, ns.Buckets["with_defaults"], 100, 50, 1000, -1, 10000, 50)
 
 	namespace = "only_dynamic"
@@ -65,7 +69,7 @@ func TestConfig(t *testing.T) {
 	ns = cfg.Namespaces[namespace]
 
 	assertNamespace(t, namespace, ns, 0, true, false, 0)
-	assertBucket(t, DefaultBucketName, namespace, ns.DefaultBucket, 100, 800, 7777, 40000, 10000, 800)
+	assertBucket(t, DefaultBucketName, namespace, ns.DefaultBucket, 100, 800, 7777, 40000, 10000, 100)
 }
 
 func assertNamespace(t *testing.T, namespace string, ns *pbconfig.NamespaceConfig, numBuckets int, expectDefault, expectDynamic bool, maxDynamic int32) {
@@ -132,8 +136,59 @@ func assertBucket(t *testing.T, name, namespace string, b *pbconfig.BucketConfig
 	}
 }
 
+func TestEvictionPolicy(t *testing.T) {
+	cfg, err := ReadConfig(strings.NewReader(`namespaces:
+  lru:
+    max_dynamic_buckets: 10
+    dynamic_bucket_eviction_policy: EVICT_LRU
+    dynamic_bucket_template:
+      size: 10
+  reject:
+    max_dynamic_buckets: 10
+    dynamic_bucket_template:
+      size: 10
+`))
+	helpers.CheckError(t, err)
+
+	if p := cfg.Namespaces["lru"].DynamicBucketEvictionPolicy; p != pbconfig.NamespaceConfig_EVICT_LRU {
+		t.Fatalf("Expected eviction policy EVICT_LRU; was %v", p)
+	}
+
+	if p := cfg.Namespaces["reject"].DynamicBucketEvictionPolicy; p != pbconfig.NamespaceConfig_REJECT {
+		t.Fatalf("Expected eviction policy REJECT; was %v", p)
+	}
+
+	changed := CloneConfig(cfg).Namespaces["lru"]
+	changed.DynamicBucketEvictionPolicy = pbconfig.NamespaceConfig_REJECT
+	if !DifferentNamespaceConfigs(cfg.Namespaces["lru"], changed) {
+		t.Fatal("Namespaces with different eviction policies should be different")
+	}
+}
+
+func TestHashConfigDeterministic(t *testing.T) {
+	cfg := NewDefaultServiceConfig()
+	for i := 0; i < 20; i++ {
+		ns := NewDefaultNamespaceConfig(fmt.Sprintf("ns%v", i))
+		helpers.CheckError(t, AddBucket(ns, NewDefaultBucketConfig("b")))
+		helpers.CheckError(t, AddNamespace(cfg, ns))
+	}
+
+	hash := HashConfig(cfg)
+	for i := 0; i < 10; i++ {
+		if h := HashConfig(CloneConfig(cfg)); h != hash {
+			t.Fatalf("Hashes of equal configs differ: %v != %v", h, hash)
+		}
+	}
+}
+
 func TestNonexistentFile(t *testing.T) {
-	helpers.ExpectingPanic(t, func() {
-		_ = ReadConfigFromFile("/does/not/exist")
-	})
+	if _, err := ReadConfigFromFile("/does/not/exist"); err == nil {
+		t.Fatal("Expecting an error reading a nonexistent file")
+	}
+}
+
+func TestBadYaml(t *testing.T) {
+	if _, err := ReadConfig(strings.NewReader("namespaces: [")); err == nil {
+		t.Fatal("Expecting an error reading bad YAML")
+	}
 }
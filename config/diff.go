@@ -0,0 +1,249 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package config
+
+import (
+	"github.com/golang/protobuf/proto"
+
+	pb "github.com/square/quotaservice/protos/config"
+)
+
+// ChangeType describes how a namespace or bucket differs between two configs.
+type ChangeType string
+
+const (
+	Added   ChangeType = "added"
+	Removed ChangeType = "removed"
+	Changed ChangeType = "changed"
+)
+
+// FieldChange describes a single field with different values in two configs. Fields are named as in the
+// YAML representation of the config.
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// BucketDiff describes a bucket that was added, removed or changed. A namespace's default bucket and dynamic
+// bucket template are named DefaultBucketName and DynamicBucketTemplateName respectively.
+type BucketDiff struct {
+	Name   string        `json:"name"`
+	Change ChangeType    `json:"change"`
+	Fields []FieldChange `json:"fields,omitempty"`
+}
+
+// NamespaceDiff describes a namespace that was added, removed or changed. Fields and Buckets are only
+// populated for changed namespaces.
+type NamespaceDiff struct {
+	Name    string        `json:"name"`
+	Change  ChangeType    `json:"change"`
+	Fields  []FieldChange `json:"fields,omitempty"`
+	Buckets []BucketDiff  `json:"buckets,omitempty"`
+}
+
+// ConfigDiff describes the differences between two service configs. Metadata such as the version, user and
+// date of the configs isn't compared.
+type ConfigDiff struct {
+	GlobalDefaultBucket *BucketDiff     `json:"global_default_bucket,omitempty"`
+	Namespaces          []NamespaceDiff `json:"namespaces,omitempty"`
+}
+
+// Empty returns true if the configs compared are equivalent.
+func (d *ConfigDiff) Empty() bool {
+	return d.GlobalDefaultBucket == nil && len(d.Namespaces) == 0
+}
+
+// DiffConfigs compares two configs, returning the namespaces and buckets added, removed or changed going
+// from one to the other. Namespaces and buckets are listed in name order.
+func DiffConfigs(from, to *pb.ServiceConfig) *ConfigDiff {
+	d := &ConfigDiff{}
+
+	if from == nil {
+		from = &pb.ServiceConfig{}
+	}
+
+	if to == nil {
+		to = &pb.ServiceConfig{}
+	}
+
+	d.GlobalDefaultBucket = diffBucket(DefaultBucketName, from.GlobalDefaultBucket, to.GlobalDefaultBucket)
+
+	for _, name := range mergedKeys(from.Namespaces, to.Namespaces) {
+		ns1, ns2 := from.Namespaces[name], to.Namespaces[name]
+
+		switch {
+		case ns1 == nil:
+			d.Namespaces = append(d.Namespaces, NamespaceDiff{Name: name, Change: Added})
+		case ns2 == nil:
+			d.Namespaces = append(d.Namespaces, NamespaceDiff{Name: name, Change: Removed})
+		default:
+			if nsDiff := diffNamespace(name, ns1, ns2); nsDiff != nil {
+				d.Namespaces = append(d.Namespaces, *nsDiff)
+			}
+		}
+	}
+
+	return d
+}
+
+func diffNamespace(name string, ns1, ns2 *pb.NamespaceConfig) *NamespaceDiff {
+	var buckets []BucketDiff
+
+	if b := diffBucket(DefaultBucketName, ns1.DefaultBucket, ns2.DefaultBucket); b != nil {
+		buckets = append(buckets, *b)
+	}
+
+	if b := diffBucket(DynamicBucketTemplateName, ns1.DynamicBucketTemplate, ns2.DynamicBucketTemplate); b != nil {
+		buckets = append(buckets, *b)
+	}
+
+	for _, bucketName := range mergedKeys(ns1.Buckets, ns2.Buckets) {
+		if b := diffBucket(bucketName, ns1.Buckets[bucketName], ns2.Buckets[bucketName]); b != nil {
+			buckets = append(buckets, *b)
+		}
+	}
+
+	fields := NamespaceConfigChanges(ns1, ns2)
+
+	if len(fields) == 0 && len(buckets) == 0 {
+		return nil
+	}
+
+	return &NamespaceDiff{Name: name, Change: Changed, Fields: fields, Buckets: buckets}
+}
+
+func diffBucket(name string, b1, b2 *pb.BucketConfig) *BucketDiff {
+	switch {
+	case b1 == nil && b2 == nil:
+		return nil
+	case b1 == nil:
+		return &BucketDiff{Name: name, Change: Added}
+	case b2 == nil:
+		return &BucketDiff{Name: name, Change: Removed}
+	}
+
+	if fields := BucketConfigChanges(b1, b2); len(fields) > 0 {
+		return &BucketDiff{Name: name, Change: Changed, Fields: fields}
+	}
+
+	return nil
+}
+
+// BucketConfigChanges lists the fields that differ between two bucket configs. Both configs must be non-nil.
+func BucketConfigChanges(c1, c2 *pb.BucketConfig) []FieldChange {
+	var changes fieldChanges
+	changes.compare("name", c1.Name, c2.Name)
+	changes.compare("namespace", c1.Namespace, c2.Namespace)
+	changes.compare("size", c1.Size, c2.Size)
+	changes.compare("fill_rate", c1.FillRate, c2.FillRate)
+	changes.compare("wait_timeout_millis", c1.WaitTimeoutMillis, c2.WaitTimeoutMillis)
+	changes.compare("max_idle_millis", c1.MaxIdleMillis, c2.MaxIdleMillis)
+	changes.compare("max_debt_millis", c1.MaxDebtMillis, c2.MaxDebtMillis)
+	changes.compare("max_tokens_per_request", c1.MaxTokensPerRequest, c2.MaxTokensPerRequest)
+	changes.compare("schedules", formatSchedules(c1.Schedules), formatSchedules(c2.Schedules))
+	return changes
+}
+
+// NamespaceConfigChanges lists the fields that differ between two namespace configs, ignoring the buckets
+// they contain. Both configs must be non-nil.
+func NamespaceConfigChanges(c1, c2 *pb.NamespaceConfig) []FieldChange {
+	var changes fieldChanges
+	changes.compare("name", c1.Name, c2.Name)
+	changes.compare("max_dynamic_buckets", c1.MaxDynamicBuckets, c2.MaxDynamicBuckets)
+	changes.compare("dynamic_bucket_eviction_policy",
+		c1.DynamicBucketEvictionPolicy.String(), c2.DynamicBucketEvictionPolicy.String())
+	return changes
+}
+
+type fieldChanges []FieldChange
+
+func (f *fieldChanges) compare(field string, from, to interface{}) {
+	if from != to {
+		*f = append(*f, FieldChange{Field: field, From: from, To: to})
+	}
+}
+
+// mergedKeys returns the keys present in either map, in order.
+func mergedKeys[V any](m1, m2 map[string]V) []string {
+	merged := make(map[string]struct{}, len(m1)+len(m2))
+	for k := range m1 {
+		merged[k] = struct{}{}
+	}
+
+	for k := range m2 {
+		merged[k] = struct{}{}
+	}
+
+	return sortedKeys(merged)
+}
+
+// Reconcile returns the config that results from applying a desired config to the current one. Namespaces,
+// and the buckets within them, that are present in desired replace those in current; a namespace's default
+// bucket and dynamic bucket template are kept unless desired sets either of them. If prune is true,
+// anything missing from desired is removed; otherwise it is kept. Metadata such as the version is copied from
+// current, as are overrides and alert rules, which are dropped if the bucket or namespace they apply to no longer
+// exists. Neither config is modified.
+func Reconcile(current, desired *pb.ServiceConfig, prune bool) *pb.ServiceConfig {
+	if prune {
+		reconciled := CloneConfig(desired)
+		reconciled.Version = current.Version
+		reconciled.User = current.User
+		reconciled.Date = current.Date
+		reconciled.Overrides = nil
+
+		for _, o := range current.Overrides {
+			if overriddenBucket(reconciled, o.Namespace, o.Bucket) != nil {
+				reconciled.Overrides = append(reconciled.Overrides, proto.Clone(o).(*pb.BucketOverride))
+			}
+		}
+
+		reconciled.AlertRules = nil
+
+		for _, r := range current.AlertRules {
+			if reconciled.Namespaces[r.Namespace] != nil {
+				reconciled.AlertRules = append(reconciled.AlertRules, proto.Clone(r).(*pb.AlertRule))
+			}
+		}
+
+		return reconciled
+	}
+
+	reconciled := CloneConfig(current)
+
+	if desired.GlobalDefaultBucket != nil {
+		reconciled.GlobalDefaultBucket = proto.Clone(desired.GlobalDefaultBucket).(*pb.BucketConfig)
+	}
+
+	if reconciled.Namespaces == nil {
+		reconciled.Namespaces = make(map[string]*pb.NamespaceConfig)
+	}
+
+	for name, ns := range desired.Namespaces {
+		merged := proto.Clone(ns).(*pb.NamespaceConfig)
+
+		if existing := reconciled.Namespaces[name]; existing != nil {
+			// A namespace can't have both a default bucket and a dynamic bucket template, so setting either one
+			// replaces both.
+			if merged.DefaultBucket == nil && merged.DynamicBucketTemplate == nil {
+				merged.DefaultBucket = existing.DefaultBucket
+				merged.DynamicBucketTemplate = existing.DynamicBucketTemplate
+			}
+
+			if merged.Buckets == nil {
+				merged.Buckets = make(map[string]*pb.BucketConfig)
+			}
+
+			for bucketName, b := range existing.Buckets {
+				if _, exists := merged.Buckets[bucketName]; !exists {
+					merged.Buckets[bucketName] = b
+				}
+			}
+		}
+
+		reconciled.Namespaces[name] = merged
+	}
+
+	return reconciled
+}
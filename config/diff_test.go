@@ -0,0 +1,164 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	pb "github.com/square/quotaservice/protos/config"
+	"github.com/square/quotaservice/test/helpers"
+)
+
+func TestDiffConfigsEqual(t *testing.T) {
+	cfg, err := ReadConfig(strings.NewReader(cfgYaml))
+	helpers.CheckError(t, err)
+
+	changed := CloneConfig(cfg)
+	changed.Version = 10
+	changed.User = "someone"
+
+	if d := DiffConfigs(cfg, changed); !d.Empty() {
+		t.Fatalf("Expected configs differing only in metadata to be equal; got %+v", d)
+	}
+}
+
+func TestDiffConfigs(t *testing.T) {
+	from, err := ReadConfig(strings.NewReader(cfgYaml))
+	helpers.CheckError(t, err)
+
+	to := CloneConfig(from)
+	to.GlobalDefaultBucket = NewDefaultBucketConfig(DefaultBucketName)
+	delete(to.Namespaces, "only_default")
+	helpers.CheckError(t, AddNamespace(to, NewDefaultNamespaceConfig("new")))
+
+	ns := to.Namespaces["no_default_no_dynamic"]
+	ns.MaxDynamicBuckets = 5
+	ns.Buckets["one"].Size = 200
+	delete(ns.Buckets, "with_defaults")
+	helpers.CheckError(t, AddBucket(ns, NewDefaultBucketConfig("two")))
+	SetDynamicBucketTemplate(ns, NewDefaultBucketConfig(""))
+
+	expected := &ConfigDiff{
+		GlobalDefaultBucket: &BucketDiff{Name: DefaultBucketName, Change: Added},
+		Namespaces: []NamespaceDiff{
+			{Name: "new", Change: Added},
+			{
+				Name:   "no_default_no_dynamic",
+				Change: Changed,
+				Fields: []FieldChange{{Field: "max_dynamic_buckets", From: int32(0), To: int32(5)}},
+				Buckets: []BucketDiff{
+					{Name: DynamicBucketTemplateName, Change: Added},
+					{Name: "one", Change: Changed, Fields: []FieldChange{{Field: "size", From: int64(100), To: int64(200)}}},
+					{Name: "two", Change: Added},
+					{Name: "with_defaults", Change: Removed}}},
+			{Name: "only_default", Change: Removed}}}
+
+	if d := DiffConfigs(from, to); !reflect.DeepEqual(d, expected) {
+		t.Fatalf("Expected diff %+v; got %+v", expected, d)
+	}
+
+	if !DifferentNamespaceConfigs(from.Namespaces["no_default_no_dynamic"], ns) {
+		t.Fatal("Expected namespaces to be different")
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	current, err := ReadConfig(strings.NewReader(cfgYaml))
+	helpers.CheckError(t, err)
+	current.Version = 5
+
+	desired, err := ReadConfig(strings.NewReader(`namespaces:
+  no_default_no_dynamic:
+    buckets:
+      one:
+        size: 200
+  new:
+    default_bucket:
+      size: 10
+`))
+	helpers.CheckError(t, err)
+
+	reconciled := Reconcile(current, desired, false)
+
+	if reconciled.Version != 5 {
+		t.Fatalf("Expected version to be kept; was %v", reconciled.Version)
+	}
+
+	if len(reconciled.Namespaces) != 4 || reconciled.Namespaces["new"] == nil {
+		t.Fatalf("Expected a new namespace and the existing ones to be kept; got %v", NamespaceNames(reconciled))
+	}
+
+	ns := reconciled.Namespaces["no_default_no_dynamic"]
+	if len(ns.Buckets) != 2 || ns.Buckets["one"].Size != 200 || ns.Buckets["with_defaults"] == nil {
+		t.Fatalf("Expected bucket one to be updated and with_defaults to be kept; got %v", ns.Buckets)
+	}
+
+	current.Overrides = []*pb.BucketOverride{
+		{Namespace: "no_default_no_dynamic", Bucket: "one", Config: &pb.BucketConfig{Size: 300}, ExpiresAt: 100},
+		{Namespace: "only_default", Bucket: DefaultBucketName, Config: &pb.BucketConfig{Size: 300}, ExpiresAt: 100}}
+
+	pruned := Reconcile(current, desired, true)
+	d := DiffConfigs(desired, pruned)
+
+	if !d.Empty() || pruned.Version != 5 {
+		t.Fatalf("Expected pruned config to match desired config at version 5; got %+v, version %v", d, pruned.Version)
+	}
+
+	if len(pruned.Overrides) != 1 || pruned.Overrides[0].Bucket != "one" {
+		t.Fatalf("Expected only the override of a remaining bucket to be kept; got %v", pruned.Overrides)
+	}
+
+	if current.Namespaces["no_default_no_dynamic"].Buckets["one"].Size != 100 {
+		t.Fatal("Current config should not be modified")
+	}
+}
+
+func TestReconcileDefaultBucketOrTemplate(t *testing.T) {
+	current, err := ReadConfig(strings.NewReader(cfgYaml))
+	helpers.CheckError(t, err)
+
+	desired, err := ReadConfig(strings.NewReader(`namespaces:
+  only_default:
+    max_dynamic_buckets: 10
+    dynamic_bucket_template:
+      size: 10
+      fill_rate: 10
+  only_dynamic:
+    default_bucket:
+      size: 20
+      fill_rate: 10
+`))
+	helpers.CheckError(t, err)
+
+	reconciled := Reconcile(current, desired, false)
+
+	if ns := reconciled.Namespaces["only_default"]; ns.DefaultBucket != nil || ns.DynamicBucketTemplate.Size != 10 {
+		t.Fatalf("Expected the default bucket to be replaced by the dynamic bucket template; got %v", ns)
+	}
+
+	if ns := reconciled.Namespaces["only_dynamic"]; ns.DynamicBucketTemplate != nil || ns.DefaultBucket.Size != 20 {
+		t.Fatalf("Expected the dynamic bucket template to be replaced by the default bucket; got %v", ns)
+	}
+
+	if errs := Validate(reconciled); len(errs) != 0 {
+		t.Fatalf("Expected the reconciled config to be valid; got %v", errs)
+	}
+}
+
+func TestReadConfigStrict(t *testing.T) {
+	f := filepath.Join(t.TempDir(), "config.yaml")
+	helpers.CheckError(t, ioutil.WriteFile(f, []byte("namespaces:\n  ns:\n    max_dynamic_bukets: 5\n"), 0644))
+
+	if _, err := ReadConfigFromFile(f); err != nil {
+		t.Fatalf("Expected unknown fields to be ignored; got %v", err)
+	}
+
+	if _, err := ReadConfigFromFileStrict(f); err == nil {
+		t.Fatal("Expected an error for an unknown field")
+	}
+}
@@ -5,18 +5,27 @@ package config
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
+	"sync"
+	"time"
 
-	"github.com/golang/protobuf/proto"
 	"github.com/square/quotaservice/config/internal"
 	pb "github.com/square/quotaservice/protos/config"
 )
 
 // DiskConfigPersister is a ConfigPersister that saves configs to the local filesystem.
 type DiskConfigPersister struct {
-	location string
+	location  string
+	retention RetentionPolicy
+	pruner    *internal.Pruner
+	// Set if the location is watched for changes made by others; see NewWatchingDiskConfigPersister.
+	watch *diskWatch
 	*internal.Notifier
+	// Serializes writes, so that the check against oldHash and the write are atomic within this process.
+	sync.Mutex
 }
 
 // NewDiskConfigPersister creates a new DiskConfigPersister
@@ -28,7 +37,7 @@ func NewDiskConfigPersister(location string) (*DiskConfigPersister, error) {
 		return nil, e
 	}
 
-	d := &DiskConfigPersister{location, internal.NewNotifier()}
+	d := &DiskConfigPersister{location: location, Notifier: internal.NewNotifier()}
 
 	// Notify that we're available for reading
 	d.Notify()
@@ -50,9 +59,19 @@ func writeFile(path string, bytes []byte) error {
 
 // PersistAndNotify persists a configuration passed in.
 func (d *DiskConfigPersister) PersistAndNotify(oldHash string, cfg *pb.ServiceConfig) error {
-	// TODO(manik) Optimistic version check with oldHash
+	d.Lock()
+	defer d.Unlock()
 
-	b, e := proto.Marshal(cfg)
+	currentHash, e := d.currentHash()
+	if e != nil {
+		return e
+	}
+
+	if e = CheckConfigHash(oldHash, currentHash); e != nil {
+		return e
+	}
+
+	b, e := MarshalBytes(cfg)
 	if e != nil {
 		return e
 	}
@@ -78,47 +97,205 @@ func (d *DiskConfigPersister) PersistAndNotify(oldHash string, cfg *pb.ServiceCo
 		return e
 	}
 
+	if d.watch != nil {
+		// So that the watch doesn't treat our own write as a change.
+		d.watch.set(cfg, HashConfig(cfg))
+	}
+
 	// ... and notify
 	d.Notify()
 
 	return nil
 }
 
-// ReadPersistedConfig provides a config previously persisted.
+// currentHash returns the hash of the currently persisted config, or an empty string if nothing has been
+// persisted yet. If the location is watched, that's the hash of the config as served, with its version bumped.
+func (d *DiskConfigPersister) currentHash() (string, error) {
+	b, e := ioutil.ReadFile(d.location)
+	if os.IsNotExist(e) {
+		return "", nil
+	}
+
+	if e != nil {
+		return "", e
+	}
+
+	cfg, e := readConfigBytes(d.location, b)
+	if e != nil {
+		return "", e
+	}
+
+	if d.watch != nil {
+		return d.watch.configHash(HashConfig(cfg)), nil
+	}
+
+	return HashConfig(cfg), nil
+}
+
+// ReadPersistedConfig provides a config previously persisted. The config is stored as a serialized protobuf, or as
+// YAML if the location is named .yaml or .yml. If the location is watched, this is the last valid config read.
 func (d *DiskConfigPersister) ReadPersistedConfig() (*pb.ServiceConfig, error) {
-	r, e := os.Open(d.location)
+	if d.watch != nil {
+		return d.watch.get()
+	}
+
+	b, e := ioutil.ReadFile(d.location)
 	if e != nil {
 		return nil, e
 	}
 
-	return Unmarshal(r)
+	return readConfigBytes(d.location, b)
 }
 
 // ReadHistoricalConfigs returns an array of previously persisted configs
 func (d *DiskConfigPersister) ReadHistoricalConfigs() ([]*pb.ServiceConfig, error) {
+	files, err := d.historicalConfigs()
+	if err != nil {
+		return nil, err
+	}
+
+	configs := make([]*pb.ServiceConfig, 0, len(files))
+	for _, cfg := range files {
+		configs = append(configs, cfg)
+	}
+
+	return configs, nil
+}
+
+// historicalConfigs reads previously persisted configs, keyed on the file they are stored in.
+func (d *DiskConfigPersister) historicalConfigs() (map[string]*pb.ServiceConfig, error) {
 	files, err := filepath.Glob(fmt.Sprintf("%s-*", d.location))
 
 	if err != nil {
 		return nil, err
 	}
 
-	configs := make([]*pb.ServiceConfig, len(files))
+	configs := make(map[string]*pb.ServiceConfig, len(files))
+
+	for _, file := range files {
+		b, e := ioutil.ReadFile(file)
+		if os.IsNotExist(e) {
+			// Pruned since globbing.
+			continue
+		}
+
+		if e != nil {
+			return nil, e
+		}
+
+		configs[file], e = UnmarshalBytes(b)
+		if e != nil {
+			return nil, e
+		}
+	}
+
+	return configs, nil
+}
+
+// ReadHistoricalConfigsPage returns a page of previously persisted configs, newest first by when their files were
+// written. Only the files of the page are read.
+func (d *DiskConfigPersister) ReadHistoricalConfigsPage(offset, limit int) ([]*pb.ServiceConfig, error) {
+	files, err := filepath.Glob(fmt.Sprintf("%s-*", d.location))
+	if err != nil {
+		return nil, err
+	}
+
+	modTimes := make(map[string]time.Time, len(files))
+	for _, file := range files {
+		info, e := os.Stat(file)
+		if os.IsNotExist(e) {
+			// Pruned since globbing.
+			continue
+		}
 
-	for i, file := range files {
-		reader, e := os.Open(file)
 		if e != nil {
 			return nil, e
 		}
 
-		configs[i], e = Unmarshal(reader)
+		modTimes[file] = info.ModTime()
+	}
+
+	files = files[:0]
+	for file := range modTimes {
+		files = append(files, file)
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		if t1, t2 := modTimes[files[i]], modTimes[files[j]]; !t1.Equal(t2) {
+			return t1.After(t2)
+		}
+
+		return files[i] < files[j]
+	})
+
+	configs := make([]*pb.ServiceConfig, 0)
+	for _, file := range page(files, offset, limit) {
+		b, e := ioutil.ReadFile(file)
+		if os.IsNotExist(e) {
+			continue
+		}
+
 		if e != nil {
 			return nil, e
 		}
+
+		cfg, e := UnmarshalBytes(b)
+		if e != nil {
+			return nil, e
+		}
+
+		configs = append(configs, cfg)
 	}
 
 	return configs, nil
 }
 
+// SetRetentionPolicy sets the policy used to prune historical configs, pruning in the background if the
+// policy is enabled.
+func (d *DiskConfigPersister) SetRetentionPolicy(policy RetentionPolicy) {
+	d.Lock()
+	old := d.pruner
+	d.retention = policy
+	d.pruner = nil
+	if policy.Enabled() {
+		d.pruner = internal.NewPruner(policy.Interval(), d.Prune)
+	}
+	d.Unlock()
+
+	old.Stop()
+}
+
+// Prune deletes the files of historical configs that fall outside the retention policy. The file the current
+// config is read from is never deleted.
+func (d *DiskConfigPersister) Prune() error {
+	d.Lock()
+	defer d.Unlock()
+
+	configs, e := d.historicalConfigs()
+	if e != nil {
+		return e
+	}
+
+	// The location is normally a symlink to the current config's file, but may be a regular file written by
+	// someone else, which won't be pruned.
+	var current string
+	if info, e := os.Lstat(d.location); e == nil && info.Mode()&os.ModeSymlink != 0 {
+		if current, e = os.Readlink(d.location); e != nil {
+			return e
+		}
+	} else if e != nil && !os.IsNotExist(e) {
+		return e
+	}
+
+	for _, file := range ExpiredConfigs(d.retention, configs, current, time.Now()) {
+		if e := os.Remove(file); e != nil && !os.IsNotExist(e) {
+			return e
+		}
+	}
+
+	return nil
+}
+
 // ConfigChangedWatcher returns a channel that is notified whenever configuration changes are
 // detected. Changes are coalesced so that a single notification may be emitted for multiple
 // changes.
@@ -128,5 +305,10 @@ func (d *DiskConfigPersister) ConfigChangedWatcher() <-chan struct{} {
 
 // Close closes the notification channel.
 func (d *DiskConfigPersister) Close() {
+	d.SetRetentionPolicy(RetentionPolicy{})
+	if d.watch != nil {
+		d.watch.stop()
+	}
+
 	close(d.Notifier.Watcher)
 }
@@ -4,8 +4,12 @@
 package config
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 
 	pbconfig "github.com/square/quotaservice/protos/config"
 	"github.com/square/quotaservice/test/helpers"
@@ -75,3 +79,101 @@ func TestDiskPersistence(t *testing.T) {
 		t.Fatal("Config channel should be closed")
 	}
 }
+
+func TestDiskPersistenceConflict(t *testing.T) {
+	dir, e := ioutil.TempDir("", "qs_test_conflict")
+	helpers.CheckError(t, e)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	persister, e := NewDiskConfigPersister(filepath.Join(dir, "config"))
+	helpers.CheckError(t, e)
+	defer persister.Close()
+
+	s := NewDefaultServiceConfig()
+	helpers.CheckError(t, persister.PersistAndNotify("", s))
+
+	oldHash := HashConfig(s)
+	s2 := CloneConfig(s)
+	s2.Version = 1
+	helpers.CheckError(t, persister.PersistAndNotify(oldHash, s2))
+
+	s3 := CloneConfig(s)
+	s3.Version = 2
+	if e = persister.PersistAndNotify(oldHash, s3); !IsConfigConflict(e) {
+		t.Fatalf("Expecting a config conflict, got %v", e)
+	}
+
+	helpers.CheckError(t, persister.PersistAndNotify(HashConfig(s2), s3))
+}
+
+func TestDiskPrune(t *testing.T) {
+	location := filepath.Join(t.TempDir(), "config")
+	persister, e := NewDiskConfigPersister(location)
+	helpers.CheckError(t, e)
+	defer persister.Close()
+
+	for v := int32(1); v <= 3; v++ {
+		cfg := NewDefaultServiceConfig()
+		cfg.Version = v
+		helpers.CheckError(t, persister.PersistAndNotify("", cfg))
+	}
+
+	persister.SetRetentionPolicy(RetentionPolicy{MaxVersions: 1})
+	helpers.CheckError(t, persister.Prune())
+
+	files, e := filepath.Glob(location + "-*")
+	helpers.CheckError(t, e)
+
+	if len(files) != 1 {
+		t.Fatalf("Expected only the current config's file to be kept; got %v", files)
+	}
+
+	current, e := persister.ReadPersistedConfig()
+	helpers.CheckError(t, e)
+
+	if current.Version != 3 {
+		t.Fatalf("Expected current config to be version 3; got %v", current.Version)
+	}
+}
+
+func TestDiskHistoricalConfigsPage(t *testing.T) {
+	location := filepath.Join(t.TempDir(), "config")
+	persister, e := NewDiskConfigPersister(location)
+	helpers.CheckError(t, e)
+	defer persister.Close()
+
+	written := time.Now().Add(-time.Hour)
+	for v := int32(1); v <= 4; v++ {
+		cfg := NewDefaultServiceConfig()
+		cfg.Version = v
+		helpers.CheckError(t, persister.PersistAndNotify("", cfg))
+
+		// Pages are ordered by when files were written, which is too close together to rely on here.
+		b, e := MarshalBytes(cfg)
+		helpers.CheckError(t, e)
+		written = written.Add(time.Second)
+		helpers.CheckError(t, os.Chtimes(location+"-"+HashConfigBytes(b), written, written))
+	}
+
+	for _, test := range []struct {
+		offset, limit int
+		expected      []int32
+	}{
+		{0, 2, []int32{4, 3}},
+		{1, 2, []int32{3, 2}},
+		{3, 0, []int32{1}},
+		{4, 1, []int32{}},
+	} {
+		cfgs, e := persister.ReadHistoricalConfigsPage(test.offset, test.limit)
+		helpers.CheckError(t, e)
+
+		versions := []int32{}
+		for _, c := range cfgs {
+			versions = append(versions, c.Version)
+		}
+
+		if !reflect.DeepEqual(versions, test.expected) {
+			t.Errorf("Expected versions %v for offset %v and limit %v; got %v", test.expected, test.offset, test.limit, versions)
+		}
+	}
+}
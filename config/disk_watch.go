@@ -0,0 +1,246 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"gopkg.in/yaml.v2"
+
+	"github.com/square/quotaservice/logging"
+	pb "github.com/square/quotaservice/protos/config"
+)
+
+const (
+	// DefaultDiskPollInterval is how often a watched location is polled if the filesystem can't be watched.
+	DefaultDiskPollInterval = 5 * time.Second
+
+	// How long to wait for a burst of filesystem events, such as several writes to the same file, to finish
+	// before reading the location.
+	diskWatchSettleTime = 100 * time.Millisecond
+)
+
+// newFsWatcher creates filesystem watchers; replaced in tests to exercise polling.
+var newFsWatcher = fsnotify.NewWatcher
+
+// diskWatch holds the last valid config read from a watched location.
+type diskWatch struct {
+	cfg *pb.ServiceConfig
+	// fileHash is the hash of cfg as read from the location, before its version was bumped.
+	fileHash string
+	stopper  chan struct{}
+	wg       sync.WaitGroup
+	sync.RWMutex
+}
+
+// NewWatchingDiskConfigPersister creates a DiskConfigPersister that also watches its location for changes made by
+// others, such as a configuration management tool dropping a new file in place. The file may contain a serialized
+// protobuf, as written by the persister, or YAML if the location is named .yaml or .yml. Changes are validated, and ConfigChangedWatcher is
+// only notified of valid configs; invalid ones are logged and ignored.
+//
+// The location is watched using filesystem notifications where they are supported, and otherwise polled every
+// pollInterval, or DefaultDiskPollInterval if pollInterval isn't positive.
+func NewWatchingDiskConfigPersister(location string, pollInterval time.Duration) (*DiskConfigPersister, error) {
+	d, e := NewDiskConfigPersister(location)
+	if e != nil {
+		return nil, e
+	}
+
+	d.watch = &diskWatch{stopper: make(chan struct{})}
+
+	// Start from whatever is there now.
+	if e := d.reload(); e != nil && !os.IsNotExist(e) {
+		return nil, e
+	}
+
+	if pollInterval <= 0 {
+		pollInterval = DefaultDiskPollInterval
+	}
+
+	watcher, e := newFsWatcher()
+	if e == nil {
+		// Watch the directory rather than the file, to see the file being replaced rather than modified.
+		if e = watcher.Add(filepath.Dir(location)); e != nil {
+			_ = watcher.Close()
+		}
+	}
+
+	d.watch.wg.Add(1)
+	if e != nil {
+		logging.Printf("Unable to watch %v for changes (%v); polling every %v instead", location, e, pollInterval)
+		go d.poll(pollInterval)
+	} else {
+		logging.Printf("Watching %v for changes", location)
+		go d.waitForEvents(watcher)
+	}
+
+	return d, nil
+}
+
+func (d *DiskConfigPersister) waitForEvents(watcher *fsnotify.Watcher) {
+	defer d.watch.wg.Done()
+	defer func() { _ = watcher.Close() }()
+
+	settle := time.NewTimer(diskWatchSettleTime)
+	settle.Stop()
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+
+			// Any change in the directory could change what the location refers to, e.g. if it is a symlink.
+			logging.Printf("Received event %v watching %v", event, d.location)
+			settle.Reset(diskWatchSettleTime)
+		case e, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+
+			logging.Printf("Received error watching %v: %v", d.location, e)
+		case <-settle.C:
+			d.reloadAndLog()
+		case <-d.watch.stopper:
+			settle.Stop()
+			return
+		}
+	}
+}
+
+func (d *DiskConfigPersister) poll(interval time.Duration) {
+	defer d.watch.wg.Done()
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			d.reloadAndLog()
+		case <-d.watch.stopper:
+			return
+		}
+	}
+}
+
+func (d *DiskConfigPersister) reloadAndLog() {
+	if e := d.reload(); e != nil {
+		logging.Printf("Not reloading config from %v: %v", d.location, e)
+	}
+}
+
+// reload reads the config at the location, notifying if it is valid and has changed.
+func (d *DiskConfigPersister) reload() error {
+	d.Lock()
+	defer d.Unlock()
+
+	b, e := ioutil.ReadFile(d.location)
+	if e != nil {
+		return e
+	}
+
+	cfg, e := readConfigBytes(d.location, b)
+	if e != nil {
+		return e
+	}
+
+	fileHash := HashConfig(cfg)
+	if fileHash == d.watch.currentFileHash() {
+		return nil
+	}
+
+	validated := CloneConfig(cfg)
+	ApplyDefaults(validated)
+	if errs := Validate(validated); len(errs) > 0 {
+		return ValidationErrors(errs)
+	}
+
+	// Tools dropping configs in place rarely bump the version, and servers ignore configs without a newer version.
+	if current, e := d.watch.get(); e == nil && cfg.Version <= current.Version {
+		cfg.Version = current.Version + 1
+	}
+
+	logging.Printf("Read new config version %v from %v", cfg.Version, d.location)
+	d.watch.set(cfg, fileHash)
+	d.Notify()
+
+	return nil
+}
+
+// set sets the config read from the location, which hashed to fileHash as read.
+func (w *diskWatch) set(cfg *pb.ServiceConfig, fileHash string) {
+	w.Lock()
+	defer w.Unlock()
+
+	w.cfg = CloneConfig(cfg)
+	w.fileHash = fileHash
+}
+
+func (w *diskWatch) get() (*pb.ServiceConfig, error) {
+	w.RLock()
+	defer w.RUnlock()
+
+	if w.cfg == nil {
+		return nil, errors.New("no valid config has been read")
+	}
+
+	return CloneConfig(w.cfg), nil
+}
+
+func (w *diskWatch) currentFileHash() string {
+	w.RLock()
+	defer w.RUnlock()
+
+	return w.fileHash
+}
+
+// configHash returns the hash of the config read from the location, given the hash of what is there now. That's
+// the hash of the config as served, whose version may have been bumped, unless the location has changed since.
+func (w *diskWatch) configHash(fileHash string) string {
+	w.RLock()
+	defer w.RUnlock()
+
+	if w.cfg == nil || fileHash != w.fileHash {
+		return fileHash
+	}
+
+	return HashConfig(w.cfg)
+}
+
+func (w *diskWatch) stop() {
+	close(w.stopper)
+	w.wg.Wait()
+}
+
+// readConfigBytes parses a config read from location. Configs are stored as serialized protobufs, as written by
+// the persister, but locations named .yaml or .yml may hold YAML instead, to which defaults are applied as with
+// ReadConfig.
+func readConfigBytes(location string, b []byte) (*pb.ServiceConfig, error) {
+	cfg, protoErr := UnmarshalBytes(b)
+	if protoErr == nil || !isYAMLFile(location) {
+		return cfg, protoErr
+	}
+
+	cfg, yamlErr := readConfigFromBytes(b, yaml.Unmarshal)
+	if yamlErr == nil {
+		return cfg, nil
+	}
+
+	return nil, fmt.Errorf("config is neither a serialized protobuf (%v) nor YAML (%v)", protoErr, yamlErr)
+}
+
+func isYAMLFile(location string) bool {
+	ext := strings.ToLower(filepath.Ext(location))
+	return ext == ".yaml" || ext == ".yml"
+}
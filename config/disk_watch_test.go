@@ -0,0 +1,167 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/square/quotaservice/test/helpers"
+)
+
+func TestWatchingDiskPersister(t *testing.T) {
+	testWatchingDiskPersister(t)
+}
+
+func TestWatchingDiskPersisterPolling(t *testing.T) {
+	newFsWatcher = func() (*fsnotify.Watcher, error) {
+		return nil, errors.New("not supported")
+	}
+	defer func() { newFsWatcher = fsnotify.NewWatcher }()
+
+	testWatchingDiskPersister(t)
+}
+
+func testWatchingDiskPersister(t *testing.T) {
+	location := filepath.Join(t.TempDir(), "config.yaml")
+	replaceFile(t, location, "namespaces:\n  first:\n    default_bucket:\n      size: 10\n      fill_rate: 10\n")
+
+	persister, e := NewWatchingDiskConfigPersister(location, 20*time.Millisecond)
+	helpers.CheckError(t, e)
+	defer persister.Close()
+
+	<-persister.ConfigChangedWatcher()
+	assertPersistedNamespace(t, persister, "first")
+	assertPersistedVersion(t, persister, 0)
+
+	// Changes made by others are picked up, with a newer version even if the file doesn't have one.
+	replaceFile(t, location, "namespaces:\n  second:\n    default_bucket:\n      size: 10\n      fill_rate: 10\n")
+	expectNotification(t, persister, true)
+	assertPersistedNamespace(t, persister, "second")
+	assertPersistedVersion(t, persister, 1)
+
+	replaceFile(t, location, "version: 10\nnamespaces:\n  second:\n    default_bucket:\n      size: 20\n      fill_rate: 10\n")
+	expectNotification(t, persister, true)
+	assertPersistedVersion(t, persister, 10)
+
+	// Changes based on the config as served, with its version bumped, aren't conflicts.
+	served, e := persister.ReadPersistedConfig()
+	helpers.CheckError(t, e)
+	helpers.CheckError(t, persister.PersistAndNotify(HashConfig(served), served))
+	expectNotification(t, persister, true)
+
+	replaceFile(t, location, "namespaces:\n  second:\n    default_bucket:\n      size: 10\n      fill_rate: 10\n")
+	expectNotification(t, persister, true)
+	assertPersistedVersion(t, persister, 11)
+
+	// Invalid configs are ignored.
+	replaceFile(t, location, "namespaces:\n  third:\n    default_bucket:\n      size: -1\n")
+	expectNotification(t, persister, false)
+	assertPersistedNamespace(t, persister, "second")
+
+	// Configs we write ourselves are read back as protobufs.
+	cfg := NewDefaultServiceConfig()
+	helpers.CheckError(t, AddNamespace(cfg, NewDefaultNamespaceConfig("fourth")))
+	helpers.CheckError(t, persister.PersistAndNotify("", cfg))
+	expectNotification(t, persister, true)
+	assertPersistedNamespace(t, persister, "fourth")
+	expectNotification(t, persister, false)
+}
+
+func TestWatchingDiskPersisterInvalid(t *testing.T) {
+	location := filepath.Join(t.TempDir(), "config.yaml")
+	replaceFile(t, location, "namespaces:\n  ns:\n    max_dynamic_buckets: -1\n")
+
+	if _, e := NewWatchingDiskConfigPersister(location, 0); e == nil {
+		t.Fatal("Expected an error watching an invalid config")
+	}
+}
+
+// replaceFile atomically replaces a file, as configuration management tools do.
+func replaceFile(t *testing.T, path, contents string) {
+	t.Helper()
+
+	tmp := path + ".tmp"
+	helpers.CheckError(t, ioutil.WriteFile(tmp, []byte(contents), 0644))
+	helpers.CheckError(t, os.Rename(tmp, path))
+}
+
+func expectNotification(t *testing.T, p ConfigPersister, expected bool) {
+	t.Helper()
+
+	select {
+	case <-p.ConfigChangedWatcher():
+		if !expected {
+			t.Fatal("Did not expect a notification")
+		}
+	case <-time.After(500 * time.Millisecond):
+		if expected {
+			t.Fatal("Expected a notification")
+		}
+	}
+}
+
+func assertPersistedNamespace(t *testing.T, p ConfigPersister, namespace string) {
+	t.Helper()
+
+	cfg, e := p.ReadPersistedConfig()
+	helpers.CheckError(t, e)
+
+	if len(cfg.Namespaces) != 1 || cfg.Namespaces[namespace] == nil {
+		t.Fatalf("Expected config with namespace %v; got %v", namespace, NamespaceNames(cfg))
+	}
+}
+
+func assertPersistedVersion(t *testing.T, p ConfigPersister, version int32) {
+	t.Helper()
+
+	cfg, e := p.ReadPersistedConfig()
+	helpers.CheckError(t, e)
+
+	if cfg.Version != version {
+		t.Fatalf("Expected config version %v; got %v", version, cfg.Version)
+	}
+}
+
+func TestReadConfigBytes(t *testing.T) {
+	yamlConfig := []byte("version: 3\nnamespaces:\n  ns: {}\n")
+
+	cfg := NewDefaultServiceConfig()
+	cfg.Version = 4
+	protoConfig, e := MarshalBytes(cfg)
+	helpers.CheckError(t, e)
+
+	for _, test := range []struct {
+		location string
+		b        []byte
+		version  int32
+	}{
+		{"config.yaml", yamlConfig, 3},
+		{"config.YML", yamlConfig, 3},
+		{"config.yaml", protoConfig, 4},
+		{"config", protoConfig, 4},
+		// Only locations named as YAML may hold YAML.
+		{"config", yamlConfig, -1},
+	} {
+		cfg, e := readConfigBytes(test.location, test.b)
+		if test.version < 0 {
+			if e == nil {
+				t.Errorf("Expected %v not to be read as YAML, got %+v", test.location, cfg)
+			}
+
+			continue
+		}
+
+		helpers.CheckError(t, e)
+		if cfg.Version != test.version {
+			t.Errorf("Expected version %v from %v, got %v", test.version, test.location, cfg.Version)
+		}
+	}
+}
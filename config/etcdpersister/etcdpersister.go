@@ -0,0 +1,396 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+// Package etcdpersister implements a ConfigPersister that stores configs in etcd.
+package etcdpersister
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/square/quotaservice/config"
+	"github.com/square/quotaservice/config/internal"
+	"github.com/square/quotaservice/logging"
+	pb "github.com/square/quotaservice/protos/config"
+)
+
+const (
+	dialTimeout    = 3 * time.Second
+	requestTimeout = 5 * time.Second
+
+	// Delay before re-establishing a watch that failed.
+	rewatchDelay = time.Second
+
+	currentKey = "/current"
+	configsKey = "/configs/"
+)
+
+// EtcdPersister stores configs in etcd. Every config persisted is stored under <prefix>/configs/<hash>, and
+// <prefix>/current holds the hash of the current config. All nodes watch the prefix, so a config persisted by
+// one node is picked up by all of them.
+type EtcdPersister struct {
+	client *clientv3.Client
+	prefix string
+
+	// Current configuration hash
+	config string
+
+	// Historical map of configurations
+	// hash -> config
+	configs map[string]*pb.ServiceConfig
+
+	// etcd revision configs were last read at
+	rev int64
+
+	retention config.RetentionPolicy
+	pruner    *internal.Pruner
+
+	notifier *internal.Notifier
+	cancel   context.CancelFunc
+
+	wg sync.WaitGroup
+	sync.RWMutex
+}
+
+// New connects to the etcd cluster at endpoints and returns a persister storing configs under prefix.
+func New(prefix string, endpoints []string) (*EtcdPersister, error) {
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   endpoints,
+		DialTimeout: dialTimeout,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewWithClient(prefix, client)
+}
+
+// NewWithClient returns a persister storing configs under prefix, using an existing client. The client is
+// closed when the persister is closed.
+func NewWithClient(prefix string, client *clientv3.Client) (*EtcdPersister, error) {
+	p := &EtcdPersister{
+		client:   client,
+		prefix:   strings.TrimSuffix(prefix, "/"),
+		configs:  make(map[string]*pb.ServiceConfig),
+		notifier: internal.NewNotifier(),
+	}
+
+	logging.Printf("Reading configs from etcd prefix %v for the first time", p.prefix)
+	rev, err := p.refresh()
+	if err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+
+	p.wg.Add(1)
+	go p.watch(ctx, rev)
+
+	return p, nil
+}
+
+func (p *EtcdPersister) configKey(hash string) string {
+	return p.prefix + configsKey + hash
+}
+
+// refresh reads all configs under the prefix, notifies the watcher and returns the etcd revision read at.
+func (p *EtcdPersister) refresh() (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	resp, err := p.client.Get(ctx, p.prefix+"/", clientv3.WithPrefix())
+	if err != nil {
+		return 0, err
+	}
+
+	configs := make(map[string]*pb.ServiceConfig)
+	var current string
+
+	for _, kv := range resp.Kvs {
+		key := string(kv.Key)
+
+		if key == p.prefix+currentKey {
+			current = string(kv.Value)
+			continue
+		}
+
+		if !strings.HasPrefix(key, p.prefix+configsKey) {
+			continue
+		}
+
+		hash := strings.TrimPrefix(key, p.prefix+configsKey)
+		cfg, err := config.UnmarshalBytes(kv.Value)
+		if err != nil {
+			logging.Printf("Could not unmarshal config %v, error: %s", key, err)
+			continue
+		}
+
+		configs[hash] = cfg
+	}
+
+	p.Lock()
+	if resp.Header.Revision < p.rev {
+		// Raced with a more recent refresh or prune.
+		p.Unlock()
+		return resp.Header.Revision, nil
+	}
+
+	p.configs = configs
+	p.config = current
+	p.rev = resp.Header.Revision
+	p.Unlock()
+
+	if cfg := configs[current]; cfg != nil {
+		logging.Printf("Setting latest config hash to %v (version %v)", current, cfg.Version)
+	}
+
+	p.notifier.Notify()
+
+	return resp.Header.Revision, nil
+}
+
+// watch refreshes the configs whenever anything under the prefix changes, until ctx is cancelled.
+func (p *EtcdPersister) watch(ctx context.Context, rev int64) {
+	defer p.wg.Done()
+
+	for {
+		logging.Printf("Establishing etcd watch on %v from revision %v", p.prefix, rev+1)
+		ch := p.client.Watch(ctx, p.prefix+"/", clientv3.WithPrefix(), clientv3.WithRev(rev+1))
+
+		for resp := range ch {
+			if err := resp.Err(); err != nil {
+				logging.Printf("Received error from etcd watch: %v", err)
+				break
+			}
+
+			if len(resp.Events) == 0 {
+				continue
+			}
+
+			logging.Printf("Received %v event(s) on etcd watch", len(resp.Events))
+			newRev, err := p.refresh()
+			if err != nil {
+				logging.Printf("Received error from etcd refreshing configs: %v", err)
+				break
+			}
+
+			rev = newRev
+		}
+
+		select {
+		case <-ctx.Done():
+			logging.Print("Received stop signal; stopping etcd watcher goroutine")
+			return
+		case <-time.After(rewatchDelay):
+		}
+
+		// Catch up with anything missed while the watch was down, e.g. if the revision was compacted.
+		if newRev, err := p.refresh(); err != nil {
+			logging.Printf("Received error from etcd refreshing configs: %v", err)
+		} else {
+			rev = newRev
+		}
+	}
+}
+
+// PersistAndNotify persists a configuration passed in. The current config's hash is swapped in the same
+// transaction that stores the config, comparing revisions so that concurrent writers can't overwrite each other.
+func (p *EtcdPersister) PersistAndNotify(oldHash string, cfg *pb.ServiceConfig) error {
+	b, err := config.MarshalBytes(cfg)
+	if err != nil {
+		return err
+	}
+
+	key := config.HashConfigBytes(b)
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	resp, err := p.client.Get(ctx, p.prefix+currentKey)
+	if err != nil {
+		return err
+	}
+
+	// Until a config is persisted, the default config is read, so updates are based on it.
+	currentHash := config.HashConfig(config.NewDefaultServiceConfig())
+	var modRevision int64
+	if len(resp.Kvs) > 0 {
+		currentHash = string(resp.Kvs[0].Value)
+		modRevision = resp.Kvs[0].ModRevision
+	}
+
+	if key == currentHash {
+		return nil
+	}
+
+	if err := config.CheckConfigHash(oldHash, currentHash); err != nil {
+		return err
+	}
+
+	var cmps []clientv3.Cmp
+	if oldHash != "" {
+		// A ModRevision of 0 means the key doesn't exist.
+		cmps = append(cmps, clientv3.Compare(clientv3.ModRevision(p.prefix+currentKey), "=", modRevision))
+	}
+
+	logging.Printf("Storing config version %v in key %v", cfg.Version, p.configKey(key))
+	txn, err := p.client.Txn(ctx).
+		If(cmps...).
+		Then(clientv3.OpPut(p.configKey(key), string(b)), clientv3.OpPut(p.prefix+currentKey, key)).
+		Commit()
+
+	if err != nil {
+		return err
+	}
+
+	if !txn.Succeeded {
+		// Someone else got there first.
+		conflict := &config.ConfigConflictError{ExpectedHash: oldHash}
+		if resp, e := p.client.Get(ctx, p.prefix+currentKey); e == nil && len(resp.Kvs) > 0 {
+			conflict.ActualHash = string(resp.Kvs[0].Value)
+		}
+
+		return conflict
+	}
+
+	// There is no notification, that happens when etcd alerts the watcher
+
+	return nil
+}
+
+// ConfigChangedWatcher returns a channel that is notified whenever configuration changes are
+// detected. Changes are coalesced so that a single notification may be emitted for multiple
+// changes.
+func (p *EtcdPersister) ConfigChangedWatcher() <-chan struct{} {
+	return p.notifier.Watcher
+}
+
+// ReadPersistedConfig provides a config previously persisted, or the default config if no config has been
+// persisted.
+func (p *EtcdPersister) ReadPersistedConfig() (*pb.ServiceConfig, error) {
+	p.RLock()
+	defer p.RUnlock()
+
+	cfg := p.configs[p.config]
+	if cfg == nil {
+		return config.NewDefaultServiceConfig(), nil
+	}
+
+	return config.CloneConfig(cfg), nil
+}
+
+// ReadHistoricalConfigs returns an array of previously persisted configs
+func (p *EtcdPersister) ReadHistoricalConfigs() ([]*pb.ServiceConfig, error) {
+	p.RLock()
+	defer p.RUnlock()
+
+	return config.CloneConfigs(p.configs), nil
+}
+
+// ReadHistoricalConfigsPage returns a page of previously persisted configs, newest first. Configs are read from
+// etcd in the order their keys were created, which is the order they were persisted in, up to the end of the page.
+func (p *EtcdPersister) ReadHistoricalConfigsPage(offset, limit int) ([]*pb.ServiceConfig, error) {
+	offset = max(offset, 0)
+	opts := []clientv3.OpOption{
+		clientv3.WithPrefix(),
+		clientv3.WithSort(clientv3.SortByCreateRevision, clientv3.SortDescend)}
+	if limit > 0 {
+		opts = append(opts, clientv3.WithLimit(int64(offset+limit)))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	resp, err := p.client.Get(ctx, p.prefix+configsKey, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	cfgs := make([]*pb.ServiceConfig, 0, max(len(resp.Kvs)-offset, 0))
+	for i := offset; i < len(resp.Kvs); i++ {
+		cfg, err := config.UnmarshalBytes(resp.Kvs[i].Value)
+		if err != nil {
+			logging.Printf("Could not unmarshal config %s, error: %s", resp.Kvs[i].Key, err)
+			continue
+		}
+
+		cfgs = append(cfgs, cfg)
+	}
+
+	return cfgs, nil
+}
+
+// SetRetentionPolicy sets the policy used to prune historical configs, pruning in the background if the
+// policy is enabled.
+func (p *EtcdPersister) SetRetentionPolicy(policy config.RetentionPolicy) {
+	p.Lock()
+	old := p.pruner
+	p.retention = policy
+	p.pruner = nil
+	if policy.Enabled() {
+		p.pruner = internal.NewPruner(policy.Interval(), p.Prune)
+	}
+	p.Unlock()
+
+	old.Stop()
+}
+
+// Prune deletes the keys of historical configs that fall outside the retention policy. Other quotaservice
+// nodes pruning at the same time is harmless.
+func (p *EtcdPersister) Prune() error {
+	p.RLock()
+	expired := config.ExpiredConfigs(p.retention, p.configs, p.config, time.Now())
+	p.RUnlock()
+
+	if len(expired) == 0 {
+		return nil
+	}
+
+	ops := make([]clientv3.Op, 0, len(expired))
+	for _, hash := range expired {
+		ops = append(ops, clientv3.OpDelete(p.configKey(hash)))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	logging.Printf("Pruning configs %v", expired)
+	txn, err := p.client.Txn(ctx).Then(ops...).Commit()
+	if err != nil {
+		return err
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	if txn.Header.Revision > p.rev {
+		p.rev = txn.Header.Revision
+	}
+
+	for _, hash := range expired {
+		delete(p.configs, hash)
+	}
+
+	return nil
+}
+
+// Close stops watching etcd, closes the notification channel and then closes the client.
+func (p *EtcdPersister) Close() {
+	p.SetRetentionPolicy(config.RetentionPolicy{})
+
+	p.cancel()
+	p.wg.Wait()
+
+	close(p.notifier.Watcher)
+
+	if err := p.client.Close(); err != nil {
+		logging.Printf("Could not close etcd client: %v", err)
+	}
+}
@@ -0,0 +1,256 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package etcdpersister
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/server/v3/embed"
+
+	"github.com/square/quotaservice/config"
+	pb "github.com/square/quotaservice/protos/config"
+	"github.com/square/quotaservice/test/helpers"
+)
+
+var endpoints []string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "qs_etcd_test")
+	helpers.PanicError(err)
+
+	cfg := embed.NewConfig()
+	cfg.Dir = dir
+	cfg.LogLevel = "error"
+
+	clientURL, peerURL := localURL(), localURL()
+	cfg.ListenClientUrls = []url.URL{clientURL}
+	cfg.AdvertiseClientUrls = []url.URL{clientURL}
+	cfg.ListenPeerUrls = []url.URL{peerURL}
+	cfg.AdvertisePeerUrls = []url.URL{peerURL}
+	cfg.InitialCluster = fmt.Sprintf("%s=%s", cfg.Name, peerURL.String())
+
+	e, err := embed.StartEtcd(cfg)
+	helpers.PanicError(err)
+
+	select {
+	case <-e.Server.ReadyNotify():
+	case <-time.After(10 * time.Second):
+		panic("embedded etcd took too long to start")
+	}
+
+	endpoints = []string{clientURL.String()}
+	code := m.Run()
+
+	// You can't defer this because os.Exit doesn't care for defer
+	e.Close()
+	_ = os.RemoveAll(dir)
+
+	os.Exit(code)
+}
+
+// localURL returns a URL on a free local port.
+func localURL() url.URL {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	helpers.PanicError(err)
+	defer func() { _ = l.Close() }()
+
+	return url.URL{Scheme: "http", Host: l.Addr().String()}
+}
+
+func newPersister(t *testing.T) *EtcdPersister {
+	// Unique per run, so that repeated runs don't see each other's configs.
+	p, err := New(fmt.Sprintf("/%s/%d", t.Name(), time.Now().UnixNano()), endpoints)
+	helpers.CheckError(t, err)
+
+	// Clear the notification sent when the persister starts
+	expectNotification(t, p)
+
+	return p
+}
+
+func expectNotification(t *testing.T, p *EtcdPersister) {
+	select {
+	case <-p.ConfigChangedWatcher():
+	case <-time.After(5 * time.Second):
+		t.Fatal("No notification received")
+	}
+}
+
+func newConfig(version int32) *pb.ServiceConfig {
+	cfg := config.NewDefaultServiceConfig()
+	cfg.Version = version
+	return cfg
+}
+
+func TestNew(t *testing.T) {
+	p := newPersister(t)
+	defer p.Close()
+
+	cfg, err := p.ReadPersistedConfig()
+	helpers.CheckError(t, err)
+
+	if config.HashConfig(cfg) != config.HashConfig(config.NewDefaultServiceConfig()) {
+		t.Errorf("Expected the default config on new prefix, got %+v", cfg)
+	}
+
+	// The first update is based on the default config.
+	cfg.Version = 1
+	helpers.CheckError(t, p.PersistAndNotify(config.HashConfig(config.NewDefaultServiceConfig()), cfg))
+}
+
+func TestPersistAndNotify(t *testing.T) {
+	p := newPersister(t)
+	defer p.Close()
+
+	cfg := newConfig(1)
+	helpers.CheckError(t, p.PersistAndNotify("", cfg))
+	expectNotification(t, p)
+
+	persisted, err := p.ReadPersistedConfig()
+	helpers.CheckError(t, err)
+
+	if config.HashConfig(persisted) != config.HashConfig(cfg) {
+		t.Errorf("Expected %+v, got %+v", cfg, persisted)
+	}
+
+	// Another node should read the same config.
+	other, err := New(p.prefix, endpoints)
+	helpers.CheckError(t, err)
+	defer other.Close()
+
+	persisted, err = other.ReadPersistedConfig()
+	helpers.CheckError(t, err)
+
+	if persisted.Version != 1 {
+		t.Errorf("Expected version 1, got %+v", persisted)
+	}
+
+	// And be notified of changes made by the first.
+	<-other.ConfigChangedWatcher()
+	helpers.CheckError(t, p.PersistAndNotify(config.HashConfig(cfg), newConfig(2)))
+
+	select {
+	case <-other.ConfigChangedWatcher():
+	case <-time.After(5 * time.Second):
+		t.Fatal("No notification received")
+	}
+
+	persisted, err = other.ReadPersistedConfig()
+	helpers.CheckError(t, err)
+
+	if persisted.Version != 2 {
+		t.Errorf("Expected version 2, got %+v", persisted)
+	}
+
+	history, err := other.ReadHistoricalConfigs()
+	helpers.CheckError(t, err)
+
+	if len(history) != 2 {
+		t.Errorf("Expected 2 historical configs, got %+v", history)
+	}
+}
+
+func TestPersistConflict(t *testing.T) {
+	p := newPersister(t)
+	defer p.Close()
+
+	cfg := newConfig(1)
+	helpers.CheckError(t, p.PersistAndNotify("", cfg))
+
+	oldHash := config.HashConfig(cfg)
+	cfg2 := newConfig(2)
+	helpers.CheckError(t, p.PersistAndNotify(oldHash, cfg2))
+
+	err := p.PersistAndNotify(oldHash, newConfig(3))
+	if !config.IsConfigConflict(err) {
+		t.Fatalf("Expecting a config conflict, got %v", err)
+	}
+
+	if actual := err.(*config.ConfigConflictError).ActualHash; actual != config.HashConfig(cfg2) {
+		t.Errorf("Expected actual hash %v, got %v", config.HashConfig(cfg2), actual)
+	}
+
+	helpers.CheckError(t, p.PersistAndNotify(config.HashConfig(cfg2), newConfig(3)))
+}
+
+func TestPrune(t *testing.T) {
+	p := newPersister(t)
+	defer p.Close()
+
+	for v := int32(1); v <= 3; v++ {
+		helpers.CheckError(t, p.PersistAndNotify("", newConfig(v)))
+	}
+
+	// Wait for the watch to catch up with all three versions.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		cfgs, err := p.ReadHistoricalConfigs()
+		helpers.CheckError(t, err)
+
+		if len(cfgs) == 3 {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected 3 historical configs, got %+v", cfgs)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	p.SetRetentionPolicy(config.RetentionPolicy{MaxVersions: 1})
+	helpers.CheckError(t, p.Prune())
+
+	cfgs, err := p.ReadHistoricalConfigs()
+	helpers.CheckError(t, err)
+
+	if len(cfgs) != 1 || cfgs[0].Version != 3 {
+		t.Fatalf("Expected only version 3 to be kept; got %+v", cfgs)
+	}
+
+	resp, err := p.client.Get(p.client.Ctx(), p.prefix+configsKey, clientv3.WithPrefix(), clientv3.WithCountOnly())
+	helpers.CheckError(t, err)
+
+	if resp.Count != 1 {
+		t.Fatalf("Expected 1 config in etcd, got %v", resp.Count)
+	}
+}
+
+func TestReadHistoricalConfigsPage(t *testing.T) {
+	p := newPersister(t)
+	defer p.Close()
+
+	for v := int32(1); v <= 4; v++ {
+		helpers.CheckError(t, p.PersistAndNotify("", newConfig(v)))
+	}
+
+	for _, c := range []struct {
+		offset, limit int
+		expected      []int32
+	}{
+		{0, 2, []int32{4, 3}},
+		{1, 2, []int32{3, 2}},
+		{3, 0, []int32{1}},
+		{4, 1, []int32{}},
+	} {
+		cfgs, err := p.ReadHistoricalConfigsPage(c.offset, c.limit)
+		helpers.CheckError(t, err)
+
+		versions := make([]int32, 0, len(cfgs))
+		for _, cfg := range cfgs {
+			versions = append(versions, cfg.Version)
+		}
+
+		if !reflect.DeepEqual(versions, c.expected) {
+			t.Errorf("Expected versions %v at offset %v with limit %v, got %v", c.expected, c.offset, c.limit, versions)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package internal
+
+import (
+	"sync"
+	"time"
+
+	"github.com/square/quotaservice/logging"
+)
+
+// Pruner calls a function periodically in the background, until stopped.
+type Pruner struct {
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewPruner starts calling prune every interval, logging any errors returned.
+func NewPruner(interval time.Duration, prune func() error) *Pruner {
+	p := &Pruner{stop: make(chan struct{})}
+	p.wg.Add(1)
+
+	go func() {
+		defer p.wg.Done()
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-t.C:
+				if err := prune(); err != nil {
+					logging.Printf("Error pruning historical configs: %v", err)
+				}
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+
+	return p
+}
+
+// Stop stops the pruner, waiting for any prune in progress to complete. It is safe to call on a nil Pruner.
+func (p *Pruner) Stop() {
+	if p == nil {
+		return
+	}
+
+	close(p.stop)
+	p.wg.Wait()
+}
@@ -5,6 +5,7 @@ package config
 
 import (
 	"sync"
+	"time"
 
 	"github.com/square/quotaservice/config/internal"
 	pb "github.com/square/quotaservice/protos/config"
@@ -13,6 +14,10 @@ import (
 type MemoryConfigPersister struct {
 	config  string
 	configs map[string]*pb.ServiceConfig
+	// Hashes of configs, newest first
+	history   []string
+	retention RetentionPolicy
+	pruner    *internal.Pruner
 	*internal.Notifier
 	*sync.RWMutex
 }
@@ -29,13 +34,16 @@ func NewMemoryConfigPersister() *MemoryConfigPersister {
 
 // PersistAndNotify persists a configuration passed in.
 func (m *MemoryConfigPersister) PersistAndNotify(oldHash string, cfg *pb.ServiceConfig) error {
-	// TODO(manik) Optimistic version check with oldHash
-
 	m.Lock()
 	defer m.Unlock()
 
+	if err := CheckConfigHash(oldHash, m.config); err != nil {
+		return err
+	}
+
 	m.config = HashConfig(cfg)
 	m.configs[m.config] = CloneConfig(cfg)
+	m.history = newestFirst(m.configs)
 
 	// ... and notify
 	m.Notify()
@@ -59,6 +67,42 @@ func (m *MemoryConfigPersister) ReadHistoricalConfigs() ([]*pb.ServiceConfig, er
 	return CloneConfigs(m.configs), nil
 }
 
+// ReadHistoricalConfigsPage returns a page of previously persisted configs, newest first.
+func (m *MemoryConfigPersister) ReadHistoricalConfigsPage(offset, limit int) ([]*pb.ServiceConfig, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	return pageOfConfigs(m.configs, m.history, offset, limit), nil
+}
+
+// SetRetentionPolicy sets the policy used to prune historical configs, pruning in the background if the
+// policy is enabled.
+func (m *MemoryConfigPersister) SetRetentionPolicy(policy RetentionPolicy) {
+	m.Lock()
+	old := m.pruner
+	m.retention = policy
+	m.pruner = nil
+	if policy.Enabled() {
+		m.pruner = internal.NewPruner(policy.Interval(), m.Prune)
+	}
+	m.Unlock()
+
+	old.Stop()
+}
+
+// Prune removes historical configs that fall outside the retention policy.
+func (m *MemoryConfigPersister) Prune() error {
+	m.Lock()
+	defer m.Unlock()
+
+	for _, hash := range ExpiredConfigs(m.retention, m.configs, m.config, time.Now()) {
+		delete(m.configs, hash)
+	}
+
+	m.history = newestFirst(m.configs)
+	return nil
+}
+
 // ConfigChangedWatcher returns a channel that is notified whenever configuration changes are
 // detected. Changes are coalesced so that a single notification may be emitted for multiple
 // changes.
@@ -68,5 +112,6 @@ func (m *MemoryConfigPersister) ConfigChangedWatcher() <-chan struct{} {
 
 // Close closes the notification channel.
 func (m *MemoryConfigPersister) Close() {
+	m.SetRetentionPolicy(RetentionPolicy{})
 	close(m.Notifier.Watcher)
 }
@@ -63,6 +63,20 @@ func TestMemoryPersistence(t *testing.T) {
 		t.Fatalf("Configs should be equal! %+v != %+v", s, cfgs[0])
 	}
 
+	// Test optimistic concurrency
+	oldHash := HashConfig(s)
+	s2 := CloneConfig(s)
+	s2.Version = 93
+	helpers.CheckError(t, persister.PersistAndNotify(oldHash, s2))
+
+	s3 := CloneConfig(s)
+	s3.Version = 94
+	if e = persister.PersistAndNotify(oldHash, s3); !IsConfigConflict(e) {
+		t.Fatalf("Expecting a config conflict, got %v", e)
+	}
+
+	<-persister.ConfigChangedWatcher()
+
 	// Test close
 	persister.Close()
 	select {
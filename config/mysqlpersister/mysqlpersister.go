@@ -1,255 +1,54 @@
+// Package mysqlpersister implements a ConfigPersister that stores configs in MySQL.
 package mysqlpersister
 
 import (
-	"database/sql"
-	"errors"
-	"github.com/square/quotaservice/config/internal"
-	"sort"
-	"sync"
 	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/go-sql-driver/mysql"
-	"github.com/golang/protobuf/proto"
 
-	"github.com/square/quotaservice/config"
-	"github.com/square/quotaservice/logging"
-	qsc "github.com/square/quotaservice/protos/config"
+	"github.com/square/quotaservice/config/sqlpersister"
 )
 
-var ErrDuplicateConfig = errors.New("config with provided version number already exists")
+var ErrDuplicateConfig = sqlpersister.ErrDuplicateConfig
 
 const (
 	mysqlErrDuplicateEntry = 1062
 )
 
-type MysqlPersister struct {
-	latestVersion int
-	db            *sql.DB
-	m             *sync.RWMutex
+type MysqlPersister = sqlpersister.SQLPersister
 
-	notifier        *internal.Notifier
-	shutdown        chan struct{}
-	fetcherShutdown chan struct{}
+type Connector = sqlpersister.Connector
 
-	configs map[int]*qsc.ServiceConfig
-}
-
-type configRow struct {
-	Version int    `db:"Version"`
-	Config  string `db:"Config"`
-}
-
-type Connector interface {
-	Connect() (*sql.DB, error)
-}
-
-func New(c Connector, pollingInterval time.Duration) (*MysqlPersister, error) {
-	logging.Print("Connecting to MySQL")
-	db, err := c.Connect()
-	if err != nil {
-		return nil, err
-	}
-	logging.Print("Connecting to MySQL: OK")
-
-	logging.Print("Verifying table exists")
-	q, args, err := sq.Select("1").From("quotaservice").Limit(1).ToSql()
-	if err != nil {
-		return nil, err
-	}
-
-	_, err = db.Exec(q, args...)
-	if err != nil {
-		return nil, errors.New("table quotaservice does not exist")
-	}
-	logging.Print("Verifying table exists: OK")
-
-	mp := &MysqlPersister{
-		db:              db,
-		configs:         make(map[int]*qsc.ServiceConfig),
-		m:               &sync.RWMutex{},
-		notifier:        internal.NewNotifier(),
-		shutdown:        make(chan struct{}),
-		fetcherShutdown: make(chan struct{}),
-		latestVersion:   -1,
-	}
-
-	logging.Print("Pulling configs from MySQL")
-	if _, err := mp.pullConfigs(); err != nil {
-		return nil, err
-	}
-
-	mp.m.RLock()
-	v := mp.latestVersion
-	mp.m.RUnlock()
-	logging.Printf("Pulling configs from MySQL: OK; Latest Version: %v", v)
-
-	mp.notifyWatcher()
-
-	go mp.configFetcher(pollingInterval)
-
-	return mp, nil
-}
-
-func (mp *MysqlPersister) configFetcher(pollingInterval time.Duration) {
-	defer func() {
-		close(mp.fetcherShutdown)
-	}()
-
-	for {
-		select {
-		case <-time.After(pollingInterval):
-			if newConf, err := mp.pullConfigs(); err != nil {
-				logging.Printf("Received an error trying to fetch config updates: %s", err)
-			} else if newConf {
-				logging.Print("New config(s) found in MySQL")
-				mp.notifyWatcher()
-			}
-		case <-mp.shutdown:
-			logging.Print("Received shutdown signal, shutting down mysql watcher")
-			return
-		}
-	}
-}
-
-// pullConfigs checks the database for new configs and returns true if there is a new config
-func (mp *MysqlPersister) pullConfigs() (bool, error) {
-	mp.m.RLock()
-	v := mp.latestVersion
-	mp.m.RUnlock()
-
-	logging.Printf("Fetching configs later than %v", v)
-	q, args, err := sq.
-		Select("Version", "Config").
-		From("quotaservice").
-		Where("Version > ?", v).
-		OrderBy("Version ASC").ToSql()
-	if err != nil {
-		return false, err
-	}
-
-	rows, err := mp.db.Query(q, args...)
-	if err != nil {
-		return false, err
-	}
-	logging.Printf("Fetching configs later than %v: OK", v)
-
-	rowCount := 0
-	maxVersion := -1
-	for rows.Next() {
-		rowCount++
-
-		var r configRow
-		err := rows.Scan(&r.Version, &r.Config)
-		if err != nil {
-			return false, err
-		}
-
-		var c qsc.ServiceConfig
-		err = proto.Unmarshal([]byte(r.Config), &c)
-		if err != nil {
-			logging.Printf("Could not unmarshal config version %v, error: %s", r.Version, err)
-			continue
-		}
+// Dialect adapts sqlpersister to MySQL.
+type Dialect struct{}
 
-		mp.m.Lock()
-		mp.configs[r.Version] = &c
-		mp.m.Unlock()
-
-		maxVersion = r.Version
-	}
-
-	if rowCount == 0 {
-		logging.Printf("No versions later than %v found", v)
-		return false, nil
-	}
-
-	logging.Printf("Upgrading from version %v to %v", v, maxVersion)
-
-	mp.m.Lock()
-	mp.latestVersion = maxVersion
-	mp.m.Unlock()
-
-	return true, nil
+func (Dialect) Name() string {
+	return "MySQL"
 }
 
-func (mp *MysqlPersister) notifyWatcher() {
-	logging.Print("Notifying config watcher")
-	mp.notifier.Notify()
+func (Dialect) Placeholder() sq.PlaceholderFormat {
+	return sq.Question
 }
 
-// PersistAndNotify persists a marshalled configuration passed in.
-func (mp *MysqlPersister) PersistAndNotify(_ string, c *qsc.ServiceConfig) error {
-	logging.Printf("Persisting version %v", c.GetVersion())
-	b, err := proto.Marshal(c)
-	q, args, err := sq.Insert("quotaservice").Columns("Version", "Config").Values(c.GetVersion(), string(b)).ToSql()
-	if err != nil {
-		return err
+func (Dialect) Migrations() []string {
+	return []string{
+		"CREATE TABLE IF NOT EXISTS quotaservice (ID BIGINT UNSIGNED PRIMARY KEY AUTO_INCREMENT, Version INT UNIQUE, Config BLOB, INDEX version_index (Version))",
+		"CREATE TABLE IF NOT EXISTS quotaservice_audit (ID BIGINT UNSIGNED PRIMARY KEY AUTO_INCREMENT, Time BIGINT, Username VARCHAR(255), Action VARCHAR(64), Entry MEDIUMBLOB, INDEX time_index (Time))",
 	}
-
-	_, err = mp.db.Exec(q, args...)
-	if err != nil {
-		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == mysqlErrDuplicateEntry {
-			return ErrDuplicateConfig
-		}
-
-		return err
-	}
-
-	logging.Printf("Persisting version %v: OK", c.GetVersion())
-	return nil
 }
 
-// ConfigChangedWatcher returns a channel that is notified whenever a new config is available.
-func (mp *MysqlPersister) ConfigChangedWatcher() <-chan struct{} {
-	return mp.notifier.Watcher
+func (Dialect) LockSuffix() string {
+	return "FOR UPDATE"
 }
 
-// ReadPersistedConfig provides a config previously persisted.
-func (mp *MysqlPersister) ReadPersistedConfig() (*qsc.ServiceConfig, error) {
-	mp.m.RLock()
-	defer mp.m.RUnlock()
-	c := mp.configs[mp.latestVersion]
-	if c == nil {
-		return nil, errors.New("persister has a nil config")
-	}
-	c = config.CloneConfig(c)
-
-	return c, nil
-}
-
-// ReadHistoricalConfigs returns an array of previously persisted configs
-func (mp *MysqlPersister) ReadHistoricalConfigs() ([]*qsc.ServiceConfig, error) {
-	var configs []*qsc.ServiceConfig
-
-	mp.m.RLock()
-	defer mp.m.RUnlock()
-
-	var versions []int
-	for k := range mp.configs {
-		versions = append(versions, k)
-	}
-
-	sort.Ints(versions)
-
-	for _, v := range versions {
-		configs = append(configs, config.CloneConfig(mp.configs[v]))
-	}
-
-	return configs, nil
+func (Dialect) IsDuplicateEntry(err error) bool {
+	mysqlErr, ok := err.(*mysql.MySQLError)
+	return ok && mysqlErr.Number == mysqlErrDuplicateEntry
 }
 
-// Close closes the notification channel and the connection to MySQL.
-func (mp *MysqlPersister) Close() {
-	logging.Print("Shutting down MySQL persister")
-	close(mp.shutdown)
-	<-mp.fetcherShutdown
-
-	close(mp.notifier.Watcher)
-	err := mp.db.Close()
-	if err != nil {
-		logging.Printf("Could not terminate mysql connection: %v", err)
-	} else {
-		logging.Printf("Shutting down MySQL persister: OK")
-	}
+// New connects to MySQL, creating the quotaservice table if it doesn't exist, and polls for new configs every
+// pollingInterval.
+func New(c Connector, pollingInterval time.Duration) (*MysqlPersister, error) {
+	return sqlpersister.New(c, Dialect{}, pollingInterval)
 }
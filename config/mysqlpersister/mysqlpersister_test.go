@@ -13,6 +13,7 @@ import (
 	dockertest "github.com/ory/dockertest/v3"
 	r "github.com/stretchr/testify/require"
 
+	"github.com/square/quotaservice/config"
 	qsc "github.com/square/quotaservice/protos/config"
 )
 
@@ -281,20 +282,82 @@ func TestDuplicateConfig(t *testing.T) {
 	require.Equal(ErrDuplicateConfig, p.PersistAndNotify("", config))
 }
 
-func TestNoTable(t *testing.T) {
+func TestConfigConflict(t *testing.T) {
+	require := r.New(t)
+
+	setup(require, db)
+
+	p, err := New(NewUnsafeConnector("root", "secret", "localhost", int(port), "quotaservice"), pollingInterval)
+	require.NoError(err)
+	defer p.Close()
+
+	// Clear the notify that's sent when the persister starts
+	<-p.ConfigChangedWatcher()
+
+	c1 := &qsc.ServiceConfig{Version: 1}
+	require.NoError(p.PersistAndNotify("", c1))
+	oldHash := config.HashConfig(c1)
+
+	c2 := &qsc.ServiceConfig{Version: 2, User: "first"}
+	require.NoError(p.PersistAndNotify(oldHash, c2))
+
+	// A second update based on the same config should be rejected.
+	c3 := &qsc.ServiceConfig{Version: 3, User: "second"}
+	err = p.PersistAndNotify(oldHash, c3)
+	require.True(config.IsConfigConflict(err), "expected conflict, got %v", err)
+
+	require.NoError(p.PersistAndNotify(config.HashConfig(c2), c3))
+}
+
+func TestPrune(t *testing.T) {
+	require := r.New(t)
+
+	setup(require, db)
+
+	p, err := New(NewUnsafeConnector("root", "secret", "localhost", int(port), "quotaservice"), pollingInterval)
+	require.NoError(err)
+	defer p.Close()
+
+	// Clear the notify that's sent when the persister starts
+	<-p.ConfigChangedWatcher()
+
+	for v := int32(1); v <= 3; v++ {
+		require.NoError(p.PersistAndNotify("", &qsc.ServiceConfig{Version: v}))
+	}
+
+	select {
+	case <-time.After(2 * pollingInterval):
+		require.Fail("No notification received for new config")
+	case <-p.ConfigChangedWatcher():
+	}
+
+	p.SetRetentionPolicy(config.RetentionPolicy{MaxVersions: 2})
+	require.NoError(p.Prune())
+
+	var count int
+	require.NoError(db.QueryRow("SELECT COUNT(*) FROM quotaservice.quotaservice").Scan(&count))
+	require.Equal(2, count)
+
+	cHistorical, err := p.ReadHistoricalConfigsPage(0, 1)
+	require.NoError(err)
+	require.Equal([]*qsc.ServiceConfig{{Version: 3}}, cHistorical)
+}
+
+func TestCreatesTable(t *testing.T) {
 	require := r.New(t)
 
 	_, err := db.Exec("DROP TABLE quotaservice.quotaservice")
 	require.NoError(err)
 
-	defer func() {
-		_, err = db.Query(tableCreateStatement)
-		if err != nil {
-			panic(err)
-		}
-	}()
+	_, err = db.Exec("DROP TABLE IF EXISTS quotaservice.quotaservice_migrations")
+	require.NoError(err)
 
-	_, err = New(NewUnsafeConnector("root", "secret", "localhost", int(port), "quotaservice"), pollingInterval)
-	require.Error(err)
+	p, err := New(NewUnsafeConnector("root", "secret", "localhost", int(port), "quotaservice"), pollingInterval)
+	require.NoError(err)
+	defer p.Close()
+
+	// Clear the notify that's sent when the persister starts
+	<-p.ConfigChangedWatcher()
 
+	require.NoError(p.PersistAndNotify("", &qsc.ServiceConfig{Version: 1}))
 }
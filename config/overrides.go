@@ -0,0 +1,153 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package config
+
+import (
+	"errors"
+	"time"
+
+	pb "github.com/square/quotaservice/protos/config"
+)
+
+// OverrideExpired tells you whether an override no longer applies at the given time.
+func OverrideExpired(o *pb.BucketOverride, now time.Time) bool {
+	return now.Unix() >= o.ExpiresAt
+}
+
+// overriddenBucket returns the config of the bucket an override targets, or nil if there is no such bucket.
+// The global default bucket is addressed as the DefaultBucketName of the GlobalNamespace.
+func overriddenBucket(cfg *pb.ServiceConfig, namespace, name string) *pb.BucketConfig {
+	if namespace == GlobalNamespace {
+		if name == DefaultBucketName {
+			return cfg.GlobalDefaultBucket
+		}
+
+		return nil
+	}
+
+	ns := cfg.Namespaces[namespace]
+	if ns == nil {
+		return nil
+	}
+
+	switch name {
+	case DefaultBucketName:
+		return ns.DefaultBucket
+	case DynamicBucketTemplateName:
+		return ns.DynamicBucketTemplate
+	default:
+		return ns.Buckets[name]
+	}
+}
+
+func findOverride(cfg *pb.ServiceConfig, namespace, name string) int {
+	for i, o := range cfg.Overrides {
+		if o.Namespace == namespace && o.Bucket == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// AddOverride adds an override to a config, replacing any existing override of the same bucket.
+func AddOverride(clonedCfg *pb.ServiceConfig, o *pb.BucketOverride) error {
+	if overriddenBucket(clonedCfg, o.Namespace, o.Bucket) == nil {
+		return errors.New("No such bucket " + FullyQualifiedName(o.Namespace, o.Bucket) + ".")
+	}
+
+	if i := findOverride(clonedCfg, o.Namespace, o.Bucket); i >= 0 {
+		clonedCfg.Overrides[i] = o
+	} else {
+		clonedCfg.Overrides = append(clonedCfg.Overrides, o)
+	}
+
+	return nil
+}
+
+// RemoveOverride removes the override of a bucket from a config.
+func RemoveOverride(clonedCfg *pb.ServiceConfig, namespace, name string) error {
+	i := findOverride(clonedCfg, namespace, name)
+	if i < 0 {
+		return errors.New("No override for bucket " + FullyQualifiedName(namespace, name) + ".")
+	}
+
+	clonedCfg.Overrides = append(clonedCfg.Overrides[:i], clonedCfg.Overrides[i+1:]...)
+	return nil
+}
+
+// RemoveExpiredOverrides removes overrides that have expired from a config, returning true if any were removed.
+func RemoveExpiredOverrides(clonedCfg *pb.ServiceConfig, now time.Time) bool {
+	var active []*pb.BucketOverride
+	for _, o := range clonedCfg.Overrides {
+		if !OverrideExpired(o, now) {
+			active = append(active, o)
+		}
+	}
+
+	removed := len(active) != len(clonedCfg.Overrides)
+	clonedCfg.Overrides = active
+	return removed
+}
+
+// NextOverrideExpiry returns the time at which the next override of a config expires, which may be in the
+// past. Returns the zero time if the config has no overrides.
+func NextOverrideExpiry(cfg *pb.ServiceConfig) time.Time {
+	var next time.Time
+	for _, o := range cfg.Overrides {
+		if t := time.Unix(o.ExpiresAt, 0); next.IsZero() || t.Before(next) {
+			next = t
+		}
+	}
+
+	return next
+}
+
+// ApplyOverrides returns the effective config at the given time: a copy of cfg in which the non-zero fields of
+// each override that hasn't expired replace those of the bucket it targets. cfg itself is returned if no
+// override applies, and is never modified.
+func ApplyOverrides(cfg *pb.ServiceConfig, now time.Time) *pb.ServiceConfig {
+	var effective *pb.ServiceConfig
+	for _, o := range cfg.Overrides {
+		if OverrideExpired(o, now) || o.Config == nil {
+			continue
+		}
+
+		if effective == nil {
+			effective = CloneConfig(cfg)
+		}
+
+		if b := overriddenBucket(effective, o.Namespace, o.Bucket); b != nil {
+			applyOverride(b, o.Config)
+		}
+	}
+
+	if effective == nil {
+		return cfg
+	}
+
+	return effective
+}
+
+func applyOverride(b, o *pb.BucketConfig) {
+	for _, f := range overridableBucketFields {
+		if v := *f.field(o); v != 0 {
+			*f.field(b) = v
+		}
+	}
+}
+
+func overridesAnyField(o *pb.BucketConfig) bool {
+	if o == nil {
+		return false
+	}
+
+	for _, f := range overridableBucketFields {
+		if *f.field(o) != 0 {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,99 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	pb "github.com/square/quotaservice/protos/config"
+	"github.com/square/quotaservice/test/helpers"
+)
+
+func overridesTestConfig() *pb.ServiceConfig {
+	cfg := NewDefaultServiceConfig()
+	cfg.GlobalDefaultBucket = NewDefaultBucketConfig(DefaultBucketName)
+	ns := NewDefaultNamespaceConfig("ns")
+	helpers.PanicError(AddBucket(ns, &pb.BucketConfig{Name: "b", Size: 300, FillRate: 40}))
+	helpers.PanicError(AddNamespace(cfg, ns))
+	ApplyDefaults(cfg)
+	return cfg
+}
+
+func TestAddAndRemoveOverride(t *testing.T) {
+	cfg := overridesTestConfig()
+
+	if err := AddOverride(cfg, &pb.BucketOverride{Namespace: "ns", Bucket: "missing"}); err == nil {
+		t.Error("Expected an error overriding a missing bucket")
+	}
+
+	helpers.CheckError(t, AddOverride(cfg, &pb.BucketOverride{
+		Namespace: GlobalNamespace, Bucket: DefaultBucketName, Config: &pb.BucketConfig{Size: 500}, ExpiresAt: 100}))
+	helpers.CheckError(t, AddOverride(cfg, &pb.BucketOverride{
+		Namespace: "ns", Bucket: "b", Config: &pb.BucketConfig{Size: 500}, ExpiresAt: 100}))
+	helpers.CheckError(t, AddOverride(cfg, &pb.BucketOverride{
+		Namespace: "ns", Bucket: "b", Config: &pb.BucketConfig{Size: 600}, ExpiresAt: 200}))
+
+	if len(cfg.Overrides) != 2 || cfg.Overrides[1].Config.Size != 600 {
+		t.Fatalf("Expected the second override of ns:b to replace the first, got %v", cfg.Overrides)
+	}
+
+	if errs := Validate(cfg); len(errs) != 0 {
+		t.Fatalf("Expected config to be valid; got %v", errs)
+	}
+
+	helpers.CheckError(t, RemoveOverride(cfg, GlobalNamespace, DefaultBucketName))
+
+	if err := RemoveOverride(cfg, GlobalNamespace, DefaultBucketName); err == nil {
+		t.Error("Expected an error removing a missing override")
+	}
+
+	if len(cfg.Overrides) != 1 || cfg.Overrides[0].Bucket != "b" {
+		t.Fatalf("Expected only the override of ns:b to remain, got %v", cfg.Overrides)
+	}
+}
+
+func TestApplyOverrides(t *testing.T) {
+	cfg := overridesTestConfig()
+	now := time.Now()
+
+	if ApplyOverrides(cfg, now) != cfg {
+		t.Fatal("Expected a config without overrides to be returned as is")
+	}
+
+	cfg.Overrides = []*pb.BucketOverride{
+		{Namespace: "ns", Bucket: "b", Config: &pb.BucketConfig{FillRate: 80}, ExpiresAt: now.Unix() + 10},
+		{Namespace: GlobalNamespace, Bucket: DefaultBucketName, Config: &pb.BucketConfig{Size: 5}, ExpiresAt: now.Unix()}}
+
+	effective := ApplyOverrides(cfg, now)
+
+	if b := effective.Namespaces["ns"].Buckets["b"]; b.Size != 300 || b.FillRate != 80 {
+		t.Errorf("Expected fill rate to be overridden and size kept, got %+v", b)
+	}
+
+	if effective.GlobalDefaultBucket.Size == 5 {
+		t.Error("Expected expired override not to apply")
+	}
+
+	if cfg.Namespaces["ns"].Buckets["b"].FillRate != 40 {
+		t.Error("Config should not be modified")
+	}
+
+	if next := NextOverrideExpiry(cfg); next.Unix() != now.Unix() {
+		t.Errorf("Expected next expiry at %v, got %v", now.Unix(), next.Unix())
+	}
+
+	if !RemoveExpiredOverrides(cfg, now) || len(cfg.Overrides) != 1 || cfg.Overrides[0].Bucket != "b" {
+		t.Fatalf("Expected the expired override to be removed, got %v", cfg.Overrides)
+	}
+
+	if RemoveExpiredOverrides(cfg, now) {
+		t.Error("Expected no more overrides to be removed")
+	}
+
+	cfg.Overrides = nil
+	if next := NextOverrideExpiry(cfg); !next.IsZero() {
+		t.Errorf("Expected no expiry without overrides, got %v", next)
+	}
+}
@@ -5,6 +5,7 @@ package config
 
 import (
 	"crypto/md5"
+	"errors"
 	"fmt"
 	"github.com/square/quotaservice/logging"
 	pb "github.com/square/quotaservice/protos/config"
@@ -13,7 +14,9 @@ import (
 
 // ConfigPersister is an interface that persists configs and notifies a channel of changes.
 type ConfigPersister interface {
-	// PersistAndNotify persists a configuration passed in.
+	// PersistAndNotify persists a configuration passed in. If oldHash is not empty, the configuration is only
+	// persisted if oldHash matches the hash of the currently persisted configuration; otherwise a
+	// *ConfigConflictError is returned.
 	PersistAndNotify(oldHash string, newConfig *pb.ServiceConfig) error
 	// ConfigChangedWatcher returns a channel that is notified whenever configuration changes are
 	// detected. Changes are coalesced so that a single notification may be emitted for multiple
@@ -23,16 +26,58 @@ type ConfigPersister interface {
 	ReadPersistedConfig() (*pb.ServiceConfig, error)
 	// Returns an array of historical configurations, used to display a history for admin consoles.
 	ReadHistoricalConfigs() ([]*pb.ServiceConfig, error)
+	// ReadHistoricalConfigsPage returns a page of historical configurations, newest first, skipping offset
+	// configurations and returning at most limit. A limit that isn't positive returns everything after offset.
+	ReadHistoricalConfigsPage(offset, limit int) ([]*pb.ServiceConfig, error)
 	// Close closes the ConfigPersister. It is not safe to use after it has been closed.
 	Close()
 }
 
+// AnyVersion is the expected version of a change to be made to whichever version of the configuration is current.
+const AnyVersion int32 = -1
+
+// ConfigConflictError is returned by ConfigPersister.PersistAndNotify when the persisted configuration has been
+// changed since the caller read it, identified by its hash. It is also returned when a change expected to be made
+// to one version of the configuration is made after another version has replaced it.
+type ConfigConflictError struct {
+	ExpectedHash    string
+	ActualHash      string
+	ExpectedVersion int32
+	ActualVersion   int32
+}
+
+func (e *ConfigConflictError) Error() string {
+	if e.ExpectedHash == "" {
+		return fmt.Sprintf("The config version sent (%d) is different than the latest server version (%d). Please refresh and redo your changes.",
+			e.ExpectedVersion, e.ActualVersion)
+	}
+
+	return fmt.Sprintf("Configuration has been modified concurrently; expected hash %v but found %v. Please refresh and redo your changes.",
+		e.ExpectedHash, e.ActualHash)
+}
+
+// IsConfigConflict tells you whether an error is, or wraps, a *ConfigConflictError.
+func IsConfigConflict(err error) bool {
+	var conflict *ConfigConflictError
+	return errors.As(err, &conflict)
+}
+
+// CheckConfigHash returns a *ConfigConflictError if oldHash is not empty and doesn't match currentHash. Used by
+// ConfigPersisters to implement compare-and-swap semantics in PersistAndNotify.
+func CheckConfigHash(oldHash, currentHash string) error {
+	if oldHash != "" && oldHash != currentHash {
+		return &ConfigConflictError{ExpectedHash: oldHash, ActualHash: currentHash}
+	}
+
+	return nil
+}
+
 // HashConfigBytes returns the MD5 of a config byte array.
 func HashConfigBytes(cfgBytes []byte) string {
 	return fmt.Sprintf("%x", md5.Sum(cfgBytes))
 }
 
-// HashConfig returns the MD5 of a service config
+// HashConfig returns the MD5 of a service config. Configs that are equal always have the same hash.
 func HashConfig(config *pb.ServiceConfig) string {
 	r, err := Marshal(config)
 
@@ -0,0 +1,136 @@
+// Package postgrespersister implements a ConfigPersister that stores configs in PostgreSQL. Rather than polling,
+// it uses LISTEN/NOTIFY to find out about new configs.
+package postgrespersister
+
+import (
+	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+
+	"github.com/square/quotaservice/config/sqlpersister"
+	"github.com/square/quotaservice/logging"
+)
+
+const (
+	pgErrUniqueViolation = "23505"
+	notifyChannel        = "quotaservice"
+
+	minReconnectInterval = 100 * time.Millisecond
+	maxReconnectInterval = 10 * time.Second
+
+	// Listener connections are pinged when idle, as dead connections aren't otherwise detected.
+	pingInterval = 90 * time.Second
+
+	// Only used if notifications stop.
+	pollingInterval = time.Minute
+)
+
+type PostgresPersister = sqlpersister.SQLPersister
+
+// Dialect adapts sqlpersister to PostgreSQL.
+type Dialect struct {
+	dsn string
+}
+
+// NewDialect returns a Dialect that listens for notifications on a connection to dsn.
+func NewDialect(dsn string) *Dialect {
+	return &Dialect{dsn: dsn}
+}
+
+func (d *Dialect) Name() string {
+	return "PostgreSQL"
+}
+
+func (d *Dialect) Placeholder() sq.PlaceholderFormat {
+	return sq.Dollar
+}
+
+func (d *Dialect) Migrations() []string {
+	return []string{
+		"CREATE TABLE IF NOT EXISTS quotaservice (ID BIGSERIAL PRIMARY KEY, Version INT UNIQUE, Config BYTEA)",
+		"CREATE TABLE IF NOT EXISTS quotaservice_audit (ID BIGSERIAL PRIMARY KEY, Time BIGINT, Username TEXT, Action TEXT, Entry BYTEA)",
+		"CREATE INDEX IF NOT EXISTS quotaservice_audit_time ON quotaservice_audit (Time)",
+	}
+}
+
+func (d *Dialect) LockSuffix() string {
+	return "FOR UPDATE"
+}
+
+func (d *Dialect) IsDuplicateEntry(err error) bool {
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code == pgErrUniqueViolation
+}
+
+func (d *Dialect) NotifyStatement() string {
+	return "NOTIFY " + notifyChannel
+}
+
+func (d *Dialect) Listen() (sqlpersister.Listener, error) {
+	l := pq.NewListener(d.dsn, minReconnectInterval, maxReconnectInterval, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			logging.Printf("Received error from PostgreSQL listener: %v", err)
+		}
+	})
+
+	if err := l.Listen(notifyChannel); err != nil {
+		_ = l.Close()
+		return nil, err
+	}
+
+	ls := &listener{l: l, notifications: make(chan struct{}, 1)}
+	go ls.forward()
+
+	return ls, nil
+}
+
+type listener struct {
+	l             *pq.Listener
+	notifications chan struct{}
+}
+
+// forward coalesces notifications until the listener is closed. pq sends a nil notification after
+// reconnecting, which is forwarded too since notifications may have been missed.
+func (ls *listener) forward() {
+	defer close(ls.notifications)
+
+	for {
+		select {
+		case _, ok := <-ls.l.Notify:
+			if !ok {
+				return
+			}
+
+			select {
+			case ls.notifications <- struct{}{}:
+			default:
+				// Already a notification pending.
+			}
+		case <-time.After(pingInterval):
+			go func() { _ = ls.l.Ping() }()
+		}
+	}
+}
+
+func (ls *listener) Notifications() <-chan struct{} {
+	return ls.notifications
+}
+
+func (ls *listener) Close() error {
+	return ls.l.Close()
+}
+
+type connector struct {
+	dsn string
+}
+
+func (c connector) Connect() (*sql.DB, error) {
+	return sql.Open("postgres", c.dsn)
+}
+
+// New connects to PostgreSQL at dsn, creating the quotaservice table if it doesn't exist.
+func New(dsn string) (*PostgresPersister, error) {
+	return sqlpersister.New(connector{dsn}, NewDialect(dsn), pollingInterval)
+}
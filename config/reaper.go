@@ -3,13 +3,31 @@
 
 package config
 
-import "time"
+import (
+	"time"
+
+	pb "github.com/square/quotaservice/protos/config"
+)
 
 // ReaperConfig represents the configuration settings for the bucket reaper.
 type ReaperConfig struct {
 	BucketWatcherBuffer int
 	InitSleep           time.Duration
 	MinFrequency        time.Duration
+	// ReapStaticBuckets enables reaping of idle, statically configured buckets. Reaped static buckets
+	// are lazily re-created the next time they are used. Only buckets with a max idle time are reaped.
+	ReapStaticBuckets bool
+	// Namespaces holds per-namespace overrides, keyed on namespace name.
+	Namespaces map[string]NamespaceReaperConfig
+}
+
+// NamespaceReaperConfig overrides the bucket reaper's behavior for a single namespace.
+type NamespaceReaperConfig struct {
+	// MaxIdle, if positive, overrides the max idle time of all buckets in the namespace. If negative,
+	// buckets in the namespace are never reaped.
+	MaxIdle time.Duration
+	// ReapStaticBuckets, if set, overrides ReaperConfig.ReapStaticBuckets for the namespace.
+	ReapStaticBuckets *bool
 }
 
 // NewReaperConfig returns a new ReaperConfig with defaults.
@@ -19,3 +37,22 @@ func NewReaperConfig() ReaperConfig {
 		InitSleep:           10 * time.Second,
 		MinFrequency:        10 * time.Minute}
 }
+
+// MaxIdle returns the max idle time for a bucket in the given namespace, taking namespace overrides
+// into account. A non-positive return value means the bucket should not be reaped.
+func (r ReaperConfig) MaxIdle(namespace string, cfg *pb.BucketConfig) time.Duration {
+	if override := r.Namespaces[namespace].MaxIdle; override != 0 {
+		return override
+	}
+
+	return time.Duration(cfg.MaxIdleMillis) * time.Millisecond
+}
+
+// ReapsStaticBuckets tells you whether idle static buckets in the given namespace may be reaped.
+func (r ReaperConfig) ReapsStaticBuckets(namespace string) bool {
+	if override := r.Namespaces[namespace].ReapStaticBuckets; override != nil {
+		return *override
+	}
+
+	return r.ReapStaticBuckets
+}
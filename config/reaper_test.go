@@ -0,0 +1,36 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReaperConfigOverrides(t *testing.T) {
+	r := NewReaperConfig()
+	r.ReapStaticBuckets = true
+	off := false
+	r.Namespaces = map[string]NamespaceReaperConfig{
+		"overridden": {MaxIdle: time.Minute, ReapStaticBuckets: &off}}
+
+	b := NewDefaultBucketConfig("b")
+	b.MaxIdleMillis = 1000
+
+	if maxIdle := r.MaxIdle("overridden", b); maxIdle != time.Minute {
+		t.Fatalf("Expected overridden max idle of 1m; was %v", maxIdle)
+	}
+
+	if maxIdle := r.MaxIdle("other", b); maxIdle != time.Second {
+		t.Fatalf("Expected bucket max idle of 1s; was %v", maxIdle)
+	}
+
+	if r.ReapsStaticBuckets("overridden") {
+		t.Fatal("Namespace override should disable reaping static buckets")
+	}
+
+	if !r.ReapsStaticBuckets("other") {
+		t.Fatal("Should reap static buckets by default")
+	}
+}
@@ -0,0 +1,129 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package config
+
+import (
+	"sort"
+	"time"
+
+	pb "github.com/square/quotaservice/protos/config"
+)
+
+// DefaultPruneInterval is how often persisters prune historical configs, unless a RetentionPolicy says otherwise.
+const DefaultPruneInterval = 10 * time.Minute
+
+// RetentionPolicy limits the historical configs kept by a ConfigPersister. Configs are pruned if they fall
+// outside either limit; the current config is never pruned. The zero value keeps everything.
+type RetentionPolicy struct {
+	// MaxVersions is the number of most recent configs to keep, including the current one. Zero means no limit.
+	MaxVersions int
+	// MaxAge is how long to keep configs for, based on their date. Zero means no limit.
+	MaxAge time.Duration
+	// PruneInterval is how often to prune in the background. Defaults to DefaultPruneInterval.
+	PruneInterval time.Duration
+}
+
+// Enabled returns true if the policy prunes anything.
+func (p RetentionPolicy) Enabled() bool {
+	return p.MaxVersions > 0 || p.MaxAge > 0
+}
+
+// Interval returns how often to prune.
+func (p RetentionPolicy) Interval() time.Duration {
+	if p.PruneInterval <= 0 {
+		return DefaultPruneInterval
+	}
+
+	return p.PruneInterval
+}
+
+// ExpiredConfigs returns the keys of configs that fall outside a retention policy. Configs are keyed however
+// the persister stores them, e.g. by hash or version, and the current config, keyed on current, is never
+// returned.
+func ExpiredConfigs[K comparable](p RetentionPolicy, configs map[K]*pb.ServiceConfig, current K, now time.Time) []K {
+	if !p.Enabled() {
+		return nil
+	}
+
+	keys := newestFirst(configs)
+
+	var expired []K
+	cutoff := now.Add(-p.MaxAge).Unix()
+
+	for i, k := range keys {
+		if k == current {
+			continue
+		}
+
+		if (p.MaxVersions > 0 && i >= p.MaxVersions) || (p.MaxAge > 0 && configs[k].GetDate() < cutoff) {
+			expired = append(expired, k)
+		}
+	}
+
+	return expired
+}
+
+// PageConfigs returns a page of configs, newest first. A limit that isn't positive returns all configs after
+// offset. The slice passed in is sorted in place.
+func PageConfigs(cfgs []*pb.ServiceConfig, offset, limit int) []*pb.ServiceConfig {
+	sort.SliceStable(cfgs, func(i, j int) bool {
+		return newerThan(cfgs[i], cfgs[j])
+	})
+
+	return page(cfgs, offset, limit)
+}
+
+// page returns the items of a page of a sorted slice. A limit that isn't positive returns all items after offset.
+func page[T any](items []T, offset, limit int) []T {
+	if offset < 0 {
+		offset = 0
+	}
+
+	if offset >= len(items) {
+		return []T{}
+	}
+
+	items = items[offset:]
+	if limit > 0 && limit < len(items) {
+		items = items[:limit]
+	}
+
+	return items
+}
+
+// newestFirst returns the keys of configs, newest first. Persisters that keep their configs in memory keep the keys
+// in this order as configs are added and pruned, so that a page of configs can be read without sorting or cloning
+// the rest.
+func newestFirst[K comparable](configs map[K]*pb.ServiceConfig) []K {
+	keys := make([]K, 0, len(configs))
+	for k := range configs {
+		keys = append(keys, k)
+	}
+
+	sort.SliceStable(keys, func(i, j int) bool {
+		return newerThan(configs[keys[i]], configs[keys[j]])
+	})
+
+	return keys
+}
+
+// pageOfConfigs returns clones of a page of configs, given their keys, newest first.
+func pageOfConfigs[K comparable](configs map[K]*pb.ServiceConfig, keys []K, offset, limit int) []*pb.ServiceConfig {
+	keys = page(keys, offset, limit)
+	cfgs := make([]*pb.ServiceConfig, len(keys))
+	for i, k := range keys {
+		cfgs[i] = CloneConfig(configs[k])
+	}
+
+	return cfgs
+}
+
+// newerThan orders configs by version and then date, newest first.
+func newerThan(c1, c2 *pb.ServiceConfig) bool {
+	if c1.GetVersion() != c2.GetVersion() {
+		return c1.GetVersion() > c2.GetVersion()
+	}
+
+	return c1.GetDate() > c2.GetDate()
+}
@@ -0,0 +1,115 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package config
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	pbconfig "github.com/square/quotaservice/protos/config"
+)
+
+func TestExpiredConfigs(t *testing.T) {
+	now := time.Unix(10000, 0)
+	configs := map[int]*pbconfig.ServiceConfig{
+		1: {Version: 1, Date: 1000},
+		2: {Version: 2, Date: 9000},
+		3: {Version: 3, Date: 9500},
+		4: {Version: 4, Date: 9900}}
+
+	tests := []struct {
+		name     string
+		policy   RetentionPolicy
+		current  int
+		expected []int
+	}{
+		{"disabled", RetentionPolicy{}, 4, nil},
+		{"max versions", RetentionPolicy{MaxVersions: 2}, 4, []int{1, 2}},
+		{"max age", RetentionPolicy{MaxAge: 2 * time.Hour}, 4, []int{1}},
+		{"both", RetentionPolicy{MaxVersions: 3, MaxAge: 800 * time.Second}, 4, []int{1, 2}},
+		{"current is kept", RetentionPolicy{MaxVersions: 1, MaxAge: time.Second}, 1, []int{2, 3, 4}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expired := ExpiredConfigs(test.policy, configs, test.current, now)
+			sort.Ints(expired)
+
+			if !reflect.DeepEqual(expired, test.expected) {
+				t.Fatalf("Expected %v to be expired; got %v", test.expected, expired)
+			}
+		})
+	}
+}
+
+func TestPageConfigs(t *testing.T) {
+	var cfgs []*pbconfig.ServiceConfig
+	for _, v := range []int32{3, 1, 4, 2} {
+		cfgs = append(cfgs, &pbconfig.ServiceConfig{Version: v})
+	}
+
+	tests := []struct {
+		offset, limit int
+		expected      []int32
+	}{
+		{0, 0, []int32{4, 3, 2, 1}},
+		{0, 2, []int32{4, 3}},
+		{1, 2, []int32{3, 2}},
+		{3, 5, []int32{1}},
+		{4, 1, []int32{}},
+	}
+
+	for _, test := range tests {
+		versions := []int32{}
+		for _, c := range PageConfigs(cfgs, test.offset, test.limit) {
+			versions = append(versions, c.Version)
+		}
+
+		if !reflect.DeepEqual(versions, test.expected) {
+			t.Errorf("Expected versions %v for offset %v and limit %v; got %v", test.expected, test.offset, test.limit, versions)
+		}
+	}
+}
+
+func TestMemoryPrune(t *testing.T) {
+	persister := NewMemoryConfigPersister()
+	defer persister.Close()
+
+	for v := int32(1); v <= 5; v++ {
+		cfg := NewDefaultServiceConfig()
+		cfg.Version = v
+		cfg.Date = time.Now().Unix()
+		if e := persister.PersistAndNotify("", cfg); e != nil {
+			t.Fatal(e)
+		}
+	}
+
+	persister.SetRetentionPolicy(RetentionPolicy{MaxVersions: 2, PruneInterval: 10 * time.Millisecond})
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		cfgs, _ := persister.ReadHistoricalConfigs()
+		if len(cfgs) == 2 {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected 2 configs to be kept in the background; got %v", len(cfgs))
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	page, e := persister.ReadHistoricalConfigsPage(0, 1)
+	if e != nil || len(page) != 1 || page[0].Version != 5 {
+		t.Fatalf("Expected a page containing version 5; got %v, %v", page, e)
+	}
+
+	current, _ := persister.ReadPersistedConfig()
+	if current.Version != 5 {
+		t.Fatalf("Current config should not be pruned; got version %v", current.Version)
+	}
+}
@@ -0,0 +1,213 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	pb "github.com/square/quotaservice/protos/config"
+)
+
+// ScheduleState describes which of a bucket's schedules is in effect.
+type ScheduleState struct {
+	Namespace string `json:"namespace"`
+	Bucket    string `json:"bucket"`
+	// Active is the name of the schedule in effect, or empty if the bucket's own settings are.
+	Active string `json:"active,omitempty"`
+	// NextChange is when a schedule next starts or ends.
+	NextChange time.Time `json:"next_change"`
+}
+
+// ParseScheduleStart parses the cron expression a schedule starts on. Expressions are in UTC unless prefixed with
+// CRON_TZ=<time zone>.
+func ParseScheduleStart(start string) (cron.Schedule, error) {
+	if !strings.HasPrefix(start, "CRON_TZ=") && !strings.HasPrefix(start, "TZ=") {
+		start = "CRON_TZ=UTC " + start
+	}
+
+	schedule, err := cron.ParseStandard(start)
+	if err != nil {
+		return nil, err
+	}
+
+	// Windows need fixed start times, which @every schedules don't have.
+	if _, ok := schedule.(*cron.SpecSchedule); !ok {
+		return nil, errors.New("@every is not supported")
+	}
+
+	return schedule, nil
+}
+
+// scheduleWindow returns the start of the window of s that contains now, if any, and the time the next window
+// after now starts. Schedules that can't be parsed are never active; Validate reports them.
+func scheduleWindow(s *pb.BucketSchedule, now time.Time) (current, next time.Time) {
+	start, err := ParseScheduleStart(s.Start)
+	if err != nil || s.DurationMillis <= 0 {
+		return time.Time{}, time.Time{}
+	}
+
+	duration := time.Duration(s.DurationMillis) * time.Millisecond
+	if t := start.Next(now.Add(-duration)); !t.After(now) {
+		current = t
+	}
+
+	return current, start.Next(now)
+}
+
+// ActiveSchedule returns the schedule of a bucket in effect at the given time, or nil if none is. If windows
+// overlap, the first schedule listed takes effect.
+func ActiveSchedule(b *pb.BucketConfig, now time.Time) *pb.BucketSchedule {
+	for _, s := range b.Schedules {
+		if current, _ := scheduleWindow(s, now); !current.IsZero() {
+			return s
+		}
+	}
+
+	return nil
+}
+
+// nextScheduleChange returns the time after now at which a schedule of a bucket next starts or ends, or the zero
+// time if the bucket has no valid schedules.
+func nextScheduleChange(b *pb.BucketConfig, now time.Time) time.Time {
+	var next time.Time
+	earliest := func(t time.Time) {
+		if !t.IsZero() && (next.IsZero() || t.Before(next)) {
+			next = t
+		}
+	}
+
+	for _, s := range b.Schedules {
+		current, start := scheduleWindow(s, now)
+		earliest(start)
+
+		if !current.IsZero() {
+			earliest(current.Add(time.Duration(s.DurationMillis) * time.Millisecond))
+		}
+	}
+
+	return next
+}
+
+// NextScheduleChange returns the time after now at which any schedule in a config next starts or ends, or the
+// zero time if no bucket has a schedule.
+func NextScheduleChange(cfg *pb.ServiceConfig, now time.Time) time.Time {
+	var next time.Time
+	forEachBucket(cfg, func(_, _ string, b *pb.BucketConfig) {
+		if t := nextScheduleChange(b, now); !t.IsZero() && (next.IsZero() || t.Before(next)) {
+			next = t
+		}
+	})
+
+	return next
+}
+
+// ApplySchedules returns the effective config at the given time: a copy of cfg in which each bucket with a
+// schedule in effect uses the schedule's settings. cfg itself is returned if no schedule is in effect, and is
+// never modified.
+func ApplySchedules(cfg *pb.ServiceConfig, now time.Time) *pb.ServiceConfig {
+	active := false
+	forEachBucket(cfg, func(_, _ string, b *pb.BucketConfig) {
+		active = active || ActiveSchedule(b, now) != nil
+	})
+
+	if !active {
+		return cfg
+	}
+
+	effective := CloneConfig(cfg)
+	forEachBucket(effective, func(_, _ string, b *pb.BucketConfig) {
+		if s := ActiveSchedule(b, now); s != nil {
+			applySchedule(b, s)
+		}
+	})
+
+	return effective
+}
+
+func applySchedule(b *pb.BucketConfig, s *pb.BucketSchedule) {
+	if s.Size != 0 {
+		b.Size = s.Size
+	}
+
+	if s.FillRate != 0 {
+		b.FillRate = s.FillRate
+	}
+}
+
+// ScheduleStates describes the schedule in effect at the given time for each bucket with schedules, in order of
+// fully qualified bucket name.
+func ScheduleStates(cfg *pb.ServiceConfig, now time.Time) []ScheduleState {
+	states := make([]ScheduleState, 0)
+	forEachBucket(cfg, func(namespace, name string, b *pb.BucketConfig) {
+		if len(b.Schedules) == 0 {
+			return
+		}
+
+		state := ScheduleState{Namespace: namespace, Bucket: name, NextChange: nextScheduleChange(b, now)}
+		if s := ActiveSchedule(b, now); s != nil {
+			state.Active = s.Name
+		}
+
+		states = append(states, state)
+	})
+
+	sort.Slice(states, func(i, j int) bool {
+		return FullyQualifiedName(states[i].Namespace, states[i].Bucket) <
+			FullyQualifiedName(states[j].Namespace, states[j].Bucket)
+	})
+
+	return states
+}
+
+// formatSchedules describes schedules in a single line, for diffs.
+func formatSchedules(schedules []*pb.BucketSchedule) string {
+	descriptions := make([]string, len(schedules))
+	for i, s := range schedules {
+		descriptions[i] = fmt.Sprintf("%v: %v for %v", s.Name, s.Start, time.Duration(s.DurationMillis)*time.Millisecond)
+
+		if s.Size != 0 {
+			descriptions[i] += fmt.Sprintf(", size %v", s.Size)
+		}
+
+		if s.FillRate != 0 {
+			descriptions[i] += fmt.Sprintf(", fill_rate %v", s.FillRate)
+		}
+	}
+
+	return strings.Join(descriptions, "; ")
+}
+
+// forEachBucket calls f with every bucket of a config, including default buckets and dynamic bucket templates,
+// addressed as DefaultBucketName and DynamicBucketTemplateName. The global default bucket is addressed as the
+// DefaultBucketName of the GlobalNamespace.
+func forEachBucket(cfg *pb.ServiceConfig, f func(namespace, name string, b *pb.BucketConfig)) {
+	if cfg.GlobalDefaultBucket != nil {
+		f(GlobalNamespace, DefaultBucketName, cfg.GlobalDefaultBucket)
+	}
+
+	for nsName, ns := range cfg.Namespaces {
+		if ns == nil {
+			continue
+		}
+
+		if ns.DefaultBucket != nil {
+			f(nsName, DefaultBucketName, ns.DefaultBucket)
+		}
+
+		if ns.DynamicBucketTemplate != nil {
+			f(nsName, DynamicBucketTemplateName, ns.DynamicBucketTemplate)
+		}
+
+		for name, b := range ns.Buckets {
+			if b != nil {
+				f(nsName, name, b)
+			}
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	pb "github.com/square/quotaservice/protos/config"
+	"github.com/square/quotaservice/test/helpers"
+)
+
+func schedulesTestConfig() *pb.ServiceConfig {
+	cfg := NewDefaultServiceConfig()
+	ns := NewDefaultNamespaceConfig("ns")
+	helpers.PanicError(AddBucket(ns, &pb.BucketConfig{Name: "b", Size: 300, FillRate: 40, Schedules: []*pb.BucketSchedule{
+		// 22:00 to 04:00 UTC
+		{Name: "overnight", Start: "0 22 * * *", DurationMillis: (6 * time.Hour).Milliseconds(), FillRate: 400},
+		// Midnight to 01:00 in New York, i.e. 05:00 to 06:00 UTC in winter and 04:00 to 05:00 UTC in summer
+		{Name: "ny", Start: "CRON_TZ=America/New_York 0 0 * * *", DurationMillis: time.Hour.Milliseconds(), Size: 1000}}}))
+	helpers.PanicError(AddBucket(ns, &pb.BucketConfig{Name: "unscheduled", Size: 300, FillRate: 40}))
+	helpers.PanicError(AddNamespace(cfg, ns))
+	ApplyDefaults(cfg)
+	return cfg
+}
+
+func TestActiveSchedule(t *testing.T) {
+	b := schedulesTestConfig().Namespaces["ns"].Buckets["b"]
+
+	tests := []struct {
+		time     string
+		expected string
+	}{
+		{"2016-01-15T12:00:00Z", ""},
+		{"2016-01-15T21:59:59Z", ""},
+		{"2016-01-15T22:00:00Z", "overnight"},
+		{"2016-01-16T03:59:59Z", "overnight"},
+		{"2016-01-16T04:30:00Z", ""},
+		{"2016-01-16T05:30:00Z", "ny"},
+		{"2016-01-16T06:00:00Z", ""},
+		{"2016-07-16T04:30:00Z", "ny"},
+		{"2016-07-16T05:30:00Z", ""},
+	}
+
+	for _, test := range tests {
+		now, err := time.Parse(time.RFC3339, test.time)
+		helpers.CheckError(t, err)
+
+		active := ""
+		if s := ActiveSchedule(b, now); s != nil {
+			active = s.Name
+		}
+
+		if active != test.expected {
+			t.Errorf("Expected schedule %q to be active at %v, got %q", test.expected, test.time, active)
+		}
+	}
+
+	// Overlapping windows favor the first schedule listed.
+	b.Schedules = append([]*pb.BucketSchedule{
+		{Name: "first", Start: "0 23 * * *", DurationMillis: time.Hour.Milliseconds(), Size: 500}}, b.Schedules...)
+	now, _ := time.Parse(time.RFC3339, "2016-01-15T23:30:00Z")
+	if s := ActiveSchedule(b, now); s == nil || s.Name != "first" {
+		t.Errorf("Expected first to be active, got %v", s)
+	}
+}
+
+func TestApplySchedules(t *testing.T) {
+	cfg := schedulesTestConfig()
+
+	noon, _ := time.Parse(time.RFC3339, "2016-01-15T12:00:00Z")
+	if ApplySchedules(cfg, noon) != cfg {
+		t.Fatal("Expected a config without active schedules to be returned as is")
+	}
+
+	night, _ := time.Parse(time.RFC3339, "2016-01-15T23:00:00Z")
+	effective := ApplySchedules(cfg, night)
+
+	if b := effective.Namespaces["ns"].Buckets["b"]; b.Size != 300 || b.FillRate != 400 {
+		t.Errorf("Expected overnight fill rate, got %+v", b)
+	}
+
+	if cfg.Namespaces["ns"].Buckets["b"].FillRate != 40 {
+		t.Error("Config should not be modified")
+	}
+
+	if next := NextScheduleChange(cfg, noon); next.Format(time.RFC3339) != "2016-01-15T22:00:00Z" {
+		t.Errorf("Expected next change at 22:00, got %v", next)
+	}
+
+	if next := NextScheduleChange(cfg, night); next.Format(time.RFC3339) != "2016-01-16T04:00:00Z" {
+		t.Errorf("Expected next change at 04:00, got %v", next)
+	}
+
+	states := ScheduleStates(cfg, night)
+	if len(states) != 1 || states[0].Bucket != "b" || states[0].Active != "overnight" {
+		t.Errorf("Unexpected schedule states %+v", states)
+	}
+}
+
+func TestScheduleChangesAreDiffed(t *testing.T) {
+	cfg := schedulesTestConfig()
+	changed := CloneConfig(cfg)
+	changed.Namespaces["ns"].Buckets["b"].Schedules[0].FillRate = 500
+
+	if !DifferentBucketConfigs(cfg.Namespaces["ns"].Buckets["b"], changed.Namespaces["ns"].Buckets["b"]) {
+		t.Error("Expected a change of schedule to change the bucket")
+	}
+}
+
+func TestParseScheduleStart(t *testing.T) {
+	if _, err := ParseScheduleStart("@every 1h"); err == nil {
+		t.Error("Expected @every to be rejected")
+	}
+
+	if _, err := ParseScheduleStart("CRON_TZ=Nowhere/Special 0 0 * * *"); err == nil {
+		t.Error("Expected an unknown time zone to be rejected")
+	}
+
+	if _, err := ParseScheduleStart("@daily"); err != nil {
+		t.Errorf("Expected @daily to be accepted, got %v", err)
+	}
+}
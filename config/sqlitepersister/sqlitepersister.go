@@ -0,0 +1,69 @@
+// Package sqlitepersister implements a ConfigPersister that stores configs in SQLite. Requires cgo.
+package sqlitepersister
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/mattn/go-sqlite3"
+
+	"github.com/square/quotaservice/config/sqlpersister"
+)
+
+// How long to wait for other processes to release locks on the database.
+const busyTimeoutMillis = 5000
+
+type SQLitePersister = sqlpersister.SQLPersister
+
+// Dialect adapts sqlpersister to SQLite.
+type Dialect struct{}
+
+func (Dialect) Name() string {
+	return "SQLite"
+}
+
+func (Dialect) Placeholder() sq.PlaceholderFormat {
+	return sq.Question
+}
+
+func (Dialect) Migrations() []string {
+	return []string{
+		"CREATE TABLE IF NOT EXISTS quotaservice (ID INTEGER PRIMARY KEY AUTOINCREMENT, Version INT UNIQUE, Config BLOB)",
+		"CREATE TABLE IF NOT EXISTS quotaservice_audit (ID INTEGER PRIMARY KEY AUTOINCREMENT, Time BIGINT, Username TEXT, Action TEXT, Entry BLOB)",
+		"CREATE INDEX IF NOT EXISTS quotaservice_audit_time ON quotaservice_audit (Time)",
+	}
+}
+
+// LockSuffix is empty, as SQLite serializes writes.
+func (Dialect) LockSuffix() string {
+	return ""
+}
+
+func (Dialect) IsDuplicateEntry(err error) bool {
+	sqliteErr, ok := err.(sqlite3.Error)
+	return ok && (sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique ||
+		sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey)
+}
+
+type connector struct {
+	path string
+}
+
+func (c connector) Connect() (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?_busy_timeout=%d", c.path, busyTimeoutMillis))
+	if err != nil {
+		return nil, err
+	}
+
+	// Writes are serialized anyway; a single connection avoids "database is locked" errors within the process.
+	db.SetMaxOpenConns(1)
+	return db, nil
+}
+
+// New opens the SQLite database at path, creating it and the quotaservice table if they don't exist, and polls
+// for configs persisted by other processes every pollingInterval.
+func New(path string, pollingInterval time.Duration) (*SQLitePersister, error) {
+	return sqlpersister.New(connector{path}, Dialect{}, pollingInterval)
+}
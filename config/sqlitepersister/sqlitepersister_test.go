@@ -0,0 +1,232 @@
+package sqlitepersister
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	r "github.com/stretchr/testify/require"
+
+	"github.com/square/quotaservice/audit"
+	"github.com/square/quotaservice/config"
+	"github.com/square/quotaservice/config/sqlpersister"
+	qsc "github.com/square/quotaservice/protos/config"
+)
+
+const pollingInterval = 100 * time.Millisecond
+
+func setup(t *testing.T) (string, *SQLitePersister) {
+	path := filepath.Join(t.TempDir(), "quotaservice.db")
+	p, err := New(path, pollingInterval)
+	r.NoError(t, err)
+
+	// Clear the notify that's sent when the persister starts
+	<-p.ConfigChangedWatcher()
+
+	return path, p
+}
+
+func waitForNotification(require *r.Assertions, p *SQLitePersister) {
+	select {
+	case <-time.After(5 * pollingInterval):
+		require.Fail("No notification received for new config")
+	case <-p.ConfigChangedWatcher():
+	}
+}
+
+func TestReadPersistedConfig(t *testing.T) {
+	require := r.New(t)
+	_, p := setup(t)
+	defer p.Close()
+
+	cPersisted, err := p.ReadPersistedConfig()
+	require.Error(err)
+	require.Nil(cPersisted)
+
+	c1 := &qsc.ServiceConfig{Version: 1}
+	require.NoError(p.PersistAndNotify("", c1))
+	waitForNotification(require, p)
+
+	cPersisted, err = p.ReadPersistedConfig()
+	require.NoError(err)
+	require.Equal(c1, cPersisted)
+
+	c2 := &qsc.ServiceConfig{Version: 2, User: "other"}
+	require.NoError(p.PersistAndNotify(config.HashConfig(c1), c2))
+	waitForNotification(require, p)
+
+	cHistorical, err := p.ReadHistoricalConfigs()
+	require.NoError(err)
+	require.Equal([]*qsc.ServiceConfig{c1, c2}, cHistorical)
+}
+
+func TestSharedDatabase(t *testing.T) {
+	require := r.New(t)
+	path, p := setup(t)
+	defer p.Close()
+
+	other, err := New(path, pollingInterval)
+	require.NoError(err)
+	defer other.Close()
+	<-other.ConfigChangedWatcher()
+
+	c := &qsc.ServiceConfig{Version: 1}
+	require.NoError(p.PersistAndNotify("", c))
+	waitForNotification(require, other)
+
+	cPersisted, err := other.ReadPersistedConfig()
+	require.NoError(err)
+	require.Equal(c, cPersisted)
+}
+
+func TestDuplicateConfig(t *testing.T) {
+	require := r.New(t)
+	_, p := setup(t)
+	defer p.Close()
+
+	c := &qsc.ServiceConfig{Version: 123}
+	require.NoError(p.PersistAndNotify("", c))
+	require.Equal(sqlpersister.ErrDuplicateConfig, p.PersistAndNotify("", c))
+}
+
+func TestConfigConflict(t *testing.T) {
+	require := r.New(t)
+	_, p := setup(t)
+	defer p.Close()
+
+	c1 := &qsc.ServiceConfig{Version: 1}
+	require.NoError(p.PersistAndNotify("", c1))
+	oldHash := config.HashConfig(c1)
+
+	c2 := &qsc.ServiceConfig{Version: 2, User: "first"}
+	require.NoError(p.PersistAndNotify(oldHash, c2))
+
+	// A second update based on the same config should be rejected.
+	c3 := &qsc.ServiceConfig{Version: 3, User: "second"}
+	err := p.PersistAndNotify(oldHash, c3)
+	require.True(config.IsConfigConflict(err), "expected conflict, got %v", err)
+
+	require.NoError(p.PersistAndNotify(config.HashConfig(c2), c3))
+}
+
+// TestConcurrentWriters checks that of two writers changing the same config, the second is rejected as a conflict,
+// whether its hash check or the unique version catches it.
+func TestConcurrentWriters(t *testing.T) {
+	require := r.New(t)
+	path, p := setup(t)
+	defer p.Close()
+
+	other, err := New(path, pollingInterval)
+	require.NoError(err)
+	defer other.Close()
+	<-other.ConfigChangedWatcher()
+
+	c1 := &qsc.ServiceConfig{Version: 1}
+	require.NoError(p.PersistAndNotify("", c1))
+
+	first := &qsc.ServiceConfig{Version: 2, User: "first"}
+	require.NoError(p.PersistAndNotify(config.HashConfig(c1), first))
+
+	err = other.PersistAndNotify(config.HashConfig(c1), &qsc.ServiceConfig{Version: 2, User: "second"})
+	require.True(config.IsConfigConflict(err), "expected conflict, got %v", err)
+
+	// The hash check passes, as it can on PostgreSQL after waiting for the first writer's lock, but the version has
+	// been taken.
+	err = other.PersistAndNotify(config.HashConfig(first), &qsc.ServiceConfig{Version: 2, User: "second"})
+	require.True(config.IsConfigConflict(err), "expected conflict, got %v", err)
+}
+
+func TestPrune(t *testing.T) {
+	require := r.New(t)
+	path, p := setup(t)
+	defer p.Close()
+
+	other, err := New(path, pollingInterval)
+	require.NoError(err)
+	defer other.Close()
+
+	for v := int32(1); v <= 3; v++ {
+		require.NoError(p.PersistAndNotify("", &qsc.ServiceConfig{Version: v}))
+	}
+
+	require.Eventually(func() bool {
+		c, err := p.ReadPersistedConfig()
+		return err == nil && c.Version == 3
+	}, 5*time.Second, 10*time.Millisecond)
+
+	p.SetRetentionPolicy(config.RetentionPolicy{MaxVersions: 2})
+	require.NoError(p.Prune())
+
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(err)
+	defer func() { _ = db.Close() }()
+
+	var count int
+	require.NoError(db.QueryRow("SELECT COUNT(*) FROM quotaservice").Scan(&count))
+	require.Equal(2, count)
+
+	cHistorical, err := p.ReadHistoricalConfigsPage(0, 1)
+	require.NoError(err)
+	require.Equal([]*qsc.ServiceConfig{{Version: 3}}, cHistorical)
+
+	cHistorical, err = p.ReadHistoricalConfigsPage(1, 0)
+	require.NoError(err)
+	require.Equal([]*qsc.ServiceConfig{{Version: 2}}, cHistorical)
+
+	// Others sharing the database stop serving the pruned version.
+	require.Eventually(func() bool {
+		cHistorical, err := other.ReadHistoricalConfigs()
+		return err == nil && len(cHistorical) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestMigrate(t *testing.T) {
+	require := r.New(t)
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "quotaservice.db"))
+	require.NoError(err)
+	defer func() { _ = db.Close() }()
+
+	// Migrating twice should be harmless.
+	require.NoError(sqlpersister.Migrate(db, Dialect{}))
+	require.NoError(sqlpersister.Migrate(db, Dialect{}))
+
+	var version int
+	require.NoError(db.QueryRow("SELECT MAX(Version) FROM quotaservice_migrations").Scan(&version))
+	require.Equal(len(Dialect{}.Migrations()), version)
+
+	_, err = db.Exec("INSERT INTO quotaservice (Version, Config) VALUES (1, x'')")
+	require.NoError(err)
+}
+
+func TestAuditTrail(t *testing.T) {
+	require := r.New(t)
+	_, p := setup(t)
+	defer p.Close()
+
+	start := time.Unix(1000, 0)
+	for i, action := range []string{"add_bucket", "read_configs", "add_bucket"} {
+		require.NoError(p.Record(&audit.Entry{
+			Time:    start.Add(time.Duration(i) * time.Minute),
+			Actor:   audit.Actor{User: "alice", IP: "10.0.0.1", Source: audit.CLI},
+			Action:  action,
+			Version: int32(i)}))
+	}
+
+	entries, err := p.Query(audit.Query{Action: "add_bucket"})
+	require.NoError(err)
+	require.Len(entries, 2)
+	require.Equal(int32(2), entries[0].Version)
+	require.Equal(audit.CLI, entries[0].Source)
+	require.True(entries[0].Time.Equal(start.Add(2 * time.Minute)))
+
+	entries, err = p.Query(audit.Query{Since: start.Add(time.Minute), Limit: 1, Offset: 1})
+	require.NoError(err)
+	require.Len(entries, 1)
+	require.Equal("read_configs", entries[0].Action)
+
+	entries, err = p.Query(audit.Query{User: "bob"})
+	require.NoError(err)
+	require.Empty(entries)
+}
@@ -0,0 +1,87 @@
+package sqlpersister
+
+import (
+	"encoding/json"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/square/quotaservice/audit"
+)
+
+const auditTableName = "quotaservice_audit"
+
+// Record appends an audit entry to the quotaservice_audit table, so that the SQLPersister can be used as an
+// audit.Sink.
+func (p *SQLPersister) Record(e *audit.Entry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	q, args, err := p.builder.
+		Insert(auditTableName).
+		Columns("Time", "Username", "Action", "Entry").
+		Values(e.Time.UnixNano(), e.User, e.Action, b).ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = p.db.Exec(q, args...)
+	return err
+}
+
+// Query returns the audit entries matching a query, newest first.
+func (p *SQLPersister) Query(query audit.Query) ([]*audit.Entry, error) {
+	query = query.WithDefaults()
+
+	b := p.builder.
+		Select("Entry").
+		From(auditTableName).
+		OrderBy("ID DESC").
+		Offset(uint64(query.Offset)).
+		Limit(uint64(query.Limit))
+
+	if query.User != "" {
+		b = b.Where(sq.Eq{"Username": query.User})
+	}
+
+	if query.Action != "" {
+		b = b.Where(sq.Eq{"Action": query.Action})
+	}
+
+	if !query.Since.IsZero() {
+		b = b.Where(sq.GtOrEq{"Time": query.Since.UnixNano()})
+	}
+
+	if !query.Until.IsZero() {
+		b = b.Where(sq.LtOrEq{"Time": query.Until.UnixNano()})
+	}
+
+	q, args, err := b.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := p.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	entries := make([]*audit.Entry, 0)
+	for rows.Next() {
+		var raw []byte
+		if err := rows.Scan(&raw); err != nil {
+			return nil, err
+		}
+
+		e := &audit.Entry{}
+		if err := json.Unmarshal(raw, e); err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
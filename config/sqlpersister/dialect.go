@@ -0,0 +1,41 @@
+package sqlpersister
+
+import (
+	sq "github.com/Masterminds/squirrel"
+)
+
+// Dialect adapts SQLPersister to a specific database.
+type Dialect interface {
+	// Name of the database, used in log messages.
+	Name() string
+	// Placeholder is the format of bind parameters in queries.
+	Placeholder() sq.PlaceholderFormat
+	// Migrations returns the statements that create and update the schema, oldest first. Once released, a
+	// migration must never be changed or removed; add a new one instead. Migrations should be idempotent, e.g.
+	// CREATE TABLE IF NOT EXISTS, as tables may have been created by hand before migrations were supported.
+	Migrations() []string
+	// LockSuffix is appended to a SELECT to lock the selected rows until the transaction completes. It may be
+	// empty if the database serializes transactions anyway.
+	LockSuffix() string
+	// IsDuplicateEntry tells you whether err was caused by violating a unique constraint.
+	IsDuplicateEntry(err error) bool
+}
+
+// ListeningDialect is implemented by dialects for databases that can notify listeners when a config is
+// persisted, so that SQLPersister doesn't have to poll for new configs.
+type ListeningDialect interface {
+	Dialect
+	// NotifyStatement is executed in the same transaction that persists a config.
+	NotifyStatement() string
+	// Listen starts listening for notifications sent by NotifyStatement.
+	Listen() (Listener, error)
+}
+
+// Listener receives notifications sent by a ListeningDialect's NotifyStatement.
+type Listener interface {
+	// Notifications returns a channel that receives a value whenever a config may have been persisted. This
+	// includes after reconnecting to the database, as notifications may have been missed while disconnected.
+	Notifications() <-chan struct{}
+	// Close stops listening.
+	Close() error
+}
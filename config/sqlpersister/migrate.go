@@ -0,0 +1,75 @@
+package sqlpersister
+
+import (
+	"database/sql"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/square/quotaservice/logging"
+)
+
+const (
+	migrationsTable           = "quotaservice_migrations"
+	migrationsCreateStatement = "CREATE TABLE IF NOT EXISTS " + migrationsTable + " (Version INT PRIMARY KEY)"
+)
+
+// Migrate brings the schema up to date, applying any of the dialect's migrations that haven't been applied yet.
+// Applied migrations are recorded in the quotaservice_migrations table.
+func Migrate(db *sql.DB, dialect Dialect) error {
+	if _, err := db.Exec(migrationsCreateStatement); err != nil {
+		return err
+	}
+
+	builder := sq.StatementBuilder.PlaceholderFormat(dialect.Placeholder())
+
+	var current sql.NullInt64
+	q, args, err := builder.Select("MAX(Version)").From(migrationsTable).ToSql()
+	if err != nil {
+		return err
+	}
+
+	if err := db.QueryRow(q, args...).Scan(&current); err != nil {
+		return err
+	}
+
+	migrations := dialect.Migrations()
+	for v := int(current.Int64) + 1; v <= len(migrations); v++ {
+		logging.Printf("Applying migration %v", v)
+		if err := migrate(db, builder, dialect, v, migrations[v-1]); err != nil {
+			return err
+		}
+	}
+
+	logging.Printf("Schema is at version %v", len(migrations))
+	return nil
+}
+
+func migrate(db *sql.DB, builder sq.StatementBuilderType, dialect Dialect, version int, statement string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	// No-op once the transaction has been committed.
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec(statement); err != nil {
+		return err
+	}
+
+	q, args, err := builder.Insert(migrationsTable).Columns("Version").Values(version).ToSql()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(q, args...); err != nil {
+		if dialect.IsDuplicateEntry(err) {
+			logging.Printf("Migration %v was applied concurrently", version)
+			return nil
+		}
+
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,530 @@
+// Package sqlpersister implements a ConfigPersister on top of database/sql. Databases are supported by
+// implementing a Dialect; see the mysqlpersister, postgrespersister and sqlitepersister packages.
+package sqlpersister
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/square/quotaservice/config"
+	"github.com/square/quotaservice/config/internal"
+	"github.com/square/quotaservice/logging"
+	qsc "github.com/square/quotaservice/protos/config"
+)
+
+var ErrDuplicateConfig = errors.New("config with provided version number already exists")
+
+const tableName = "quotaservice"
+
+type SQLPersister struct {
+	latestVersion int
+	db            *sql.DB
+	dialect       Dialect
+	builder       sq.StatementBuilderType
+	listener      Listener
+	m             *sync.RWMutex
+
+	notifier        *internal.Notifier
+	shutdown        chan struct{}
+	fetcherShutdown chan struct{}
+
+	configs map[int]*qsc.ServiceConfig
+
+	retention config.RetentionPolicy
+	pruner    *internal.Pruner
+}
+
+type Connector interface {
+	Connect() (*sql.DB, error)
+}
+
+// New connects to a database and migrates its schema. Dialects implementing ListeningDialect are notified of
+// new configs by the database; otherwise the database is polled every pollingInterval.
+func New(c Connector, dialect Dialect, pollingInterval time.Duration) (*SQLPersister, error) {
+	logging.Printf("Connecting to %v", dialect.Name())
+	db, err := c.Connect()
+	if err != nil {
+		return nil, err
+	}
+	logging.Printf("Connecting to %v: OK", dialect.Name())
+
+	logging.Print("Migrating schema")
+	if err := Migrate(db, dialect); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	logging.Print("Migrating schema: OK")
+
+	p := &SQLPersister{
+		db:              db,
+		dialect:         dialect,
+		builder:         sq.StatementBuilder.PlaceholderFormat(dialect.Placeholder()),
+		configs:         make(map[int]*qsc.ServiceConfig),
+		m:               &sync.RWMutex{},
+		notifier:        internal.NewNotifier(),
+		shutdown:        make(chan struct{}),
+		fetcherShutdown: make(chan struct{}),
+		latestVersion:   -1,
+	}
+
+	var notifications <-chan struct{}
+	if ld, ok := dialect.(ListeningDialect); ok {
+		// Listen before pulling configs, so that no config persisted in between is missed.
+		logging.Printf("Listening for notifications from %v", dialect.Name())
+		if p.listener, err = ld.Listen(); err != nil {
+			_ = db.Close()
+			return nil, err
+		}
+
+		notifications = p.listener.Notifications()
+	}
+
+	logging.Printf("Pulling configs from %v", dialect.Name())
+	if _, err := p.pullConfigs(); err != nil {
+		p.closeConnections()
+		return nil, err
+	}
+
+	p.m.RLock()
+	v := p.latestVersion
+	p.m.RUnlock()
+	logging.Printf("Pulling configs from %v: OK; Latest Version: %v", dialect.Name(), v)
+
+	p.notifyWatcher()
+
+	go p.configFetcher(pollingInterval, notifications)
+
+	return p, nil
+}
+
+// configFetcher pulls new configs whenever notified, or every pollingInterval if notifications is nil.
+func (p *SQLPersister) configFetcher(pollingInterval time.Duration, notifications <-chan struct{}) {
+	defer func() {
+		close(p.fetcherShutdown)
+	}()
+
+	for {
+		var poll <-chan time.Time
+		if notifications == nil {
+			poll = time.After(pollingInterval)
+		}
+
+		select {
+		case <-poll:
+		case _, ok := <-notifications:
+			if !ok {
+				logging.Printf("Stopped receiving notifications from %v, polling instead", p.dialect.Name())
+				notifications = nil
+			}
+		case <-p.shutdown:
+			logging.Printf("Received shutdown signal, shutting down %v watcher", p.dialect.Name())
+			return
+		}
+
+		if newConf, err := p.pullConfigs(); err != nil {
+			logging.Printf("Received an error trying to fetch config updates: %s", err)
+		} else if newConf {
+			logging.Printf("New config(s) found in %v", p.dialect.Name())
+			p.notifyWatcher()
+		}
+	}
+}
+
+// pullConfigs checks the database for new configs and returns true if there is a new config
+func (p *SQLPersister) pullConfigs() (bool, error) {
+	p.m.RLock()
+	v := p.latestVersion
+	p.m.RUnlock()
+
+	if err := p.dropPruned(v); err != nil {
+		return false, err
+	}
+
+	logging.Printf("Fetching configs later than %v", v)
+	q, args, err := p.builder.
+		Select("Version", "Config").
+		From(tableName).
+		Where("Version > ?", v).
+		OrderBy("Version ASC").ToSql()
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := p.db.Query(q, args...)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = rows.Close() }()
+	logging.Printf("Fetching configs later than %v: OK", v)
+
+	rowCount := 0
+	maxVersion := -1
+	for rows.Next() {
+		rowCount++
+
+		var version int
+		var raw []byte
+		err := rows.Scan(&version, &raw)
+		if err != nil {
+			return false, err
+		}
+
+		c, err := config.UnmarshalBytes(raw)
+		if err != nil {
+			logging.Printf("Could not unmarshal config version %v, error: %s", version, err)
+			continue
+		}
+
+		p.m.Lock()
+		p.configs[version] = c
+		p.m.Unlock()
+
+		maxVersion = version
+	}
+
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+
+	if rowCount == 0 {
+		logging.Printf("No versions later than %v found", v)
+		return false, nil
+	}
+
+	logging.Printf("Upgrading from version %v to %v", v, maxVersion)
+
+	p.m.Lock()
+	p.latestVersion = maxVersion
+	p.m.Unlock()
+
+	return true, nil
+}
+
+func (p *SQLPersister) notifyWatcher() {
+	logging.Print("Notifying config watcher")
+	p.notifier.Notify()
+}
+
+// PersistAndNotify persists a marshalled configuration passed in. If oldHash is not empty, the most recent
+// config is locked and compared against oldHash in the same transaction that inserts the new config, and a
+// *config.ConfigConflictError is returned rather than ErrDuplicateConfig if its version already exists.
+func (p *SQLPersister) PersistAndNotify(oldHash string, c *qsc.ServiceConfig) error {
+	logging.Printf("Persisting version %v", c.GetVersion())
+	b, err := config.MarshalBytes(c)
+	if err != nil {
+		return err
+	}
+
+	tx, err := p.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	// No-op once the transaction has been committed.
+	defer func() { _ = tx.Rollback() }()
+
+	if oldHash != "" {
+		currentHash, err := p.latestHash(tx)
+		if err != nil {
+			return err
+		}
+
+		if err := config.CheckConfigHash(oldHash, currentHash); err != nil {
+			return err
+		}
+	}
+
+	q, args, err := p.builder.Insert(tableName).Columns("Version", "Config").Values(c.GetVersion(), b).ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(q, args...)
+	if err != nil {
+		if p.dialect.IsDuplicateEntry(err) {
+			if oldHash != "" {
+				// Someone else persisted this version after the hash was checked. On PostgreSQL, the row locked while
+				// checking can be the one that was most recent before waiting for the lock, so the check passes.
+				return &config.ConfigConflictError{ExpectedHash: oldHash}
+			}
+
+			return ErrDuplicateConfig
+		}
+
+		return err
+	}
+
+	if ld, ok := p.dialect.(ListeningDialect); ok {
+		if _, err := tx.Exec(ld.NotifyStatement()); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	logging.Printf("Persisting version %v: OK", c.GetVersion())
+	return nil
+}
+
+// latestHash returns the hash of the most recent config, or an empty string if there is none. The row is
+// locked until the transaction completes.
+func (p *SQLPersister) latestHash(tx *sql.Tx) (string, error) {
+	b := p.builder.
+		Select("Config").
+		From(tableName).
+		OrderBy("Version DESC").
+		Limit(1)
+	if suffix := p.dialect.LockSuffix(); suffix != "" {
+		b = b.Suffix(suffix)
+	}
+
+	q, args, err := b.ToSql()
+	if err != nil {
+		return "", err
+	}
+
+	var raw []byte
+	err = tx.QueryRow(q, args...).Scan(&raw)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	c, err := config.UnmarshalBytes(raw)
+	if err != nil {
+		return "", err
+	}
+
+	return config.HashConfig(c), nil
+}
+
+// ConfigChangedWatcher returns a channel that is notified whenever a new config is available.
+func (p *SQLPersister) ConfigChangedWatcher() <-chan struct{} {
+	return p.notifier.Watcher
+}
+
+// ReadPersistedConfig provides a config previously persisted.
+func (p *SQLPersister) ReadPersistedConfig() (*qsc.ServiceConfig, error) {
+	p.m.RLock()
+	defer p.m.RUnlock()
+	c := p.configs[p.latestVersion]
+	if c == nil {
+		return nil, errors.New("persister has a nil config")
+	}
+	c = config.CloneConfig(c)
+
+	return c, nil
+}
+
+// ReadHistoricalConfigs returns an array of previously persisted configs
+func (p *SQLPersister) ReadHistoricalConfigs() ([]*qsc.ServiceConfig, error) {
+	var configs []*qsc.ServiceConfig
+
+	p.m.RLock()
+	defer p.m.RUnlock()
+
+	var versions []int
+	for k := range p.configs {
+		versions = append(versions, k)
+	}
+
+	sort.Ints(versions)
+
+	for _, v := range versions {
+		configs = append(configs, config.CloneConfig(p.configs[v]))
+	}
+
+	return configs, nil
+}
+
+// ReadHistoricalConfigsPage returns a page of previously persisted configs, newest first. Only the page is read
+// from the database.
+func (p *SQLPersister) ReadHistoricalConfigsPage(offset, limit int) ([]*qsc.ServiceConfig, error) {
+	b := p.builder.Select("Version", "Config").From(tableName).OrderBy("Version DESC")
+	if limit > 0 {
+		b = b.Limit(uint64(limit))
+	} else if offset > 0 {
+		// Not every database supports an offset without a limit.
+		b = b.Limit(math.MaxInt64)
+	}
+
+	if offset > 0 {
+		b = b.Offset(uint64(offset))
+	}
+
+	q, args, err := b.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := p.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	configs := make([]*qsc.ServiceConfig, 0)
+	for rows.Next() {
+		var version int
+		var raw []byte
+		if err := rows.Scan(&version, &raw); err != nil {
+			return nil, err
+		}
+
+		c, err := config.UnmarshalBytes(raw)
+		if err != nil {
+			logging.Printf("Could not unmarshal config version %v, error: %s", version, err)
+			continue
+		}
+
+		configs = append(configs, c)
+	}
+
+	return configs, rows.Err()
+}
+
+// SetRetentionPolicy sets the policy used to prune historical configs, pruning in the background if the
+// policy is enabled.
+func (p *SQLPersister) SetRetentionPolicy(policy config.RetentionPolicy) {
+	p.m.Lock()
+	old := p.pruner
+	p.retention = policy
+	p.pruner = nil
+	if policy.Enabled() {
+		p.pruner = internal.NewPruner(policy.Interval(), p.Prune)
+	}
+	p.m.Unlock()
+
+	old.Stop()
+}
+
+// Prune deletes historical configs that fall outside the retention policy from the database. The latest
+// version is never deleted.
+func (p *SQLPersister) Prune() error {
+	p.m.RLock()
+	expired := config.ExpiredConfigs(p.retention, p.configs, p.latestVersion, time.Now())
+	p.m.RUnlock()
+
+	if len(expired) == 0 {
+		return nil
+	}
+
+	logging.Printf("Pruning versions %v", expired)
+	q, args, err := p.builder.Delete(tableName).Where(sq.Eq{"Version": expired}).ToSql()
+	if err != nil {
+		return err
+	}
+
+	if _, err = p.db.Exec(q, args...); err != nil {
+		return err
+	}
+
+	p.m.Lock()
+	for _, v := range expired {
+		delete(p.configs, v)
+	}
+	p.m.Unlock()
+
+	// Others sharing the database drop the pruned versions when they next pull configs.
+	if ld, ok := p.dialect.(ListeningDialect); ok {
+		if _, err := p.db.Exec(ld.NotifyStatement()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// dropPruned drops configs up to version v that others have pruned from the database since they were pulled.
+func (p *SQLPersister) dropPruned(v int) error {
+	q, args, err := p.builder.Select("COUNT(*)").From(tableName).Where("Version <= ?", v).ToSql()
+	if err != nil {
+		return err
+	}
+
+	var count int
+	if err := p.db.QueryRow(q, args...).Scan(&count); err != nil {
+		return err
+	}
+
+	p.m.RLock()
+	cached := len(p.configs)
+	p.m.RUnlock()
+
+	if count == cached {
+		return nil
+	}
+
+	q, args, err = p.builder.Select("Version").From(tableName).Where("Version <= ?", v).ToSql()
+	if err != nil {
+		return err
+	}
+
+	rows, err := p.db.Query(q, args...)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+
+	versions := make(map[int]bool, count)
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return err
+		}
+
+		versions[version] = true
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	for version := range p.configs {
+		if !versions[version] && version != p.latestVersion {
+			logging.Printf("Dropping pruned version %v", version)
+			delete(p.configs, version)
+		}
+	}
+
+	return nil
+}
+
+// Close closes the notification channel and the connection to the database.
+func (p *SQLPersister) Close() {
+	logging.Printf("Shutting down %v persister", p.dialect.Name())
+	p.SetRetentionPolicy(config.RetentionPolicy{})
+	close(p.shutdown)
+	<-p.fetcherShutdown
+
+	close(p.notifier.Watcher)
+	p.closeConnections()
+}
+
+func (p *SQLPersister) closeConnections() {
+	if p.listener != nil {
+		if err := p.listener.Close(); err != nil {
+			logging.Printf("Could not stop listening to %v: %v", p.dialect.Name(), err)
+		}
+	}
+
+	if err := p.db.Close(); err != nil {
+		logging.Printf("Could not terminate %v connection: %v", p.dialect.Name(), err)
+	} else {
+		logging.Printf("Shutting down %v persister: OK", p.dialect.Name())
+	}
+}
@@ -0,0 +1,317 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	pb "github.com/square/quotaservice/protos/config"
+)
+
+// ValidationError describes a single problem found in a config.
+type ValidationError struct {
+	// Path locates the offending field, using the field names of the YAML representation of the config,
+	// e.g. namespaces.my_namespace.buckets.my_bucket.size
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (e ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+
+	return e.Path + ": " + e.Message
+}
+
+// ValidationErrors is an error wrapping all of the problems found when validating a config.
+type ValidationErrors []ValidationError
+
+func (v ValidationErrors) Error() string {
+	msgs := make([]string, len(v))
+	for i, e := range v {
+		msgs[i] = e.Error()
+	}
+
+	return "Invalid config: " + strings.Join(msgs, "; ")
+}
+
+// AsValidationErrors returns the ValidationErrors wrapped by err, if any.
+func AsValidationErrors(err error) (ValidationErrors, bool) {
+	var v ValidationErrors
+	ok := errors.As(err, &v)
+	return v, ok
+}
+
+// Validate checks a config for problems, returning all that were found. Validate expects defaults to
+// have been applied to the config, using ApplyDefaults. An empty return value means the config is valid.
+func Validate(cfg *pb.ServiceConfig) []ValidationError {
+	v := &validator{}
+
+	if cfg == nil {
+		v.addf("", "config is missing")
+		return v.errs
+	}
+
+	if cfg.Version < 0 {
+		v.addf("version", "must not be negative; was %v", cfg.Version)
+	}
+
+	if cfg.GlobalDefaultBucket != nil {
+		v.validateBucket("global_default_bucket", cfg.GlobalDefaultBucket)
+	}
+
+	for _, name := range sortedKeys(cfg.Namespaces) {
+		v.validateNamespace(name, cfg.Namespaces[name])
+	}
+
+	v.validateOverrides(cfg)
+	v.validateAlertRules(cfg)
+
+	return v.errs
+}
+
+type validator struct {
+	errs []ValidationError
+}
+
+func (v *validator) addf(path, format string, args ...interface{}) {
+	v.errs = append(v.errs, ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) validateNamespace(name string, ns *pb.NamespaceConfig) {
+	path := "namespaces." + name
+
+	if ns == nil {
+		v.addf(path, "namespace is empty")
+		return
+	}
+
+	v.validateName(path, name, ns.Name)
+
+	if ns.DefaultBucket != nil && ns.DynamicBucketTemplate != nil {
+		v.addf(path, "cannot have a default bucket as well as allow dynamic buckets")
+	}
+
+	if ns.MaxDynamicBuckets < 0 {
+		v.addf(path+".max_dynamic_buckets", "must not be negative; was %v", ns.MaxDynamicBuckets)
+	}
+
+	if _, ok := pb.NamespaceConfig_EvictionPolicy_name[int32(ns.DynamicBucketEvictionPolicy)]; !ok {
+		v.addf(path+".dynamic_bucket_eviction_policy", "unknown policy %v", int32(ns.DynamicBucketEvictionPolicy))
+	}
+
+	if ns.DefaultBucket != nil {
+		v.validateBucket(path+".default_bucket", ns.DefaultBucket)
+	}
+
+	if ns.DynamicBucketTemplate != nil {
+		v.validateBucket(path+".dynamic_bucket_template", ns.DynamicBucketTemplate)
+	}
+
+	for _, bucketName := range sortedKeys(ns.Buckets) {
+		bucketPath := path + ".buckets." + bucketName
+		b := ns.Buckets[bucketName]
+
+		if b == nil {
+			v.addf(bucketPath, "bucket is empty")
+			continue
+		}
+
+		if bucketName == DefaultBucketName || bucketName == DynamicBucketTemplateName {
+			v.addf(bucketPath, "%v is a reserved bucket name", bucketName)
+		}
+
+		v.validateName(bucketPath, bucketName, b.Name)
+
+		if b.Namespace != "" && b.Namespace != name {
+			v.addf(bucketPath+".namespace", "does not match namespace %v; was %v", name, b.Namespace)
+		}
+
+		v.validateBucket(bucketPath, b)
+	}
+}
+
+// validateName checks that an entry in a map is keyed on its name.
+func (v *validator) validateName(path, key, name string) {
+	if key == "" {
+		v.addf(path, "name must not be empty")
+	} else if strings.Contains(key, ":") {
+		v.addf(path, "name must not contain ':'")
+	}
+
+	if name != "" && name != key {
+		v.addf(path+".name", "does not match key %v; was %v", key, name)
+	}
+}
+
+func (v *validator) validateBucket(path string, b *pb.BucketConfig) {
+	if b.Size <= 0 {
+		v.addf(path+".size", "must be positive; was %v", b.Size)
+	}
+
+	if b.FillRate <= 0 {
+		v.addf(path+".fill_rate", "must be positive; was %v", b.FillRate)
+	}
+
+	if b.WaitTimeoutMillis < 0 {
+		v.addf(path+".wait_timeout_millis", "must not be negative; was %v", b.WaitTimeoutMillis)
+	}
+
+	if b.MaxDebtMillis < 0 {
+		v.addf(path+".max_debt_millis", "must not be negative; was %v", b.MaxDebtMillis)
+	}
+
+	// A max_tokens_per_request that isn't positive means requests aren't limited. One that isn't set defaults to
+	// fill_rate, so a fill_rate greater than size also needs an explicit max_tokens_per_request.
+	if b.Size > 0 && b.MaxTokensPerRequest > b.Size {
+		v.addf(path+".max_tokens_per_request", "must not be greater than size %v; was %v", b.Size, b.MaxTokensPerRequest)
+	}
+
+	for i, s := range b.Schedules {
+		v.validateSchedule(fmt.Sprintf("%v.schedules.%d", path, i), b, s)
+	}
+}
+
+func (v *validator) validateSchedule(path string, b *pb.BucketConfig, s *pb.BucketSchedule) {
+	if s == nil {
+		v.addf(path, "schedule is empty")
+		return
+	}
+
+	if _, err := ParseScheduleStart(s.Start); err != nil {
+		v.addf(path+".start", "invalid cron expression %q: %v", s.Start, err)
+	}
+
+	if s.DurationMillis <= 0 {
+		v.addf(path+".duration_millis", "must be positive; was %v", s.DurationMillis)
+	}
+
+	if s.Size == 0 && s.FillRate == 0 {
+		v.addf(path, "must set size or fill_rate")
+	}
+
+	if s.Size < 0 {
+		v.addf(path+".size", "must not be negative; was %v", s.Size)
+	} else if s.Size > 0 && b.MaxTokensPerRequest > s.Size {
+		v.addf(path+".size", "must not be less than max_tokens_per_request %v; was %v", b.MaxTokensPerRequest, s.Size)
+	}
+
+	if s.FillRate < 0 {
+		v.addf(path+".fill_rate", "must not be negative; was %v", s.FillRate)
+	}
+}
+
+// validateOverrides checks that each override targets a bucket, and that the bucket would still be valid
+// with the override applied. Overrides aren't required to expire in the future, as expired overrides remain in
+// a config until they are removed.
+func (v *validator) validateOverrides(cfg *pb.ServiceConfig) {
+	targets := make(map[string]bool)
+
+	for i, o := range cfg.Overrides {
+		path := fmt.Sprintf("overrides.%d", i)
+
+		if o == nil {
+			v.addf(path, "override is empty")
+			continue
+		}
+
+		fqn := FullyQualifiedName(o.Namespace, o.Bucket)
+		if targets[fqn] {
+			v.addf(path, "bucket %v is already overridden", fqn)
+		}
+
+		targets[fqn] = true
+
+		if o.ExpiresAt <= 0 {
+			v.addf(path+".expires_at", "must be positive; was %v", o.ExpiresAt)
+		}
+
+		b := overriddenBucket(cfg, o.Namespace, o.Bucket)
+		if b == nil {
+			v.addf(path, "bucket %v does not exist", fqn)
+			continue
+		}
+
+		if !overridesAnyField(o.Config) {
+			v.addf(path+".config", "must override at least one field")
+			continue
+		}
+
+		// The bucket's schedules have already been validated.
+		overridden := proto.Clone(b).(*pb.BucketConfig)
+		overridden.Schedules = nil
+		applyOverride(overridden, o.Config)
+		v.validateBucket(path+".config", overridden)
+	}
+}
+
+// validateAlertRules checks that each alert rule is named uniquely, applies to a namespace and has a threshold it
+// can exceed and webhooks to notify. The bucket needn't exist, since rules may apply to dynamic buckets.
+func (v *validator) validateAlertRules(cfg *pb.ServiceConfig) {
+	names := make(map[string]bool)
+
+	for i, r := range cfg.AlertRules {
+		path := fmt.Sprintf("alert_rules.%d", i)
+
+		if r == nil {
+			v.addf(path, "alert rule is empty")
+			continue
+		}
+
+		if r.Name == "" {
+			v.addf(path+".name", "must not be empty")
+		} else if names[r.Name] {
+			v.addf(path+".name", "alert rule %v already exists", r.Name)
+		}
+
+		names[r.Name] = true
+
+		if cfg.Namespaces[r.Namespace] == nil {
+			v.addf(path+".namespace", "namespace %q does not exist", r.Namespace)
+		}
+
+		if _, ok := pb.AlertRule_Condition_name[int32(r.Condition)]; !ok {
+			v.addf(path+".condition", "unknown condition %v", int32(r.Condition))
+		}
+
+		if r.ThresholdPercent < 0 || r.ThresholdPercent >= 100 {
+			v.addf(path+".threshold_percent", "must be at least 0 and less than 100; was %v", r.ThresholdPercent)
+		}
+
+		if r.WindowMillis < minAlertWindowMillis {
+			v.addf(path+".window_millis", "must be at least %v; was %v", minAlertWindowMillis, r.WindowMillis)
+		}
+
+		if r.MinRequests < 0 {
+			v.addf(path+".min_requests", "must not be negative; was %v", r.MinRequests)
+		}
+
+		if len(r.Webhooks) == 0 {
+			v.addf(path+".webhooks", "must not be empty")
+		}
+
+		for j, webhook := range r.Webhooks {
+			if u, err := url.Parse(webhook); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				v.addf(fmt.Sprintf("%v.webhooks.%d", path, j), "must be an http or https URL; was %q", webhook)
+			}
+		}
+	}
+}
+
+// sortedKeys returns the keys of a map in order, so that errors are reported deterministically.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
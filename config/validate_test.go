@@ -0,0 +1,223 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	pb "github.com/square/quotaservice/protos/config"
+	"github.com/square/quotaservice/test/helpers"
+)
+
+func TestValidConfig(t *testing.T) {
+	cfg, err := ReadConfig(strings.NewReader(cfgYaml))
+	helpers.CheckError(t, err)
+
+	if errs := Validate(cfg); len(errs) != 0 {
+		t.Fatalf("Expected config to be valid; got %v", errs)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		yaml     string
+		expected []string
+	}{
+		{"negative size", `
+global_default_bucket:
+  size: -1
+`, []string{"global_default_bucket.size"}},
+		{"negative fill rate", `
+namespaces:
+  ns:
+    buckets:
+      b:
+        fill_rate: -5
+`, []string{"namespaces.ns.buckets.b.fill_rate"}},
+		{"too many tokens per request", `
+namespaces:
+  ns:
+    dynamic_bucket_template:
+      size: 10
+      max_tokens_per_request: 11
+`, []string{"namespaces.ns.dynamic_bucket_template.max_tokens_per_request"}},
+		{"too many tokens per request by default", `
+namespaces:
+  ns:
+    default_bucket:
+      size: 10
+      fill_rate: 20
+`, []string{"namespaces.ns.default_bucket.max_tokens_per_request"}},
+		{"default bucket and dynamic template", `
+namespaces:
+  ns:
+    default_bucket:
+      size: 10
+      fill_rate: 5
+    dynamic_bucket_template:
+      size: 10
+      fill_rate: 5
+`, []string{"namespaces.ns"}},
+		{"negative timeouts", `
+namespaces:
+  ns:
+    max_dynamic_buckets: -1
+    default_bucket:
+      wait_timeout_millis: -1
+      max_debt_millis: -1
+`, []string{
+			"namespaces.ns.max_dynamic_buckets",
+			"namespaces.ns.default_bucket.wait_timeout_millis",
+			"namespaces.ns.default_bucket.max_debt_millis"}},
+		{"reserved and invalid names", `
+namespaces:
+  "a:b":
+    buckets:
+      ___DEFAULT_BUCKET___:
+        size: 10
+        fill_rate: 5
+`, []string{"namespaces.a:b", "namespaces.a:b.buckets.___DEFAULT_BUCKET___"}},
+		{"invalid overrides", `
+namespaces:
+  ns:
+    buckets:
+      b:
+        size: 10
+        fill_rate: 5
+overrides:
+  - namespace: ns
+    bucket: b
+    expires_at: 100
+    config:
+      max_tokens_per_request: 11
+  - namespace: ns
+    bucket: missing
+    expires_at: 100
+    config:
+      size: 5
+  - namespace: ns
+    bucket: b
+    config:
+      name: b
+`, []string{
+			"overrides.0.config.max_tokens_per_request",
+			"overrides.1",
+			"overrides.2",
+			"overrides.2.expires_at",
+			"overrides.2.config"}},
+		{"invalid schedules", `
+namespaces:
+  ns:
+    buckets:
+      b:
+        size: 10
+        max_tokens_per_request: 5
+        schedules:
+          - start: "0 25 * * *"
+            duration_millis: 1000
+            fill_rate: 5
+          - start: "0 22 * * *"
+            size: 2
+          - start: "0 22 * * *"
+            duration_millis: 1000
+`, []string{
+			"namespaces.ns.buckets.b.schedules.0.start",
+			"namespaces.ns.buckets.b.schedules.1.duration_millis",
+			"namespaces.ns.buckets.b.schedules.1.size",
+			"namespaces.ns.buckets.b.schedules.2"}},
+		{"invalid alert rules", `
+namespaces:
+  ns:
+    buckets:
+      b:
+        size: 10
+        fill_rate: 5
+alert_rules:
+  - name: timeouts
+    namespace: ns
+    condition: REJECTIONS
+    threshold_percent: 100
+    window_millis: 1000
+    webhooks: ["ftp://example.com"]
+  - name: timeouts
+    namespace: missing
+    threshold_percent: 5
+    window_millis: 60000
+    min_requests: -1
+`, []string{
+			"alert_rules.0.threshold_percent",
+			"alert_rules.0.window_millis",
+			"alert_rules.0.webhooks.0",
+			"alert_rules.1.name",
+			"alert_rules.1.namespace",
+			"alert_rules.1.min_requests",
+			"alert_rules.1.webhooks"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg, err := ReadConfig(strings.NewReader(test.yaml))
+			helpers.CheckError(t, err)
+
+			var paths []string
+			for _, e := range Validate(cfg) {
+				paths = append(paths, e.Path)
+			}
+
+			if !reflect.DeepEqual(paths, test.expected) {
+				t.Fatalf("Expected errors for %v; got %v", test.expected, Validate(cfg))
+			}
+		})
+	}
+}
+
+func TestValidateNil(t *testing.T) {
+	if errs := Validate(nil); len(errs) != 1 {
+		t.Fatalf("Expected a single error; got %v", errs)
+	}
+
+	cfg := NewDefaultServiceConfig()
+	cfg.Namespaces["ns"] = nil
+	cfg.Namespaces["ok"] = &pb.NamespaceConfig{Buckets: map[string]*pb.BucketConfig{"b": nil}}
+	ApplyDefaults(cfg)
+
+	errs := Validate(cfg)
+	if len(errs) != 2 || errs[0].Path != "namespaces.ns" || errs[1].Path != "namespaces.ok.buckets.b" {
+		t.Fatalf("Expected errors for empty namespace and bucket; got %v", errs)
+	}
+}
+
+func TestValidateNames(t *testing.T) {
+	// ApplyDefaults would fix these up.
+	b := NewDefaultBucketConfig("c")
+	b.Namespace = "other"
+	cfg := NewDefaultServiceConfig()
+	cfg.Namespaces["ns"] = &pb.NamespaceConfig{Name: "x", Buckets: map[string]*pb.BucketConfig{"b": b}}
+
+	var paths []string
+	for _, e := range Validate(cfg) {
+		paths = append(paths, e.Path)
+	}
+
+	expected := []string{"namespaces.ns.name", "namespaces.ns.buckets.b.name", "namespaces.ns.buckets.b.namespace"}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Fatalf("Expected errors for %v; got %v", expected, Validate(cfg))
+	}
+}
+
+func TestValidationErrors(t *testing.T) {
+	var err error = ValidationErrors{{Path: "a", Message: "bad"}, {Path: "b", Message: "worse"}}
+
+	if err.Error() != "Invalid config: a: bad; b: worse" {
+		t.Fatalf("Unexpected message %v", err)
+	}
+
+	v, ok := AsValidationErrors(err)
+	if !ok || len(v) != 2 {
+		t.Fatalf("Expected to unwrap validation errors; got %v", v)
+	}
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/go-zookeeper/zk"
 	"github.com/golang/protobuf/proto"
+	"github.com/square/quotaservice/config/internal"
 	"github.com/square/quotaservice/logging"
 	pb "github.com/square/quotaservice/protos/config"
 )
@@ -36,10 +37,15 @@ type ZkConfigPersister struct {
 	// Historical map of configurations
 	// hash -> config
 	configs map[string]*pb.ServiceConfig
+	// Hashes of configs, newest first
+	history []string
 
 	// Base Zookeeper path
 	path string
 
+	retention RetentionPolicy
+	pruner    *internal.Pruner
+
 	watcher chan struct{}
 
 	conn  *zk.Conn
@@ -170,9 +176,7 @@ func createPath(conn *zk.Conn, path string) (err error) {
 
 // PersistAndNotify persists a configuration passed in.
 func (z *ZkConfigPersister) PersistAndNotify(oldHash string, cfg *pb.ServiceConfig) error {
-	// TODO(manik) Optimistic version check with oldHash
-
-	b, e := proto.Marshal(cfg)
+	b, e := MarshalBytes(cfg)
 	if e != nil {
 		return e
 	}
@@ -180,11 +184,27 @@ func (z *ZkConfigPersister) PersistAndNotify(oldHash string, cfg *pb.ServiceConf
 	z.RLock()
 	defer z.RUnlock()
 
-	key := HashConfigBytes(b)
+	key := HashConfig(cfg)
 	if key == z.config {
 		return nil
 	}
 
+	// The base path holds the hash of the most recently persisted config. Its znode version is used to make sure
+	// no one else persists a config between checking the hash and updating it.
+	current, stat, err := z.conn.Get(z.path)
+	if err != nil {
+		return err
+	}
+
+	currentHash, err := z.persistedHash(string(current))
+	if err != nil {
+		return err
+	}
+
+	if err := CheckConfigHash(oldHash, currentHash); err != nil {
+		return err
+	}
+
 	path := fmt.Sprintf("%s/%s", z.path, key)
 	logging.Printf("Storing config version %v in path %v", cfg.Version, path)
 
@@ -192,13 +212,58 @@ func (z *ZkConfigPersister) PersistAndNotify(oldHash string, cfg *pb.ServiceConf
 		return err
 	}
 
-	_, err := z.conn.Set(z.path, []byte(key), -1)
+	expectedVersion := stat.Version
+	if oldHash == "" {
+		// No version check requested.
+		expectedVersion = -1
+	}
+
+	_, err = z.conn.Set(z.path, []byte(key), expectedVersion)
+	if err == zk.ErrBadVersion {
+		// Someone else got there first.
+		conflict := &ConfigConflictError{ExpectedHash: oldHash}
+		if current, _, e := z.conn.Get(z.path); e == nil {
+			conflict.ActualHash, _ = z.persistedHash(string(current))
+		}
+
+		return conflict
+	}
 
 	// There is no notification, that happens when zookeeper alerts the watcher
 
 	return err
 }
 
+// persistedHash returns the HashConfig of the config stored under a child of the base path, as named by the base
+// path, or of the latest config read if the base path doesn't name one. Configs persisted by older versions are
+// named with the hash of their serialized bytes instead, which may not match, so the hash is always recomputed.
+// The persister must be locked.
+func (z *ZkConfigPersister) persistedHash(name string) (string, error) {
+	if name == "" {
+		name = z.config
+	}
+
+	if name == "" {
+		return "", nil
+	}
+
+	cfg, ok := z.configs[name]
+	if !ok {
+		// Persisted since the configs were last read.
+		data, _, err := z.conn.Get(fmt.Sprintf("%s/%s", z.path, name))
+		if err != nil {
+			return "", err
+		}
+
+		cfg = &pb.ServiceConfig{}
+		if err := proto.Unmarshal(data, cfg); err != nil {
+			return "", err
+		}
+	}
+
+	return HashConfig(cfg), nil
+}
+
 // ReadPersistedConfig provides a config previously persisted.
 func (z *ZkConfigPersister) ReadPersistedConfig() (*pb.ServiceConfig, error) {
 	z.RLock()
@@ -250,6 +315,11 @@ func (z *ZkConfigPersister) currentConfigEventListener() (<-chan zk.Event, error
 		path := fmt.Sprintf("%s/%s", z.path, hash)
 		data, _, err := z.conn.Get(path)
 
+		if err == zk.ErrNoNode {
+			// Pruned since listing the children.
+			continue
+		}
+
 		if err != nil {
 			logging.Printf("Received error from zookeeper when fetching %s: %+v", path, err)
 			return nil, err
@@ -271,6 +341,7 @@ func (z *ZkConfigPersister) currentConfigEventListener() (<-chan zk.Event, error
 	defer z.Unlock()
 
 	z.configs = configs
+	z.history = newestFirst(configs)
 	z.config = latestHash
 
 	logging.Printf("Setting latest config hash to %v (version %v)", z.config, latestHashVersion)
@@ -307,9 +378,55 @@ func (z *ZkConfigPersister) ReadHistoricalConfigs() ([]*pb.ServiceConfig, error)
 	return CloneConfigs(z.configs), nil
 }
 
+// ReadHistoricalConfigsPage returns a page of previously persisted configs, newest first.
+func (z *ZkConfigPersister) ReadHistoricalConfigsPage(offset, limit int) ([]*pb.ServiceConfig, error) {
+	z.RLock()
+	defer z.RUnlock()
+
+	return pageOfConfigs(z.configs, z.history, offset, limit), nil
+}
+
+// SetRetentionPolicy sets the policy used to prune historical configs, pruning in the background if the
+// policy is enabled.
+func (z *ZkConfigPersister) SetRetentionPolicy(policy RetentionPolicy) {
+	z.Lock()
+	old := z.pruner
+	z.retention = policy
+	z.pruner = nil
+	if policy.Enabled() {
+		z.pruner = internal.NewPruner(policy.Interval(), z.Prune)
+	}
+	z.Unlock()
+
+	old.Stop()
+}
+
+// Prune deletes the znodes of historical configs that fall outside the retention policy. Other quotaservice
+// nodes pruning at the same time is harmless.
+func (z *ZkConfigPersister) Prune() error {
+	z.Lock()
+	defer z.Unlock()
+	defer func() { z.history = newestFirst(z.configs) }()
+
+	for _, hash := range ExpiredConfigs(z.retention, z.configs, z.config, time.Now()) {
+		path := fmt.Sprintf("%s/%s", z.path, hash)
+		logging.Printf("Pruning config version %v in path %v", z.configs[hash].Version, path)
+
+		if err := z.conn.Delete(path, -1); err != nil && err != zk.ErrNoNode {
+			return err
+		}
+
+		delete(z.configs, hash)
+	}
+
+	return nil
+}
+
 // Close makes sure all event listeners are done
 // and then closes the connection
 func (z *ZkConfigPersister) Close() {
+	z.SetRetentionPolicy(RetentionPolicy{})
+
 	z.watch.stopper <- struct{}{}
 	z.wg.Wait()
 
@@ -13,6 +13,7 @@ import (
 	"os"
 
 	"github.com/go-zookeeper/zk"
+	"github.com/golang/protobuf/proto"
 	"github.com/square/quotaservice/config/zkhelpers"
 	pb "github.com/square/quotaservice/protos/config"
 	"github.com/square/quotaservice/test/helpers"
@@ -178,6 +179,41 @@ func TestHistoricalConfigs(t *testing.T) {
 	}
 }
 
+func TestPrune(t *testing.T) {
+	conn := setup("/prune")
+
+	p, err := NewZkConfigPersisterWithConnection("/prune", conn)
+	helpers.CheckError(t, err)
+
+	defer p.Close()
+
+	waitOrTimeout(p.ConfigChangedWatcher(), time.Minute)
+
+	for v := int32(1); v <= 3; v++ {
+		cfg := NewDefaultServiceConfig()
+		cfg.Version = v
+		persistOrPanic(p, cfg)
+		waitOrTimeout(p.ConfigChangedWatcher(), time.Minute)
+	}
+
+	p.SetRetentionPolicy(RetentionPolicy{MaxVersions: 1})
+	helpers.CheckError(t, p.Prune())
+
+	children, _, err := conn.Children("/prune")
+	helpers.CheckError(t, err)
+
+	if len(children) != 1 {
+		t.Fatalf("Expected only the current config to be kept; got %v", children)
+	}
+
+	cfg, err := p.ReadPersistedConfig()
+	helpers.CheckError(t, err)
+
+	if cfg.Version != 3 {
+		t.Fatalf("Expected current config to be version 3; got %v", cfg.Version)
+	}
+}
+
 func TestReadingStaleVersions(t *testing.T) {
 	conn := setup("/conflicting")
 
@@ -218,6 +254,47 @@ func TestReadingStaleVersions(t *testing.T) {
 	}
 }
 
+// TestLegacyHash checks that a config persisted by an older version, named with the hash of its bytes as marshalled
+// non-deterministically, can be replaced by a config based on it.
+func TestLegacyHash(t *testing.T) {
+	conn := setup()
+
+	cfg := NewDefaultServiceConfig()
+	cfg.Namespaces["a"] = NewDefaultNamespaceConfig("a")
+	cfg.Namespaces["b"] = NewDefaultNamespaceConfig("b")
+
+	b, err := proto.Marshal(cfg)
+	helpers.CheckError(t, err)
+
+	// The bytes may well marshal deterministically here, so make sure the name doesn't match.
+	legacyHash := HashConfigBytes(append([]byte("legacy"), b...))
+	_, err = conn.Create("/legacy", []byte(legacyHash), 0, zk.WorldACL(zk.PermAll))
+	helpers.CheckError(t, err)
+	_, err = conn.Create("/legacy/"+legacyHash, b, 0, zk.WorldACL(zk.PermAll))
+	helpers.CheckError(t, err)
+
+	p, err := NewZkConfigPersisterWithConnection("/legacy", conn)
+	helpers.CheckError(t, err)
+
+	defer p.Close()
+
+	<-p.ConfigChangedWatcher()
+	current, err := p.ReadPersistedConfig()
+	helpers.CheckError(t, err)
+
+	newCfg := CloneConfig(current)
+	newCfg.Version++
+	helpers.CheckError(t, p.PersistAndNotify(HashConfig(current), newCfg))
+
+	// Stale changes are still rejected.
+	staleCfg := CloneConfig(current)
+	staleCfg.Version += 2
+	staleCfg.Namespaces["c"] = NewDefaultNamespaceConfig("c")
+	if err := p.PersistAndNotify(HashConfig(current), staleCfg); !IsConfigConflict(err) {
+		t.Fatalf("Expecting a config conflict, got %v", err)
+	}
+}
+
 // TestConcurrentUpdate attempts to recreate a case where two concurrent readers read a version, attempt an update to
 // the next version, and both succeed, creating potential for lost changes.
 func TestConcurrentUpdate(t *testing.T) {
@@ -0,0 +1,99 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package events
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrTooManySubscribers is returned by Broadcaster.Subscribe when the Broadcaster already has as many
+// subscribers as it allows.
+var ErrTooManySubscribers = errors.New("too many subscribers")
+
+// Broadcaster is a Listener that passes events on to any number of subscribers, such as admin console clients
+// watching events as they happen. Subscribers that don't keep up miss events, rather than slowing down the
+// Broadcaster.
+type Broadcaster struct {
+	subscribers    map[*Subscription]bool
+	maxSubscribers int
+	sync.RWMutex   // Embedded mutex guards subscribers
+}
+
+// Subscription receives the events of a Broadcaster that pass its filter.
+type Subscription struct {
+	events      chan Event
+	filter      func(Event) bool
+	dropped     uint64
+	broadcaster *Broadcaster
+}
+
+// NewBroadcaster creates a Broadcaster allowing up to maxSubscribers subscribers at a time.
+func NewBroadcaster(maxSubscribers int) *Broadcaster {
+	return &Broadcaster{subscribers: make(map[*Subscription]bool), maxSubscribers: maxSubscribers}
+}
+
+// HandleEvent passes an event on to each subscriber whose filter it passes. It can be used as a Listener.
+func (b *Broadcaster) HandleEvent(e Event) {
+	b.RLock()
+	defer b.RUnlock()
+
+	for s := range b.subscribers {
+		if s.filter != nil && !s.filter(e) {
+			continue
+		}
+
+		select {
+		case s.events <- e:
+		default:
+			atomic.AddUint64(&s.dropped, 1)
+		}
+	}
+}
+
+// Subscribe creates a Subscription receiving the events that pass filter, or every event if filter is nil,
+// buffering up to bufSize of them. The filter is called from the Broadcaster's listener goroutine, and should be
+// quick. Close the Subscription once done with it.
+func (b *Broadcaster) Subscribe(bufSize int, filter func(Event) bool) (*Subscription, error) {
+	b.Lock()
+	defer b.Unlock()
+
+	if len(b.subscribers) >= b.maxSubscribers {
+		return nil, ErrTooManySubscribers
+	}
+
+	s := &Subscription{events: make(chan Event, bufSize), filter: filter, broadcaster: b}
+	b.subscribers[s] = true
+	return s, nil
+}
+
+// Subscribers returns the number of subscribers.
+func (b *Broadcaster) Subscribers() int {
+	b.RLock()
+	defer b.RUnlock()
+
+	return len(b.subscribers)
+}
+
+// Events returns the channel events are received on, which is closed when the Subscription is.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Dropped returns the number of events that passed the filter but were dropped because the buffer was full.
+func (s *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Close unsubscribes from the Broadcaster.
+func (s *Subscription) Close() {
+	s.broadcaster.Lock()
+	defer s.broadcaster.Unlock()
+
+	if s.broadcaster.subscribers[s] {
+		delete(s.broadcaster.subscribers, s)
+		close(s.events)
+	}
+}
@@ -5,6 +5,8 @@ package events
 
 import (
 	"fmt"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/square/quotaservice/logging"
@@ -43,6 +45,23 @@ func (et EventType) String() string {
 	return name
 }
 
+// ParseEventType parses the name of an event type, case-insensitively and with or without the EVENT_ prefix,
+// such as "tokens_served".
+func ParseEventType(s string) (EventType, error) {
+	name := strings.ToUpper(s)
+	if !strings.HasPrefix(name, "EVENT_") {
+		name = "EVENT_" + name
+	}
+
+	for et, n := range eventNames {
+		if n == name {
+			return EventType(et), nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown event type %v", s)
+}
+
 type Event interface {
 	EventType() EventType
 	Namespace() string
@@ -52,21 +71,80 @@ type Event interface {
 	WaitTime() time.Duration
 }
 
+// DropPolicy decides what an EventProducer does with an event when its buffer is full.
+type DropPolicy int
+
+const (
+	// DROP_NEWEST drops the event being emitted.
+	DROP_NEWEST DropPolicy = iota
+	// DROP_OLDEST drops the oldest event in the buffer to make room for the event being emitted. Producers
+	// without a buffer drop the event being emitted instead.
+	DROP_OLDEST
+	// BLOCK waits for the listener to make room for the event being emitted, slowing down whoever emits it.
+	BLOCK
+)
+
+var dropPolicyNames = []string{
+	DROP_NEWEST: "DROP_NEWEST",
+	DROP_OLDEST: "DROP_OLDEST",
+	BLOCK:       "BLOCK",
+}
+
+func (p DropPolicy) String() string {
+	if p < 0 || int(p) >= len(dropPolicyNames) {
+		return fmt.Sprintf("DropPolicy(%d)", p)
+	}
+
+	return dropPolicyNames[p]
+}
+
 // EventProducer is a hook into the notification system, to inform listeners that certain events
 // take place.
 type EventProducer struct {
-	c chan Event
+	c       chan Event
+	policy  DropPolicy
+	dropped uint64
 }
 
 func (e *EventProducer) Emit(event Event) {
-	select {
-	case e.c <- event:
-	// OK
+	switch {
+	case e.policy == BLOCK:
+		e.c <- event
+	case e.policy == DROP_OLDEST && cap(e.c) > 0:
+		for {
+			select {
+			case e.c <- event:
+				return
+			default:
+			}
+
+			// The buffer is full, unless the listener emptied it in the meantime.
+			select {
+			case oldest := <-e.c:
+				e.drop(oldest)
+			default:
+			}
+		}
 	default:
-		logging.Printf("Event buffer full; dropping %s event for %s.%s", event.EventType(), event.Namespace(), event.BucketName())
+		select {
+		case e.c <- event:
+		// OK
+		default:
+			e.drop(event)
+		}
 	}
 }
 
+func (e *EventProducer) drop(event Event) {
+	atomic.AddUint64(&e.dropped, 1)
+	logging.Printf("Event buffer full; dropping %s event for %s.%s", event.EventType(), event.Namespace(), event.BucketName())
+}
+
+// Dropped returns the number of events dropped so far because the event buffer was full.
+func (e *EventProducer) Dropped() uint64 {
+	return atomic.LoadUint64(&e.dropped)
+}
+
 func (e *EventProducer) notifyListeners(l Listener) {
 	for event := range e.c {
 		l(event)
@@ -77,13 +155,20 @@ func (e *EventProducer) notifyListeners(l Listener) {
 type Listener func(details Event)
 
 // RegisterListener takes a Listener and a buffer size and
-// returns an EventProducer that consumes events and notifies listeners
+// returns an EventProducer that consumes events and notifies listeners.
+// Events emitted while the buffer is full are dropped.
 func RegisterListener(listener Listener, bufsize int) *EventProducer {
+	return RegisterListenerWithDropPolicy(listener, bufsize, DROP_NEWEST)
+}
+
+// RegisterListenerWithDropPolicy is like RegisterListener, but applies the given DropPolicy when the
+// buffer is full.
+func RegisterListenerWithDropPolicy(listener Listener, bufsize int, policy DropPolicy) *EventProducer {
 	if listener == nil {
 		panic("Cannot register a nil listener")
 	}
 
-	ep := &EventProducer{make(chan Event, bufsize)}
+	ep := &EventProducer{c: make(chan Event, bufsize), policy: policy}
 
 	go ep.notifyListeners(listener)
 
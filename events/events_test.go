@@ -0,0 +1,152 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package events
+
+import (
+	"testing"
+	"time"
+)
+
+// blockedListener returns a listener that blocks on its first event until unblocked, along with a channel
+// receiving the buckets of the events it is notified of.
+func blockedListener() (Listener, chan<- struct{}, <-chan string) {
+	unblock := make(chan struct{})
+	received := make(chan string, 10)
+
+	return func(e Event) {
+		<-unblock
+		received <- e.BucketName()
+	}, unblock, received
+}
+
+func emit(p *EventProducer, buckets ...string) {
+	for _, b := range buckets {
+		p.Emit(NewBucketCreatedEvent("ns", b, false))
+	}
+}
+
+func expectReceived(t *testing.T, received <-chan string, expected ...string) {
+	t.Helper()
+
+	for _, e := range expected {
+		select {
+		case b := <-received:
+			if b != e {
+				t.Fatalf("Expected event for %v, got %v", e, b)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for event for %v", e)
+		}
+	}
+}
+
+// waitForListener waits for the listener to take the first event off the queue.
+func waitForListener(p *EventProducer) {
+	for len(p.c) > 0 {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestDropNewest(t *testing.T) {
+	l, unblock, received := blockedListener()
+	p := RegisterListenerWithDropPolicy(l, 2, DROP_NEWEST)
+
+	emit(p, "a")
+	waitForListener(p)
+	emit(p, "b", "c", "d")
+	close(unblock)
+
+	expectReceived(t, received, "a", "b", "c")
+	if p.Dropped() != 1 {
+		t.Errorf("Expected 1 dropped event, got %v", p.Dropped())
+	}
+}
+
+func TestDropOldest(t *testing.T) {
+	l, unblock, received := blockedListener()
+	p := RegisterListenerWithDropPolicy(l, 2, DROP_OLDEST)
+
+	emit(p, "a")
+	waitForListener(p)
+	emit(p, "b", "c", "d", "e")
+	close(unblock)
+
+	expectReceived(t, received, "a", "d", "e")
+	if p.Dropped() != 2 {
+		t.Errorf("Expected 2 dropped events, got %v", p.Dropped())
+	}
+}
+
+func TestBlock(t *testing.T) {
+	l, unblock, received := blockedListener()
+	p := RegisterListenerWithDropPolicy(l, 1, BLOCK)
+
+	emit(p, "a")
+	waitForListener(p)
+	emit(p, "b")
+
+	emitted := make(chan struct{})
+	go func() {
+		emit(p, "c")
+		close(emitted)
+	}()
+
+	select {
+	case <-emitted:
+		t.Fatal("Expected Emit to block while the buffer is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(unblock)
+	<-emitted
+
+	expectReceived(t, received, "a", "b", "c")
+	if p.Dropped() != 0 {
+		t.Errorf("Expected no dropped events, got %v", p.Dropped())
+	}
+}
+
+func TestBroadcaster(t *testing.T) {
+	b := NewBroadcaster(2)
+	all, err := b.Subscribe(1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filtered, err := b.Subscribe(10, func(e Event) bool { return e.BucketName() != "skipped" })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := b.Subscribe(1, nil); err != ErrTooManySubscribers {
+		t.Fatalf("Expected ErrTooManySubscribers, got %v", err)
+	}
+
+	for _, bucket := range []string{"a", "skipped", "b"} {
+		b.HandleEvent(NewBucketCreatedEvent("ns", bucket, false))
+	}
+
+	if e := <-all.Events(); e.BucketName() != "a" || all.Dropped() != 2 {
+		t.Fatalf("Expected to receive a and drop 2 events, got %v and dropped %v", e.BucketName(), all.Dropped())
+	}
+
+	for _, expected := range []string{"a", "b"} {
+		if e := <-filtered.Events(); e.BucketName() != expected {
+			t.Fatalf("Expected event for %v, got %v", expected, e.BucketName())
+		}
+	}
+
+	all.Close()
+	all.Close()
+
+	if _, open := <-all.Events(); open || b.Subscribers() != 1 {
+		t.Fatalf("Expected the subscription to be closed, with 1 subscriber left, got %v", b.Subscribers())
+	}
+
+	b.HandleEvent(NewBucketCreatedEvent("ns", "c", false))
+
+	if e := <-filtered.Events(); e.BucketName() != "c" {
+		t.Fatalf("Expected event for c, got %v", e.BucketName())
+	}
+}
@@ -0,0 +1,121 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package kafka
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"github.com/square/quotaservice/events"
+	pb "github.com/square/quotaservice/protos/events"
+)
+
+// Encoding is the format of the records events are exported as.
+type Encoding int
+
+const (
+	// JSON encodes each event as a JSON object, as streamed by the admin API along with a timeMillis field.
+	JSON Encoding = iota
+	// PROTOBUF encodes each event as a quotaservice.events.Event protobuf.
+	PROTOBUF
+)
+
+var encodingNames = []string{
+	JSON:     "JSON",
+	PROTOBUF: "PROTOBUF",
+}
+
+func (e Encoding) String() string {
+	if e < 0 || int(e) >= len(encodingNames) {
+		return fmt.Sprintf("Encoding(%d)", int(e))
+	}
+
+	return encodingNames[e]
+}
+
+// Compression is the codec record batches are compressed with.
+type Compression int
+
+const (
+	NONE Compression = iota
+	GZIP
+	SNAPPY
+	LZ4
+	ZSTD
+)
+
+var compressionNames = []string{
+	NONE:   "NONE",
+	GZIP:   "GZIP",
+	SNAPPY: "SNAPPY",
+	LZ4:    "LZ4",
+	ZSTD:   "ZSTD",
+}
+
+func (c Compression) String() string {
+	if c < 0 || int(c) >= len(compressionNames) {
+		return fmt.Sprintf("Compression(%d)", int(c))
+	}
+
+	return compressionNames[c]
+}
+
+func (c Compression) codec() (kgo.CompressionCodec, error) {
+	switch c {
+	case NONE:
+		return kgo.NoCompression(), nil
+	case GZIP:
+		return kgo.GzipCompression(), nil
+	case SNAPPY:
+		return kgo.SnappyCompression(), nil
+	case LZ4:
+		return kgo.Lz4Compression(), nil
+	case ZSTD:
+		return kgo.ZstdCompression(), nil
+	default:
+		return kgo.CompressionCodec{}, fmt.Errorf("unknown compression %v", c)
+	}
+}
+
+// jsonEvent is the JSON encoding of an event.
+type jsonEvent struct {
+	Type       string `json:"type"`
+	Namespace  string `json:"namespace"`
+	Bucket     string `json:"bucket"`
+	Dynamic    bool   `json:"dynamic"`
+	NumTokens  int64  `json:"numTokens"`
+	WaitMillis int64  `json:"waitMillis"`
+	TimeMillis int64  `json:"timeMillis"`
+}
+
+// encode encodes an event exported at t.
+func (e Encoding) encode(event events.Event, t time.Time) ([]byte, error) {
+	switch e {
+	case JSON:
+		return json.Marshal(&jsonEvent{
+			Type:       event.EventType().String(),
+			Namespace:  event.Namespace(),
+			Bucket:     event.BucketName(),
+			Dynamic:    event.Dynamic(),
+			NumTokens:  event.NumTokens(),
+			WaitMillis: event.WaitTime().Milliseconds(),
+			TimeMillis: t.UnixMilli()})
+	case PROTOBUF:
+		// The values of pb.Event_Type mirror those of events.EventType.
+		return proto.Marshal(&pb.Event{
+			Type:       pb.Event_Type(event.EventType()),
+			Namespace:  event.Namespace(),
+			Bucket:     event.BucketName(),
+			Dynamic:    event.Dynamic(),
+			NumTokens:  event.NumTokens(),
+			WaitMillis: event.WaitTime().Milliseconds(),
+			TimeMillis: t.UnixMilli()})
+	default:
+		return nil, fmt.Errorf("unknown encoding %v", e)
+	}
+}
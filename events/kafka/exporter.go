@@ -0,0 +1,319 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+// Package kafka exports the events of a quotaservice to a Kafka topic, or to any service speaking Kafka's protocol,
+// e.g. to keep every throttling decision in a data warehouse.
+package kafka
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"github.com/square/quotaservice/events"
+	"github.com/square/quotaservice/logging"
+)
+
+// Defaults for Options.
+const (
+	DefaultBatchSize      = 500
+	DefaultBatchInterval  = time.Second
+	DefaultQueueSize      = 10000
+	DefaultPublishTimeout = 10 * time.Second
+	DefaultRetryInterval  = 10 * time.Second
+	DefaultMaxSpoolBytes  = 1 << 30
+)
+
+// Options configures an Exporter. Brokers and Topic are required; everything else has a default.
+type Options struct {
+	// Brokers are the addresses of the brokers the client first connects to, as host:port.
+	Brokers []string
+	Topic   string
+	// Encoding is the format of records. Defaults to JSON.
+	Encoding Encoding
+	// Compression is the codec batches are compressed with. Defaults to NONE.
+	Compression Compression
+	// BatchSize is the most events published at a time. Fewer are published once events have been waiting for
+	// BatchInterval.
+	BatchSize     int
+	BatchInterval time.Duration
+	// KeyByNamespace keys records by namespace, so that the events of a namespace are all published to the same
+	// partition, in order. Otherwise records aren't keyed, and are spread across partitions.
+	KeyByNamespace bool
+	// QueueSize is the number of events that can be waiting to be batched before more are dropped.
+	QueueSize int
+	// PublishTimeout is how long publishing a batch may take before the brokers are considered unavailable.
+	// Publishing is then retried every RetryInterval, with batches being spooled in the meantime.
+	PublishTimeout time.Duration
+	RetryInterval  time.Duration
+	// SpoolDir is a directory in which batches are kept while the brokers are unavailable, to be published in
+	// order once they're back, including by another Exporter using the same directory after a restart. Without
+	// one, such batches are dropped.
+	SpoolDir string
+	// MaxSpoolBytes is the most the spooled batches may take up on disk. Batches that don't fit are dropped.
+	MaxSpoolBytes int64
+	// ClientOpts are further options for the Kafka client, e.g. to set up TLS or SASL.
+	ClientOpts []kgo.Opt
+}
+
+// Stats describes what an Exporter has done with the events it handled.
+type Stats struct {
+	Published uint64 `json:"published"`
+	// Dropped events were dropped because the queue was full, or couldn't be published or spooled.
+	Dropped        uint64 `json:"dropped"`
+	SpooledBatches int64  `json:"spooledBatches"`
+	SpooledBytes   int64  `json:"spooledBytes"`
+}
+
+// Exporter is an events.Listener publishing events to a Kafka topic, one record per event. Events are batched
+// by a goroutine started by New, and published at least once: an event may be published again if the brokers
+// fail to acknowledge it in time.
+type Exporter struct {
+	opts    Options
+	client  *kgo.Client
+	spool   *spool
+	records chan *kgo.Record
+	stop    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+
+	// retryAt is when to next try publishing, after failing to. It's only accessed by the batching goroutine.
+	retryAt time.Time
+
+	published, dropped           uint64
+	spooledBatches, spooledBytes int64
+}
+
+// New creates an Exporter, which connects to the brokers lazily. Register its HandleEvent as a listener of the
+// server, and Close it once the server has stopped.
+func New(opts Options) (*Exporter, error) {
+	if len(opts.Brokers) == 0 || opts.Topic == "" {
+		return nil, errors.New("brokers and topic are required")
+	}
+
+	opts = withDefaults(opts)
+
+	codec, err := opts.Compression.codec()
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Exporter{
+		opts:    opts,
+		records: make(chan *kgo.Record, opts.QueueSize),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{})}
+
+	if opts.SpoolDir != "" {
+		if e.spool, err = openSpool(opts.SpoolDir, opts.MaxSpoolBytes); err != nil {
+			return nil, err
+		}
+
+		e.updateSpoolStats()
+	}
+
+	// Batches are made up here rather than by the client, so the client doesn't linger. Idempotent writes are
+	// disabled, as the client would otherwise keep retrying unacknowledged records past PublishTimeout.
+	clientOpts := []kgo.Opt{
+		kgo.SeedBrokers(opts.Brokers...),
+		kgo.DefaultProduceTopic(opts.Topic),
+		kgo.ProducerBatchCompression(codec),
+		kgo.ProducerLinger(0),
+		kgo.DisableIdempotentWrite()}
+
+	if e.client, err = kgo.NewClient(append(clientOpts, opts.ClientOpts...)...); err != nil {
+		return nil, err
+	}
+
+	go e.run()
+	return e, nil
+}
+
+func withDefaults(opts Options) Options {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+
+	if opts.BatchInterval <= 0 {
+		opts.BatchInterval = DefaultBatchInterval
+	}
+
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = DefaultQueueSize
+	}
+
+	if opts.PublishTimeout <= 0 {
+		opts.PublishTimeout = DefaultPublishTimeout
+	}
+
+	if opts.RetryInterval <= 0 {
+		opts.RetryInterval = DefaultRetryInterval
+	}
+
+	if opts.MaxSpoolBytes <= 0 {
+		opts.MaxSpoolBytes = DefaultMaxSpoolBytes
+	}
+
+	return opts
+}
+
+// HandleEvent queues an event to be published. It's an events.Listener.
+func (e *Exporter) HandleEvent(event events.Event) {
+	now := time.Now()
+
+	value, err := e.opts.Encoding.encode(event, now)
+	if err != nil {
+		atomic.AddUint64(&e.dropped, 1)
+		logging.Error("Unable to encode event", "type", event.EventType(), "error", err)
+		return
+	}
+
+	r := &kgo.Record{Value: value, Timestamp: now}
+	if e.opts.KeyByNamespace {
+		r.Key = []byte(event.Namespace())
+	}
+
+	select {
+	case e.records <- r:
+	default:
+		atomic.AddUint64(&e.dropped, 1)
+	}
+}
+
+// Stats returns what the Exporter has done with the events it handled so far.
+func (e *Exporter) Stats() Stats {
+	return Stats{
+		Published:      atomic.LoadUint64(&e.published),
+		Dropped:        atomic.LoadUint64(&e.dropped),
+		SpooledBatches: atomic.LoadInt64(&e.spooledBatches),
+		SpooledBytes:   atomic.LoadInt64(&e.spooledBytes)}
+}
+
+// Close publishes or spools the events already queued, and closes the connections to the brokers. Events handled
+// afterwards are dropped.
+func (e *Exporter) Close() {
+	e.once.Do(func() {
+		close(e.stop)
+		<-e.done
+		e.client.Close()
+	})
+}
+
+func (e *Exporter) run() {
+	defer close(e.done)
+
+	ticker := time.NewTicker(e.opts.BatchInterval)
+	defer ticker.Stop()
+
+	// Batches are published or spooled before flush returns, so the slice is reused.
+	batch := make([]*kgo.Record, 0, e.opts.BatchSize)
+
+	add := func(r *kgo.Record) {
+		if batch = append(batch, r); len(batch) >= e.opts.BatchSize {
+			e.flush(batch)
+			batch = batch[:0]
+		}
+	}
+
+	for {
+		select {
+		case r := <-e.records:
+			add(r)
+		case <-ticker.C:
+			e.flush(batch)
+			batch = batch[:0]
+		case <-e.stop:
+			for {
+				select {
+				case r := <-e.records:
+					add(r)
+				default:
+					e.flush(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+// flush publishes a batch, which may be empty, along with any batches spooled before it. Batches are published in
+// order, so the batch is spooled if earlier batches are.
+func (e *Exporter) flush(batch []*kgo.Record) {
+	if len(batch) > 0 {
+		if (e.spool == nil || e.spool.empty()) && e.publish(batch) {
+			return
+		}
+
+		e.spoolBatch(batch)
+	}
+
+	e.drainSpool()
+}
+
+// publish publishes a batch, returning false if the brokers are unavailable.
+func (e *Exporter) publish(batch []*kgo.Record) bool {
+	if time.Now().Before(e.retryAt) {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), e.opts.PublishTimeout)
+	defer cancel()
+
+	if err := e.client.ProduceSync(ctx, batch...).FirstErr(); err != nil {
+		e.retryAt = time.Now().Add(e.opts.RetryInterval)
+		logging.Warn("Unable to publish events to Kafka", "topic", e.opts.Topic, "events", len(batch),
+			"error", err)
+		return false
+	}
+
+	atomic.AddUint64(&e.published, uint64(len(batch)))
+	return true
+}
+
+func (e *Exporter) spoolBatch(batch []*kgo.Record) {
+	if e.spool == nil {
+		atomic.AddUint64(&e.dropped, uint64(len(batch)))
+		return
+	}
+
+	if err := e.spool.push(batch); err != nil {
+		atomic.AddUint64(&e.dropped, uint64(len(batch)))
+		logging.Error("Unable to spool events", "dir", e.opts.SpoolDir, "events", len(batch), "error", err)
+		return
+	}
+
+	e.updateSpoolStats()
+}
+
+// drainSpool publishes spooled batches, oldest first, until the spool is empty or publishing fails.
+func (e *Exporter) drainSpool() {
+	if e.spool == nil {
+		return
+	}
+
+	defer e.updateSpoolStats()
+
+	for !e.spool.empty() {
+		batch, err := e.spool.peek()
+		if err != nil {
+			logging.Error("Dropping unreadable spooled events", "dir", e.opts.SpoolDir, "error", err)
+		} else if !e.publish(batch) {
+			return
+		}
+
+		if err := e.spool.pop(); err != nil {
+			// Retried on the next flush, which may publish the batch again.
+			logging.Error("Unable to remove spooled events", "dir", e.opts.SpoolDir, "error", err)
+			return
+		}
+	}
+}
+
+func (e *Exporter) updateSpoolStats() {
+	atomic.StoreInt64(&e.spooledBatches, int64(len(e.spool.files)))
+	atomic.StoreInt64(&e.spooledBytes, e.spool.size)
+}
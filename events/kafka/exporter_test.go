@@ -0,0 +1,264 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/kmsg"
+
+	"github.com/square/quotaservice/events"
+	pb "github.com/square/quotaservice/protos/events"
+	"github.com/square/quotaservice/test/helpers"
+)
+
+const topic = "quotaservice-events"
+
+func newCluster(t *testing.T) *kfake.Cluster {
+	t.Helper()
+
+	c, err := kfake.NewCluster(kfake.NumBrokers(1), kfake.SeedTopics(3, topic))
+	helpers.CheckError(t, err)
+	t.Cleanup(c.Close)
+	return c
+}
+
+func newExporter(t *testing.T, opts Options) *Exporter {
+	t.Helper()
+
+	opts.Topic = topic
+	e, err := New(opts)
+	helpers.CheckError(t, err)
+	t.Cleanup(e.Close)
+	return e
+}
+
+// consume reads n records from the start of the topic.
+func consume(t *testing.T, c *kfake.Cluster, n int) []*kgo.Record {
+	t.Helper()
+
+	client, err := kgo.NewClient(
+		kgo.SeedBrokers(c.ListenAddrs()...),
+		kgo.ConsumeTopics(topic),
+		kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()))
+	helpers.CheckError(t, err)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var records []*kgo.Record
+	for len(records) < n {
+		fetches := client.PollFetches(ctx)
+		if err := ctx.Err(); err != nil {
+			t.Fatalf("Expecting %v records, got %v before %v", n, len(records), err)
+		}
+
+		records = append(records, fetches.Records()...)
+	}
+
+	return records
+}
+
+func TestExportJSON(t *testing.T) {
+	c := newCluster(t)
+	e := newExporter(t, Options{
+		Brokers:        c.ListenAddrs(),
+		BatchSize:      2,
+		BatchInterval:  time.Minute,
+		KeyByNamespace: true})
+
+	e.HandleEvent(events.NewTokensServedEvent("a", "b1", false, 3, 20*time.Millisecond))
+	e.HandleEvent(events.NewBucketMissedEvent("b", "b2", true))
+	e.HandleEvent(events.NewTimedOutEvent("a", "b3", false, 1))
+	e.HandleEvent(events.NewTooManyTokensRequestedEvent("b", "b4", false, 100))
+
+	records := consume(t, c, 4)
+
+	partitions := make(map[string]int32)
+	for _, r := range records {
+		ns := string(r.Key)
+		if p, ok := partitions[ns]; ok && p != r.Partition {
+			t.Errorf("Expecting the events of namespace %v to be published to one partition", ns)
+		}
+
+		partitions[ns] = r.Partition
+	}
+
+	var served *jsonEvent
+	for _, r := range records {
+		event := &jsonEvent{}
+		helpers.CheckError(t, json.Unmarshal(r.Value, event))
+
+		if event.Namespace != string(r.Key) {
+			t.Errorf("Expecting record keyed by namespace %v, got %q", event.Namespace, r.Key)
+		}
+
+		if event.Bucket == "b1" {
+			served = event
+		}
+	}
+
+	if served == nil || served.Type != "EVENT_TOKENS_SERVED" || served.NumTokens != 3 || served.WaitMillis != 20 ||
+		served.TimeMillis == 0 {
+		t.Errorf("Unexpected tokens served event %+v", served)
+	}
+
+	// The records may be consumed before the exporter has seen them acknowledged.
+	start := time.Now()
+	for e.Stats().Published != 4 {
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("Expecting 4 events to be published, got %+v", e.Stats())
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if stats := e.Stats(); stats.Dropped != 0 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestExportProtobuf(t *testing.T) {
+	c := newCluster(t)
+	e := newExporter(t, Options{
+		Brokers:       c.ListenAddrs(),
+		Encoding:      PROTOBUF,
+		Compression:   ZSTD,
+		BatchInterval: 10 * time.Millisecond})
+
+	e.HandleEvent(events.NewBucketCreatedEvent("ns", "b", true))
+
+	records := consume(t, c, 1)
+	if records[0].Key != nil {
+		t.Errorf("Expecting an unkeyed record, got key %q", records[0].Key)
+	}
+
+	event := &pb.Event{}
+	helpers.CheckError(t, proto.Unmarshal(records[0].Value, event))
+
+	if event.Type != pb.Event_BUCKET_CREATED || event.Namespace != "ns" || event.Bucket != "b" || !event.Dynamic {
+		t.Errorf("Unexpected event %+v", event)
+	}
+}
+
+func TestEventTypesMatchProto(t *testing.T) {
+	for et := events.EVENT_TOKENS_SERVED; et <= events.EVENT_BUCKET_ERROR; et++ {
+		if name := "EVENT_" + pb.Event_Type(et).String(); name != et.String() {
+			t.Errorf("Expecting %v to be encoded as the same type, got %v", et, name)
+		}
+	}
+}
+
+func TestSpoolDuringOutage(t *testing.T) {
+	c := newCluster(t)
+	dir := t.TempDir()
+
+	// Fail every produce request while the broker is down.
+	var down atomic.Bool
+	down.Store(true)
+	c.ControlKey(int16(kmsg.Produce), func(kmsg.Request) (kmsg.Response, error, bool) {
+		if !down.Load() {
+			return nil, nil, false
+		}
+
+		c.KeepControl()
+		return nil, errors.New("broker down"), true
+	})
+
+	// Keyed by namespace, so that the events are published to one partition, in order.
+	opts := Options{
+		Brokers:        c.ListenAddrs(),
+		KeyByNamespace: true,
+		BatchSize:      1,
+		BatchInterval:  10 * time.Millisecond,
+		PublishTimeout: 200 * time.Millisecond,
+		RetryInterval:  time.Hour,
+		SpoolDir:       dir}
+
+	e := newExporter(t, opts)
+	e.HandleEvent(events.NewTokensServedEvent("ns", "first", false, 1, 0))
+	e.HandleEvent(events.NewTokensServedEvent("ns", "second", false, 1, 0))
+	e.Close()
+
+	if stats := e.Stats(); stats.Published != 0 || stats.Dropped != 0 || stats.SpooledBatches != 2 {
+		t.Fatalf("Expecting both events to be spooled, got %+v", stats)
+	}
+
+	// Once the broker is back, an exporter using the same spool publishes the spooled events first.
+	down.Store(false)
+	opts.RetryInterval = 0
+	e = newExporter(t, opts)
+	e.HandleEvent(events.NewTokensServedEvent("ns", "third", false, 1, 0))
+
+	var buckets []string
+	for _, r := range consume(t, c, 3) {
+		event := &jsonEvent{}
+		helpers.CheckError(t, json.Unmarshal(r.Value, event))
+		buckets = append(buckets, event.Bucket)
+	}
+
+	if expected := []string{"first", "second", "third"}; !reflect.DeepEqual(buckets, expected) {
+		t.Errorf("Expecting events %v in order, got %v", expected, buckets)
+	}
+
+	if files, _ := os.ReadDir(dir); len(files) != 0 || e.Stats().SpooledBytes != 0 {
+		t.Errorf("Expecting the spool to be empty, got %v and %+v", files, e.Stats())
+	}
+}
+
+func TestSpoolFull(t *testing.T) {
+	c := newCluster(t)
+	c.ControlKey(int16(kmsg.Produce), func(kmsg.Request) (kmsg.Response, error, bool) {
+		c.KeepControl()
+		return nil, errors.New("broker down"), true
+	})
+
+	e := newExporter(t, Options{
+		Brokers:        c.ListenAddrs(),
+		BatchSize:      1,
+		PublishTimeout: 200 * time.Millisecond,
+		RetryInterval:  time.Hour,
+		SpoolDir:       t.TempDir(),
+		MaxSpoolBytes:  1})
+
+	e.HandleEvent(events.NewTokensServedEvent("ns", "b", false, 1, 0))
+	e.Close()
+
+	if stats := e.Stats(); stats.Dropped != 1 || stats.SpooledBatches != 0 {
+		t.Errorf("Expecting the event to be dropped, got %+v", stats)
+	}
+}
+
+func TestSpoolRecords(t *testing.T) {
+	now := time.Unix(0, time.Now().UnixNano())
+	records := []*kgo.Record{
+		{Key: []byte("ns"), Value: []byte("v1"), Timestamp: now},
+		{Key: []byte{}, Value: []byte("v2"), Timestamp: now},
+		{Value: []byte{}, Timestamp: now}}
+
+	decoded, err := decodeRecords(encodeRecords(records))
+	helpers.CheckError(t, err)
+
+	for i, r := range decoded {
+		if !reflect.DeepEqual(r.Key, records[i].Key) || string(r.Value) != string(records[i].Value) ||
+			!r.Timestamp.Equal(now) {
+			t.Errorf("Expecting record %+v, got %+v", records[i], r)
+		}
+	}
+
+	if _, err := decodeRecords(encodeRecords(records)[:5]); err == nil {
+		t.Error("Expecting a truncated batch to be rejected")
+	}
+}
@@ -0,0 +1,216 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package kafka
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+const (
+	spoolSuffix = ".batch"
+	tmpSuffix   = ".tmp"
+)
+
+var errSpoolFull = errors.New("spool is full")
+
+// spool keeps batches of records that couldn't be published in a directory, one file per batch named by its
+// sequence number, until they can be. It isn't safe for concurrent use.
+type spool struct {
+	dir      string
+	maxBytes int64
+	// files are the spooled batches, oldest first, and size their total size in bytes.
+	files []spoolFile
+	size  int64
+	next  uint64
+}
+
+type spoolFile struct {
+	seq  uint64
+	size int64
+}
+
+// openSpool opens the spool in dir, creating dir if it doesn't exist. Batches spooled by a previous exporter are
+// kept, to be published first.
+func openSpool(dir string, maxBytes int64) (*spool, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &spool{dir: dir, maxBytes: maxBytes}
+
+	for _, entry := range entries {
+		name := entry.Name()
+
+		switch {
+		case strings.HasSuffix(name, tmpSuffix):
+			// A batch that was being written when the process stopped.
+			if err := os.Remove(filepath.Join(dir, name)); err != nil {
+				return nil, err
+			}
+		case strings.HasSuffix(name, spoolSuffix):
+			seq, err := strconv.ParseUint(strings.TrimSuffix(name, spoolSuffix), 10, 64)
+			if err != nil {
+				continue
+			}
+
+			info, err := entry.Info()
+			if err != nil {
+				return nil, err
+			}
+
+			s.files = append(s.files, spoolFile{seq, info.Size()})
+			s.size += info.Size()
+
+			if seq >= s.next {
+				s.next = seq + 1
+			}
+		}
+	}
+
+	sort.Slice(s.files, func(i, j int) bool { return s.files[i].seq < s.files[j].seq })
+	return s, nil
+}
+
+func (s *spool) empty() bool {
+	return len(s.files) == 0
+}
+
+// push spools a batch, failing with errSpoolFull if that would take the spool over its maximum size.
+func (s *spool) push(records []*kgo.Record) error {
+	b := encodeRecords(records)
+	if s.size+int64(len(b)) > s.maxBytes {
+		return errSpoolFull
+	}
+
+	// Written to a temporary file first, so that a partly written batch is never published.
+	path := s.path(s.next)
+	if err := os.WriteFile(path+tmpSuffix, b, 0600); err != nil {
+		return err
+	}
+
+	if err := os.Rename(path+tmpSuffix, path); err != nil {
+		return err
+	}
+
+	s.files = append(s.files, spoolFile{s.next, int64(len(b))})
+	s.size += int64(len(b))
+	s.next++
+	return nil
+}
+
+// peek reads the oldest batch.
+func (s *spool) peek() ([]*kgo.Record, error) {
+	b, err := os.ReadFile(s.path(s.files[0].seq))
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeRecords(b)
+}
+
+// pop removes the oldest batch.
+func (s *spool) pop() error {
+	if err := os.Remove(s.path(s.files[0].seq)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	s.size -= s.files[0].size
+	s.files = s.files[1:]
+	return nil
+}
+
+func (s *spool) path(seq uint64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%020d%v", seq, spoolSuffix))
+}
+
+// encodeRecords encodes the timestamp, key and value of each record, as varints and length-prefixed bytes. A nil
+// key is encoded with a length of 0, and any other key with its length plus one.
+func encodeRecords(records []*kgo.Record) []byte {
+	var b []byte
+
+	for _, r := range records {
+		b = binary.AppendVarint(b, r.Timestamp.UnixNano())
+
+		if r.Key == nil {
+			b = binary.AppendUvarint(b, 0)
+		} else {
+			b = binary.AppendUvarint(b, uint64(len(r.Key))+1)
+			b = append(b, r.Key...)
+		}
+
+		b = binary.AppendUvarint(b, uint64(len(r.Value)))
+		b = append(b, r.Value...)
+	}
+
+	return b
+}
+
+func decodeRecords(b []byte) ([]*kgo.Record, error) {
+	var records []*kgo.Record
+	errCorrupt := errors.New("corrupt spooled batch")
+
+	readBytes := func(n uint64) ([]byte, bool) {
+		if n > uint64(len(b)) {
+			return nil, false
+		}
+
+		bytes := b[:n:n]
+		b = b[n:]
+		return bytes, true
+	}
+
+	for len(b) > 0 {
+		r := &kgo.Record{}
+
+		nanos, n := binary.Varint(b)
+		if n <= 0 {
+			return nil, errCorrupt
+		}
+		b = b[n:]
+		r.Timestamp = time.Unix(0, nanos)
+
+		keyLen, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, errCorrupt
+		}
+		b = b[n:]
+
+		if keyLen > 0 {
+			var ok bool
+			if r.Key, ok = readBytes(keyLen - 1); !ok {
+				return nil, errCorrupt
+			}
+		}
+
+		valueLen, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, errCorrupt
+		}
+		b = b[n:]
+
+		var ok bool
+		if r.Value, ok = readBytes(valueLen); !ok {
+			return nil, errCorrupt
+		}
+
+		records = append(records, r)
+	}
+
+	return records, nil
+}
@@ -155,6 +155,77 @@ func TestBucketRemoval(t *testing.T) {
 	}
 }
 
+func TestAddListener(t *testing.T) {
+	cfg := config.NewDefaultServiceConfig()
+	ns := config.NewDefaultNamespaceConfig("listened")
+	helpers.CheckError(t, config.AddBucket(ns, config.NewDefaultBucketConfig("b")))
+	helpers.CheckError(t, config.AddNamespace(cfg, ns))
+
+	srv := New(&MockBucketFactory{}, config.NewMemoryConfig(cfg), NewReaperConfigForTests(), 0, &MockEndpoint{}).(*server)
+
+	// A listener that never returns shouldn't hold up the other.
+	unblock := make(chan struct{})
+	defer close(unblock)
+	srv.SetListener(func(events.Event) { <-unblock }, 1)
+	_, err := srv.Start()
+	helpers.CheckError(t, err)
+	defer stopServer(t, srv)
+
+	received := make(chan events.Event, 10)
+	helpers.CheckError(t, srv.AddListener("fast", func(e events.Event) { received <- e }, 10, events.BLOCK))
+
+	if err := srv.AddListener("fast", func(events.Event) {}, 10, events.BLOCK); err == nil {
+		t.Error("Expecting an error adding a listener with the same name")
+	}
+
+	for i := 0; i < 5; i++ {
+		if _, _, e := srv.Allow(context.Background(), "listened", "b", 1, 0, false); e != nil {
+			t.Fatalf("Not expecting error %+v", e)
+		}
+	}
+
+	for i := 0; i < 5; i++ {
+		checkEvent("listened", "b", false, events.EVENT_TOKENS_SERVED, 1, 0, <-received, t)
+	}
+
+	dropped := srv.DroppedEvents()
+	if dropped["fast"] != 0 || dropped[defaultListenerName] == 0 {
+		t.Errorf("Expecting only the blocked listener to drop events, got %v", dropped)
+	}
+}
+
+func TestSubscribeEvents(t *testing.T) {
+	cfg := config.NewDefaultServiceConfig()
+	ns := config.NewDefaultNamespaceConfig("streamed")
+	helpers.CheckError(t, config.AddBucket(ns, config.NewDefaultBucketConfig("b")))
+	helpers.CheckError(t, config.AddNamespace(cfg, ns))
+
+	srv := New(&MockBucketFactory{}, config.NewMemoryConfig(cfg), NewReaperConfigForTests(), 0, &MockEndpoint{}).(*server)
+	_, err := srv.Start()
+	helpers.CheckError(t, err)
+	defer stopServer(t, srv)
+
+	if _, exists := srv.DroppedEvents()[streamListenerName]; exists {
+		t.Fatal("Not expecting a stream listener before subscribing")
+	}
+
+	subscription, err := srv.SubscribeEvents(10, func(e events.Event) bool {
+		return e.EventType() == events.EVENT_TOKENS_SERVED
+	})
+	helpers.CheckError(t, err)
+	defer subscription.Close()
+
+	if _, _, e := srv.Allow(context.Background(), "streamed", "b", 1, 0, false); e != nil {
+		t.Fatalf("Not expecting error %+v", e)
+	}
+
+	checkEvent("streamed", "b", false, events.EVENT_TOKENS_SERVED, 1, 0, <-subscription.Events(), t)
+
+	if _, exists := srv.DroppedEvents()[streamListenerName]; !exists {
+		t.Error("Expecting a stream listener once subscribed")
+	}
+}
+
 func checkEvent(namespace, name string, dyn bool, eventType events.EventType, tokens int64, waitTime time.Duration, actual events.Event, t *testing.T) {
 	t.Helper()
 
@@ -4,14 +4,49 @@
 package logging
 
 import (
+	"fmt"
 	"log"
 	"os"
+	"strings"
 )
 
-// Use golang's standard logger by default.
-var logger Logger = log.New(os.Stderr, "", log.LstdFlags)
+// Level is the severity of a log message.
+type Level int
+
+const (
+	DEBUG Level = iota
+	INFO
+	WARN
+	ERROR
+)
+
+var levelNames = []string{
+	DEBUG: "DEBUG",
+	INFO:  "INFO",
+	WARN:  "WARN",
+	ERROR: "ERROR",
+}
+
+func (l Level) String() string {
+	if l < DEBUG || l > ERROR {
+		return fmt.Sprintf("Level(%d)", l)
+	}
+
+	return levelNames[l]
+}
+
+// StructuredLogger logs messages at a level, along with fields passed as alternating keys and values.
+type StructuredLogger interface {
+	// Enabled tells you whether messages at the given level are logged, so callers can avoid building
+	// expensive fields for messages that won't be.
+	Enabled(level Level) bool
+	Log(level Level, msg string, keysAndValues ...interface{})
+}
 
 // Logger mimics golang's standard Logger as an interface.
+//
+// Deprecated: Use StructuredLogger, which the package's Print and Fatal functions log to at the INFO and
+// ERROR levels respectively.
 type Logger interface {
 	Fatal(args ...interface{})
 	Fatalf(format string, args ...interface{})
@@ -21,42 +56,160 @@ type Logger interface {
 	Println(args ...interface{})
 }
 
-// SetLogger sets the logger to be used
+// Use golang's standard logger by default.
+var (
+	legacyLogger Logger           = log.New(os.Stderr, "", log.LstdFlags)
+	logger       StructuredLogger = &legacyAdapter{l: legacyLogger, level: INFO}
+)
+
+// SetLogger sets the logger to be used, logging messages at the INFO level and above. Messages are printed
+// as they always have been, without their level, followed by any fields as key=value.
+//
+// Deprecated: Use SetStructuredLogger.
 func SetLogger(l Logger) {
-	logger = l
+	legacyLogger = l
+	logger = &legacyAdapter{l: l, level: INFO}
 }
 
-// CurrentLogger gets the logger to be used
+// CurrentLogger gets the logger to be used, for libraries that expect a Logger. If a StructuredLogger was set,
+// the Logger returned logs to it.
 func CurrentLogger() Logger {
+	return legacyLogger
+}
+
+// SetStructuredLogger sets the logger to be used.
+func SetStructuredLogger(l StructuredLogger) {
+	logger = l
+	legacyLogger = &printLogger{l}
+}
+
+// CurrentStructuredLogger gets the logger to be used.
+func CurrentStructuredLogger() StructuredLogger {
 	return logger
 }
 
-// Fatal is equivalent to Print() followed by a call to os.Exit() with a non-zero exit code.
+// Debug logs a message at the DEBUG level, along with fields passed as alternating keys and values.
+func Debug(msg string, keysAndValues ...interface{}) {
+	logger.Log(DEBUG, msg, keysAndValues...)
+}
+
+// Info logs a message at the INFO level, along with fields passed as alternating keys and values.
+func Info(msg string, keysAndValues ...interface{}) {
+	logger.Log(INFO, msg, keysAndValues...)
+}
+
+// Warn logs a message at the WARN level, along with fields passed as alternating keys and values.
+func Warn(msg string, keysAndValues ...interface{}) {
+	logger.Log(WARN, msg, keysAndValues...)
+}
+
+// Error logs a message at the ERROR level, along with fields passed as alternating keys and values.
+func Error(msg string, keysAndValues ...interface{}) {
+	logger.Log(ERROR, msg, keysAndValues...)
+}
+
+// Fatal is equivalent to Print() followed by a call to os.Exit() with a non-zero exit code. Library code
+// should return an error instead.
 func Fatal(args ...interface{}) {
-	logger.Fatal(args...)
+	legacyLogger.Fatal(args...)
 }
 
-// Fatalf is equivalent to Printf() followed by a call to os.Exit() with a non-zero exit code.
+// Fatalf is equivalent to Printf() followed by a call to os.Exit() with a non-zero exit code. Library code
+// should return an error instead.
 func Fatalf(format string, args ...interface{}) {
-	logger.Fatalf(format, args...)
+	legacyLogger.Fatalf(format, args...)
 }
 
-// Fatalln is equivalent to Println() followed by a call to os.Exit()) with a non-zero exit code.
+// Fatalln is equivalent to Println() followed by a call to os.Exit()) with a non-zero exit code. Library code
+// should return an error instead.
 func Fatalln(args ...interface{}) {
-	logger.Fatalln(args...)
+	legacyLogger.Fatalln(args...)
 }
 
 // Print prints to the logger. Arguments are handled in the manner of fmt.Print.
 func Print(args ...interface{}) {
-	logger.Print(args...)
+	logger.Log(INFO, fmt.Sprint(args...))
 }
 
 // Printf prints to the logger. Arguments are handled in the manner of fmt.Printf.
 func Printf(format string, args ...interface{}) {
-	logger.Printf(format, args...)
+	logger.Log(INFO, fmt.Sprintf(format, args...))
 }
 
 // Println prints to the logger. Arguments are handled in the manner of fmt.Println.
 func Println(args ...interface{}) {
-	logger.Println(args...)
+	logger.Log(INFO, strings.TrimSuffix(fmt.Sprintln(args...), "\n"))
+}
+
+// FromLogger adapts a Logger to a StructuredLogger, printing messages at or above the given level as the
+// level, the message and then each field as key=value.
+func FromLogger(l Logger, level Level) StructuredLogger {
+	return &legacyAdapter{l: l, level: level, printLevel: true}
+}
+
+type legacyAdapter struct {
+	l          Logger
+	level      Level
+	printLevel bool
+}
+
+func (a *legacyAdapter) Enabled(level Level) bool {
+	return level >= a.level
+}
+
+func (a *legacyAdapter) Log(level Level, msg string, keysAndValues ...interface{}) {
+	if !a.Enabled(level) {
+		return
+	}
+
+	var b strings.Builder
+	if a.printLevel {
+		b.WriteString(level.String())
+		b.WriteString(" ")
+	}
+
+	b.WriteString(msg)
+
+	for i := 0; i < len(keysAndValues); i += 2 {
+		if i+1 < len(keysAndValues) {
+			_, _ = fmt.Fprintf(&b, " %v=%v", keysAndValues[i], keysAndValues[i+1])
+		} else {
+			// A key without a value.
+			_, _ = fmt.Fprintf(&b, " %v", keysAndValues[i])
+		}
+	}
+
+	a.l.Print(b.String())
+}
+
+// printLogger adapts a StructuredLogger to the Logger interface, for code that still expects one.
+type printLogger struct {
+	l StructuredLogger
+}
+
+func (p *printLogger) Fatal(args ...interface{}) {
+	p.l.Log(ERROR, fmt.Sprint(args...))
+	os.Exit(1)
+}
+
+func (p *printLogger) Fatalf(format string, args ...interface{}) {
+	p.l.Log(ERROR, fmt.Sprintf(format, args...))
+	os.Exit(1)
+}
+
+func (p *printLogger) Fatalln(args ...interface{}) {
+	p.l.Log(ERROR, strings.TrimSuffix(fmt.Sprintln(args...), "\n"))
+	os.Exit(1)
+}
+
+func (p *printLogger) Print(args ...interface{}) {
+	p.l.Log(INFO, fmt.Sprint(args...))
+}
+
+func (p *printLogger) Printf(format string, args ...interface{}) {
+	p.l.Log(INFO, fmt.Sprintf(format, args...))
+}
+
+func (p *printLogger) Println(args ...interface{}) {
+	p.l.Log(INFO, strings.TrimSuffix(fmt.Sprintln(args...), "\n"))
 }
@@ -0,0 +1,74 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package logging
+
+import (
+	"bytes"
+	"log"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestFromLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := FromLogger(log.New(&buf, "", 0), WARN)
+
+	if l.Enabled(INFO) || !l.Enabled(WARN) || !l.Enabled(ERROR) {
+		t.Fatal("Expected only WARN and above to be enabled")
+	}
+
+	l.Log(INFO, "ignored")
+	l.Log(ERROR, "failed", "namespace", "ns", "attempts", 3, "dangling")
+
+	if expected := "ERROR failed namespace=ns attempts=3 dangling\n"; buf.String() != expected {
+		t.Fatalf("Expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestFromSlog(t *testing.T) {
+	var buf bytes.Buffer
+	l := FromSlog(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn})))
+
+	if l.Enabled(INFO) || !l.Enabled(WARN) {
+		t.Fatal("Expected only WARN and above to be enabled")
+	}
+
+	l.Log(WARN, "slow", "bucket", "b")
+
+	if out := buf.String(); !strings.Contains(out, "level=WARN msg=slow bucket=b") {
+		t.Fatalf("Unexpected output %q", out)
+	}
+}
+
+func TestSetStructuredLogger(t *testing.T) {
+	defer SetLogger(CurrentLogger())
+
+	var buf bytes.Buffer
+	SetStructuredLogger(FromLogger(log.New(&buf, "", 0), DEBUG))
+
+	Debug("debugging", "k", "v")
+	Printf("printed %d", 1)
+	CurrentLogger().Print("legacy")
+
+	if expected := "DEBUG debugging k=v\nINFO printed 1\nINFO legacy\n"; buf.String() != expected {
+		t.Fatalf("Expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestSetLogger(t *testing.T) {
+	defer SetLogger(CurrentLogger())
+
+	var buf bytes.Buffer
+	SetLogger(log.New(&buf, "", 0))
+
+	Debug("ignored")
+	Printf("printed %d", 1)
+	Error("failed", "k", "v")
+
+	// Messages are printed as they were before structured logging.
+	if expected := "printed 1\nfailed k=v\n"; buf.String() != expected {
+		t.Fatalf("Expected %q, got %q", expected, buf.String())
+	}
+}
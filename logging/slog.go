@@ -0,0 +1,41 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package logging
+
+import (
+	"context"
+	"log/slog"
+)
+
+var slogLevels = []slog.Level{
+	DEBUG: slog.LevelDebug,
+	INFO:  slog.LevelInfo,
+	WARN:  slog.LevelWarn,
+	ERROR: slog.LevelError,
+}
+
+// FromSlog adapts a log/slog Logger to a StructuredLogger. Fields are passed on as slog attributes.
+func FromSlog(l *slog.Logger) StructuredLogger {
+	return &slogAdapter{l}
+}
+
+type slogAdapter struct {
+	l *slog.Logger
+}
+
+func toSlogLevel(level Level) slog.Level {
+	if level < DEBUG || level > ERROR {
+		return slog.Level(level)
+	}
+
+	return slogLevels[level]
+}
+
+func (a *slogAdapter) Enabled(level Level) bool {
+	return a.l.Enabled(context.Background(), toSlogLevel(level))
+}
+
+func (a *slogAdapter) Log(level Level, msg string, keysAndValues ...interface{}) {
+	a.l.Log(context.Background(), toSlogLevel(level), msg, keysAndValues...)
+}
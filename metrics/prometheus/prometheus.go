@@ -0,0 +1,260 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+// Package prometheus exports metrics about a quotaservice to Prometheus.
+package prometheus
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/redis/go-redis/v9"
+	"github.com/square/quotaservice/admin"
+	"github.com/square/quotaservice/config"
+	"github.com/square/quotaservice/events"
+)
+
+// Status label values for the events counted as requests. Bucket creation and removal aren't requests, and are
+// reflected in the number of dynamic buckets instead.
+var statuses = map[events.EventType]string{
+	events.EVENT_TOKENS_SERVED:             "served",
+	events.EVENT_TIMEOUT_SERVING_TOKENS:    "timed_out",
+	events.EVENT_TOO_MANY_TOKENS_REQUESTED: "too_many_tokens",
+	events.EVENT_BUCKET_MISS:               "bucket_miss",
+	events.EVENT_SERVER_ERROR:              "server_error",
+	events.EVENT_BUCKET_ERROR:              "bucket_error",
+}
+
+// Options configures an Exporter.
+type Options struct {
+	// DynamicBucketLabels is the number of dynamic buckets per namespace that are labelled with their own name.
+	// Requests to any other dynamic bucket are labelled with config.DynamicBucketTemplateName, which bounds the
+	// number of time series when clients create many dynamic buckets. Defaults to 0, labelling no dynamic bucket
+	// by name.
+	DynamicBucketLabels int
+	// WaitTimeBuckets are the upper bounds, in seconds, of the wait time histogram. Defaults to
+	// prometheus.DefBuckets.
+	WaitTimeBuckets []float64
+}
+
+// Exporter collects metrics from the events of a quotaservice and the state of its Administrable, serving them to
+// Prometheus. Register HandleEvent as the server's listener, and the Exporter itself as its metrics handler.
+type Exporter struct {
+	a        admin.Administrable
+	opts     Options
+	registry *prometheus.Registry
+
+	requests       *prometheus.CounterVec
+	tokens         *prometheus.CounterVec
+	waitTime       *prometheus.HistogramVec
+	scriptDuration prometheus.Histogram
+	dynamicBuckets *prometheus.Desc
+	droppedEvents  *prometheus.Desc
+	reaperWatchers *prometheus.Desc
+	reaperReaped   *prometheus.Desc
+	reaperSweeps   *prometheus.Desc
+	reaperDuration *prometheus.Desc
+	handler        http.Handler
+
+	// Names of the dynamic buckets labelled by name, keyed on namespace.
+	dynamicLabels map[string]map[string]bool
+	sync.Mutex    // Embedded mutex guards dynamicLabels
+}
+
+// New creates an Exporter reading server state from a, such as the Administrable returned by
+// quotaservice.Server.GetServerAdministrable().
+func New(a admin.Administrable, opts Options) *Exporter {
+	if opts.WaitTimeBuckets == nil {
+		opts.WaitTimeBuckets = prometheus.DefBuckets
+	}
+
+	e := &Exporter{
+		a:        a,
+		opts:     opts,
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "quotaservice_requests_total",
+			Help: "Requests for tokens, by namespace, bucket and outcome.",
+		}, []string{"namespace", "bucket", "status"}),
+		tokens: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "quotaservice_tokens_served_total",
+			Help: "Tokens served, by namespace and bucket.",
+		}, []string{"namespace", "bucket"}),
+		waitTime: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "quotaservice_wait_time_seconds",
+			Help:    "Time clients were asked to wait for the tokens they were served, by namespace.",
+			Buckets: opts.WaitTimeBuckets,
+		}, []string{"namespace"}),
+		scriptDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "quotaservice_redis_script_duration_seconds",
+			Help:    "Time taken to run the token bucket script on Redis.",
+			Buckets: prometheus.ExponentialBuckets(0.0001, 2, 14),
+		}),
+		dynamicBuckets: prometheus.NewDesc("quotaservice_dynamic_buckets",
+			"Dynamic buckets currently in each namespace.", []string{"namespace"}, nil),
+		droppedEvents: prometheus.NewDesc("quotaservice_events_dropped_total",
+			"Events dropped because a listener's queue was full, by listener.", []string{"listener"}, nil),
+		reaperWatchers: prometheus.NewDesc("quotaservice_reaper_watchers",
+			"Buckets currently watched by the reaper for inactivity.", nil, nil),
+		reaperReaped: prometheus.NewDesc("quotaservice_reaper_reaped_buckets_total",
+			"Buckets reaped due to inactivity, by namespace.", []string{"namespace"}, nil),
+		reaperSweeps: prometheus.NewDesc("quotaservice_reaper_sweeps_total",
+			"Times the reaper has checked buckets for inactivity.", nil, nil),
+		reaperDuration: prometheus.NewDesc("quotaservice_reaper_last_sweep_duration_seconds",
+			"Time taken by the reaper's most recent sweep.", nil, nil),
+		dynamicLabels: make(map[string]map[string]bool),
+	}
+
+	e.registry.MustRegister(e.requests, e.tokens, e.waitTime, e.scriptDuration, e)
+	e.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "quotaservice_config_version",
+		Help: "Version of the config in use.",
+	}, func() float64 {
+		return float64(a.Configs().Version)
+	}))
+
+	e.handler = promhttp.HandlerFor(e.registry, promhttp.HandlerOpts{})
+	return e
+}
+
+// Registry returns the registry metrics are served from, to which further collectors may be added.
+func (e *Exporter) Registry() *prometheus.Registry {
+	return e.registry
+}
+
+// ServeHTTP serves metrics in the Prometheus exposition format, implementing http.Handler.
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.handler.ServeHTTP(w, r)
+}
+
+// HandleEvent records an event. It can be used as an events.Listener.
+func (e *Exporter) HandleEvent(event events.Event) {
+	status, ok := statuses[event.EventType()]
+	if !ok {
+		return
+	}
+
+	namespace, bucket := e.labels(event)
+	e.requests.WithLabelValues(namespace, bucket, status).Inc()
+
+	if event.EventType() == events.EVENT_TOKENS_SERVED {
+		e.tokens.WithLabelValues(namespace, bucket).Add(float64(event.NumTokens()))
+		e.waitTime.WithLabelValues(namespace).Observe(event.WaitTime().Seconds())
+	}
+}
+
+// labels returns the namespace and bucket labels of an event. Names requested by clients are only used if they
+// are configured, or are among the first Options.DynamicBucketLabels dynamic buckets of their namespace.
+// Requests served by a default bucket are labelled with the default bucket's name, and requests for buckets
+// that don't exist with an empty bucket name.
+func (e *Exporter) labels(event events.Event) (namespace, bucket string) {
+	missed := event.EventType() == events.EVENT_BUCKET_MISS
+	ns := e.a.Configs().Namespaces[event.Namespace()]
+
+	switch {
+	case ns == nil && missed:
+		return config.GlobalNamespace, ""
+	case ns == nil:
+		return config.GlobalNamespace, config.DefaultBucketName
+	case ns.Buckets[event.BucketName()] != nil:
+		return event.Namespace(), event.BucketName()
+	case event.Dynamic():
+		return event.Namespace(), e.dynamicLabel(event.Namespace(), event.BucketName())
+	case missed:
+		return event.Namespace(), ""
+	default:
+		return event.Namespace(), config.DefaultBucketName
+	}
+}
+
+func (e *Exporter) dynamicLabel(namespace, bucket string) string {
+	e.Lock()
+	defer e.Unlock()
+
+	labelled := e.dynamicLabels[namespace]
+	if labelled == nil {
+		labelled = make(map[string]bool)
+		e.dynamicLabels[namespace] = labelled
+	}
+
+	if !labelled[bucket] {
+		if len(labelled) >= e.opts.DynamicBucketLabels {
+			return config.DynamicBucketTemplateName
+		}
+
+		labelled[bucket] = true
+	}
+
+	return bucket
+}
+
+// Describe implements prometheus.Collector, for the metrics read from the Administrable when scraped.
+func (e *Exporter) Describe(ch chan<- *prometheus.Desc) {
+	ch <- e.dynamicBuckets
+	ch <- e.droppedEvents
+	ch <- e.reaperWatchers
+	ch <- e.reaperReaped
+	ch <- e.reaperSweeps
+	ch <- e.reaperDuration
+}
+
+// Collect implements prometheus.Collector, for the metrics read from the Administrable when scraped.
+func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
+	for namespace, count := range e.a.DynamicBucketCounts() {
+		ch <- prometheus.MustNewConstMetric(e.dynamicBuckets, prometheus.GaugeValue, float64(count), namespace)
+	}
+
+	for listener, dropped := range e.a.DroppedEvents() {
+		ch <- prometheus.MustNewConstMetric(e.droppedEvents, prometheus.CounterValue, float64(dropped), listener)
+	}
+
+	// The reaper isn't running before the server starts, or if it has been stopped.
+	reaperStats := e.a.ReaperStats()
+	if reaperStats == nil {
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(e.reaperWatchers, prometheus.GaugeValue, float64(reaperStats.Watchers))
+	ch <- prometheus.MustNewConstMetric(e.reaperSweeps, prometheus.CounterValue, float64(reaperStats.Sweeps))
+	ch <- prometheus.MustNewConstMetric(e.reaperDuration, prometheus.GaugeValue,
+		reaperStats.LastSweepDuration.Seconds())
+	for namespace, reaped := range reaperStats.Reaped {
+		ch <- prometheus.MustNewConstMetric(e.reaperReaped, prometheus.CounterValue, float64(reaped), namespace)
+	}
+}
+
+// RedisHook returns a hook that measures how long Redis token buckets take to run their script. Add it to a
+// Redis bucket factory with redis.AddHook from the buckets/redis package.
+func (e *Exporter) RedisHook() redis.Hook {
+	return scriptHook{e.scriptDuration}
+}
+
+type scriptHook struct {
+	duration prometheus.Histogram
+}
+
+func (h scriptHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h scriptHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if name := strings.ToLower(cmd.Name()); name != "evalsha" && name != "eval" {
+			return next(ctx, cmd)
+		}
+
+		start := time.Now()
+		err := next(ctx, cmd)
+		h.duration.Observe(time.Since(start).Seconds())
+		return err
+	}
+}
+
+func (h scriptHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
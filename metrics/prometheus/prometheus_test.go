@@ -0,0 +1,124 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package prometheus
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/square/quotaservice/admin"
+	"github.com/square/quotaservice/config"
+	"github.com/square/quotaservice/events"
+)
+
+func newTestExporter(t *testing.T, opts Options) *Exporter {
+	a := admin.NewMockAdministrable()
+	ns := config.NewDefaultNamespaceConfig("ns")
+	ns.Buckets["static"] = config.NewDefaultBucketConfig("static")
+	ns.DynamicBucketTemplate = config.NewDefaultBucketConfig(config.DynamicBucketTemplateName)
+	a.Configs().Namespaces["ns"] = ns
+	a.Configs().Version = 7
+
+	return New(a, opts)
+}
+
+func scrape(t *testing.T, e *Exporter) string {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	body, err := io.ReadAll(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(body)
+}
+
+func expectMetrics(t *testing.T, body string, metrics ...string) {
+	t.Helper()
+
+	for _, m := range metrics {
+		if !strings.Contains(body, m+"\n") {
+			t.Errorf("Expected metric %v in:\n%v", m, body)
+		}
+	}
+}
+
+func TestHandleEvent(t *testing.T) {
+	e := newTestExporter(t, Options{})
+	e.HandleEvent(events.NewTokensServedEvent("ns", "static", false, 3, 20*time.Millisecond))
+	e.HandleEvent(events.NewTokensServedEvent("ns", "static", false, 2, 0))
+	e.HandleEvent(events.NewTimedOutEvent("ns", "static", false, 1))
+	e.HandleEvent(events.NewBucketMissedEvent("ns", "unknown", false))
+	e.HandleEvent(events.NewBucketCreatedEvent("ns", "static", false))
+
+	expectMetrics(t, scrape(t, e),
+		`quotaservice_requests_total{bucket="static",namespace="ns",status="served"} 2`,
+		`quotaservice_requests_total{bucket="static",namespace="ns",status="timed_out"} 1`,
+		`quotaservice_requests_total{bucket="",namespace="ns",status="bucket_miss"} 1`,
+		`quotaservice_tokens_served_total{bucket="static",namespace="ns"} 5`,
+		`quotaservice_wait_time_seconds_bucket{namespace="ns",le="0.025"} 2`,
+		`quotaservice_wait_time_seconds_bucket{namespace="ns",le="0.005"} 1`,
+		`quotaservice_config_version 7`,
+		`quotaservice_events_dropped_total{listener="default"} 0`,
+		`quotaservice_dynamic_buckets{namespace="test"} 3`)
+}
+
+func TestReaperMetrics(t *testing.T) {
+	expectMetrics(t, scrape(t, newTestExporter(t, Options{})),
+		`quotaservice_reaper_watchers 1`,
+		`quotaservice_reaper_sweeps_total 4`,
+		`quotaservice_reaper_reaped_buckets_total{namespace="test"} 2`,
+		`quotaservice_reaper_last_sweep_duration_seconds 0.25`)
+
+	// Without a running reaper, none are exported.
+	e := New(admin.NewMockErrorAdministrable(), Options{})
+	if body := scrape(t, e); strings.Contains(body, "quotaservice_reaper_") {
+		t.Errorf("Didn't expect reaper metrics in:\n%v", body)
+	}
+}
+
+func TestBoundedLabels(t *testing.T) {
+	e := newTestExporter(t, Options{DynamicBucketLabels: 2})
+	for _, name := range []string{"d1", "d2", "d3", "d4", "d1"} {
+		e.HandleEvent(events.NewTokensServedEvent("ns", name, true, 1, 0))
+	}
+
+	e.HandleEvent(events.NewTokensServedEvent("other", "x", false, 1, 0))
+	e.HandleEvent(events.NewBucketMissedEvent("other", "y", false))
+
+	body := scrape(t, e)
+	expectMetrics(t, body,
+		`quotaservice_requests_total{bucket="d1",namespace="ns",status="served"} 2`,
+		`quotaservice_requests_total{bucket="d2",namespace="ns",status="served"} 1`,
+		`quotaservice_requests_total{bucket="___DYNAMIC_BUCKET_TPL___",namespace="ns",status="served"} 2`,
+		`quotaservice_requests_total{bucket="___DEFAULT_BUCKET___",namespace="___GLOBAL___",status="served"} 1`,
+		`quotaservice_requests_total{bucket="",namespace="___GLOBAL___",status="bucket_miss"} 1`)
+
+	for _, unexpected := range []string{`"d3"`, `"d4"`, `"other"`} {
+		if strings.Contains(body, unexpected) {
+			t.Errorf("Didn't expect label %v in:\n%v", unexpected, body)
+		}
+	}
+}
+
+func TestRedisHook(t *testing.T) {
+	e := newTestExporter(t, Options{})
+	process := e.RedisHook().ProcessHook(func(ctx context.Context, cmd redis.Cmder) error {
+		return nil
+	})
+
+	ctx := context.Background()
+	_ = process(ctx, redis.NewCmd(ctx, "evalsha", "sha", 0))
+	_ = process(ctx, redis.NewCmd(ctx, "get", "key"))
+
+	expectMetrics(t, scrape(t, e), `quotaservice_redis_script_duration_seconds_count 1`)
+}
@@ -12,6 +12,9 @@ It has these top-level messages:
 	ServiceConfig
 	NamespaceConfig
 	BucketConfig
+	BucketOverride
+	BucketSchedule
+	AlertRule
 */
 package quotaservice_configs
 
@@ -30,6 +33,54 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
+// What to do when a new dynamic bucket is requested but max_dynamic_buckets has been reached.
+type NamespaceConfig_EvictionPolicy int32
+
+const (
+	// Don't create the bucket; the request is rejected.
+	NamespaceConfig_REJECT NamespaceConfig_EvictionPolicy = 0
+	// Evict the least recently used dynamic bucket to make room for the new one.
+	NamespaceConfig_EVICT_LRU NamespaceConfig_EvictionPolicy = 1
+)
+
+var NamespaceConfig_EvictionPolicy_name = map[int32]string{
+	0: "REJECT",
+	1: "EVICT_LRU",
+}
+var NamespaceConfig_EvictionPolicy_value = map[string]int32{
+	"REJECT":    0,
+	"EVICT_LRU": 1,
+}
+
+func (x NamespaceConfig_EvictionPolicy) String() string {
+	return proto.EnumName(NamespaceConfig_EvictionPolicy_name, int32(x))
+}
+func (NamespaceConfig_EvictionPolicy) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{1, 0} }
+
+// Requests that count against the threshold.
+type AlertRule_Condition int32
+
+const (
+	// Requests that timed out waiting for tokens.
+	AlertRule_TIMEOUTS AlertRule_Condition = 0
+	// Requests that timed out, asked for too many tokens or named a bucket that doesn't exist.
+	AlertRule_REJECTIONS AlertRule_Condition = 1
+)
+
+var AlertRule_Condition_name = map[int32]string{
+	0: "TIMEOUTS",
+	1: "REJECTIONS",
+}
+var AlertRule_Condition_value = map[string]int32{
+	"TIMEOUTS":   0,
+	"REJECTIONS": 1,
+}
+
+func (x AlertRule_Condition) String() string {
+	return proto.EnumName(AlertRule_Condition_name, int32(x))
+}
+func (AlertRule_Condition) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{5, 0} }
+
 // Representations of configuration elements, for persisting and sharing across nodes.
 type ServiceConfig struct {
 	GlobalDefaultBucket *BucketConfig               `protobuf:"bytes,1,opt,name=global_default_bucket,json=globalDefaultBucket" json:"global_default_bucket,omitempty" yaml:"global_default_bucket"`
@@ -38,6 +89,10 @@ type ServiceConfig struct {
 	Version int32  `protobuf:"varint,3,opt,name=version" json:"version,omitempty" yaml:"version"`
 	User    string `protobuf:"bytes,4,opt,name=user" json:"user,omitempty" yaml:"user"`
 	Date    int64  `protobuf:"varint,5,opt,name=date" json:"date,omitempty" yaml:"date"`
+	// Temporary changes to bucket configs, reverted once they expire.
+	Overrides []*BucketOverride `protobuf:"bytes,6,rep,name=overrides" json:"overrides,omitempty" yaml:"overrides"`
+	// Rules alerting on-call when requests to a namespace or bucket are being rejected.
+	AlertRules []*AlertRule `protobuf:"bytes,7,rep,name=alert_rules,json=alertRules" json:"alert_rules,omitempty" yaml:"alert_rules"`
 }
 
 func (m *ServiceConfig) Reset()                    { *m = ServiceConfig{} }
@@ -80,12 +135,27 @@ func (m *ServiceConfig) GetDate() int64 {
 	return 0
 }
 
+func (m *ServiceConfig) GetOverrides() []*BucketOverride {
+	if m != nil {
+		return m.Overrides
+	}
+	return nil
+}
+
+func (m *ServiceConfig) GetAlertRules() []*AlertRule {
+	if m != nil {
+		return m.AlertRules
+	}
+	return nil
+}
+
 type NamespaceConfig struct {
-	Name                  string                   `protobuf:"bytes,1,opt,name=name" json:"name,omitempty" yaml:"name"`
-	DefaultBucket         *BucketConfig            `protobuf:"bytes,2,opt,name=default_bucket,json=defaultBucket" json:"default_bucket,omitempty" yaml:"default_bucket"`
-	DynamicBucketTemplate *BucketConfig            `protobuf:"bytes,3,opt,name=dynamic_bucket_template,json=dynamicBucketTemplate" json:"dynamic_bucket_template,omitempty" yaml:"dynamic_bucket_template"`
-	MaxDynamicBuckets     int32                    `protobuf:"varint,4,opt,name=max_dynamic_buckets,json=maxDynamicBuckets" json:"max_dynamic_buckets,omitempty" yaml:"max_dynamic_buckets"`
-	Buckets               map[string]*BucketConfig `protobuf:"bytes,5,rep,name=buckets" json:"buckets,omitempty" yaml:"buckets" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Name                        string                         `protobuf:"bytes,1,opt,name=name" json:"name,omitempty" yaml:"name"`
+	DefaultBucket               *BucketConfig                  `protobuf:"bytes,2,opt,name=default_bucket,json=defaultBucket" json:"default_bucket,omitempty" yaml:"default_bucket"`
+	DynamicBucketTemplate       *BucketConfig                  `protobuf:"bytes,3,opt,name=dynamic_bucket_template,json=dynamicBucketTemplate" json:"dynamic_bucket_template,omitempty" yaml:"dynamic_bucket_template"`
+	MaxDynamicBuckets           int32                          `protobuf:"varint,4,opt,name=max_dynamic_buckets,json=maxDynamicBuckets" json:"max_dynamic_buckets,omitempty" yaml:"max_dynamic_buckets"`
+	Buckets                     map[string]*BucketConfig       `protobuf:"bytes,5,rep,name=buckets" json:"buckets,omitempty" yaml:"buckets" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	DynamicBucketEvictionPolicy NamespaceConfig_EvictionPolicy `protobuf:"varint,6,opt,name=dynamic_bucket_eviction_policy,json=dynamicBucketEvictionPolicy,enum=quotaservice.configs.NamespaceConfig_EvictionPolicy" json:"dynamic_bucket_eviction_policy,omitempty" yaml:"dynamic_bucket_eviction_policy"`
 }
 
 func (m *NamespaceConfig) Reset()                    { *m = NamespaceConfig{} }
@@ -128,6 +198,13 @@ func (m *NamespaceConfig) GetBuckets() map[string]*BucketConfig {
 	return nil
 }
 
+func (m *NamespaceConfig) GetDynamicBucketEvictionPolicy() NamespaceConfig_EvictionPolicy {
+	if m != nil {
+		return m.DynamicBucketEvictionPolicy
+	}
+	return NamespaceConfig_REJECT
+}
+
 type BucketConfig struct {
 	Name                string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty" yaml:"name"`
 	Namespace           string `protobuf:"bytes,2,opt,name=namespace" json:"namespace,omitempty" yaml:"namespace"`
@@ -137,6 +214,8 @@ type BucketConfig struct {
 	MaxIdleMillis       int64  `protobuf:"varint,6,opt,name=max_idle_millis,json=maxIdleMillis" json:"max_idle_millis,omitempty" yaml:"max_idle_millis"`
 	MaxDebtMillis       int64  `protobuf:"varint,7,opt,name=max_debt_millis,json=maxDebtMillis" json:"max_debt_millis,omitempty" yaml:"max_debt_millis"`
 	MaxTokensPerRequest int64  `protobuf:"varint,8,opt,name=max_tokens_per_request,json=maxTokensPerRequest" json:"max_tokens_per_request,omitempty" yaml:"max_tokens_per_request"`
+	// Recurring windows during which the bucket uses alternative settings.
+	Schedules []*BucketSchedule `protobuf:"bytes,9,rep,name=schedules" json:"schedules,omitempty" yaml:"schedules"`
 }
 
 func (m *BucketConfig) Reset()                    { *m = BucketConfig{} }
@@ -200,46 +279,271 @@ func (m *BucketConfig) GetMaxTokensPerRequest() int64 {
 	return 0
 }
 
+func (m *BucketConfig) GetSchedules() []*BucketSchedule {
+	if m != nil {
+		return m.Schedules
+	}
+	return nil
+}
+
+// A temporary change to a bucket's config. Non-zero fields of config replace those of the bucket's config
+// until expires_at.
+type BucketOverride struct {
+	Namespace string        `protobuf:"bytes,1,opt,name=namespace" json:"namespace,omitempty" yaml:"namespace"`
+	Bucket    string        `protobuf:"bytes,2,opt,name=bucket" json:"bucket,omitempty" yaml:"bucket"`
+	Config    *BucketConfig `protobuf:"bytes,3,opt,name=config" json:"config,omitempty" yaml:"config"`
+	// Unix time, in seconds, after which the override no longer applies.
+	ExpiresAt int64  `protobuf:"varint,4,opt,name=expires_at,json=expiresAt" json:"expires_at,omitempty" yaml:"expires_at"`
+	User      string `protobuf:"bytes,5,opt,name=user" json:"user,omitempty" yaml:"user"`
+	Reason    string `protobuf:"bytes,6,opt,name=reason" json:"reason,omitempty" yaml:"reason"`
+}
+
+func (m *BucketOverride) Reset()                    { *m = BucketOverride{} }
+func (m *BucketOverride) String() string            { return proto.CompactTextString(m) }
+func (*BucketOverride) ProtoMessage()               {}
+func (*BucketOverride) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
+func (m *BucketOverride) GetNamespace() string {
+	if m != nil {
+		return m.Namespace
+	}
+	return ""
+}
+
+func (m *BucketOverride) GetBucket() string {
+	if m != nil {
+		return m.Bucket
+	}
+	return ""
+}
+
+func (m *BucketOverride) GetConfig() *BucketConfig {
+	if m != nil {
+		return m.Config
+	}
+	return nil
+}
+
+func (m *BucketOverride) GetExpiresAt() int64 {
+	if m != nil {
+		return m.ExpiresAt
+	}
+	return 0
+}
+
+func (m *BucketOverride) GetUser() string {
+	if m != nil {
+		return m.User
+	}
+	return ""
+}
+
+func (m *BucketOverride) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+// A recurring window during which a bucket uses alternative settings. Non-zero values replace those of the
+// bucket for duration_millis after each time matched by start.
+type BucketSchedule struct {
+	Name string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty" yaml:"name"`
+	// Cron expression (minute, hour, day of month, month and day of week) matching the start of each window, in
+	// UTC unless prefixed with CRON_TZ=<time zone>.
+	Start          string `protobuf:"bytes,2,opt,name=start" json:"start,omitempty" yaml:"start"`
+	DurationMillis int64  `protobuf:"varint,3,opt,name=duration_millis,json=durationMillis" json:"duration_millis,omitempty" yaml:"duration_millis"`
+	Size           int64  `protobuf:"varint,4,opt,name=size" json:"size,omitempty" yaml:"size"`
+	FillRate       int64  `protobuf:"varint,5,opt,name=fill_rate,json=fillRate" json:"fill_rate,omitempty" yaml:"fill_rate"`
+}
+
+func (m *BucketSchedule) Reset()                    { *m = BucketSchedule{} }
+func (m *BucketSchedule) String() string            { return proto.CompactTextString(m) }
+func (*BucketSchedule) ProtoMessage()               {}
+func (*BucketSchedule) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+func (m *BucketSchedule) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *BucketSchedule) GetStart() string {
+	if m != nil {
+		return m.Start
+	}
+	return ""
+}
+
+func (m *BucketSchedule) GetDurationMillis() int64 {
+	if m != nil {
+		return m.DurationMillis
+	}
+	return 0
+}
+
+func (m *BucketSchedule) GetSize() int64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+func (m *BucketSchedule) GetFillRate() int64 {
+	if m != nil {
+		return m.FillRate
+	}
+	return 0
+}
+
+// A rule that fires when the share of requests to a namespace, or to one of its buckets, matching condition
+// exceeds threshold_percent over the last window_millis. Webhooks are notified when it fires and when it resolves.
+type AlertRule struct {
+	Name      string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty" yaml:"name"`
+	Namespace string `protobuf:"bytes,2,opt,name=namespace" json:"namespace,omitempty" yaml:"namespace"`
+	// The bucket the rule applies to. Requests to any bucket of the namespace count if empty.
+	Bucket           string              `protobuf:"bytes,3,opt,name=bucket" json:"bucket,omitempty" yaml:"bucket"`
+	Condition        AlertRule_Condition `protobuf:"varint,4,opt,name=condition,enum=quotaservice.configs.AlertRule_Condition" json:"condition,omitempty" yaml:"condition"`
+	ThresholdPercent float64             `protobuf:"fixed64,5,opt,name=threshold_percent,json=thresholdPercent" json:"threshold_percent,omitempty" yaml:"threshold_percent"`
+	WindowMillis     int64               `protobuf:"varint,6,opt,name=window_millis,json=windowMillis" json:"window_millis,omitempty" yaml:"window_millis"`
+	// The fewest requests within the window for the rule to fire, so that a handful of requests can't.
+	MinRequests int64 `protobuf:"varint,7,opt,name=min_requests,json=minRequests" json:"min_requests,omitempty" yaml:"min_requests"`
+	// URLs the alert is POSTed to as JSON.
+	Webhooks []string `protobuf:"bytes,8,rep,name=webhooks" json:"webhooks,omitempty" yaml:"webhooks"`
+}
+
+func (m *AlertRule) Reset()                    { *m = AlertRule{} }
+func (m *AlertRule) String() string            { return proto.CompactTextString(m) }
+func (*AlertRule) ProtoMessage()               {}
+func (*AlertRule) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+func (m *AlertRule) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *AlertRule) GetNamespace() string {
+	if m != nil {
+		return m.Namespace
+	}
+	return ""
+}
+
+func (m *AlertRule) GetBucket() string {
+	if m != nil {
+		return m.Bucket
+	}
+	return ""
+}
+
+func (m *AlertRule) GetCondition() AlertRule_Condition {
+	if m != nil {
+		return m.Condition
+	}
+	return AlertRule_TIMEOUTS
+}
+
+func (m *AlertRule) GetThresholdPercent() float64 {
+	if m != nil {
+		return m.ThresholdPercent
+	}
+	return 0
+}
+
+func (m *AlertRule) GetWindowMillis() int64 {
+	if m != nil {
+		return m.WindowMillis
+	}
+	return 0
+}
+
+func (m *AlertRule) GetMinRequests() int64 {
+	if m != nil {
+		return m.MinRequests
+	}
+	return 0
+}
+
+func (m *AlertRule) GetWebhooks() []string {
+	if m != nil {
+		return m.Webhooks
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*ServiceConfig)(nil), "quotaservice.configs.ServiceConfig")
 	proto.RegisterType((*NamespaceConfig)(nil), "quotaservice.configs.NamespaceConfig")
 	proto.RegisterType((*BucketConfig)(nil), "quotaservice.configs.BucketConfig")
+	proto.RegisterType((*BucketOverride)(nil), "quotaservice.configs.BucketOverride")
+	proto.RegisterType((*BucketSchedule)(nil), "quotaservice.configs.BucketSchedule")
+	proto.RegisterType((*AlertRule)(nil), "quotaservice.configs.AlertRule")
+	proto.RegisterEnum("quotaservice.configs.NamespaceConfig_EvictionPolicy", NamespaceConfig_EvictionPolicy_name, NamespaceConfig_EvictionPolicy_value)
+	proto.RegisterEnum("quotaservice.configs.AlertRule_Condition", AlertRule_Condition_name, AlertRule_Condition_value)
 }
 
 func init() { proto.RegisterFile("protos/config/configs.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 509 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x8c, 0x54, 0x4d, 0x6f, 0xd3, 0x40,
-	0x10, 0x95, 0xed, 0xa4, 0x69, 0xa6, 0x84, 0xd2, 0x2d, 0x05, 0xab, 0xe5, 0x10, 0x45, 0x02, 0xe5,
-	0x64, 0xa4, 0xe4, 0x52, 0xc1, 0x0d, 0xc2, 0xa1, 0x12, 0x20, 0xb4, 0x8d, 0x38, 0x70, 0xc0, 0xda,
-	0xc4, 0x93, 0x6a, 0x95, 0xb5, 0x9d, 0x7a, 0xd7, 0x21, 0xe1, 0x07, 0x71, 0xe0, 0x77, 0xf1, 0x43,
-	0xd0, 0x7e, 0x38, 0xd8, 0x55, 0x84, 0x72, 0xca, 0x64, 0xde, 0x9b, 0x37, 0xbb, 0xef, 0xad, 0x0c,
-	0x57, 0xab, 0x22, 0x57, 0xb9, 0x7c, 0x3d, 0xcf, 0xb3, 0x05, 0xbf, 0x73, 0x3f, 0x32, 0x32, 0x5d,
-	0xf2, 0xf4, 0xbe, 0xcc, 0x15, 0x93, 0x58, 0xac, 0xf9, 0x1c, 0x23, 0x87, 0x0d, 0xfe, 0xf8, 0xd0,
-	0xbb, 0xb5, 0xbd, 0xf7, 0xa6, 0x45, 0xbe, 0xc2, 0xc5, 0x9d, 0xc8, 0x67, 0x4c, 0xc4, 0x09, 0x2e,
-	0x58, 0x29, 0x54, 0x3c, 0x2b, 0xe7, 0x4b, 0x54, 0xa1, 0xd7, 0xf7, 0x86, 0x27, 0xa3, 0x41, 0xb4,
-	0x4f, 0x27, 0x7a, 0x67, 0x38, 0x56, 0x82, 0x9e, 0x5b, 0x81, 0x89, 0x9d, 0xb7, 0x10, 0xb9, 0x05,
-	0xc8, 0x58, 0x8a, 0x72, 0xc5, 0xe6, 0x28, 0x43, 0xbf, 0x1f, 0x0c, 0x4f, 0x46, 0xe3, 0xfd, 0x62,
-	0x8d, 0x03, 0x45, 0x9f, 0x77, 0x53, 0x1f, 0x32, 0x55, 0x6c, 0x69, 0x4d, 0x86, 0x84, 0xd0, 0x59,
-	0x63, 0x21, 0x79, 0x9e, 0x85, 0x41, 0xdf, 0x1b, 0xb6, 0x69, 0xf5, 0x97, 0x10, 0x68, 0x95, 0x12,
-	0x8b, 0xb0, 0xd5, 0xf7, 0x86, 0x5d, 0x6a, 0x6a, 0xdd, 0x4b, 0x98, 0xc2, 0xb0, 0xdd, 0xf7, 0x86,
-	0x01, 0x35, 0xf5, 0x65, 0x02, 0xa7, 0x0f, 0x16, 0x90, 0x27, 0x10, 0x2c, 0x71, 0x6b, 0xee, 0xdb,
-	0xa5, 0xba, 0x24, 0x6f, 0xa1, 0xbd, 0x66, 0xa2, 0xc4, 0xd0, 0x37, 0x1e, 0xbc, 0xdc, 0x7f, 0xec,
-	0x9d, 0x8e, 0xb3, 0xc1, 0xce, 0xbc, 0xf1, 0xaf, 0xbd, 0xc1, 0xef, 0xa0, 0xb6, 0xc6, 0x19, 0x4d,
-	0xa0, 0xa5, 0x6f, 0xe2, 0xf6, 0x98, 0x9a, 0xdc, 0xc0, 0xe3, 0x07, 0xae, 0xfb, 0x07, 0xbb, 0xde,
-	0x4b, 0x1a, 0x7e, 0x7f, 0x83, 0xe7, 0xc9, 0x36, 0x63, 0x29, 0x9f, 0x3b, 0xa9, 0x58, 0x61, 0xba,
-	0x12, 0xfa, 0xfe, 0xc1, 0xc1, 0x9a, 0x17, 0x4e, 0xc2, 0x36, 0xa7, 0x4e, 0x80, 0x44, 0x70, 0x9e,
-	0xb2, 0x4d, 0xdc, 0xd4, 0x97, 0xc6, 0xeb, 0x36, 0x3d, 0x4b, 0xd9, 0x66, 0x52, 0x1f, 0x93, 0xe4,
-	0x23, 0x74, 0x2a, 0x4e, 0xdb, 0x04, 0x3f, 0x3a, 0xc8, 0x41, 0x77, 0x16, 0x97, 0x7b, 0x25, 0x71,
-	0xf9, 0x1d, 0x1e, 0xd5, 0x81, 0x3d, 0x79, 0x5d, 0x37, 0xf3, 0x3a, 0xe4, 0xa6, 0xb5, 0xb0, 0x7e,
-	0xf9, 0xd5, 0x82, 0xff, 0x24, 0xf5, 0x02, 0xba, 0xbb, 0x77, 0x68, 0xd6, 0x74, 0xe9, 0xbf, 0x86,
-	0x9e, 0x90, 0xfc, 0xa7, 0x75, 0x3a, 0xa0, 0xa6, 0x26, 0x57, 0xd0, 0x5d, 0x70, 0x21, 0xe2, 0x42,
-	0x47, 0xd0, 0x32, 0xc0, 0xb1, 0x6e, 0x50, 0xe7, 0xe8, 0x0f, 0xc6, 0x55, 0xac, 0x78, 0x8a, 0x79,
-	0xa9, 0xe2, 0x94, 0x0b, 0xc1, 0xa5, 0x7b, 0xa9, 0x67, 0x1a, 0x9a, 0x5a, 0xe4, 0x93, 0x01, 0xc8,
-	0x2b, 0x38, 0xd5, 0x09, 0xf0, 0x44, 0x60, 0xc5, 0x3d, 0x32, 0xdc, 0x5e, 0xca, 0x36, 0x37, 0x89,
-	0xc0, 0x26, 0x2f, 0xc1, 0xd9, 0x4e, 0xb3, 0xb3, 0xe3, 0x4d, 0x70, 0x56, 0xe9, 0x8d, 0xe1, 0x99,
-	0xe6, 0xa9, 0x7c, 0x89, 0x99, 0x8c, 0x57, 0x58, 0xc4, 0x05, 0xde, 0x97, 0x28, 0x55, 0x78, 0x6c,
-	0xe8, 0x3a, 0xef, 0xa9, 0x01, 0xbf, 0x60, 0x41, 0x2d, 0x34, 0x3b, 0x32, 0x5f, 0x96, 0xf1, 0xdf,
-	0x00, 0x00, 0x00, 0xff, 0xff, 0x29, 0xba, 0x7e, 0x7d, 0x78, 0x04, 0x00, 0x00,
+	// 898 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x9c, 0x56, 0x5b, 0x73, 0xda, 0x46,
+	0x14, 0x8e, 0x10, 0x60, 0x74, 0x0c, 0x18, 0x6f, 0x12, 0x57, 0x63, 0xf7, 0x42, 0xe9, 0x8d, 0x4c,
+	0x66, 0xe8, 0x0c, 0xee, 0x43, 0x26, 0x7d, 0x69, 0x62, 0x33, 0x1d, 0x3a, 0x49, 0xec, 0x59, 0x48,
+	0x1e, 0xfa, 0x50, 0x8d, 0x90, 0x8e, 0xe3, 0x1d, 0xeb, 0x42, 0x76, 0x57, 0xd8, 0xf4, 0x77, 0xf4,
+	0x6f, 0xf5, 0xa5, 0x8f, 0xfd, 0x09, 0xf9, 0x15, 0x1d, 0xad, 0x56, 0x02, 0x31, 0xb4, 0xa1, 0x79,
+	0xe2, 0xec, 0xb9, 0x7c, 0x7b, 0x2e, 0xdf, 0x1e, 0x04, 0x27, 0x73, 0x1e, 0xcb, 0x58, 0x7c, 0xef,
+	0xc5, 0xd1, 0x15, 0x7b, 0xab, 0x7f, 0xc4, 0x40, 0x69, 0xc9, 0x83, 0x77, 0x49, 0x2c, 0x5d, 0x81,
+	0x7c, 0xc1, 0x3c, 0x1c, 0x68, 0x5b, 0xef, 0xbd, 0x09, 0xad, 0x49, 0xa6, 0x3b, 0x53, 0x2a, 0xf2,
+	0x06, 0x1e, 0xbe, 0x0d, 0xe2, 0x99, 0x1b, 0x38, 0x3e, 0x5e, 0xb9, 0x49, 0x20, 0x9d, 0x59, 0xe2,
+	0xdd, 0xa0, 0xb4, 0x8d, 0xae, 0xd1, 0xdf, 0x1f, 0xf6, 0x06, 0xdb, 0x70, 0x06, 0xcf, 0x95, 0x4f,
+	0x06, 0x41, 0xef, 0x67, 0x00, 0xe7, 0x59, 0x7c, 0x66, 0x22, 0x13, 0x80, 0xc8, 0x0d, 0x51, 0xcc,
+	0x5d, 0x0f, 0x85, 0x5d, 0xe9, 0x9a, 0xfd, 0xfd, 0xe1, 0xe9, 0x76, 0xb0, 0x52, 0x42, 0x83, 0x57,
+	0x45, 0xd4, 0x28, 0x92, 0x7c, 0x49, 0xd7, 0x60, 0x88, 0x0d, 0x7b, 0x0b, 0xe4, 0x82, 0xc5, 0x91,
+	0x6d, 0x76, 0x8d, 0x7e, 0x8d, 0xe6, 0x47, 0x42, 0xa0, 0x9a, 0x08, 0xe4, 0x76, 0xb5, 0x6b, 0xf4,
+	0x2d, 0xaa, 0xe4, 0x54, 0xe7, 0xbb, 0x12, 0xed, 0x5a, 0xd7, 0xe8, 0x9b, 0x54, 0xc9, 0xe4, 0x39,
+	0x58, 0xf1, 0x02, 0x39, 0x67, 0x3e, 0x0a, 0xbb, 0xae, 0xb2, 0xfa, 0xfa, 0xbf, 0x4a, 0xbc, 0xd0,
+	0xce, 0x74, 0x15, 0x46, 0x7e, 0x82, 0x7d, 0x37, 0x40, 0x2e, 0x1d, 0x9e, 0x04, 0x28, 0xec, 0x3d,
+	0x85, 0xf2, 0xc5, 0x76, 0x94, 0x67, 0xa9, 0x23, 0x4d, 0x02, 0xa4, 0xe0, 0xe6, 0xa2, 0x38, 0xf6,
+	0xe1, 0x60, 0xa3, 0x4c, 0xd2, 0x01, 0xf3, 0x06, 0x97, 0xaa, 0xeb, 0x16, 0x4d, 0x45, 0xf2, 0x23,
+	0xd4, 0x16, 0x6e, 0x90, 0xa0, 0x5d, 0x51, 0x93, 0xf8, 0x66, 0xfb, 0x05, 0x05, 0x8e, 0x1e, 0x46,
+	0x16, 0xf3, 0xb4, 0xf2, 0xc4, 0xe8, 0xfd, 0x55, 0x85, 0x83, 0x0d, 0x73, 0xda, 0x93, 0xb4, 0x9f,
+	0xfa, 0x1e, 0x25, 0x93, 0x31, 0xb4, 0x37, 0x66, 0x5f, 0xd9, 0x79, 0xf6, 0x2d, 0xbf, 0x34, 0xf5,
+	0x5f, 0xe1, 0x13, 0x7f, 0x19, 0xb9, 0x21, 0xf3, 0x34, 0x94, 0x23, 0x31, 0x9c, 0x07, 0xe9, 0x14,
+	0xcc, 0x9d, 0x31, 0x1f, 0x6a, 0x88, 0x4c, 0x39, 0xd5, 0x00, 0x64, 0x00, 0xf7, 0x43, 0xf7, 0xce,
+	0x29, 0xe3, 0x0b, 0x35, 0xf1, 0x1a, 0x3d, 0x0c, 0xdd, 0xbb, 0xf3, 0xf5, 0x30, 0x41, 0x5e, 0xc0,
+	0x5e, 0xee, 0x53, 0x53, 0x23, 0x1a, 0xee, 0xd4, 0x41, 0x9d, 0x8b, 0x66, 0x5f, 0x0e, 0x41, 0x96,
+	0xf0, 0xf9, 0x46, 0x65, 0xb8, 0x60, 0x9e, 0x64, 0x71, 0xe4, 0xcc, 0xe3, 0x80, 0x79, 0x4b, 0xbb,
+	0xde, 0x35, 0xfa, 0xed, 0xe1, 0x0f, 0xbb, 0x5d, 0x32, 0xd2, 0xc1, 0x97, 0x2a, 0x96, 0x9e, 0x94,
+	0x4a, 0x2e, 0x1b, 0x8f, 0x7f, 0x83, 0xe6, 0x7a, 0x4e, 0x5b, 0xa8, 0xf2, 0xa4, 0x4c, 0x95, 0x5d,
+	0x9a, 0xbc, 0xc6, 0x93, 0xc7, 0xd0, 0x2e, 0xdf, 0x48, 0x00, 0xea, 0x74, 0xf4, 0xcb, 0xe8, 0x6c,
+	0xda, 0xb9, 0x47, 0x5a, 0x60, 0x8d, 0xde, 0x8c, 0xcf, 0xa6, 0xce, 0x0b, 0xfa, 0xba, 0x63, 0xf4,
+	0xde, 0x57, 0xa0, 0xb9, 0x0e, 0xb4, 0x95, 0x51, 0x9f, 0x82, 0x55, 0xbc, 0x5a, 0x95, 0x93, 0x45,
+	0x57, 0x8a, 0x34, 0x42, 0xb0, 0xdf, 0x33, 0x46, 0x98, 0x54, 0xc9, 0xe4, 0x04, 0xac, 0x2b, 0x16,
+	0x04, 0x0e, 0x4f, 0xa9, 0x52, 0x55, 0x86, 0x46, 0xaa, 0xa0, 0x7a, 0xf2, 0xb7, 0x2e, 0x93, 0x8e,
+	0x64, 0x21, 0xc6, 0x89, 0x74, 0x42, 0x16, 0x04, 0x4c, 0xe8, 0x77, 0x7d, 0x98, 0x9a, 0xa6, 0x99,
+	0xe5, 0xa5, 0x32, 0x90, 0x6f, 0xe1, 0x20, 0x65, 0x0a, 0xf3, 0x03, 0xcc, 0x7d, 0xeb, 0xca, 0xb7,
+	0x15, 0xba, 0x77, 0x63, 0x3f, 0xc0, 0xb2, 0x9f, 0x8f, 0xb3, 0x02, 0x73, 0xaf, 0xf0, 0x3b, 0xc7,
+	0x59, 0x8e, 0x77, 0x0a, 0x47, 0xa9, 0x9f, 0x8c, 0x6f, 0x30, 0x12, 0xce, 0x1c, 0xb9, 0xc3, 0xf1,
+	0x5d, 0x82, 0x42, 0xda, 0x0d, 0xe5, 0x9e, 0xf2, 0x72, 0xaa, 0x8c, 0x97, 0xc8, 0x69, 0x66, 0x4a,
+	0x37, 0x8d, 0xf0, 0xae, 0xd1, 0x57, 0x3b, 0xc2, 0xfa, 0xf0, 0xa6, 0x99, 0x68, 0x67, 0xba, 0x0a,
+	0xeb, 0xfd, 0x69, 0x40, 0xbb, 0xbc, 0x87, 0xca, 0xad, 0x35, 0x36, 0x5b, 0x7b, 0x04, 0xf5, 0xb5,
+	0x27, 0x6c, 0x51, 0x7d, 0x22, 0x4f, 0xa1, 0x9e, 0xdd, 0xf6, 0x3f, 0x9e, 0xa1, 0x8e, 0x20, 0x9f,
+	0x01, 0xe0, 0xdd, 0x9c, 0x71, 0x14, 0x8e, 0x2b, 0xf5, 0x6c, 0x2c, 0xad, 0x79, 0x26, 0x8b, 0xcd,
+	0x5b, 0x5b, 0xdb, 0xbc, 0x47, 0x50, 0xe7, 0xe8, 0x8a, 0x38, 0x52, 0x7d, 0xb7, 0xa8, 0x3e, 0xf5,
+	0xfe, 0x28, 0xea, 0xc9, 0xab, 0xdd, 0x4a, 0x9f, 0x07, 0x50, 0x13, 0xd2, 0xe5, 0x79, 0x11, 0xd9,
+	0x81, 0x7c, 0x07, 0x07, 0x7e, 0xc2, 0x5d, 0xf5, 0xe4, 0xf4, 0xb4, 0x32, 0x06, 0xb5, 0x73, 0xb5,
+	0x1e, 0x57, 0xce, 0xaf, 0xea, 0xbf, 0xf1, 0xab, 0x56, 0xe6, 0x57, 0xef, 0xef, 0x0a, 0x58, 0xc5,
+	0xa2, 0xfe, 0x08, 0x42, 0xaf, 0xba, 0x6e, 0x96, 0xba, 0xfe, 0x33, 0x58, 0x5e, 0x1c, 0xf9, 0x2c,
+	0xcd, 0x4d, 0x65, 0xd3, 0x1e, 0x3e, 0xfa, 0xc0, 0xdf, 0xc4, 0xe0, 0x2c, 0x0f, 0xa0, 0xab, 0x58,
+	0xf2, 0x18, 0x0e, 0xe5, 0x35, 0x47, 0x71, 0x1d, 0x07, 0x7e, 0xca, 0x3f, 0x0f, 0x23, 0xa9, 0xaa,
+	0x30, 0x68, 0xa7, 0x30, 0x5c, 0x66, 0x7a, 0xf2, 0x15, 0xb4, 0x6e, 0x59, 0xe4, 0xc7, 0xb7, 0x65,
+	0xee, 0x37, 0x33, 0xa5, 0xee, 0xd1, 0x97, 0xd0, 0x0c, 0x59, 0x94, 0xf3, 0x38, 0xe7, 0xfd, 0x7e,
+	0xc8, 0x22, 0xcd, 0x5f, 0x41, 0x8e, 0xa1, 0x71, 0x8b, 0xb3, 0xeb, 0x38, 0xbe, 0x11, 0x76, 0xa3,
+	0x6b, 0xf6, 0x2d, 0x5a, 0x9c, 0x7b, 0x8f, 0xc0, 0x2a, 0x12, 0x25, 0x4d, 0x68, 0x4c, 0xc7, 0x2f,
+	0x47, 0x17, 0xaf, 0xa7, 0x93, 0xce, 0x3d, 0xd2, 0x06, 0xc8, 0x76, 0xc7, 0xf8, 0xe2, 0xd5, 0xa4,
+	0x63, 0xcc, 0xea, 0xea, 0x7b, 0xe4, 0xf4, 0x9f, 0x01, 0x00, 0xd3, 0x8b, 0x56, 0xc0, 0xae, 0x08,
+	0x00, 0x00,
 }
@@ -0,0 +1,56 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package quotaservice_configs
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Enums are represented by name when configs are marshalled to YAML or JSON, rather than by number.
+// This isn't generated by protoc, so it lives outside configs.pb.go.
+
+// MarshalText implements encoding.TextMarshaler.
+func (x NamespaceConfig_EvictionPolicy) MarshalText() ([]byte, error) {
+	return []byte(x.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. Both names and numeric values are accepted.
+func (x *NamespaceConfig_EvictionPolicy) UnmarshalText(text []byte) error {
+	if v, ok := NamespaceConfig_EvictionPolicy_value[string(text)]; ok {
+		*x = NamespaceConfig_EvictionPolicy(v)
+		return nil
+	}
+
+	if v, err := strconv.ParseInt(string(text), 10, 32); err == nil {
+		if _, ok := NamespaceConfig_EvictionPolicy_name[int32(v)]; ok {
+			*x = NamespaceConfig_EvictionPolicy(v)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown eviction policy %q", text)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (x AlertRule_Condition) MarshalText() ([]byte, error) {
+	return []byte(x.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. Both names and numeric values are accepted.
+func (x *AlertRule_Condition) UnmarshalText(text []byte) error {
+	if v, ok := AlertRule_Condition_value[string(text)]; ok {
+		*x = AlertRule_Condition(v)
+		return nil
+	}
+
+	if v, err := strconv.ParseInt(string(text), 10, 32); err == nil {
+		if _, ok := AlertRule_Condition_name[int32(v)]; ok {
+			*x = AlertRule_Condition(v)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown alert condition %q", text)
+}
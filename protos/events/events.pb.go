@@ -0,0 +1,167 @@
+// Code generated by protoc-gen-go.
+// source: protos/events/events.proto
+// DO NOT EDIT!
+
+/*
+Package quotaservice_events is a generated protocol buffer package.
+
+It is generated from these files:
+	protos/events/events.proto
+
+It has these top-level messages:
+	Event
+*/
+package quotaservice_events
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+// Mirrors events.EventType.
+type Event_Type int32
+
+const (
+	Event_TOKENS_SERVED             Event_Type = 0
+	Event_TIMEOUT_SERVING_TOKENS    Event_Type = 1
+	Event_TOO_MANY_TOKENS_REQUESTED Event_Type = 2
+	Event_BUCKET_MISS               Event_Type = 3
+	Event_BUCKET_CREATED            Event_Type = 4
+	Event_BUCKET_REMOVED            Event_Type = 5
+	Event_SERVER_ERROR              Event_Type = 6
+	Event_BUCKET_ERROR              Event_Type = 7
+)
+
+var Event_Type_name = map[int32]string{
+	0: "TOKENS_SERVED",
+	1: "TIMEOUT_SERVING_TOKENS",
+	2: "TOO_MANY_TOKENS_REQUESTED",
+	3: "BUCKET_MISS",
+	4: "BUCKET_CREATED",
+	5: "BUCKET_REMOVED",
+	6: "SERVER_ERROR",
+	7: "BUCKET_ERROR",
+}
+var Event_Type_value = map[string]int32{
+	"TOKENS_SERVED":             0,
+	"TIMEOUT_SERVING_TOKENS":    1,
+	"TOO_MANY_TOKENS_REQUESTED": 2,
+	"BUCKET_MISS":               3,
+	"BUCKET_CREATED":            4,
+	"BUCKET_REMOVED":            5,
+	"SERVER_ERROR":              6,
+	"BUCKET_ERROR":              7,
+}
+
+func (x Event_Type) String() string {
+	return proto.EnumName(Event_Type_name, int32(x))
+}
+func (Event_Type) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{0, 0} }
+
+// An event emitted by a quotaservice, as exported to Kafka.
+type Event struct {
+	Type       Event_Type `protobuf:"varint,1,opt,name=type,enum=quotaservice.events.Event_Type" json:"type,omitempty"`
+	Namespace  string     `protobuf:"bytes,2,opt,name=namespace" json:"namespace,omitempty"`
+	Bucket     string     `protobuf:"bytes,3,opt,name=bucket" json:"bucket,omitempty"`
+	Dynamic    bool       `protobuf:"varint,4,opt,name=dynamic" json:"dynamic,omitempty"`
+	NumTokens  int64      `protobuf:"varint,5,opt,name=num_tokens,json=numTokens" json:"num_tokens,omitempty"`
+	WaitMillis int64      `protobuf:"varint,6,opt,name=wait_millis,json=waitMillis" json:"wait_millis,omitempty"`
+	// When the event was exported, in milliseconds since the epoch.
+	TimeMillis int64 `protobuf:"varint,7,opt,name=time_millis,json=timeMillis" json:"time_millis,omitempty"`
+}
+
+func (m *Event) Reset()                    { *m = Event{} }
+func (m *Event) String() string            { return proto.CompactTextString(m) }
+func (*Event) ProtoMessage()               {}
+func (*Event) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
+func (m *Event) GetType() Event_Type {
+	if m != nil {
+		return m.Type
+	}
+	return Event_TOKENS_SERVED
+}
+
+func (m *Event) GetNamespace() string {
+	if m != nil {
+		return m.Namespace
+	}
+	return ""
+}
+
+func (m *Event) GetBucket() string {
+	if m != nil {
+		return m.Bucket
+	}
+	return ""
+}
+
+func (m *Event) GetDynamic() bool {
+	if m != nil {
+		return m.Dynamic
+	}
+	return false
+}
+
+func (m *Event) GetNumTokens() int64 {
+	if m != nil {
+		return m.NumTokens
+	}
+	return 0
+}
+
+func (m *Event) GetWaitMillis() int64 {
+	if m != nil {
+		return m.WaitMillis
+	}
+	return 0
+}
+
+func (m *Event) GetTimeMillis() int64 {
+	if m != nil {
+		return m.TimeMillis
+	}
+	return 0
+}
+
+func init() {
+	proto.RegisterType((*Event)(nil), "quotaservice.events.Event")
+	proto.RegisterEnum("quotaservice.events.Event_Type", Event_Type_name, Event_Type_value)
+}
+
+func init() { proto.RegisterFile("protos/events/events.proto", fileDescriptor0) }
+
+var fileDescriptor0 = []byte{
+	// 335 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x6c, 0xd1, 0xcd, 0x6e, 0xaa, 0x40,
+	0x14, 0xc0, 0xf1, 0x8b, 0x20, 0x5e, 0x8f, 0xad, 0xa5, 0xd3, 0xc4, 0x50, 0x53, 0x23, 0x71, 0xc5,
+	0x8a, 0x26, 0xf5, 0x09, 0xac, 0x4e, 0x1a, 0x63, 0x90, 0x74, 0x18, 0x9b, 0x74, 0x35, 0x41, 0x3a,
+	0x0b, 0xa2, 0x7c, 0x54, 0x06, 0x1b, 0x1f, 0xa7, 0x8f, 0xd0, 0x37, 0x6c, 0x66, 0xc0, 0xb4, 0x8b,
+	0xae, 0xe0, 0xfc, 0xce, 0x3f, 0x39, 0x24, 0xc0, 0xb0, 0x38, 0xe4, 0x22, 0x2f, 0xef, 0xf9, 0x91,
+	0x67, 0xe2, 0xfc, 0xf0, 0x14, 0xa2, 0x9b, 0xf7, 0x2a, 0x17, 0x51, 0xc9, 0x0f, 0xc7, 0x24, 0xe6,
+	0x5e, 0xbd, 0x9a, 0x7c, 0xea, 0xd0, 0xc6, 0xf2, 0x15, 0x4d, 0xc1, 0x10, 0xa7, 0x82, 0xdb, 0x9a,
+	0xa3, 0xb9, 0xfd, 0x87, 0xb1, 0xf7, 0x47, 0xed, 0xa9, 0xd2, 0xa3, 0xa7, 0x82, 0x13, 0x15, 0xa3,
+	0x3b, 0xe8, 0x66, 0x51, 0xca, 0xcb, 0x22, 0x8a, 0xb9, 0xdd, 0x72, 0x34, 0xb7, 0x4b, 0x7e, 0x00,
+	0x0d, 0xc0, 0xdc, 0x56, 0xf1, 0x8e, 0x0b, 0x5b, 0x57, 0xab, 0x66, 0x42, 0x36, 0x74, 0xde, 0x4e,
+	0x59, 0x94, 0x26, 0xb1, 0x6d, 0x38, 0x9a, 0xfb, 0x9f, 0x9c, 0x47, 0x34, 0x02, 0xc8, 0xaa, 0x94,
+	0x89, 0x7c, 0xc7, 0xb3, 0xd2, 0x6e, 0x3b, 0x9a, 0xab, 0x93, 0x6e, 0x56, 0xa5, 0x54, 0x01, 0x1a,
+	0x43, 0xef, 0x23, 0x4a, 0x04, 0x4b, 0x93, 0xfd, 0x3e, 0x29, 0x6d, 0x53, 0xed, 0x41, 0x92, 0xaf,
+	0x44, 0x06, 0x22, 0x49, 0xf9, 0x39, 0xe8, 0xd4, 0x81, 0xa4, 0x3a, 0x98, 0x7c, 0x69, 0x60, 0xc8,
+	0xef, 0x47, 0xd7, 0x70, 0x49, 0x83, 0x15, 0x5e, 0x87, 0x2c, 0xc4, 0xe4, 0x05, 0x2f, 0xac, 0x7f,
+	0x68, 0x08, 0x03, 0xba, 0xf4, 0x71, 0xb0, 0xa1, 0xca, 0x96, 0xeb, 0x27, 0x56, 0x27, 0x96, 0x86,
+	0x46, 0x70, 0x4b, 0x83, 0x80, 0xf9, 0xb3, 0xf5, 0x6b, 0x83, 0x8c, 0xe0, 0xe7, 0x0d, 0x0e, 0x29,
+	0x5e, 0x58, 0x2d, 0x74, 0x05, 0xbd, 0xc7, 0xcd, 0x7c, 0x85, 0x29, 0xf3, 0x97, 0x61, 0x68, 0xe9,
+	0x08, 0x41, 0xbf, 0x81, 0x39, 0xc1, 0x33, 0x19, 0x19, 0xbf, 0x8c, 0x60, 0x3f, 0x90, 0x37, 0xdb,
+	0xc8, 0x82, 0x0b, 0x75, 0x9f, 0x30, 0x4c, 0x48, 0x40, 0x2c, 0x53, 0x4a, 0x53, 0xd5, 0xd2, 0xd9,
+	0x9a, 0xea, 0xff, 0x4d, 0xbf, 0x07, 0x00, 0x9b, 0xbe, 0x38, 0x9c, 0xdd, 0x01, 0x00, 0x00,
+}
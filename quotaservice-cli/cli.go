@@ -10,6 +10,7 @@ import (
 
 	"github.com/alecthomas/kingpin/v2"
 
+	pb "github.com/square/quotaservice/protos/config"
 	"github.com/square/quotaservice/quotaservice-cli/client"
 )
 
@@ -45,8 +46,55 @@ var (
 	updateFile      = update.Flag("file", "File from which to read configs.").Short('f').String()
 	updateNamespace = update.Arg("namespace", "Namespace to update.").String()
 	updateBucket    = update.Arg("bucket", "Bucket to update.").String()
+
+	// lint
+	lint     = app.Command("lint", "Validates a YAML configuration file, without connecting to a server.").Alias("validate")
+	lintFile = lint.Arg("file", "File to validate.").Required().String()
+
+	// diff
+	diff     = app.Command("diff", "Shows how a YAML configuration file differs from the running configuration.")
+	diffFile = diff.Arg("file", "File to compare.").Required().String()
+
+	// apply
+	apply      = app.Command("apply", "Reconciles the running configuration with a YAML configuration file.")
+	applyFile  = apply.Flag("file", "File from which to read configs.").Short('f').Required().String()
+	applyPrune = apply.Flag("prune", "Remove namespaces and buckets missing from the file.").Default("false").Bool()
+	applyYes   = apply.Flag("yes", "Apply changes without asking for confirmation.").Short('y').Default("false").Bool()
+
+	// rollback
+	rollback        = app.Command("rollback", "Replaces the running configuration with a historical version.")
+	rollbackDryRun  = rollback.Flag("dry-run", "Only show the changes rolling back would make.").Short('n').Default("false").Bool()
+	rollbackYes     = rollback.Flag("yes", "Roll back without asking for confirmation.").Short('y').Default("false").Bool()
+	rollbackVersion = rollback.Arg("version", "Version to roll back to.").Required().Int()
+
+	// override
+	override = app.Command("override", "Manages temporary overrides of bucket configs, which are reverted once they expire.")
+
+	overrideAdd          = override.Command("add", "Temporarily overrides fields of a bucket's config, replacing any existing override of the bucket.")
+	overrideAddGDB       = overrideAdd.Flag("globaldefault", "Override the global default bucket.").Short('g').Default("false").Bool()
+	overrideAddFor       = overrideAdd.Flag("for", "How long the override lasts, e.g. 2h.").Required().Duration()
+	overrideAddReason    = overrideAdd.Flag("reason", "Why the bucket is being overridden.").String()
+	overrideAddNamespace = overrideAdd.Arg("namespace", "Namespace of the bucket to override.").String()
+	overrideAddBucket    = overrideAdd.Arg("bucket", "Bucket to override.").String()
+	overrideAddConfig    = &pb.BucketConfig{}
+
+	overrideList = override.Command("list", "Lists overrides.")
+
+	overrideRemove          = override.Command("remove", "Removes the override of a bucket, before it expires.")
+	overrideRemoveGDB       = overrideRemove.Flag("globaldefault", "Remove the override of the global default bucket.").Short('g').Default("false").Bool()
+	overrideRemoveNamespace = overrideRemove.Arg("namespace", "Namespace of the overridden bucket.").String()
+	overrideRemoveBucket    = overrideRemove.Arg("bucket", "Overridden bucket.").String()
 )
 
+func init() {
+	overrideAdd.Flag("size", "Size of the bucket.").Int64Var(&overrideAddConfig.Size)
+	overrideAdd.Flag("fill-rate", "Fill rate of the bucket.").Int64Var(&overrideAddConfig.FillRate)
+	overrideAdd.Flag("wait-timeout-millis", "Maximum time to wait for tokens.").Int64Var(&overrideAddConfig.WaitTimeoutMillis)
+	overrideAdd.Flag("max-idle-millis", "Time after which an idle dynamic bucket is removed.").Int64Var(&overrideAddConfig.MaxIdleMillis)
+	overrideAdd.Flag("max-debt-millis", "Maximum debt the bucket may accumulate.").Int64Var(&overrideAddConfig.MaxDebtMillis)
+	overrideAdd.Flag("max-tokens-per-request", "Maximum tokens a single request may ask for.").Int64Var(&overrideAddConfig.MaxTokensPerRequest)
+}
+
 func RunClient(args []string) {
 	cmd, err := app.Parse(args)
 	c := client.NewQuotaserviceClient(&http.Client{}, *verbose, *host, *port)
@@ -63,6 +111,28 @@ func RunClient(args []string) {
 	case update.FullCommand():
 		c.DoUpdate(*updateGDB, *updateNamespace, *updateBucket, *updateFile)
 		break
+	case lint.FullCommand():
+		c.DoLint(*lintFile)
+		break
+	case diff.FullCommand():
+		c.DoDiff(*diffFile)
+		break
+	case apply.FullCommand():
+		c.DoApply(*applyFile, *applyPrune, *applyYes)
+		break
+	case rollback.FullCommand():
+		c.DoRollback(*rollbackVersion, *rollbackDryRun, *rollbackYes)
+		break
+	case overrideAdd.FullCommand():
+		c.DoOverrideAdd(*overrideAddGDB, *overrideAddNamespace, *overrideAddBucket, overrideAddConfig,
+			*overrideAddFor, *overrideAddReason)
+		break
+	case overrideList.FullCommand():
+		c.DoOverrideList()
+		break
+	case overrideRemove.FullCommand():
+		c.DoOverrideRemove(*overrideRemoveGDB, *overrideRemoveNamespace, *overrideRemoveBucket)
+		break
 	default:
 		kingpin.FatalUsage("Unknown command; should never happen.")
 	}
@@ -5,6 +5,7 @@
 package client
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
 	"fmt"
@@ -12,10 +13,13 @@ import (
 	"io/ioutil"
 	"net/http"
 	"os"
+	"strings"
 
 	"github.com/alecthomas/kingpin/v2"
 
+	"github.com/square/quotaservice/audit"
 	"github.com/square/quotaservice/config"
+	pb "github.com/square/quotaservice/protos/config"
 )
 
 type QuotaserviceClient struct {
@@ -83,6 +87,159 @@ func (c *QuotaserviceClient) DoUpdate(gdb bool, namespace, bucket, file string)
 	_ = resp.Body.Close()
 }
 
+// DoLint validates a YAML configuration file without connecting to a server. Any problems found are
+// printed, and the CLI exits with a non-zero status.
+func (c *QuotaserviceClient) DoLint(file string) {
+	c.logf("Called lint(file=%v)\n", file)
+	c.readValidCfgFile(file)
+	fmt.Printf("%v is valid\n", file)
+}
+
+// DoDiff prints the changes to the server's running configuration that applying a YAML configuration file
+// would make. The CLI exits with a non-zero status if the file is invalid or differs from the running
+// configuration.
+func (c *QuotaserviceClient) DoDiff(file string) {
+	c.logf("Called diff(file=%v)\n", file)
+	cfg := c.readValidCfgFile(file)
+	d := config.DiffConfigs(c.fetchCfg(), cfg)
+
+	if d.Empty() {
+		fmt.Printf("%v matches the running config\n", file)
+		return
+	}
+
+	printDiff(os.Stdout, d)
+	kingpin.Fatalf("%v differs from the running config", file)
+}
+
+// DoApply reconciles the server's running configuration with a YAML configuration file. The changes are shown
+// and, unless yes is set, confirmed before being submitted as a single update. Namespaces and buckets missing
+// from the file are only removed if prune is set.
+func (c *QuotaserviceClient) DoApply(file string, prune, yes bool) {
+	c.logf("Called apply(file=%v, prune=%v, yes=%v)\n", file, prune, yes)
+	cfg := c.readValidCfgFile(file)
+	current := c.fetchCfg()
+	reconciled := config.Reconcile(current, cfg, prune)
+	d := config.DiffConfigs(current, reconciled)
+
+	if d.Empty() {
+		fmt.Println("No changes to apply")
+		return
+	}
+
+	fmt.Printf("Changes to apply to version %v of the running config:\n", current.Version)
+	printDiff(os.Stdout, d)
+
+	if !yes && !c.confirm("Apply these changes?") {
+		fmt.Println("Not applying changes")
+		return
+	}
+
+	cfgBytes, e := json.Marshal(reconciled)
+	kingpin.FatalIfError(e, "Could not serialize config")
+
+	r, e := http.NewRequest("POST", c.createUrl(true, "", ""), bytes.NewReader(cfgBytes))
+	kingpin.FatalIfError(e, "HTTP error")
+	// Fails if someone else changes the config before we submit ours.
+	r.Header.Set("Version", fmt.Sprintf("%v", current.Version))
+
+	resp := c.send(r)
+	_ = resp.Body.Close()
+	fmt.Printf("Applied %v\n", file)
+}
+
+// rollbackResponse is the response to a config rollback request.
+type rollbackResponse struct {
+	CurrentVersion int32              `json:"current_version"`
+	Diff           *config.ConfigDiff `json:"diff"`
+}
+
+// DoRollback replaces the server's running configuration with a historical version. The changes are shown
+// and, unless yes is set, confirmed before being made. If dryRun is set, the changes are only shown.
+func (c *QuotaserviceClient) DoRollback(version int, dryRun, yes bool) {
+	c.logf("Called rollback(version=%v, dryRun=%v, yes=%v)\n", version, dryRun, yes)
+	url := fmt.Sprintf("https://%v:%v/api/configs/%v/rollback", c.host, c.port, version)
+	plan := c.requestRollback(url+"?dry_run=true", nil)
+
+	if plan.Diff.Empty() {
+		fmt.Printf("Version %v is the same as the running config\n", version)
+	} else {
+		fmt.Printf("Changes to roll version %v of the running config back to version %v:\n", plan.CurrentVersion, version)
+		printDiff(os.Stdout, plan.Diff)
+	}
+
+	if dryRun {
+		return
+	}
+
+	if !yes && !c.confirm(fmt.Sprintf("Roll back to version %v?", version)) {
+		fmt.Println("Not rolling back")
+		return
+	}
+
+	// Fails if someone else changes the config after the changes were shown.
+	c.requestRollback(url, &plan.CurrentVersion)
+	fmt.Printf("Rolled back to version %v\n", version)
+}
+
+func (c *QuotaserviceClient) requestRollback(url string, currentVersion *int32) *rollbackResponse {
+	c.logf("Connecting to URL %v\n", url)
+	r, e := http.NewRequest("POST", url, nil)
+	kingpin.FatalIfError(e, "HTTP error")
+
+	if currentVersion != nil {
+		r.Header.Set("Version", fmt.Sprintf("%v", *currentVersion))
+	}
+
+	resp := c.send(r)
+	defer func() { _ = resp.Body.Close() }()
+
+	response := &rollbackResponse{}
+	kingpin.FatalIfError(json.NewDecoder(resp.Body).Decode(response), "Could not parse response from server")
+	return response
+}
+
+// confirm asks a yes/no question on the terminal, returning true if the answer is yes.
+func (c *QuotaserviceClient) confirm(question string) bool {
+	fmt.Printf("%v [y/N]: ", question)
+	answer, e := bufio.NewReader(os.Stdin).ReadString('\n')
+	if e != nil && e != io.EOF {
+		kingpin.FatalIfError(e, "Could not read answer")
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// readValidCfgFile reads a YAML configuration file, exiting if it can't be read or is invalid.
+func (c *QuotaserviceClient) readValidCfgFile(file string) *pb.ServiceConfig {
+	cfg, e := config.ReadConfigFromFileStrict(file)
+	kingpin.FatalIfError(e, "Could not read config from %v", file)
+
+	errs := config.Validate(cfg)
+	for _, err := range errs {
+		fmt.Println(err)
+	}
+
+	if len(errs) > 0 {
+		kingpin.Fatalf("%v is invalid; found %v problem(s)", file, len(errs))
+	}
+
+	return cfg
+}
+
+// fetchCfg retrieves the server's running configuration.
+func (c *QuotaserviceClient) fetchCfg() *pb.ServiceConfig {
+	resp := c.connectToServer("GET", c.createUrl(true, "", ""))
+	defer func() { _ = resp.Body.Close() }()
+	body, e := ioutil.ReadAll(resp.Body)
+	kingpin.FatalIfError(e, "Error reading HTTP response")
+
+	cfg, e := config.FromJSON(body)
+	kingpin.FatalIfError(e, "Could not parse config returned by server")
+	return cfg
+}
+
 func (c *QuotaserviceClient) readCfg(f, namespace, bucket string) []byte {
 	var cfgBytes []byte
 	var e error
@@ -167,6 +324,13 @@ func (c *QuotaserviceClient) connectToServer(method, url string, data ...[]byte)
 	r, e := http.NewRequest(method, url, dataReader)
 	kingpin.FatalIfError(e, "HTTP error")
 
+	return c.send(r)
+}
+
+// send makes an HTTP request, exiting if it fails.
+func (c *QuotaserviceClient) send(r *http.Request) *http.Response {
+	// Identifies the CLI as the source of changes in the server's audit trail.
+	r.Header.Set("User-Agent", audit.CLIUserAgent)
 	resp, e := c.client.Do(r)
 	kingpin.FatalIfError(e, "HTTP error")
 
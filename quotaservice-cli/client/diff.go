@@ -0,0 +1,56 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package client
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/square/quotaservice/config"
+)
+
+var changeMarkers = map[config.ChangeType]string{
+	config.Added:   "+",
+	config.Removed: "-",
+	config.Changed: "~",
+}
+
+// printDiff writes a human-readable description of a config diff, one line per namespace, bucket or field.
+func printDiff(w io.Writer, d *config.ConfigDiff) {
+	if d.GlobalDefaultBucket != nil {
+		printBucketDiff(w, "", "global_default_bucket", d.GlobalDefaultBucket)
+	}
+
+	for _, ns := range d.Namespaces {
+		_, _ = fmt.Fprintf(w, "%v namespace %v\n", changeMarkers[ns.Change], ns.Name)
+		printFieldChanges(w, "    ", ns.Fields)
+
+		for i := range ns.Buckets {
+			b := &ns.Buckets[i]
+			printBucketDiff(w, "  ", bucketLabel(b.Name), b)
+		}
+	}
+}
+
+func printBucketDiff(w io.Writer, indent, label string, b *config.BucketDiff) {
+	_, _ = fmt.Fprintf(w, "%v%v %v\n", indent, changeMarkers[b.Change], label)
+	printFieldChanges(w, indent+"    ", b.Fields)
+}
+
+func printFieldChanges(w io.Writer, indent string, fields []config.FieldChange) {
+	for _, f := range fields {
+		_, _ = fmt.Fprintf(w, "%v%v: %v -> %v\n", indent, f.Field, f.From, f.To)
+	}
+}
+
+func bucketLabel(name string) string {
+	switch name {
+	case config.DefaultBucketName:
+		return "default_bucket"
+	case config.DynamicBucketTemplateName:
+		return "dynamic_bucket_template"
+	default:
+		return "bucket " + name
+	}
+}
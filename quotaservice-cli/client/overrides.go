@@ -0,0 +1,121 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/square/quotaservice/config"
+	pb "github.com/square/quotaservice/protos/config"
+)
+
+// overrideRequest is the body of a request to add an override that expires after a duration.
+type overrideRequest struct {
+	*pb.BucketOverride
+	Duration string `json:"duration"`
+}
+
+// DoOverrideAdd temporarily overrides the non-zero fields of cfg on a bucket, for the given duration.
+func (c *QuotaserviceClient) DoOverrideAdd(gdb bool, namespace, bucket string, cfg *pb.BucketConfig,
+	duration time.Duration, reason string) {
+	namespace, bucket = c.overriddenBucket(gdb, namespace, bucket)
+	c.logf("Called override add(namespace=%v, bucket=%v, duration=%v)\n", namespace, bucket, duration)
+
+	if len(config.BucketConfigChanges(&pb.BucketConfig{}, cfg)) == 0 {
+		kingpin.FatalUsage("Nothing to override; set at least one of the bucket's fields.")
+	}
+
+	b, e := json.Marshal(&overrideRequest{
+		BucketOverride: &pb.BucketOverride{Namespace: namespace, Bucket: bucket, Config: cfg, Reason: reason},
+		Duration:       duration.String()})
+	kingpin.FatalIfError(e, "Could not serialize override")
+
+	resp := c.connectToServer("POST", c.overridesUrl(), b)
+	_ = resp.Body.Close()
+	fmt.Printf("Overrode %v until %v\n", config.FullyQualifiedName(namespace, bucket),
+		time.Now().Add(duration).Format(time.RFC3339))
+}
+
+// DoOverrideList prints the overrides of the running configuration, including any that have expired but
+// haven't been removed yet.
+func (c *QuotaserviceClient) DoOverrideList() {
+	c.logf("Called override list()\n")
+	resp := c.connectToServer("GET", c.overridesUrl())
+	defer func() { _ = resp.Body.Close() }()
+
+	var overrides []*pb.BucketOverride
+	kingpin.FatalIfError(json.NewDecoder(resp.Body).Decode(&overrides), "Could not parse response from server")
+
+	if len(overrides) == 0 {
+		fmt.Println("No overrides")
+		return
+	}
+
+	now := time.Now()
+	for _, o := range overrides {
+		printOverride(os.Stdout, o, now)
+	}
+}
+
+// DoOverrideRemove removes the override of a bucket, reverting it to its configured settings.
+func (c *QuotaserviceClient) DoOverrideRemove(gdb bool, namespace, bucket string) {
+	namespace, bucket = c.overriddenBucket(gdb, namespace, bucket)
+	c.logf("Called override remove(namespace=%v, bucket=%v)\n", namespace, bucket)
+	resp := c.connectToServer("DELETE", c.overridesUrl()+"/"+namespace+"/"+bucket)
+	_ = resp.Body.Close()
+}
+
+// overriddenBucket returns the namespace and name under which overrides address a bucket.
+func (c *QuotaserviceClient) overriddenBucket(gdb bool, namespace, bucket string) (string, string) {
+	c.validate(gdb, namespace, bucket)
+
+	if gdb {
+		return config.GlobalNamespace, config.DefaultBucketName
+	}
+
+	if bucket == "" {
+		kingpin.FatalUsage("Either a namespace and bucket, or --globaldefault, must be given.")
+	}
+
+	return namespace, bucket
+}
+
+func (c *QuotaserviceClient) overridesUrl() string {
+	url := fmt.Sprintf("https://%v:%v/api/overrides", c.host, c.port)
+	c.logf("Connecting to URL %v\n", url)
+	return url
+}
+
+// printOverride writes a human-readable description of an override, followed by the fields it overrides.
+func printOverride(w io.Writer, o *pb.BucketOverride, now time.Time) {
+	expiry := "expires"
+	if config.OverrideExpired(o, now) {
+		expiry = "expired"
+	}
+
+	_, _ = fmt.Fprintf(w, "%v %v %v", config.FullyQualifiedName(o.Namespace, o.Bucket), expiry,
+		time.Unix(o.ExpiresAt, 0).Format(time.RFC3339))
+
+	if o.User != "" {
+		_, _ = fmt.Fprintf(w, " (by %v)", o.User)
+	}
+
+	if o.Reason != "" {
+		_, _ = fmt.Fprintf(w, ": %v", o.Reason)
+	}
+
+	_, _ = fmt.Fprintln(w)
+
+	if o.Config != nil {
+		for _, f := range config.BucketConfigChanges(&pb.BucketConfig{}, o.Config) {
+			_, _ = fmt.Fprintf(w, "    %v: %v\n", f.Field, f.To)
+		}
+	}
+}
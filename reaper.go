@@ -4,11 +4,15 @@
 package quotaservice
 
 import (
+	"container/list"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/square/quotaservice/config"
 	"github.com/square/quotaservice/logging"
 	pbconfig "github.com/square/quotaservice/protos/config"
+	"github.com/square/quotaservice/stats"
 )
 
 // watcher watches reapableBuckets for activity.
@@ -19,14 +23,21 @@ type watcher struct {
 	maxIdle      time.Duration
 	lastActivity time.Time
 	activities   <-chan struct{}
+	// destroyed is set once the bucket being watched has been destroyed by something other than the reaper, e.g.
+	// when it is evicted to make room for another dynamic bucket.
+	destroyed bool
 }
 
 // activityDetected tells you if activity has been detected since the last time this method was
 // called.
 func (w *watcher) activityDetected() bool {
 	select {
-	case <-w.activities:
-		return true
+	case _, ok := <-w.activities:
+		if !ok {
+			// The bucket has been destroyed and its activity channel closed.
+			w.destroyed = true
+		}
+		return ok
 	default:
 		return false
 	}
@@ -42,14 +53,26 @@ func (w *watcher) tooIdle(now time.Time) bool {
 }
 
 // reapableBucket is a wrapper around a bucket that overrides ReportActivity(), and reports any
-// activity on a channel that is monitored by the reaper.
+// activity on a channel that is monitored by the reaper. It also records the time of the most recent
+// activity, and keeps its place in its namespace's activityList, which is used to pick dynamic buckets to
+// evict. The activity channel is nil if the bucket only tracks activity and isn't watched by the reaper.
 type reapableBucket struct {
 	Bucket
 	activities chan<- struct{}
+	// lastActivity is the time of the most recent activity, in nanos since the epoch. Accessed atomically.
+	lastActivity int64
+	// The bucket's element in its namespace's activity list, if activity is tracked
+	activity        *activityList
+	activityElement *list.Element
 }
 
 // ReportActivity is overridden to report activity to the reapableBucket's activity channel.
 func (r *reapableBucket) ReportActivity() {
+	atomic.StoreInt64(&r.lastActivity, time.Now().UnixNano())
+	if r.activity != nil {
+		r.activity.touch(r.activityElement)
+	}
+
 	select {
 	case r.activities <- struct{}{}:
 	// reported activity
@@ -61,9 +84,20 @@ func (r *reapableBucket) ReportActivity() {
 	r.Bucket.ReportActivity()
 }
 
-// Destroy closes the reapableBucket's activity channel.
+// lastActive returns the time of the most recent activity reported on this bucket.
+func (r *reapableBucket) lastActive() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&r.lastActivity))
+}
+
+// Destroy closes the reapableBucket's activity channel, and removes it from its activity list.
 func (r *reapableBucket) Destroy() {
-	close(r.activities)
+	if r.activities != nil {
+		close(r.activities)
+	}
+
+	if r.activity != nil {
+		r.activity.remove(r.activityElement)
+	}
 
 	// Now call Destroy() on the delegate bucket.
 	r.Bucket.Destroy()
@@ -82,6 +116,9 @@ type reaper struct {
 	cfg         config.ReaperConfig
 	newWatchers chan<- *watcher
 	watchers    map[string]*watcher
+	// stats is written by the reaper's goroutine, and may be read concurrently via snapshot().
+	stats     stats.ReaperStats
+	statsLock sync.RWMutex
 }
 
 func newReaper(bc *bucketContainer, r config.ReaperConfig) *reaper {
@@ -89,7 +126,8 @@ func newReaper(bc *bucketContainer, r config.ReaperConfig) *reaper {
 	reaper := &reaper{
 		cfg:         r,
 		watchers:    make(map[string]*watcher),
-		newWatchers: watcherChannel}
+		newWatchers: watcherChannel,
+		stats:       stats.ReaperStats{Reaped: make(map[string]uint64), Frequency: r.InitSleep}}
 
 	go reaper.reapIdleBuckets(bc, watcherChannel)
 
@@ -104,6 +142,39 @@ func (r *reaper) stop() {
 func (r *reaper) addNewWatcher(w *watcher) {
 	r.watchers[w.identifier] = w
 	w.lastActivity = time.Now()
+
+	r.statsLock.Lock()
+	defer r.statsLock.Unlock()
+	r.stats.Watchers = len(r.watchers)
+}
+
+// snapshot returns a copy of the reaper's current stats.
+func (r *reaper) snapshot() *stats.ReaperStats {
+	r.statsLock.RLock()
+	defer r.statsLock.RUnlock()
+
+	s := r.stats
+	s.Reaped = make(map[string]uint64, len(r.stats.Reaped))
+	for ns, reaped := range r.stats.Reaped {
+		s.Reaped[ns] = reaped
+	}
+
+	return &s
+}
+
+// recordSweep updates the reaper's stats after a sweep.
+func (r *reaper) recordSweep(start time.Time, reaped map[string]uint64, frequency time.Duration) {
+	r.statsLock.Lock()
+	defer r.statsLock.Unlock()
+
+	r.stats.Watchers = len(r.watchers)
+	r.stats.Sweeps++
+	r.stats.LastSweep = start
+	r.stats.LastSweepDuration = time.Since(start)
+	r.stats.Frequency = frequency
+	for ns, n := range reaped {
+		r.stats.Reaped[ns] += n
+	}
 }
 
 // checkExpirations checks all watches registered with the reaper, and destroys idle buckets, updating the reaper
@@ -112,11 +183,20 @@ func (r *reaper) checkExpirations(bc *bucketContainer) time.Duration {
 	now := time.Now()
 	newSleep := r.cfg.MinFrequency
 	var reaped uint64
+	reapedByNamespace := make(map[string]uint64)
 	for id, w := range r.watchers {
-		if w.tooIdle(now) {
+		idle := w.tooIdle(now)
+		if w.destroyed {
+			// The bucket has already been removed elsewhere; just stop watching it.
+			delete(r.watchers, id)
+			continue
+		}
+
+		if idle {
 			// Reap bucket
-			reaped++
 			if bc.removeBucket(w.ns, w.bucketName) {
+				reaped++
+				reapedByNamespace[w.ns]++
 				delete(r.watchers, id)
 			}
 		} else if w.maxIdle < newSleep {
@@ -124,7 +204,9 @@ func (r *reaper) checkExpirations(bc *bucketContainer) time.Duration {
 			newSleep = w.maxIdle
 		}
 	}
-	logging.Printf("Reaped %d buckets due to inactivity", reaped)
+	r.recordSweep(now, reapedByNamespace, newSleep)
+	logging.Printf("Reaped %d buckets due to inactivity in %v; %d buckets still watched",
+		reaped, time.Since(now), len(r.watchers))
 	return newSleep
 }
 
@@ -163,16 +245,33 @@ func (r *reaper) reapIdleBuckets(bc *bucketContainer, newWatchers <-chan *watche
 }
 
 // applyWatch decorates a bucket to make it "watchable", if it has a maxIdle and requires garbage
-// collection. Callers should ensure they point to the return value of this method when
-// referencing their bucket.
+// collection. The namespace's reaper overrides, if any, take precedence over the bucket's maxIdle.
+// Callers should ensure they point to the return value of this method when referencing their bucket.
 func (r *reaper) applyWatch(delegate Bucket, namespace, bucketName string, cfg *pbconfig.BucketConfig) (Bucket, *watcher) {
-	if cfg.MaxIdleMillis > 0 {
+	if maxIdle := r.cfg.MaxIdle(namespace, cfg); maxIdle > 0 {
 		activityChannel := make(chan struct{}, 1)
 		rb := &reapableBucket{Bucket: delegate, activities: activityChannel}
-		w := createWatcher(namespace, bucketName, time.Duration(cfg.MaxIdleMillis)*time.Millisecond, activityChannel)
+		w := createWatcher(namespace, bucketName, maxIdle, activityChannel)
 		r.newWatchers <- w
 		return rb, w
 	}
 
 	return delegate, nil
 }
+
+// trackActivity decorates a bucket so the time of its most recent activity can be queried, if it
+// isn't already decorated by applyWatch, and adds it to an activity list if there is one. The bucket
+// isn't registered with the reaper.
+func trackActivity(b Bucket, bucketName string, activity *activityList) *reapableBucket {
+	rb, ok := b.(*reapableBucket)
+	if !ok {
+		rb = &reapableBucket{Bucket: b}
+	}
+
+	if activity != nil && rb.activity == nil {
+		rb.activity = activity
+		rb.activityElement = activity.add(bucketName)
+	}
+
+	return rb
+}
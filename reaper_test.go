@@ -4,11 +4,14 @@
 package quotaservice
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	"github.com/square/quotaservice/config"
 	pbc "github.com/square/quotaservice/protos/config"
+	"github.com/square/quotaservice/stats"
+	"github.com/square/quotaservice/test/helpers"
 )
 
 func TestNotReapable(t *testing.T) {
@@ -88,6 +91,132 @@ func TestIdleAndActivity(t *testing.T) {
 	reaperTeardown(bc)
 }
 
+func TestDestroyedBucketNoLongerWatched(t *testing.T) {
+	_, bc, _ := reaperSetup()
+	// Create watcher but don't let the reaper's goroutine use it.
+	b, ac := createTestReapableBucketNoWatch()
+	w := createWatcher("x", "y", 1000*time.Millisecond, ac)
+
+	// Destroyed by something other than the reaper, e.g. evicted.
+	b.Destroy()
+
+	w.tooIdle(time.Now())
+	if !w.destroyed {
+		t.Fatal("Should have detected the bucket was destroyed")
+	}
+
+	reaperTeardown(bc)
+}
+
+func TestTrackActivity(t *testing.T) {
+	b := trackActivity(&MockBucket{}, "b", nil)
+	if b.activities != nil {
+		t.Fatal("Should not be watched by the reaper")
+	}
+
+	before := time.Now()
+	b.ReportActivity()
+	if b.lastActive().Before(before) {
+		t.Fatalf("Last activity %v should not be before %v", b.lastActive(), before)
+	}
+
+	if trackActivity(b, "b", nil) != b {
+		t.Fatal("Should not decorate a bucket twice")
+	}
+
+	// Shouldn't panic with no activity channel.
+	b.Destroy()
+}
+
+func TestNamespaceMaxIdleOverride(t *testing.T) {
+	rc := NewReaperConfigForTests()
+	rc.Namespaces = map[string]config.NamespaceReaperConfig{
+		"x":        {MaxIdle: time.Hour},
+		"disabled": {MaxIdle: -time.Millisecond}}
+	bc := NewBucketContainer(&MockBucketFactory{}, &MockEmitter{}, rc)
+	defer reaperTeardown(bc)
+
+	c := config.NewDefaultBucketConfig("y")
+	c.MaxIdleMillis = 100
+
+	_, w := bc.r.applyWatch(&MockBucket{}, "x", "y", c)
+	if w == nil || w.maxIdle != time.Hour {
+		t.Fatalf("Expected namespace override of %v; watcher was %+v", time.Hour, w)
+	}
+
+	if b, w := bc.r.applyWatch(&MockBucket{}, "disabled", "y", c); w != nil {
+		t.Fatalf("Should not watch buckets in namespace with reaping disabled; was %T", b)
+	}
+
+	_, w = bc.r.applyWatch(&MockBucket{}, "other", "y", c)
+	if w == nil || w.maxIdle != 100*time.Millisecond {
+		t.Fatalf("Expected bucket max idle of 100ms; watcher was %+v", w)
+	}
+}
+
+func TestReapStaticBuckets(t *testing.T) {
+	cfg := config.NewDefaultServiceConfig()
+	for _, name := range []string{"reaped", "kept"} {
+		ns := config.NewDefaultNamespaceConfig(name)
+		b := config.NewDefaultBucketConfig("b")
+		b.MaxIdleMillis = 50
+		helpers.PanicError(config.AddBucket(ns, b))
+		helpers.PanicError(config.AddNamespace(cfg, ns))
+	}
+
+	rc := NewReaperConfigForTests()
+	rc.ReapStaticBuckets = true
+	keep := false
+	rc.Namespaces = map[string]config.NamespaceReaperConfig{"kept": {ReapStaticBuckets: &keep}}
+	bc := NewBucketContainer(&MockBucketFactory{}, &MockEmitter{}, rc)
+	defer reaperTeardown(bc)
+	helpers.CheckError(t, bc.Init(cfg))
+
+	// Reaper should run every 100ms. Make sure it runs at least once.
+	time.Sleep(300 * time.Millisecond)
+
+	if bc.Exists("reaped", "b") {
+		t.Fatal("Idle static bucket should have been reaped")
+	}
+
+	if !bc.Exists("kept", "b") {
+		t.Fatal("Static bucket should not have been reaped")
+	}
+
+	if b, err := bc.FindBucket(context.Background(), "reaped", "b"); b == nil || err != nil {
+		t.Fatalf("Reaped static bucket should have been re-created; got %v, %v", b, err)
+	}
+
+	if b, _ := bc.FindBucket(context.Background(), "reaped", "nonexistent"); b != nil {
+		t.Fatalf("Should not create buckets that aren't configured; got %v", b)
+	}
+
+	s := bc.r.snapshot()
+	if s.Reaped["reaped"] != 1 || s.Reaped["kept"] != 0 {
+		t.Fatalf("Unexpected reaped counts %+v", s.Reaped)
+	}
+
+	if s.Sweeps == 0 || s.LastSweep.IsZero() {
+		t.Fatalf("Expected sweeps to be recorded; stats were %+v", s)
+	}
+}
+
+func TestReaperStatsSnapshot(t *testing.T) {
+	// Not started, so the reaper's goroutine doesn't record sweeps of its own.
+	r := &reaper{watchers: make(map[string]*watcher), stats: stats.ReaperStats{Reaped: make(map[string]uint64)}}
+	r.recordSweep(time.Now(), map[string]uint64{"x": 2}, time.Second)
+	s := r.snapshot()
+	s.Reaped["x"] = 100
+
+	if r.snapshot().Reaped["x"] != 2 {
+		t.Fatal("Snapshot should not share state with the reaper")
+	}
+
+	if s.Frequency != time.Second || s.Sweeps != 1 {
+		t.Fatalf("Unexpected stats %+v", s)
+	}
+}
+
 func createTestReapableBucket(maxIdle int64, bc *bucketContainer) (*reapableBucket, *watcher) {
 	tb := &MockBucket{}
 	c := config.NewDefaultBucketConfig("y")
@@ -28,8 +28,8 @@ type RpcEndpoint interface {
 	// Init will be called before the quotaservice starts, so the RPC subsystem can initialize.
 	Init(qs QuotaService)
 
-	// Start will be called after quotaservice has started.
-	Start()
+	// Start will be called after quotaservice has started. Errors fail the quotaservice's Start.
+	Start() error
 
 	// Stop will be called before the quotaservice stops.
 	Stop()
@@ -15,6 +15,8 @@ import (
 	"github.com/square/quotaservice/lifecycle"
 	"github.com/square/quotaservice/logging"
 	pb "github.com/square/quotaservice/protos"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/grpclog"
@@ -46,24 +48,26 @@ func (g *GrpcEndpoint) Init(qs quotaservice.QuotaService) {
 	g.qs = qs
 }
 
-func (g *GrpcEndpoint) Start() {
+func (g *GrpcEndpoint) Start() error {
 	lis, err := net.Listen("tcp", g.hostport)
 	if err != nil {
-		logging.Fatalf("Cannot start server on port %v. Error %v", g.hostport, err)
+		return fmt.Errorf("cannot start server on port %v: %v", g.hostport, err)
 	}
 
 	grpclog.SetLogger(logging.CurrentLogger())
-	g.grpcServer = grpc.NewServer()
+	// Spans of incoming requests continue any trace context propagated by the client.
+	g.grpcServer = grpc.NewServer(grpc.StatsHandler(otelgrpc.NewServerHandler()))
 	// Each service should be registered
 	pb.RegisterQuotaServiceServer(g.grpcServer, g)
 	go func() {
 		if e := g.grpcServer.Serve(lis); e != nil {
-			logging.Fatalf("Cannot start gRPC server. Error %v", e)
+			logging.Error("gRPC server stopped serving", "hostport", g.hostport, "error", e)
 		}
 	}()
 	g.currentStatus = lifecycle.Started
 	logging.Printf("Starting server on %v", g.hostport)
 	logging.Printf("Server status: %v", g.currentStatus)
+	return nil
 }
 
 func (g *GrpcEndpoint) Stop() {
@@ -85,6 +89,13 @@ func (g *GrpcEndpoint) Allow(ctx context.Context, req *pb.AllowRequest) (*pb.All
 
 	wait, dynamic, err := g.qs.Allow(ctx, req.Namespace, req.BucketName, tokensRequested, req.MaxWaitMillisOverride, req.MaxWaitTimeOverride)
 
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(
+		quotaservice.NamespaceAttribute.String(req.Namespace),
+		quotaservice.BucketAttribute.String(req.BucketName),
+		quotaservice.TokensAttribute.Int64(tokensRequested),
+		quotaservice.OutcomeAttribute.String(quotaservice.Outcome(err)))
+
 	if err != nil {
 		if qsErr, ok := err.(quotaservice.QuotaServiceError); ok {
 			rsp.Status = toPBStatus(qsErr)
@@ -98,6 +109,7 @@ func (g *GrpcEndpoint) Allow(ctx context.Context, req *pb.AllowRequest) (*pb.All
 			return rsp, nil
 		}
 
+		span.RecordError(err)
 		g.producer.Emit(events.NewServerErrorEvent(req.Namespace, req.BucketName, dynamic))
 	}
 
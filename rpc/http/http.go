@@ -29,8 +29,9 @@ func (h *HttpEndpoint) Init(qs quotaservice.QuotaService) {
 	h.qs = qs
 }
 
-func (h *HttpEndpoint) Start() {
+func (h *HttpEndpoint) Start() error {
 	h.currentStatus = lifecycle.Started
+	return nil
 }
 
 func (h *HttpEndpoint) Stop() {
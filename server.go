@@ -14,12 +14,15 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/square/quotaservice/admin"
+	"github.com/square/quotaservice/alerts"
+	"github.com/square/quotaservice/audit"
 	"github.com/square/quotaservice/config"
 	"github.com/square/quotaservice/events"
 	"github.com/square/quotaservice/lifecycle"
 	"github.com/square/quotaservice/logging"
 	pb "github.com/square/quotaservice/protos/config"
 	"github.com/square/quotaservice/stats"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Implements the quotaservice.Server interface
@@ -30,15 +33,66 @@ type server struct {
 	rpcEndpoints      []RpcEndpoint
 	listener          events.Listener
 	statsListener     stats.Listener
+	metricsHandler    http.Handler
 	eventQueueBufSize int
 	maxJitterMillis   int
-	producer          *events.EventProducer
+	listeners         []*namedListener // Guarded by listenersLock
+	listenersLock     sync.RWMutex
+	broadcaster       *events.Broadcaster
+	broadcasterOnce   sync.Once
+	broadcasterErr    error
+	alerter           *alerts.Alerter
+	alerterStarted    bool
+	auditSink         audit.Sink
 	cfgs              *pb.ServiceConfig
+	cfgsHash          string
 	persister         config.ConfigPersister
 	reaperConfig      config.ReaperConfig
+	overrideTimer     *time.Timer
+	overrideRetry     time.Duration // Delay before retrying to remove expired overrides, after failing to
+	scheduleTimer     *time.Timer
+	stopped           bool
 	sync.RWMutex      // Embedded mutex
 }
 
+// The user recorded against configs updated by the server itself, rather than through the admin console.
+const serverUser = "quotaservice"
+
+var serverActor = audit.Actor{User: serverUser, Source: audit.SERVER}
+
+// Names of the listeners set with SetListener and SetStatsListener.
+const (
+	defaultListenerName = "default"
+	statsListenerName   = "stats"
+)
+
+// Event streams, such as the admin console's, share a listener that passes events on to each of them.
+const (
+	streamListenerName = "stream"
+	streamQueueBufSize = 1000
+	maxEventStreams    = 10
+)
+
+// The alerter only listens for events once the config has alert rules.
+const (
+	alertsListenerName = "alerts"
+	alertsQueueBufSize = 1000
+)
+
+// namedListener is an event listener with its own queue, fed by the server.
+type namedListener struct {
+	name     string
+	producer *events.EventProducer
+}
+
+var errNoExpiredOverrides = errors.New("no expired overrides")
+
+// Bounds on the delay before retrying to remove expired overrides; variables so that tests can shorten them.
+var (
+	minOverrideExpiryRetry = time.Second
+	maxOverrideExpiryRetry = time.Minute
+)
+
 func (s *server) String() string {
 	return fmt.Sprintf("Quota Server running with status %v", s.currentStatus)
 }
@@ -50,19 +104,23 @@ func (s *server) Start() (bool, error) {
 		bufSize = 1
 	}
 
-	// Set up listeners
-	s.producer = events.RegisterListener(func(e events.Event) {
-		if s.listener != nil {
-			s.listener(e)
+	// Set up listeners, each with its own queue so that a slow listener doesn't cause the other to drop events.
+	if s.listener != nil {
+		if err := s.AddListener(defaultListenerName, s.listener, bufSize, events.DROP_NEWEST); err != nil {
+			return false, err
 		}
+	}
 
-		if s.statsListener != nil {
-			s.statsListener.HandleEvent(e)
+	if s.statsListener != nil {
+		if err := s.AddListener(statsListenerName, s.statsListener.HandleEvent, bufSize, events.DROP_NEWEST); err != nil {
+			return false, err
 		}
-	}, bufSize)
+	}
 
 	logging.Printf("Creating bucket container")
-	s.createBucketContainer()
+	if err := s.createBucketContainer(); err != nil {
+		return false, err
+	}
 	logging.Printf("Creating bucket container: OK")
 
 	logging.Printf("Waiting for persister to start")
@@ -70,7 +128,9 @@ func (s *server) Start() (bool, error) {
 	logging.Printf("Waiting for persister to start: OK")
 
 	logging.Printf("Reading latest config")
-	s.readUpdatedConfig(0)
+	if err := s.readUpdatedConfig(0); err != nil {
+		return false, err
+	}
 	logging.Printf("Reading latest config: OK")
 
 	go s.configListener(s.persister.ConfigChangedWatcher())
@@ -79,7 +139,9 @@ func (s *server) Start() (bool, error) {
 	logging.Printf("Starting RPC servers")
 	for _, rpcServer := range s.rpcEndpoints {
 		rpcServer.Init(s)
-		rpcServer.Start()
+		if err := rpcServer.Start(); err != nil {
+			return false, errors.Wrap(err, "failed to start RPC server")
+		}
 	}
 	logging.Printf("Starting RPC servers: OK")
 
@@ -95,6 +157,16 @@ func (s *server) Stop() (bool, error) {
 		rpcServer.Stop()
 	}
 
+	s.Lock()
+	s.stopped = true
+	s.alerter.Stop()
+	for _, timer := range []*time.Timer{s.overrideTimer, s.scheduleTimer} {
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+	s.Unlock()
+
 	// Referencing s.bucketContainer should be guarded
 	s.RLock()
 	defer s.RUnlock()
@@ -103,9 +175,13 @@ func (s *server) Stop() (bool, error) {
 	return true, nil
 }
 
-func (s *server) Allow(ctx context.Context, namespace, name string, tokensRequested int64, maxWaitMillisOverride int64, maxWaitTimeOverride bool) (time.Duration, bool, error) {
+func (s *server) Allow(ctx context.Context, namespace, name string, tokensRequested int64, maxWaitMillisOverride int64, maxWaitTimeOverride bool) (wait time.Duration, dynamic bool, err error) {
+	ctx, span := tracer.Start(ctx, "server.Allow", trace.WithAttributes(
+		NamespaceAttribute.String(namespace), BucketAttribute.String(name), TokensAttribute.Int64(tokensRequested)))
+	defer func() { endAllowSpan(span, wait, dynamic, err) }()
+
 	s.RLock()
-	b, e := s.bucketContainer.FindBucket(namespace, name)
+	b, e := s.bucketContainer.FindBucket(ctx, namespace, name)
 	s.RUnlock()
 
 	if e != nil {
@@ -154,6 +230,14 @@ func (s *server) Allow(ctx context.Context, namespace, name string, tokensReques
 
 func (s *server) ServeAdminConsole(mux *http.ServeMux, assetsDir string, development bool) {
 	admin.ServeAdminConsole(s, mux, assetsDir, development)
+
+	if s.metricsHandler != nil {
+		mux.Handle("/metrics", s.metricsHandler)
+	}
+}
+
+func (s *server) SetMetricsHandler(handler http.Handler) {
+	s.metricsHandler = handler
 }
 
 func (s *server) SetLogger(logger logging.Logger) {
@@ -163,6 +247,13 @@ func (s *server) SetLogger(logger logging.Logger) {
 	logging.SetLogger(logger)
 }
 
+func (s *server) SetStructuredLogger(logger logging.StructuredLogger) {
+	if s.currentStatus == lifecycle.Started {
+		panic("Cannot set logger after server has started!")
+	}
+	logging.SetStructuredLogger(logger)
+}
+
 func (s *server) SetStatsListener(listener stats.Listener) {
 	if s.currentStatus == lifecycle.Started {
 		panic("Cannot add listener after server has started!")
@@ -171,6 +262,14 @@ func (s *server) SetStatsListener(listener stats.Listener) {
 	s.statsListener = listener
 }
 
+func (s *server) SetAuditSink(sink audit.Sink) {
+	if s.currentStatus == lifecycle.Started {
+		panic("Cannot set audit sink after server has started!")
+	}
+
+	s.auditSink = sink
+}
+
 func (s *server) SetListener(listener events.Listener, eventQueueBufSize int) {
 	if s.currentStatus == lifecycle.Started {
 		panic("Cannot add listener after server has started!")
@@ -184,9 +283,40 @@ func (s *server) SetListener(listener events.Listener, eventQueueBufSize int) {
 	s.eventQueueBufSize = eventQueueBufSize
 }
 
+func (s *server) AddListener(name string, listener events.Listener, bufSize int, dropPolicy events.DropPolicy) error {
+	if listener == nil {
+		return errors.New("Cannot add a nil listener")
+	}
+
+	if bufSize < 1 {
+		return errors.New("Event queue buffer size must be greater than 0")
+	}
+
+	s.listenersLock.Lock()
+	defer s.listenersLock.Unlock()
+
+	for _, l := range s.listeners {
+		if l.name == name {
+			return errors.Errorf("Listener %v already exists", name)
+		}
+	}
+
+	// Copy on write, since Emit iterates over the listeners without holding the lock.
+	listeners := make([]*namedListener, len(s.listeners), len(s.listeners)+1)
+	copy(listeners, s.listeners)
+	s.listeners = append(listeners, &namedListener{
+		name:     name,
+		producer: events.RegisterListenerWithDropPolicy(listener, bufSize, dropPolicy)})
+	return nil
+}
+
 func (s *server) Emit(e events.Event) {
-	if s.producer != nil {
-		s.producer.Emit(e)
+	s.listenersLock.RLock()
+	listeners := s.listeners
+	s.listenersLock.RUnlock()
+
+	for _, l := range listeners {
+		l.producer.Emit(e)
 	}
 }
 
@@ -197,61 +327,104 @@ func (s *server) configListener(ch <-chan struct{}) {
 			// Pick a random number between 0 and maxJitterMillis
 			jitter = rand.Intn(s.maxJitterMillis)
 		}
-		s.readUpdatedConfig(time.Duration(jitter) * time.Millisecond)
+		if err := s.readUpdatedConfig(time.Duration(jitter) * time.Millisecond); err != nil {
+			logging.Error("Failed to update config", "error", err)
+		}
 	}
 }
 
-func (s *server) readUpdatedConfig(jitter time.Duration) {
+func (s *server) readUpdatedConfig(jitter time.Duration) error {
 	newConfig, err := s.persister.ReadPersistedConfig()
 
 	if err != nil {
-		logging.Println("error reading persisted config", err)
-		return
+		return errors.Wrap(err, "error reading persisted config")
 	}
 
 	if jitter != 0 {
 		time.Sleep(jitter)
 	}
 
-	s.updateBucketContainer(newConfig)
+	return s.updateBucketContainer(newConfig)
 }
 
-func (s *server) createBucketContainer() {
+func (s *server) createBucketContainer() error {
 	s.Lock()
 	defer s.Unlock()
 
 	if s.bucketContainer != nil {
-		logging.Fatalf("A bucketcontainer already exists; this shouldn't happen. BucketContainer=%v", s.bucketContainer)
+		return errors.Errorf("A bucketcontainer already exists; this shouldn't happen. BucketContainer=%v", s.bucketContainer)
 	}
 	s.bucketContainer = NewBucketContainer(s.bucketFactory, s, s.reaperConfig)
+	return nil
 }
 
-func (s *server) updateBucketContainer(newConfig *pb.ServiceConfig) {
+func (s *server) updateBucketContainer(newConfig *pb.ServiceConfig) error {
 	s.Lock()
 	defer s.Unlock()
 
 	// Guard against updating the existing config with a lower valued version number
 	if s.cfgs != nil && newConfig.Version <= s.cfgs.Version {
-		logging.Printf("Proposed config version %d is lower than existing config version %d. Ignoring.",
-			newConfig.Version, s.cfgs.Version)
-		return
+		logging.Warn("Ignoring config with a version lower than the existing config",
+			"version", newConfig.Version, "existingVersion", s.cfgs.Version)
+		return nil
 	}
 
 	s.bucketContainer.Lock()
 	defer s.bucketContainer.Unlock()
 
+	// Set the new config on the the server. Hash it first, since initializing buckets may modify it.
+	oldCfgs, oldHash := s.cfgs, s.cfgsHash
+	s.cfgsHash = config.HashConfig(newConfig)
+	s.cfgs = newConfig
+
+	if err := s.applyConfigLocked(false); err != nil {
+		// Keep using the config the buckets were initialized with.
+		s.cfgs, s.cfgsHash = oldCfgs, oldHash
+		return errors.Wrapf(err, "failed to apply config version %d", newConfig.Version)
+	}
+
+	s.scheduleOverrideExpiryLocked()
+	s.updateAlertRulesLocked()
+	return nil
+}
+
+// updateAlertRulesLocked passes the config's alert rules on to the alerter, starting it the first time there are
+// any. The server must be locked.
+func (s *server) updateAlertRulesLocked() {
+	if !s.alerterStarted && !s.stopped && len(s.cfgs.AlertRules) > 0 {
+		if err := s.AddListener(alertsListenerName, s.alerter.HandleEvent, alertsQueueBufSize,
+			events.DROP_NEWEST); err != nil {
+			logging.Error("Unable to start alerting", "error", err)
+			return
+		}
+
+		s.alerter.Start()
+		s.alerterStarted = true
+	}
+
+	s.alerter.SetRules(s.cfgs.AlertRules)
+}
+
+// applyConfigLocked brings the bucket container in line with the effective config: s.cfgs with any schedules and
+// overrides in effect applied. The server keeps the config as persisted, and applies it again whenever a schedule
+// starts or ends. Reapply is set when s.cfgs hasn't changed since it was last applied, so that only the settings of
+// buckets can differ. Both the server and its bucket container must be locked.
+func (s *server) applyConfigLocked(reapply bool) error {
+	now := time.Now()
+	newConfig := config.ApplyOverrides(config.ApplySchedules(s.cfgs, now), now)
+
 	// Initialize buckets
-	s.bucketFactory.Init(newConfig)
+	if err := s.bucketFactory.Init(newConfig); err != nil {
+		return errors.Wrap(err, "failed to initialize bucket factory")
+	}
+
+	s.scheduleNextScheduleChangeLocked(now)
 
 	// If there is no existing config, then this bucket container is brand-new and hasn't been used before.
 	firstTime := s.bucketContainer.cfg == nil
 
-	// Set the new config on the the server
-	s.cfgs = newConfig
-
 	if firstTime {
-		s.bucketContainer.initLocked(newConfig)
-		return
+		return s.bucketContainer.initLocked(newConfig)
 	}
 
 	s.bucketContainer.cfg = newConfig
@@ -272,7 +445,7 @@ func (s *server) updateBucketContainer(newConfig *pb.ServiceConfig) {
 		if newConfig.GlobalDefaultBucket == nil {
 			s.bucketContainer.defaultBucket = nil
 		} else {
-			s.bucketContainer.createGlobalDefaultBucketLocked(s.cfgs.GlobalDefaultBucket)
+			s.bucketContainer.createGlobalDefaultBucketLocked(newConfig.GlobalDefaultBucket)
 		}
 	}
 
@@ -281,11 +454,14 @@ func (s *server) updateBucketContainer(newConfig *pb.ServiceConfig) {
 	// changed, throw away the old namespace and recreate it. We *could* scan all the buckets in a namespace
 	// and only recreate the ones that have changed, but this may have little benefit, since the real cost
 	// here is with dynamic buckets, and if the namespace config has changed, it's very likely that the
-	// change involves the dynamic bucket template.
+	// change involves the dynamic bucket template. That isn't so when reapplying the same config, though:
+	// schedules usually apply to a few named buckets, and switch often.
 	for name, ns := range s.bucketContainer.namespaces {
 		newNsCfg, exists := newConfig.Namespaces[name]
 		if exists {
-			if config.DifferentNamespaceConfigs(ns.cfg, newNsCfg) {
+			if reapply {
+				s.bucketContainer.replaceChangedBucketsLocked(ns, newNsCfg)
+			} else if config.DifferentNamespaceConfigs(ns.cfg, newNsCfg) {
 				// We need to destroy the old namespace before overwriting.
 				ns.destroy()
 				// This will overwrite the existing namespace
@@ -306,28 +482,187 @@ func (s *server) updateBucketContainer(newConfig *pb.ServiceConfig) {
 			s.bucketContainer.createNamespaceLocked(nsCfg)
 		}
 	}
+
+	return nil
+}
+
+// scheduleNextScheduleChangeLocked sets a timer to apply the config again when a bucket's schedule next starts
+// or ends.
+func (s *server) scheduleNextScheduleChangeLocked(now time.Time) {
+	if s.scheduleTimer != nil {
+		s.scheduleTimer.Stop()
+		s.scheduleTimer = nil
+	}
+
+	if next := config.NextScheduleChange(s.cfgs, now); !next.IsZero() {
+		s.scheduleTimer = time.AfterFunc(next.Sub(now), s.switchSchedules)
+	}
+}
+
+// switchSchedules switches buckets to or from their schedules' settings, without changing the config's version.
+func (s *server) switchSchedules() {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.stopped {
+		return
+	}
+
+	s.bucketContainer.Lock()
+	defer s.bucketContainer.Unlock()
+
+	logging.Print("Switching bucket schedules")
+	if err := s.applyConfigLocked(true); err != nil {
+		logging.Error("Failed to switch bucket schedules", "error", err)
+	}
+}
+
+// scheduleOverrideExpiryLocked sets a timer to remove overrides from the config once they expire.
+func (s *server) scheduleOverrideExpiryLocked() {
+	if s.overrideTimer != nil {
+		s.overrideTimer.Stop()
+		s.overrideTimer = nil
+	}
+
+	if next := config.NextOverrideExpiry(s.cfgs); !next.IsZero() {
+		s.overrideTimer = time.AfterFunc(time.Until(next), s.removeExpiredOverrides)
+	}
 }
 
-func (s *server) updateConfig(user string, updater func(*pb.ServiceConfig) error) error {
+// removeExpiredOverrides reverts the buckets overridden by overrides that have expired, and persists a new version
+// of the config without them. Every server in a cluster will try to do so; all but the first fail with a conflict,
+// and pick up the new version from the persister instead. Other failures are retried, backing off.
+func (s *server) removeExpiredOverrides() {
 	s.Lock()
+	if s.stopped {
+		s.Unlock()
+		return
+	}
+
+	// Expired overrides aren't applied, so applying the config again reverts the buckets right away, even if the
+	// new version can't be persisted.
+	s.bucketContainer.Lock()
+	if err := s.applyConfigLocked(true); err != nil {
+		logging.Error("Failed to revert overridden buckets", "error", err)
+	}
+	s.bucketContainer.Unlock()
+	s.Unlock()
+
+	err := s.updateConfig(config.AnyVersion, serverActor, "expire_overrides", "", func(clonedCfg *pb.ServiceConfig) error {
+		if !config.RemoveExpiredOverrides(clonedCfg, time.Now()) {
+			return errNoExpiredOverrides
+		}
+
+		return nil
+	})
+
+	switch {
+	case err == nil:
+		logging.Print("Removed expired overrides")
+	case err == errNoExpiredOverrides:
+	case config.IsConfigConflict(err):
+		logging.Print("Expired overrides were removed concurrently")
+	default:
+		s.retryOverrideExpiry(err)
+		return
+	}
+
+	s.Lock()
+	s.overrideRetry = 0
+	s.Unlock()
+}
+
+// retryOverrideExpiry sets a timer to try removing expired overrides again, after failing to, doubling the delay
+// with each failure up to maxOverrideExpiryRetry.
+func (s *server) retryOverrideExpiry(err error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.stopped {
+		return
+	}
+
+	s.overrideRetry *= 2
+	if s.overrideRetry < minOverrideExpiryRetry {
+		s.overrideRetry = minOverrideExpiryRetry
+	} else if s.overrideRetry > maxOverrideExpiryRetry {
+		s.overrideRetry = maxOverrideExpiryRetry
+	}
+
+	logging.Error("Unable to remove expired overrides", "error", err, "retryIn", s.overrideRetry)
+
+	if s.overrideTimer != nil {
+		s.overrideTimer.Stop()
+	}
+
+	s.overrideTimer = time.AfterFunc(s.overrideRetry, s.removeExpiredOverrides)
+}
+
+// updateConfig persists a new version of the config, as changed by updater, recording the change in the audit
+// trail. Unless expectedVersion is config.AnyVersion, the change fails with a *config.ConfigConflictError if the
+// current config has another version. Changes the server fails to make itself aren't recorded, as they're expected
+// when several servers try to make the same change.
+func (s *server) updateConfig(expectedVersion int32, actor audit.Actor, action, target string,
+	updater func(*pb.ServiceConfig) error) (err error) {
+	// The expected version is checked against the same config as is hashed, so that the persister rejects the change
+	// if another is persisted after the check.
+	s.Lock()
+	currentCfg := s.cfgs
 	clonedCfg := config.CloneConfig(s.cfgs)
 	currentVersion := clonedCfg.Version
+	oldHash := s.cfgsHash
 	s.Unlock()
 
-	err := updater(clonedCfg)
+	entry := &audit.Entry{Actor: actor, Action: action, Target: target}
+	defer func() {
+		if err == nil || actor.Source != audit.SERVER {
+			s.recordAudit(entry, err)
+		}
+	}()
 
-	if err != nil {
+	if expectedVersion != config.AnyVersion && expectedVersion != currentVersion {
+		return &config.ConfigConflictError{ExpectedVersion: expectedVersion, ActualVersion: currentVersion}
+	}
+
+	if err = updater(clonedCfg); err != nil {
 		return err
 	}
 
 	config.ApplyDefaults(clonedCfg)
 
-	clonedCfg.User = user
+	if errs := config.Validate(clonedCfg); len(errs) > 0 {
+		return config.ValidationErrors(errs)
+	}
+
+	clonedCfg.User = actor.User
 	clonedCfg.Date = time.Now().Unix()
 	clonedCfg.Version = currentVersion + 1
 
-	// TODO(manik) make use of the old hash for an optimistic version check
-	return s.persister.PersistAndNotify("", clonedCfg)
+	// Fails with a *config.ConfigConflictError if someone else has persisted a config since we read ours.
+	if err = s.persister.PersistAndNotify(oldHash, clonedCfg); err != nil {
+		return err
+	}
+
+	entry.Version = clonedCfg.Version
+	entry.Changes = audit.Diff(currentCfg, clonedCfg)
+	return nil
+}
+
+// recordAudit appends an entry to the audit trail, if there is one. Failing to do so is logged, rather than
+// failing the action, which has already been taken.
+func (s *server) recordAudit(entry *audit.Entry, err error) {
+	if s.auditSink == nil {
+		return
+	}
+
+	entry.Time = time.Now()
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	if e := s.auditSink.Record(entry); e != nil {
+		logging.Error("Unable to record audit entry", "action", entry.Action, "user", entry.User, "error", e)
+	}
 }
 
 // Implements admin.Administrable
@@ -337,71 +672,180 @@ func (s *server) Configs() *pb.ServiceConfig {
 	return s.cfgs
 }
 
-func (s *server) UpdateConfig(c *pb.ServiceConfig, user string) error {
-	return s.updateConfig(user, func(clonedCfg *pb.ServiceConfig) error {
+func (s *server) UpdateConfig(c *pb.ServiceConfig, expectedVersion int32, actor audit.Actor) error {
+	return s.updateConfig(expectedVersion, actor, "update_config", "", func(clonedCfg *pb.ServiceConfig) error {
 		*clonedCfg = *c
 		return nil
 	})
 }
 
-func (s *server) AddBucket(namespace string, b *pb.BucketConfig, user string) error {
-	return s.updateConfig(user, func(clonedCfg *pb.ServiceConfig) error {
+func (s *server) AddBucket(namespace string, b *pb.BucketConfig, expectedVersion int32, actor audit.Actor) error {
+	target := config.FullyQualifiedName(namespace, b.Name)
+
+	return s.updateConfig(expectedVersion, actor, "add_bucket", target, func(clonedCfg *pb.ServiceConfig) error {
 		return config.CreateBucket(clonedCfg, namespace, b)
 	})
 }
 
-func (s *server) UpdateBucket(namespace string, b *pb.BucketConfig, user string) error {
-	return s.updateConfig(user, func(clonedCfg *pb.ServiceConfig) error {
+func (s *server) UpdateBucket(namespace string, b *pb.BucketConfig, expectedVersion int32, actor audit.Actor) error {
+	target := config.FullyQualifiedName(namespace, b.Name)
+
+	return s.updateConfig(expectedVersion, actor, "update_bucket", target, func(clonedCfg *pb.ServiceConfig) error {
 		return config.UpdateBucket(clonedCfg, namespace, b)
 	})
 }
 
-func (s *server) DeleteBucket(namespace, name, user string) error {
-	return s.updateConfig(user, func(clonedCfg *pb.ServiceConfig) error {
+func (s *server) DeleteBucket(namespace, name string, expectedVersion int32, actor audit.Actor) error {
+	target := config.FullyQualifiedName(namespace, name)
+
+	return s.updateConfig(expectedVersion, actor, "delete_bucket", target, func(clonedCfg *pb.ServiceConfig) error {
 		return config.DeleteBucket(clonedCfg, namespace, name)
 	})
 }
 
-func (s *server) AddNamespace(n *pb.NamespaceConfig, user string) error {
-	return s.updateConfig(user, func(clonedCfg *pb.ServiceConfig) error {
+func (s *server) AddNamespace(n *pb.NamespaceConfig, expectedVersion int32, actor audit.Actor) error {
+	return s.updateConfig(expectedVersion, actor, "add_namespace", n.Name, func(clonedCfg *pb.ServiceConfig) error {
 		return config.CreateNamespace(clonedCfg, n)
 	})
 }
 
-func (s *server) UpdateNamespace(n *pb.NamespaceConfig, user string) error {
-	return s.updateConfig(user, func(clonedCfg *pb.ServiceConfig) error {
+func (s *server) UpdateNamespace(n *pb.NamespaceConfig, expectedVersion int32, actor audit.Actor) error {
+	return s.updateConfig(expectedVersion, actor, "update_namespace", n.Name, func(clonedCfg *pb.ServiceConfig) error {
 		return config.UpdateNamespace(clonedCfg, n)
 	})
 }
 
-func (s *server) DeleteNamespace(n, user string) error {
-	return s.updateConfig(user, func(clonedCfg *pb.ServiceConfig) error {
+func (s *server) DeleteNamespace(n string, expectedVersion int32, actor audit.Actor) error {
+	return s.updateConfig(expectedVersion, actor, "delete_namespace", n, func(clonedCfg *pb.ServiceConfig) error {
 		return config.DeleteNamespace(clonedCfg, n)
 	})
 }
 
-func (s *server) TopDynamicHits(namespace string) []*stats.BucketScore {
+func (s *server) AddOverride(o *pb.BucketOverride, expectedVersion int32, actor audit.Actor) error {
+	target := config.FullyQualifiedName(o.Namespace, o.Bucket)
+
+	return s.updateConfig(expectedVersion, actor, "add_override", target, func(clonedCfg *pb.ServiceConfig) error {
+		if o.ExpiresAt <= time.Now().Unix() {
+			return config.ValidationErrors{{Path: "expires_at", Message: "must be in the future"}}
+		}
+
+		o.User = actor.User
+		return config.AddOverride(clonedCfg, o)
+	})
+}
+
+func (s *server) DeleteOverride(namespace, bucket string, expectedVersion int32, actor audit.Actor) error {
+	target := config.FullyQualifiedName(namespace, bucket)
+
+	return s.updateConfig(expectedVersion, actor, "delete_override", target, func(clonedCfg *pb.ServiceConfig) error {
+		return config.RemoveOverride(clonedCfg, namespace, bucket)
+	})
+}
+
+func (s *server) AddAlertRule(r *pb.AlertRule, expectedVersion int32, actor audit.Actor) error {
+	return s.updateConfig(expectedVersion, actor, "add_alert_rule", r.Name, func(clonedCfg *pb.ServiceConfig) error {
+		return config.AddAlertRule(clonedCfg, r)
+	})
+}
+
+func (s *server) DeleteAlertRule(name string, expectedVersion int32, actor audit.Actor) error {
+	return s.updateConfig(expectedVersion, actor, "delete_alert_rule", name, func(clonedCfg *pb.ServiceConfig) error {
+		return config.RemoveAlertRule(clonedCfg, name)
+	})
+}
+
+func (s *server) Alerts() []*alerts.Alert {
+	return s.alerter.Alerts()
+}
+
+func (s *server) TopBuckets(namespace string, query stats.Query) []*stats.BucketScore {
 	if s.statsListener == nil {
 		return nil
 	}
 
-	return s.statsListener.TopHits(namespace)
+	return s.statsListener.Top(namespace, query)
 }
 
-func (s *server) TopDynamicMisses(namespace string) []*stats.BucketScore {
+func (s *server) BucketStats(namespace, bucket string, query stats.Query) *stats.BucketScores {
 	if s.statsListener == nil {
 		return nil
 	}
 
-	return s.statsListener.TopMisses(namespace)
+	return s.statsListener.Get(namespace, bucket, query)
 }
 
-func (s *server) DynamicBucketStats(namespace, bucket string) *stats.BucketScores {
+func (s *server) NamespaceStats(namespace string, query stats.Query) *stats.NamespaceSummary {
 	if s.statsListener == nil {
 		return nil
 	}
 
-	return s.statsListener.Get(namespace, bucket)
+	return s.statsListener.Summary(namespace, query)
+}
+
+func (s *server) ReaperStats() *stats.ReaperStats {
+	// Referencing s.bucketContainer should be guarded
+	s.RLock()
+	defer s.RUnlock()
+
+	if s.bucketContainer == nil {
+		return nil
+	}
+
+	return s.bucketContainer.r.snapshot()
+}
+
+func (s *server) DynamicBucketCounts() map[string]int {
+	// Referencing s.bucketContainer should be guarded
+	s.RLock()
+	defer s.RUnlock()
+
+	counts := make(map[string]int)
+	if s.bucketContainer == nil {
+		return counts
+	}
+
+	s.bucketContainer.RLock()
+	defer s.bucketContainer.RUnlock()
+
+	for name, ns := range s.bucketContainer.namespaces {
+		ns.RLock()
+		counts[name] = int(ns.dynamicBucketCount)
+		ns.RUnlock()
+	}
+
+	return counts
+}
+
+func (s *server) DroppedEvents() map[string]uint64 {
+	s.listenersLock.RLock()
+	defer s.listenersLock.RUnlock()
+
+	dropped := make(map[string]uint64, len(s.listeners))
+	for _, l := range s.listeners {
+		dropped[l.name] = l.producer.Dropped()
+	}
+
+	return dropped
+}
+
+func (s *server) SubscribeEvents(bufSize int, filter func(events.Event) bool) (*events.Subscription, error) {
+	// The listener is only added once something subscribes, so that events aren't queued for it otherwise.
+	s.broadcasterOnce.Do(func() {
+		s.broadcasterErr = s.AddListener(streamListenerName, s.broadcaster.HandleEvent, streamQueueBufSize,
+			events.DROP_NEWEST)
+	})
+
+	if s.broadcasterErr != nil {
+		return nil, s.broadcasterErr
+	}
+
+	return s.broadcaster.Subscribe(bufSize, filter)
+}
+
+func (s *server) BucketSchedules() []config.ScheduleState {
+	s.RLock()
+	defer s.RUnlock()
+	return config.ScheduleStates(s.cfgs, time.Now())
 }
 
 func (s *server) HistoricalConfigs() ([]*pb.ServiceConfig, error) {
@@ -416,6 +860,30 @@ func (s *server) HistoricalConfigs() ([]*pb.ServiceConfig, error) {
 	return sorted, nil
 }
 
+func (s *server) HistoricalConfigsPage(offset, limit int) ([]*pb.ServiceConfig, error) {
+	return s.persister.ReadHistoricalConfigsPage(offset, limit)
+}
+
+func (s *server) RecordAudit(entry *audit.Entry) {
+	s.recordAudit(entry, nil)
+}
+
+func (s *server) AuditTrail(q audit.Query) ([]*audit.Entry, error) {
+	if s.auditSink == nil {
+		return nil, audit.ErrNoSink
+	}
+
+	return s.auditSink.Query(q.WithDefaults())
+}
+
+func (s *server) ConfigProvenance() *config.Provenance {
+	if p, ok := s.persister.(interface{ Provenance() *config.Provenance }); ok {
+		return p.Provenance()
+	}
+
+	return nil
+}
+
 func (s *server) GetServerAdministrable() admin.Administrable {
 	return s
 }
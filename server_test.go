@@ -5,14 +5,24 @@ package quotaservice
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/square/quotaservice/alerts"
+	"github.com/square/quotaservice/audit"
 	"github.com/square/quotaservice/config"
 	"github.com/square/quotaservice/events"
+	pb "github.com/square/quotaservice/protos/config"
 	"github.com/square/quotaservice/test/helpers"
 )
 
+var testActor = audit.Actor{User: "test", IP: "127.0.0.1", Source: audit.API}
+
 func TestWithNoRpcs(t *testing.T) {
 	helpers.ExpectingPanic(t, func() {
 		New(&MockBucketFactory{}, &config.MemoryConfigPersister{}, NewReaperConfigForTests(), 0)
@@ -41,7 +51,7 @@ func TestUpdateConfig(t *testing.T) {
 
 	newConfig := config.NewDefaultServiceConfig()
 
-	if err := s.UpdateConfig(newConfig, "test"); err != nil {
+	if err := s.UpdateConfig(newConfig, config.AnyVersion, testActor); err != nil {
 		t.Fatal("Error when updating config", err)
 	}
 
@@ -70,6 +80,182 @@ func TestUpdateConfig(t *testing.T) {
 	}
 }
 
+func TestUpdateConfigConflict(t *testing.T) {
+	p := config.NewMemoryConfigPersister()
+	originalConfig := config.NewDefaultServiceConfig()
+	helpers.CheckError(t, p.PersistAndNotify("", originalConfig))
+
+	// Load the config without starting the server, so it doesn't pick up further changes.
+	s := New(&MockBucketFactory{}, p, NewReaperConfigForTests(), 0, &MockEndpoint{}).(*server)
+	helpers.CheckError(t, s.createBucketContainer())
+	defer s.bucketContainer.Stop()
+	helpers.CheckError(t, s.readUpdatedConfig(0))
+
+	// Someone else persists a change this server hasn't seen yet.
+	concurrentConfig := config.CloneConfig(originalConfig)
+	concurrentConfig.Version = 1
+	concurrentConfig.User = "someone else"
+	helpers.CheckError(t, p.PersistAndNotify(config.HashConfig(originalConfig), concurrentConfig))
+
+	err := s.UpdateConfig(config.NewDefaultServiceConfig(), config.AnyVersion, testActor)
+	if !config.IsConfigConflict(err) {
+		t.Fatalf("Expecting a config conflict, got %v", err)
+	}
+
+	// Once the server has caught up, the update goes through.
+	helpers.CheckError(t, s.readUpdatedConfig(0))
+	helpers.CheckError(t, s.UpdateConfig(config.NewDefaultServiceConfig(), config.AnyVersion, testActor))
+}
+
+func TestUpdateConfigExpectedVersion(t *testing.T) {
+	p := config.NewMemoryConfigPersister()
+	helpers.CheckError(t, p.PersistAndNotify("", config.NewDefaultServiceConfig()))
+
+	s := New(&MockBucketFactory{}, p, NewReaperConfigForTests(), 0, &MockEndpoint{}).(*server)
+	helpers.CheckError(t, s.createBucketContainer())
+	defer s.bucketContainer.Stop()
+	helpers.CheckError(t, s.readUpdatedConfig(0))
+
+	// A client reads version 0. Meanwhile, another change is made, and the server catches up with it, so the
+	// persisted config's hash matches the server's.
+	helpers.CheckError(t, s.AddNamespace(config.NewDefaultNamespaceConfig("ns"), 0, testActor))
+	helpers.CheckError(t, s.readUpdatedConfig(0))
+
+	err := s.UpdateConfig(config.NewDefaultServiceConfig(), 0, testActor)
+	var conflict *config.ConfigConflictError
+	if !errors.As(err, &conflict) || conflict.ExpectedVersion != 0 || conflict.ActualVersion != 1 {
+		t.Fatalf("Expecting a conflict between versions 0 and 1, got %v", err)
+	}
+
+	helpers.CheckError(t, s.readUpdatedConfig(0))
+	if _, exists := s.Configs().Namespaces["ns"]; !exists {
+		t.Fatal("A change to a stale version should not have been made")
+	}
+
+	helpers.CheckError(t, s.UpdateConfig(config.NewDefaultServiceConfig(), 1, testActor))
+}
+
+func TestUpdateConfigInvalid(t *testing.T) {
+	s := New(&MockBucketFactory{}, config.NewMemoryConfig(config.NewDefaultServiceConfig()), NewReaperConfigForTests(), 0, &MockEndpoint{}).(*server)
+	_, err := s.Start()
+	helpers.CheckError(t, err)
+	defer stopServer(t, s)
+
+	b := config.NewDefaultBucketConfig("b")
+	b.Size = -1
+	ns := config.NewDefaultNamespaceConfig("ns")
+	helpers.CheckError(t, config.AddBucket(ns, b))
+
+	err = s.AddNamespace(ns, config.AnyVersion, testActor)
+	v, ok := config.AsValidationErrors(err)
+	if !ok || len(v) != 1 || v[0].Path != "namespaces.ns.buckets.b.size" {
+		t.Fatalf("Expecting a validation error for namespaces.ns.buckets.b.size, got %v", err)
+	}
+
+	if _, exists := s.Configs().Namespaces["ns"]; exists {
+		t.Fatal("Invalid namespace should not have been added")
+	}
+}
+
+func TestAuditTrail(t *testing.T) {
+	s := New(&MockBucketFactory{}, config.NewMemoryConfig(config.NewDefaultServiceConfig()), NewReaperConfigForTests(), 0, &MockEndpoint{}).(*server)
+
+	if _, err := s.AuditTrail(audit.Query{}); !errors.Is(err, audit.ErrNoSink) {
+		t.Fatalf("Expecting ErrNoSink without an audit sink, got %v", err)
+	}
+
+	sink := audit.NewMemorySink(10)
+	s.SetAuditSink(sink)
+	_, err := s.Start()
+	helpers.CheckError(t, err)
+	defer stopServer(t, s)
+
+	helpers.CheckError(t, s.AddNamespace(config.NewDefaultNamespaceConfig("ns"), config.AnyVersion, testActor))
+
+	start := time.Now()
+	for s.Configs().Namespaces["ns"] == nil {
+		if time.Since(start) > time.Second {
+			t.Fatal("Timeout waiting for config to change!")
+		}
+
+		time.Sleep(time.Millisecond * 5)
+	}
+
+	helpers.CheckError(t, s.AddBucket("ns", config.NewDefaultBucketConfig("b"), config.AnyVersion, testActor))
+
+	b := config.NewDefaultBucketConfig("invalid")
+	b.Size = -1
+	if err := s.AddBucket("ns", b, config.AnyVersion, testActor); err == nil {
+		t.Fatal("Expecting an invalid bucket to be rejected")
+	}
+
+	entries, err := s.AuditTrail(audit.Query{})
+	helpers.CheckError(t, err)
+
+	if len(entries) != 3 {
+		t.Fatalf("Expecting 3 entries, got %+v", entries)
+	}
+
+	failed, added := entries[0], entries[1]
+
+	if failed.Action != "add_bucket" || failed.Target != "ns:invalid" || failed.Error == "" || failed.Changes != nil {
+		t.Errorf("Expecting the failed change to be recorded with its error, got %+v", failed)
+	}
+
+	if added.Action != "add_bucket" || added.Target != "ns:b" || added.Actor != testActor || added.Version != 2 ||
+		added.Changes == nil || len(added.Changes.Namespaces) != 1 || added.Changes.Namespaces[0].Name != "ns" {
+		t.Errorf("Expecting the added bucket to be recorded with its changes, got %+v", added)
+	}
+
+	if entries, _ := s.AuditTrail(audit.Query{Action: "add_namespace"}); len(entries) != 1 {
+		t.Errorf("Expecting 1 add_namespace entry, got %+v", entries)
+	}
+}
+
+func TestWatchedDiskConfigChanges(t *testing.T) {
+	location := filepath.Join(t.TempDir(), "config.yaml")
+	replaceBucketSize := func(size int) {
+		t.Helper()
+
+		yaml := fmt.Sprintf("namespaces:\n  ns:\n    buckets:\n      b:\n        size: %d\n        fill_rate: 10\n", size)
+		helpers.CheckError(t, os.WriteFile(location+".tmp", []byte(yaml), 0644))
+		helpers.CheckError(t, os.Rename(location+".tmp", location))
+	}
+
+	replaceBucketSize(10)
+	p, err := config.NewWatchingDiskConfigPersister(location, 20*time.Millisecond)
+	helpers.CheckError(t, err)
+
+	s := New(&MockBucketFactory{}, p, NewReaperConfigForTests(), 0, &MockEndpoint{}).(*server)
+	_, err = s.Start()
+	helpers.CheckError(t, err)
+	defer stopServer(t, s)
+
+	// Files dropped in place don't change the version, which the persister bumps so that servers apply them.
+	for _, size := range []int64{10, 20, 30} {
+		if size > 10 {
+			replaceBucketSize(int(size))
+		}
+
+		start := time.Now()
+		for {
+			s.RLock()
+			b, _ := s.bucketContainer.FindBucket(context.Background(), "ns", "b")
+			s.RUnlock()
+
+			if b != nil && b.Config().Size == size {
+				break
+			}
+
+			if time.Since(start) > 5*time.Second {
+				t.Fatalf("Timeout waiting for bucket size %v, at config version %v", size, s.Configs().Version)
+			}
+
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+}
+
 func TestTooManyTokensRequested(t *testing.T) {
 	cfg := config.NewDefaultServiceConfig()
 	nsc := config.NewDefaultNamespaceConfig("dummy")
@@ -114,13 +300,37 @@ func TestInitWithLowerVersionedConfig(t *testing.T) {
 	newCfg := config.NewDefaultServiceConfig()
 	newCfg.Version = 1
 
-	s.updateBucketContainer(newCfg)
+	helpers.CheckError(t, s.updateBucketContainer(newCfg))
 
 	if s.cfgs.Version != 2 {
 		t.Fatal("Expected version 2 to not have been overwritten")
 	}
 }
 
+type failingBucketFactory struct {
+	MockBucketFactory
+}
+
+func (bf *failingBucketFactory) Init(cfg *pb.ServiceConfig) error {
+	return errors.New("cannot initialize")
+}
+
+func TestStartFailsWhenBucketFactoryFails(t *testing.T) {
+	s := New(&failingBucketFactory{}, config.NewMemoryConfig(config.NewDefaultServiceConfig()),
+		NewReaperConfigForTests(), 0, &MockEndpoint{}).(*server)
+	defer func() {
+		s.bucketContainer.Stop()
+	}()
+
+	if _, err := s.Start(); err == nil {
+		t.Fatal("Expected Start to fail")
+	}
+
+	if s.cfgs != nil {
+		t.Fatalf("Expected the config not to have been applied, got %+v", s.cfgs)
+	}
+}
+
 func TestBucketErrorEmitsEvent(t *testing.T) {
 	cfg := config.NewDefaultServiceConfig()
 	nsc := config.NewDefaultNamespaceConfig("dummy")
@@ -157,6 +367,349 @@ func TestBucketErrorEmitsEvent(t *testing.T) {
 	}
 }
 
+func TestOverrideExpiry(t *testing.T) {
+	cfg := config.NewDefaultServiceConfig()
+	nsc := config.NewDefaultNamespaceConfig("dummy")
+	helpers.CheckError(t, config.AddBucket(nsc, config.NewDefaultBucketConfig("dummy")))
+	helpers.CheckError(t, config.AddNamespace(cfg, nsc))
+
+	s := New(&MockBucketFactory{}, config.NewMemoryConfig(cfg), NewReaperConfigForTests(), 0, &MockEndpoint{}).(*server)
+	_, err := s.Start()
+	helpers.CheckError(t, err)
+	defer stopServer(t, s)
+
+	bucketSize := func() int64 {
+		s.RLock()
+		defer s.RUnlock()
+		b, _ := s.bucketContainer.FindBucket(context.Background(), "dummy", "dummy")
+		return b.Config().Size
+	}
+
+	originalSize := bucketSize()
+
+	err = s.AddOverride(&pb.BucketOverride{
+		Namespace: "dummy",
+		Bucket:    "dummy",
+		Config:    &pb.BucketConfig{Size: originalSize * 2},
+		ExpiresAt: time.Now().Unix() - 1}, config.AnyVersion, testActor)
+	if _, ok := config.AsValidationErrors(err); !ok {
+		t.Fatalf("Expecting a validation error for an expired override, got %v", err)
+	}
+
+	// Expiry has a resolution of a second, so this expires in one to two seconds.
+	helpers.CheckError(t, s.AddOverride(&pb.BucketOverride{
+		Namespace: "dummy",
+		Bucket:    "dummy",
+		Config:    &pb.BucketConfig{Size: originalSize * 2},
+		ExpiresAt: time.Now().Unix() + 2}, config.AnyVersion, testActor))
+
+	waitForConfig := func(condition func(*pb.ServiceConfig) bool) {
+		t.Helper()
+		start := time.Now()
+
+		for !condition(s.Configs()) {
+			if time.Since(start) > 3*time.Second {
+				t.Fatal("Timeout waiting for config to change!")
+			}
+
+			time.Sleep(time.Millisecond * 5)
+		}
+	}
+
+	waitForConfig(func(c *pb.ServiceConfig) bool { return len(c.Overrides) == 1 })
+
+	if size := bucketSize(); size != originalSize*2 {
+		t.Errorf("Expecting overridden size %v, got %v", originalSize*2, size)
+	}
+
+	if user := s.Configs().Overrides[0].User; user != "test" {
+		t.Errorf("Expecting override by test, got %v", user)
+	}
+
+	waitForConfig(func(c *pb.ServiceConfig) bool { return len(c.Overrides) == 0 })
+
+	if size := bucketSize(); size != originalSize {
+		t.Errorf("Expecting size to revert to %v, got %v", originalSize, size)
+	}
+
+	if cfg := s.Configs(); cfg.Version != 2 || cfg.User != serverUser {
+		t.Errorf("Expecting version 2 by %v, got version %v by %v", serverUser, cfg.Version, cfg.User)
+	}
+}
+
+// failingPersister fails to persist configs while fail is set.
+type failingPersister struct {
+	config.ConfigPersister
+	fail atomic.Bool
+}
+
+func (p *failingPersister) PersistAndNotify(oldHash string, cfg *pb.ServiceConfig) error {
+	if p.fail.Load() {
+		return errors.New("persister unavailable")
+	}
+
+	return p.ConfigPersister.PersistAndNotify(oldHash, cfg)
+}
+
+func TestOverrideExpiryRetried(t *testing.T) {
+	defer func(min time.Duration) { minOverrideExpiryRetry = min }(minOverrideExpiryRetry)
+	minOverrideExpiryRetry = 10 * time.Millisecond
+
+	cfg := config.NewDefaultServiceConfig()
+	nsc := config.NewDefaultNamespaceConfig("dummy")
+	helpers.CheckError(t, config.AddBucket(nsc, config.NewDefaultBucketConfig("dummy")))
+	helpers.CheckError(t, config.AddNamespace(cfg, nsc))
+	originalSize := nsc.Buckets["dummy"].Size
+
+	// Expiry has a resolution of a second, so this expires in one to two seconds.
+	cfg.Overrides = []*pb.BucketOverride{{
+		Namespace: "dummy",
+		Bucket:    "dummy",
+		Config:    &pb.BucketConfig{Size: originalSize * 2},
+		ExpiresAt: time.Now().Unix() + 2}}
+
+	p := &failingPersister{ConfigPersister: config.NewMemoryConfig(cfg)}
+	p.fail.Store(true)
+
+	s := New(&MockBucketFactory{}, p, NewReaperConfigForTests(), 0, &MockEndpoint{}).(*server)
+	_, err := s.Start()
+	helpers.CheckError(t, err)
+	defer stopServer(t, s)
+
+	bucketSize := func() int64 {
+		s.RLock()
+		defer s.RUnlock()
+		b, _ := s.bucketContainer.FindBucket(context.Background(), "dummy", "dummy")
+		return b.Config().Size
+	}
+
+	waitFor := func(description string, condition func() bool) {
+		t.Helper()
+		start := time.Now()
+
+		for !condition() {
+			if time.Since(start) > 3*time.Second {
+				t.Fatalf("Timeout waiting for %v", description)
+			}
+
+			time.Sleep(time.Millisecond * 5)
+		}
+	}
+
+	if size := bucketSize(); size != originalSize*2 {
+		t.Fatalf("Expecting overridden size %v, got %v", originalSize*2, size)
+	}
+
+	// The bucket reverts once the override expires, although the override can't be removed from the config.
+	waitFor("the bucket to revert", func() bool { return bucketSize() == originalSize })
+
+	if len(s.Configs().Overrides) != 1 {
+		t.Fatal("Expecting the expired override to still be in the config")
+	}
+
+	// Once the persister is back, the override is removed.
+	p.fail.Store(false)
+	waitFor("the override to be removed", func() bool { return len(s.Configs().Overrides) == 0 })
+
+	if size := bucketSize(); size != originalSize {
+		t.Errorf("Expecting size %v, got %v", originalSize, size)
+	}
+}
+
+type recordingNotifier chan *alerts.Alert
+
+func (n recordingNotifier) Notify(webhooks []string, alert *alerts.Alert) {
+	n <- alert
+}
+
+func TestAlertRules(t *testing.T) {
+	cfg := config.NewDefaultServiceConfig()
+	helpers.CheckError(t, config.AddNamespace(cfg, config.NewDefaultNamespaceConfig("dummy")))
+
+	s := New(&MockBucketFactory{}, config.NewMemoryConfig(cfg), NewReaperConfigForTests(), 0, &MockEndpoint{}).(*server)
+	notifier := make(recordingNotifier, 1)
+	s.alerter = alerts.NewAlerter(notifier)
+	_, err := s.Start()
+	helpers.CheckError(t, err)
+	defer stopServer(t, s)
+
+	if _, exists := s.DroppedEvents()[alertsListenerName]; exists {
+		t.Fatal("Expecting no alerts listener without any alert rules")
+	}
+
+	helpers.CheckError(t, s.AddAlertRule(&pb.AlertRule{
+		Name:         "timeouts",
+		Namespace:    "dummy",
+		WindowMillis: time.Minute.Milliseconds(),
+		Webhooks:     []string{"http://example.com"}}, config.AnyVersion, testActor))
+
+	start := time.Now()
+	for len(s.Configs().AlertRules) == 0 {
+		if time.Since(start) > 3*time.Second {
+			t.Fatal("Timeout waiting for config to change!")
+		}
+
+		time.Sleep(time.Millisecond * 5)
+	}
+
+	if _, exists := s.DroppedEvents()[alertsListenerName]; !exists {
+		t.Fatal("Expecting an alerts listener once there are alert rules")
+	}
+
+	s.Emit(events.NewTimedOutEvent("dummy", "dummy", false, 1))
+
+	// The event reaches the alerter asynchronously.
+	for {
+		s.alerter.Evaluate()
+
+		select {
+		case alert := <-notifier:
+			if alert.Rule != "timeouts" || alert.Status != alerts.Firing {
+				t.Fatalf("Expecting the timeouts rule to fire, got %+v", alert)
+			}
+
+			if firing := s.Alerts(); len(firing) != 1 {
+				t.Fatalf("Expecting one alert to be firing, got %+v", firing)
+			}
+
+			helpers.CheckError(t, s.DeleteAlertRule("timeouts", config.AnyVersion, testActor))
+
+			select {
+			case alert := <-notifier:
+				if alert.Status != alerts.Resolved {
+					t.Fatalf("Expecting deleting the rule to resolve its alert, got %+v", alert)
+				}
+			case <-time.After(3 * time.Second):
+				t.Fatal("Timeout waiting for the alert to resolve!")
+			}
+
+			return
+		case <-time.After(5 * time.Millisecond):
+			if time.Since(start) > 3*time.Second {
+				t.Fatal("Timeout waiting for the alert to fire!")
+			}
+		}
+	}
+}
+
+func TestDynamicBucketCounts(t *testing.T) {
+	cfg := config.NewDefaultServiceConfig()
+	nsc := config.NewDefaultNamespaceConfig("dyn")
+	config.SetDynamicBucketTemplate(nsc, config.NewDefaultBucketConfig(""))
+	helpers.CheckError(t, config.AddNamespace(cfg, nsc))
+	helpers.CheckError(t, config.AddNamespace(cfg, config.NewDefaultNamespaceConfig("static")))
+
+	s := New(&MockBucketFactory{}, config.NewMemoryConfig(cfg), NewReaperConfigForTests(), 0, &MockEndpoint{}).(*server)
+	s.SetListener(func(events.Event) {}, 100)
+	_, err := s.Start()
+	helpers.CheckError(t, err)
+	defer stopServer(t, s)
+
+	for _, name := range []string{"a", "b", "a"} {
+		_, _, err = s.Allow(context.Background(), "dyn", name, 1, 0, false)
+		helpers.CheckError(t, err)
+	}
+
+	counts := s.DynamicBucketCounts()
+	if counts["dyn"] != 2 || counts["static"] != 0 || len(counts) != 2 {
+		t.Errorf("Unexpected dynamic bucket counts %v", counts)
+	}
+
+	if dropped := s.DroppedEvents(); len(dropped) != 1 || dropped[defaultListenerName] != 0 {
+		t.Errorf("Didn't expect any dropped events, got %v", s.DroppedEvents())
+	}
+}
+
+func TestScheduledBucket(t *testing.T) {
+	cfg := config.NewDefaultServiceConfig()
+	nsc := config.NewDefaultNamespaceConfig("dummy")
+	bc := config.NewDefaultBucketConfig("dummy")
+	bc.Schedules = []*pb.BucketSchedule{
+		// Every window lasts two minutes, so one is always in effect.
+		{Name: "always", Start: "* * * * *", DurationMillis: (2 * time.Minute).Milliseconds(), Size: bc.Size * 2}}
+	helpers.CheckError(t, config.AddBucket(nsc, bc))
+	helpers.CheckError(t, config.AddNamespace(cfg, nsc))
+
+	s := New(&MockBucketFactory{}, config.NewMemoryConfig(cfg), NewReaperConfigForTests(), 0, &MockEndpoint{}).(*server)
+	_, err := s.Start()
+	helpers.CheckError(t, err)
+	defer stopServer(t, s)
+
+	s.RLock()
+	b, _ := s.bucketContainer.FindBucket(context.Background(), "dummy", "dummy")
+	timerSet := s.scheduleTimer != nil
+	s.RUnlock()
+
+	if b.Config().Size != bc.Size*2 {
+		t.Errorf("Expecting scheduled size %v, got %v", bc.Size*2, b.Config().Size)
+	}
+
+	if s.Configs().Namespaces["dummy"].Buckets["dummy"].Size != bc.Size {
+		t.Error("Expecting the config to keep the bucket's own size")
+	}
+
+	if !timerSet {
+		t.Error("Expecting a timer for the next schedule change")
+	}
+
+	states := s.BucketSchedules()
+	if len(states) != 1 || states[0].Active != "always" {
+		t.Errorf("Expecting schedule always to be active, got %+v", states)
+	}
+}
+
+func TestScheduleSwitchKeepsUnchangedBuckets(t *testing.T) {
+	cfg := config.NewDefaultServiceConfig()
+	nsc := config.NewDefaultNamespaceConfig("dummy")
+	helpers.CheckError(t, config.AddBucket(nsc, config.NewDefaultBucketConfig("scheduled")))
+	helpers.CheckError(t, config.AddBucket(nsc, config.NewDefaultBucketConfig("unscheduled")))
+	config.SetDynamicBucketTemplate(nsc, config.NewDefaultBucketConfig(""))
+	helpers.CheckError(t, config.AddNamespace(cfg, nsc))
+
+	s := New(&MockBucketFactory{}, config.NewMemoryConfig(cfg), NewReaperConfigForTests(), 0, &MockEndpoint{}).(*server)
+	_, err := s.Start()
+	helpers.CheckError(t, err)
+	defer stopServer(t, s)
+
+	findBucket := func(name string) Bucket {
+		s.RLock()
+		defer s.RUnlock()
+		b, _ := s.bucketContainer.FindBucket(context.Background(), "dummy", name)
+		return b
+	}
+
+	dynamic, unscheduled, scheduled := findBucket("dynamic"), findBucket("unscheduled"), findBucket("scheduled")
+	if dynamic == nil || !dynamic.Dynamic() {
+		t.Fatal("Expecting a dynamic bucket")
+	}
+
+	// As if a schedule of the scheduled bucket had just started.
+	s.Lock()
+	s.cfgs = config.CloneConfig(s.cfgs)
+	b := s.cfgs.Namespaces["dummy"].Buckets["scheduled"]
+	b.Schedules = []*pb.BucketSchedule{
+		{Name: "always", Start: "* * * * *", DurationMillis: (2 * time.Minute).Milliseconds(), Size: b.Size * 2}}
+	s.Unlock()
+
+	s.switchSchedules()
+
+	if b := findBucket("scheduled"); b == scheduled || b.Config().Size != scheduled.Config().Size*2 {
+		t.Error("Expecting the scheduled bucket to be replaced with the schedule's size")
+	}
+
+	if findBucket("unscheduled") != unscheduled {
+		t.Error("Expecting the unscheduled bucket to be kept")
+	}
+
+	if findBucket("dynamic") != dynamic {
+		t.Error("Expecting the dynamic bucket to be kept")
+	}
+
+	if counts := s.DynamicBucketCounts(); counts["dummy"] != 1 {
+		t.Errorf("Expecting a dynamic bucket, got %v", counts)
+	}
+}
+
 func stopServer(t *testing.T, s *server) {
 	t.Helper()
 
@@ -4,124 +4,197 @@
 package stats
 
 import (
-	"sort"
+	"sync"
+	"time"
 
 	"github.com/square/quotaservice/events"
 )
 
+// bucketCounters counts a bucket's metrics, with a counter per window in Windows.
+type bucketCounters struct {
+	metrics     [numMetrics][]counter
+	dynamic     bool
+	lastUpdated time.Time
+}
+
 type namespaceStats struct {
-	hits, misses map[string]*BucketScore
+	buckets   map[string]*bucketCounters
+	lastPrune time.Time
 }
 
 type memoryListener struct {
 	namespaces map[string]*namespaceStats
+	now        func() time.Time
+	sync.Mutex // Embedded mutex guards namespaces
 }
 
 // NewMemoryStatsListener creates an in-memory stats listener.
 func NewMemoryStatsListener() Listener {
-	return &memoryListener{make(map[string]*namespaceStats)}
+	return &memoryListener{namespaces: make(map[string]*namespaceStats), now: time.Now}
 }
 
-func (l *memoryListener) bucketScoreTop10(scoreMap map[string]*BucketScore) []*BucketScore {
-	arr := make(BucketScoreArray, 0)
-
-	for _, value := range scoreMap {
-		arr = append(arr, value)
+// forEach calls f with the scores of each bucket of the namespace that the query counts.
+func (l *memoryListener) forEach(namespace string, query Query, f func(bucket string, scores *BucketScores)) {
+	i := windowIndex(query.Window)
+	if i < 0 {
+		return
 	}
 
-	sort.Sort(arr)
-	length := len(arr)
+	l.Lock()
+	defer l.Unlock()
 
-	if length > 10 {
-		length = 10
+	stats, ok := l.namespaces[namespace]
+
+	if !ok {
+		return
 	}
 
-	return arr[0:length]
-}
+	slot := slotAt(query.Window, l.now())
 
-// TopHits is implemented for stats.Listener
-// TopHits returns a sorted list of the 10 buckets with the highest # of hits
-// in the specified namespace
-func (l *memoryListener) TopHits(namespace string) []*BucketScore {
-	stats, ok := l.namespaces[namespace]
+	for bucket, counters := range stats.buckets {
+		if !counters.dynamic && !query.IncludeStatic {
+			continue
+		}
 
-	if !ok {
-		return emptyArr
+		f(bucket, counters.scores(i, slot))
 	}
+}
 
-	return l.bucketScoreTop10(stats.hits)
+// scores returns the sums of the bucket's counters for the window at index i in Windows, ending with slot.
+func (c *bucketCounters) scores(i int, slot int64) *BucketScores {
+	scores := &BucketScores{}
+	for m := range c.metrics {
+		*scores.metric(Metric(m)) = c.metrics[m][i].sum(slot)
+	}
+
+	return scores
 }
 
-// TopMisses is implemented for stats.Listener
-// TopMisses returns a sorted list of the 10 buckets with the highest # of misses
-// in the specified namespace
-func (l *memoryListener) TopMisses(namespace string) []*BucketScore {
-	stats, ok := l.namespaces[namespace]
+// Top is implemented for stats.Listener
+// Top returns a sorted list of the buckets with the highest
+// query.Metric in the specified namespace within the window
+func (l *memoryListener) Top(namespace string, query Query) []*BucketScore {
+	query = query.withDefaults()
+	arr := make(BucketScoreArray, 0)
 
-	if !ok {
-		return emptyArr
-	}
+	l.forEach(namespace, query, func(bucket string, scores *BucketScores) {
+		if score := *scores.metric(query.Metric); score > 0 {
+			arr = append(arr, &BucketScore{bucket, score})
+		}
+	})
 
-	return l.bucketScoreTop10(stats.misses)
+	return arr.top(query.Limit)
 }
 
 // Get is implemented for stats.Listener
-// Get returns the hits and misses for a bucket in the specified namespace
-func (l *memoryListener) Get(namespace, bucket string) *BucketScores {
+// Get returns the metrics of a bucket in the specified namespace
+// within the window
+func (l *memoryListener) Get(namespace, bucket string, query Query) *BucketScores {
+	query = query.withDefaults()
+	i := windowIndex(query.Window)
+	if i < 0 {
+		return &BucketScores{}
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
 	stats, ok := l.namespaces[namespace]
 
 	if !ok {
-		return emptyBucketScores
+		return &BucketScores{}
 	}
 
-	scores := &BucketScores{0, 0}
+	counters, ok := stats.buckets[bucket]
 
-	if hitValue, ok := stats.hits[bucket]; ok {
-		scores.Hits = hitValue.Score
+	if !ok || (!counters.dynamic && !query.IncludeStatic) {
+		return &BucketScores{}
 	}
 
-	if missValue, ok := stats.misses[bucket]; ok {
-		scores.Misses = missValue.Score
-	}
+	return counters.scores(i, slotAt(query.Window, l.now()))
+}
 
-	return scores
+// Summary is implemented for stats.Listener
+// Summary adds up the metrics of the buckets in the specified
+// namespace within the window
+func (l *memoryListener) Summary(namespace string, query Query) *NamespaceSummary {
+	summary := &NamespaceSummary{}
+
+	l.forEach(namespace, query.withDefaults(), func(bucket string, scores *BucketScores) {
+		if *scores == (BucketScores{}) {
+			return
+		}
+
+		summary.Buckets++
+		for m := 0; m < numMetrics; m++ {
+			*summary.metric(Metric(m)) += *scores.metric(Metric(m))
+		}
+	})
+
+	return summary
 }
 
 // HandleEvent is implemented for stats.Listener
-// HandleEvent consumes dynamic bucket events (see events.Event)
+// HandleEvent consumes bucket events (see events.Event)
 func (l *memoryListener) HandleEvent(event events.Event) {
-	if !event.Dynamic() {
+	inc, counted := increments(event)
+	if !counted {
 		return
 	}
 
+	l.Lock()
+	defer l.Unlock()
+
+	now := l.now()
 	namespace := event.Namespace()
 
 	if _, ok := l.namespaces[namespace]; !ok {
-		l.namespaces[namespace] = &namespaceStats{
-			make(map[string]*BucketScore),
-			make(map[string]*BucketScore)}
+		l.namespaces[namespace] = &namespaceStats{make(map[string]*bucketCounters), now}
 	}
 
 	stats := l.namespaces[namespace]
+	stats.pruneIfDue(now)
 
-	var statsBucket map[string]*BucketScore
-	var numTokens int64 = 1
+	key := event.BucketName()
 
-	switch event.EventType() {
-	case events.EVENT_BUCKET_MISS:
-		statsBucket = stats.misses
-	case events.EVENT_TOKENS_SERVED:
-		numTokens = event.NumTokens()
-		statsBucket = stats.hits
-	default:
-		return
+	if _, ok := stats.buckets[key]; !ok {
+		counters := &bucketCounters{}
+		for m := range counters.metrics {
+			counters.metrics[m] = make([]counter, len(Windows))
+		}
+
+		stats.buckets[key] = counters
 	}
 
-	key := event.BucketName()
+	counters := stats.buckets[key]
+	counters.dynamic = event.Dynamic()
+	counters.lastUpdated = now
+
+	for m, n := range inc {
+		if n == 0 {
+			continue
+		}
+
+		for i, window := range Windows {
+			counters.metrics[m][i].add(slotAt(window, now), n)
+		}
+	}
+}
+
+// pruneIfDue removes buckets that haven't been counted in any window for a while, so buckets that are no longer
+// used don't take up memory.
+func (s *namespaceStats) pruneIfDue(now time.Time) {
+	longest := Windows[len(Windows)-1]
 
-	if _, ok := statsBucket[key]; !ok {
-		statsBucket[key] = &BucketScore{key, 0}
+	if now.Sub(s.lastPrune) < slotWidth(longest) {
+		return
 	}
 
-	statsBucket[key].Score += numTokens
+	s.lastPrune = now
+
+	for bucket, counters := range s.buckets {
+		if now.Sub(counters.lastUpdated) > longest {
+			delete(s.buckets, bucket)
+		}
+	}
 }
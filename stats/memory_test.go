@@ -5,7 +5,10 @@ package stats
 
 import (
 	"reflect"
+	"sort"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/square/quotaservice/events"
 )
@@ -16,7 +19,7 @@ func TestMemoryHandleNewHitBucket(t *testing.T) {
 	listener = NewMemoryStatsListener()
 	ev := events.NewTokensServedEvent("test", "dyn", true, 1, 0)
 	listener.HandleEvent(ev)
-	scores := listener.Get("test", "dyn")
+	scores := listener.Get("test", "dyn", Query{})
 
 	if scores.Hits != 1 || scores.Misses != 0 {
 		t.Fatalf("Bucket score was not accurate: %+v != [Hits=1, Misses=0]", scores)
@@ -24,13 +27,13 @@ func TestMemoryHandleNewHitBucket(t *testing.T) {
 
 	ev = events.NewTokensServedEvent("test", "nondyn", false, 1, 0)
 	listener.HandleEvent(ev)
-	scores = listener.Get("test", "nondyn")
+	scores = listener.Get("test", "nondyn", Query{})
 
 	if scores.Hits != 0 || scores.Misses != 0 {
 		t.Fatalf("Non-dynamic bucket score was not accurate: %+v != [Hits=0, Misses=0]", scores)
 	}
 
-	scores = listener.Get("nontest", "nondyn")
+	scores = listener.Get("nontest", "nondyn", Query{})
 
 	if scores.Hits != 0 || scores.Misses != 0 {
 		t.Fatalf("Nonexisting namespace was not accurate: %+v != [Hits=0, Misses=0]", scores)
@@ -40,14 +43,14 @@ func TestMemoryHandleNewHitBucket(t *testing.T) {
 func TestMemoryHandleNewMissBucket(t *testing.T) {
 	listener = NewMemoryStatsListener()
 	listener.HandleEvent(events.NewBucketMissedEvent("test", "dyn", true))
-	scores := listener.Get("test", "dyn")
+	scores := listener.Get("test", "dyn", Query{})
 
 	if scores.Hits != 0 || scores.Misses != 1 {
 		t.Fatalf("Bucket score was not accurate: %+v != [Hits=0, Misses=1]", scores)
 	}
 
 	listener.HandleEvent(events.NewBucketMissedEvent("test", "nondyn", false))
-	scores = listener.Get("test", "nondyn")
+	scores = listener.Get("test", "nondyn", Query{})
 
 	if scores.Hits != 0 || scores.Misses != 0 {
 		t.Fatalf("Non-dynamic bucket score was not accurate: %+v != [Hits=0, Misses=0]", scores)
@@ -59,7 +62,7 @@ func TestMemoryHandleIncrMissBucket(t *testing.T) {
 	listener.HandleEvent(events.NewBucketMissedEvent("test", "dyn", true))
 	listener.HandleEvent(events.NewBucketMissedEvent("test", "dyn", true))
 	listener.HandleEvent(events.NewBucketMissedEvent("test", "dyn", true))
-	scores := listener.Get("test", "dyn")
+	scores := listener.Get("test", "dyn", Query{})
 
 	if scores.Hits != 0 || scores.Misses != 3 {
 		t.Fatalf("Bucket score was not accurate: %+v != [Hits=0, Misses=3]", scores)
@@ -71,17 +74,17 @@ func TestMemoryHandleIncrHitBucket(t *testing.T) {
 	listener.HandleEvent(events.NewTokensServedEvent("test", "dyn", true, 1, 0))
 	listener.HandleEvent(events.NewTokensServedEvent("test", "dyn", true, 3, 0))
 	listener.HandleEvent(events.NewTokensServedEvent("test", "dyn", true, 1, 0))
-	scores := listener.Get("test", "dyn")
+	scores := listener.Get("test", "dyn", Query{})
 
-	if scores.Hits != 5 || scores.Misses != 0 {
-		t.Fatalf("Bucket score was not accurate: %+v != [Hits=5, Misses=0]", scores)
+	if scores.Hits != 3 || scores.Tokens != 5 || scores.Misses != 0 {
+		t.Fatalf("Bucket score was not accurate: %+v != [Hits=3, Tokens=5, Misses=0]", scores)
 	}
 }
 
 func TestMemoryHandleNonEvent(t *testing.T) {
 	listener = NewMemoryStatsListener()
 	listener.HandleEvent(events.NewTimedOutEvent("test", "dyn", true, 1))
-	scores := listener.Get("test", "dyn")
+	scores := listener.Get("test", "dyn", Query{})
 
 	if scores.Hits != 0 || scores.Misses != 0 {
 		t.Fatalf("Bucket score was not accurate: %+v != [Hits=0, Misses=0]", scores)
@@ -94,7 +97,7 @@ func TestMemoryTop10Hits(t *testing.T) {
 	listener.HandleEvent(events.NewTokensServedEvent("test", "dyn-2", true, 10, 0))
 	listener.HandleEvent(events.NewTokensServedEvent("test", "dyn-3", true, 1, 0))
 
-	hits := listener.TopHits("test")
+	hits := listener.Top("test", Query{Metric: TOKENS})
 	correctHits := []*BucketScore{
 		{Bucket: "dyn-2", Score: 10},
 		{Bucket: "dyn-1", Score: 3},
@@ -117,7 +120,7 @@ func TestMemoryTop10Misses(t *testing.T) {
 	listener.HandleEvent(events.NewBucketMissedEvent("test", "dyn-3", true))
 	listener.HandleEvent(events.NewBucketMissedEvent("test", "dyn-3", true))
 
-	misses := listener.TopMisses("test")
+	misses := listener.Top("test", Query{Metric: MISSES})
 	correctMisses := []*BucketScore{
 		{Bucket: "dyn-2", Score: 3},
 		{Bucket: "dyn-3", Score: 2},
@@ -127,3 +130,132 @@ func TestMemoryTop10Misses(t *testing.T) {
 		t.Fatalf("Misses top10 is not correct %+v", misses)
 	}
 }
+
+func TestMemoryWindows(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+	l := NewMemoryStatsListener().(*memoryListener)
+	l.now = func() time.Time { return now }
+
+	l.HandleEvent(events.NewTokensServedEvent("test", "old", true, 5, 0))
+	now = now.Add(2 * time.Minute)
+	l.HandleEvent(events.NewTokensServedEvent("test", "new", true, 1, 0))
+	l.HandleEvent(events.NewBucketMissedEvent("test", "new", true))
+
+	expected := map[time.Duration][]*BucketScore{
+		time.Minute:     {{Bucket: "new", Score: 1}},
+		5 * time.Minute: {{Bucket: "old", Score: 5}, {Bucket: "new", Score: 1}},
+		time.Hour:       {{Bucket: "old", Score: 5}, {Bucket: "new", Score: 1}}}
+
+	for window, correctHits := range expected {
+		if hits := l.Top("test", Query{Window: window, Metric: TOKENS}); !reflect.DeepEqual(hits, correctHits) {
+			t.Errorf("Hits in window %v are not correct %+v", window, hits)
+		}
+	}
+
+	if scores := l.Get("test", "new", Query{Window: time.Minute}); scores.Hits != 1 || scores.Misses != 1 {
+		t.Errorf("Bucket score was not accurate: %+v != [Hits=1, Misses=1]", scores)
+	}
+
+	if hits := l.Top("test", Query{Window: 2 * time.Minute}); len(hits) != 0 {
+		t.Errorf("Expected no hits for an unsupported window, got %+v", hits)
+	}
+
+	// Once out of every window, buckets are pruned.
+	now = now.Add(2 * time.Hour)
+	l.HandleEvent(events.NewTokensServedEvent("test", "newer", true, 1, 0))
+
+	if hits := l.Top("test", Query{Window: time.Hour, Metric: TOKENS}); !reflect.DeepEqual(hits, []*BucketScore{{Bucket: "newer", Score: 1}}) {
+		t.Errorf("Hits are not correct %+v", hits)
+	}
+
+	if len(l.namespaces["test"].buckets) != 1 {
+		t.Errorf("Expected idle buckets to be pruned, got %+v", l.namespaces["test"].buckets)
+	}
+}
+
+func TestMemoryConcurrentEvents(t *testing.T) {
+	l := NewMemoryStatsListener()
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				l.HandleEvent(events.NewTokensServedEvent("test", "dyn", true, 1, 0))
+				l.Top("test", Query{Window: time.Minute})
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if scores := l.Get("test", "dyn", Query{Window: time.Minute}); scores.Hits != 1000 {
+		t.Fatalf("Bucket score was not accurate: %+v != [Hits=1000, Misses=0]", scores)
+	}
+}
+
+func TestMemoryQueries(t *testing.T) {
+	l := NewMemoryStatsListener()
+	l.HandleEvent(events.NewTokensServedEvent("test", "dyn-1", true, 2, 30*time.Millisecond))
+	l.HandleEvent(events.NewTokensServedEvent("test", "dyn-2", true, 1, 10*time.Millisecond))
+	l.HandleEvent(events.NewTokensServedEvent("test", "dyn-2", true, 1, 10*time.Millisecond))
+	l.HandleEvent(events.NewTimedOutEvent("test", "dyn-3", true, 1))
+	l.HandleEvent(events.NewTokensServedEvent("test", "static", false, 10, 0))
+	l.HandleEvent(events.NewTimedOutEvent("test", "static", false, 1))
+
+	queries := []struct {
+		query    Query
+		expected []*BucketScore
+	}{
+		{Query{}, []*BucketScore{{Bucket: "dyn-2", Score: 2}, {Bucket: "dyn-1", Score: 1}}},
+		{Query{Limit: 1}, []*BucketScore{{Bucket: "dyn-2", Score: 2}}},
+		{Query{Metric: WAIT_MILLIS}, []*BucketScore{{Bucket: "dyn-1", Score: 30}, {Bucket: "dyn-2", Score: 20}}},
+		{Query{Metric: TOKENS, IncludeStatic: true},
+			[]*BucketScore{{Bucket: "static", Score: 10}, {Bucket: "dyn-1", Score: 2}, {Bucket: "dyn-2", Score: 2}}},
+		{Query{Metric: TIMEOUTS, Limit: 1}, []*BucketScore{{Bucket: "dyn-3", Score: 1}}},
+	}
+
+	for _, q := range queries {
+		top := l.Top("test", q.query)
+
+		// Buckets with the same score can be in any order.
+		sort.SliceStable(top, func(i, j int) bool {
+			return top[i].Score > top[j].Score || (top[i].Score == top[j].Score && top[i].Bucket < top[j].Bucket)
+		})
+
+		if !reflect.DeepEqual(top, q.expected) {
+			t.Errorf("Top for %+v is not correct %+v", q.query, top)
+		}
+	}
+
+	if scores := l.Get("test", "static", Query{IncludeStatic: true}); scores.Hits != 1 || scores.Tokens != 10 {
+		t.Errorf("Static bucket score was not accurate: %+v != [Hits=1, Tokens=10]", scores)
+	}
+
+	summary := l.Summary("test", Query{})
+	expected := &NamespaceSummary{BucketScores{Hits: 3, Timeouts: 1, Tokens: 4, WaitMillis: 50}, 3}
+
+	if !reflect.DeepEqual(summary, expected) {
+		t.Errorf("Summary %+v != %+v", summary, expected)
+	}
+
+	summary = l.Summary("test", Query{IncludeStatic: true})
+	expected = &NamespaceSummary{BucketScores{Hits: 4, Timeouts: 2, Tokens: 14, WaitMillis: 50}, 4}
+
+	if !reflect.DeepEqual(summary, expected) {
+		t.Errorf("Summary %+v != %+v", summary, expected)
+	}
+}
+
+func TestParseMetric(t *testing.T) {
+	for _, m := range []Metric{HITS, MISSES, TIMEOUTS, TOKENS, WAIT_MILLIS} {
+		if parsed, err := ParseMetric(m.String()); err != nil || parsed != m {
+			t.Errorf("Parsed %v as %v, %v", m, parsed, err)
+		}
+	}
+
+	if _, err := ParseMetric("unknown"); err == nil {
+		t.Error("Expected an error parsing an unknown metric")
+	}
+}
@@ -0,0 +1,22 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package stats
+
+import "time"
+
+// ReaperStats describes the activity of the bucket reaper.
+type ReaperStats struct {
+	// Watchers is the number of buckets currently being watched for inactivity.
+	Watchers int `json:"watchers"`
+	// Sweeps is the number of times the reaper has checked buckets for inactivity.
+	Sweeps uint64 `json:"sweeps"`
+	// Reaped is the number of buckets reaped since the server started, keyed on namespace.
+	Reaped map[string]uint64 `json:"reaped"`
+	// LastSweep is the time at which the most recent sweep started.
+	LastSweep time.Time `json:"lastSweep"`
+	// LastSweepDuration is how long the most recent sweep took.
+	LastSweepDuration time.Duration `json:"lastSweepDurationNanos"`
+	// Frequency is the current interval between sweeps.
+	Frequency time.Duration `json:"frequencyNanos"`
+}
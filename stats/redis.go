@@ -27,12 +27,12 @@ type redisListener struct {
 
 // NewRedisStatsListener creates a stats listener backed
 // by a standalone Redis instance.
-func NewRedisStatsListener(redisOpts *redis.Options, statsBatchSize int, statsBatchDeadline time.Duration) Listener {
+func NewRedisStatsListener(redisOpts *redis.Options, statsBatchSize int, statsBatchDeadline time.Duration) (Listener, error) {
 	client := redis.NewClient(redisOpts)
 	_, err := client.Ping(context.TODO()).Result()
 
 	if err != nil {
-		logging.Fatalf("RedisStatsListener: cannot connect to Redis, %v", err)
+		return nil, fmt.Errorf("RedisStatsListener: cannot connect to Redis, %v", err)
 	}
 
 	l := &redisListener{
@@ -47,17 +47,17 @@ func NewRedisStatsListener(redisOpts *redis.Options, statsBatchSize int, statsBa
 
 	go l.batcher()
 
-	return l
+	return l, nil
 }
 
 // NewRedisClusterStatsListener creates a stats listener backed
 // by a Redis cluster.
-func NewRedisClusterStatsListener(redisClusterOpts *redis.ClusterOptions, statsBatchSize int, statsBatchDeadline time.Duration) Listener {
+func NewRedisClusterStatsListener(redisClusterOpts *redis.ClusterOptions, statsBatchSize int, statsBatchDeadline time.Duration) (Listener, error) {
 	client := redis.NewClusterClient(redisClusterOpts)
 	_, err := client.Ping(context.TODO()).Result()
 
 	if err != nil {
-		logging.Fatalf("RedisStatsListener: cannot connect to Redis, %v", err)
+		return nil, fmt.Errorf("RedisStatsListener: cannot connect to Redis, %v", err)
 	}
 
 	l := &redisListener{
@@ -72,105 +72,180 @@ func NewRedisClusterStatsListener(redisClusterOpts *redis.ClusterOptions, statsB
 
 	go l.batcher()
 
-	return l
+	return l, nil
 }
 
-func (l *redisListener) redisTopList(key string) []*BucketScore {
-	results, err := l.client.ZRevRangeWithScores(context.TODO(), key, 0, 10).Result()
-
-	if err != nil && err.Error() != "redis: nil" {
-		logging.Printf("RedisStatsListener.TopList error (%s) %v", key, err)
-		return emptyArr
+// statsKey returns the key of the sorted set counting a metric of dynamic or static buckets in a slot of a
+// window. The namespace is a hash tag, so that the sorted sets of a namespace are on the same node of a Redis
+// cluster and can be added up with ZUNION.
+func statsKey(metric Metric, dynamic bool, namespace string, window time.Duration, slot int64) string {
+	kind := "static"
+	if dynamic {
+		kind = "dynamic"
 	}
 
-	arr := make([]*BucketScore, len(results))
+	return fmt.Sprintf("stats:{%s}:%s:%s:%d:%d", namespace, metric, kind, int64(window/time.Second), slot)
+}
 
-	for i, item := range results {
-		arr[i] = &BucketScore{item.Member.(string), int64(item.Score)}
+// queryKeys returns the keys of the sorted sets counting a metric in each slot of the query's window ending at t.
+func queryKeys(metric Metric, namespace string, query Query, t time.Time) []string {
+	newest := slotAt(query.Window, t)
+	keys := make([]string, 0, 2*slotsPerWindow)
+
+	for slot := newest - slotsPerWindow + 1; slot <= newest; slot++ {
+		keys = append(keys, statsKey(metric, true, namespace, query.Window, slot))
+
+		if query.IncludeStatic {
+			keys = append(keys, statsKey(metric, false, namespace, query.Window, slot))
+		}
 	}
 
-	return arr
+	return keys
 }
 
-func statsNamespace(key, namespace string) string {
-	return fmt.Sprintf("stats:%s:%s", namespace, key)
+// union adds up the scores of a metric for each bucket in the query's window.
+func union(pipe redis.Cmdable, metric Metric, namespace string, query Query, t time.Time) *redis.ZSliceCmd {
+	keys := queryKeys(metric, namespace, query, t)
+	return pipe.ZUnionWithScores(context.TODO(), redis.ZStore{Keys: keys, Aggregate: "SUM"})
 }
 
-// TopHits is implemented for stats.Listener
-// TopHits returns a sorted list of the 10 buckets with the highest # of hits
-// in the specified namespace within the current bucketed hour
-func (l *redisListener) TopHits(namespace string) []*BucketScore {
-	return l.redisTopList(statsNamespace("hits", namespace))
-}
+// Top is implemented for stats.Listener
+// Top returns a sorted list of the buckets with the highest
+// query.Metric in the specified namespace within the window
+func (l *redisListener) Top(namespace string, query Query) []*BucketScore {
+	query = query.withDefaults()
+
+	if windowIndex(query.Window) < 0 {
+		return emptyArr
+	}
+
+	results, err := union(l.client, query.Metric, namespace, query, time.Now()).Result()
 
-// TopMisses is implemented for stats.Listener
-// TopMisses returns a sorted list of the 10 buckets with the highest # of misses
-// in the specified namespace within the current bucketed hour
-func (l *redisListener) TopMisses(namespace string) []*BucketScore {
-	return l.redisTopList(statsNamespace("misses", namespace))
+	if err != nil && err != redis.Nil {
+		logging.Printf("RedisStatsListener.Top error (%s, %+v) %v", namespace, query, err)
+		return emptyArr
+	}
+
+	arr := make(BucketScoreArray, len(results))
+
+	for i, item := range results {
+		arr[i] = &BucketScore{item.Member.(string), int64(item.Score)}
+	}
+
+	return arr.top(query.Limit)
 }
 
 // Get is implemented for stats.Listener
-// Get returns the hits and misses for a bucket in the specified namespace
-// within the current bucketed hour
-func (l *redisListener) Get(namespace, bucket string) *BucketScores {
-	scores := &BucketScores{0, 0}
+// Get returns the metrics of a bucket in the specified namespace
+// within the window
+func (l *redisListener) Get(namespace, bucket string, query Query) *BucketScores {
+	query = query.withDefaults()
+	scores := &BucketScores{}
+
+	if windowIndex(query.Window) < 0 {
+		return scores
+	}
 
-	value, err := l.client.ZScore(context.TODO(), statsNamespace("misses", namespace), bucket).Result()
+	now := time.Now()
+	var cmds [numMetrics][]*redis.FloatCmd
 
-	if err != nil && err.Error() != "redis: nil" {
-		logging.Printf("RedisStatsListener.Get error (%s, %s) %v", namespace, bucket, err)
-	} else {
-		scores.Misses = int64(value)
-	}
+	_, err := l.client.Pipelined(context.TODO(), func(pipe redis.Pipeliner) error {
+		for m := range cmds {
+			for _, key := range queryKeys(Metric(m), namespace, query, now) {
+				cmds[m] = append(cmds[m], pipe.ZScore(context.TODO(), key, bucket))
+			}
+		}
 
-	value, err = l.client.ZScore(context.TODO(), statsNamespace("hits", namespace), bucket).Result()
+		return nil
+	})
 
-	if err != nil && err.Error() != "redis: nil" {
+	if err != nil && err != redis.Nil {
 		logging.Printf("RedisStatsListener.Get error (%s, %s) %v", namespace, bucket, err)
-	} else {
-		scores.Hits = int64(value)
+		return scores
+	}
+
+	for m := range cmds {
+		for _, cmd := range cmds[m] {
+			*scores.metric(Metric(m)) += int64(cmd.Val())
+		}
 	}
 
 	return scores
 }
 
-func nearestHour() time.Time {
-	return time.Now().Add(time.Hour).Truncate(time.Hour)
+// Summary is implemented for stats.Listener
+// Summary adds up the metrics of the buckets in the specified
+// namespace within the window
+func (l *redisListener) Summary(namespace string, query Query) *NamespaceSummary {
+	query = query.withDefaults()
+	summary := &NamespaceSummary{}
+
+	if windowIndex(query.Window) < 0 {
+		return summary
+	}
+
+	now := time.Now()
+	var cmds [numMetrics]*redis.ZSliceCmd
+
+	_, err := l.client.Pipelined(context.TODO(), func(pipe redis.Pipeliner) error {
+		for m := range cmds {
+			cmds[m] = union(pipe, Metric(m), namespace, query, now)
+		}
+
+		return nil
+	})
+
+	if err != nil && err != redis.Nil {
+		logging.Printf("RedisStatsListener.Summary error (%s, %+v) %v", namespace, query, err)
+		return summary
+	}
+
+	buckets := make(map[string]bool)
+
+	for m := range cmds {
+		for _, item := range cmds[m].Val() {
+			*summary.metric(Metric(m)) += int64(item.Score)
+			buckets[item.Member.(string)] = true
+		}
+	}
+
+	summary.Buckets = len(buckets)
+	return summary
 }
 
 // HandleEvent is implemented for stats.Listener
-// HandleEvent consumes dynamic bucket events (see events.Event)
+// HandleEvent consumes bucket events (see events.Event)
 func (l *redisListener) HandleEvent(event events.Event) {
-	if !event.Dynamic() {
+	inc, counted := increments(event)
+	if !counted {
 		return
 	}
 
-	var key string
-	var numTokens int64 = 1
+	l.queueStatsUpdate(event.Namespace(), event.Dynamic(), inc, event.BucketName())
+}
 
-	switch event.EventType() {
-	case events.EVENT_BUCKET_MISS:
-		key = "misses"
-	case events.EVENT_TOKENS_SERVED:
-		numTokens = event.NumTokens()
-		key = "hits"
-	default:
-		return
-	}
+// queueStatsUpdate queues a statsUpdate to be sent to redis via the batcher. The
+// update is counted in the current slot of each window, whose sorted set expires
+// once the slot has rolled out of the window.
+func (l *redisListener) queueStatsUpdate(namespace string, dynamic bool, inc [numMetrics]int64, bucket string) {
+	now := time.Now()
 
-	namespace := statsNamespace(key, event.Namespace())
-	bucket := event.BucketName()
+	l.statsUpdatesLock.Lock()
 
-	l.queueStatsUpdate(namespace, numTokens, bucket)
-}
+	for m, n := range inc {
+		if n == 0 {
+			continue
+		}
 
-// queueStatsUpdate queues a statsUpdate to be sent to redis via the batcher
-func (l *redisListener) queueStatsUpdate(namespace string, numTokens int64, bucket string) {
-	l.statsUpdatesLock.Lock()
+		for _, window := range Windows {
+			slot := slotAt(window, now)
+			key := statsKey(Metric(m), dynamic, namespace, window, slot)
+			expiry := time.Unix(0, (slot+1)*int64(slotWidth(window))).Add(window)
 
-	l.pipe.ZIncrBy(context.TODO(), namespace, float64(numTokens), bucket)
-	l.pipe.ExpireAt(context.TODO(), namespace, nearestHour())
+			l.pipe.ZIncrBy(context.TODO(), key, float64(n), bucket)
+			l.pipe.ExpireAt(context.TODO(), key, expiry)
+		}
+	}
 
 	l.queuedUpdates++
 
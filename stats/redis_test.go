@@ -13,6 +13,7 @@ import (
 	"github.com/redis/go-redis/v9"
 
 	"github.com/square/quotaservice/events"
+	"github.com/square/quotaservice/test/helpers"
 )
 
 var namespace string
@@ -40,7 +41,9 @@ const (
 
 func setUp() Listener {
 	rand.Seed(time.Now().UTC().UnixNano())
-	return NewRedisStatsListener(&redis.Options{Addr: "localhost:6379"}, 128, batchSubmitInterval)
+	listener, err := NewRedisStatsListener(&redis.Options{Addr: "localhost:6379"}, 128, batchSubmitInterval)
+	helpers.PanicError(err)
+	return listener
 }
 
 func teardown(listener Listener) {
@@ -54,7 +57,7 @@ func TestRedisHandleNewHitBucket(t *testing.T) {
 	ev := events.NewTokensServedEvent(namespace, "new-hit-dyn", true, 1, 0)
 	listener.HandleEvent(ev)
 	time.Sleep(waitForBatchSubmit)
-	scores := listener.Get(namespace, "new-hit-dyn")
+	scores := listener.Get(namespace, "new-hit-dyn", Query{})
 
 	if scores.Hits != 1 || scores.Misses != 0 {
 		t.Fatalf("Bucket score was not accurate: %+v != [Hits=1, Misses=0]", scores)
@@ -63,14 +66,14 @@ func TestRedisHandleNewHitBucket(t *testing.T) {
 	ev = events.NewTokensServedEvent(namespace, "nondyn", false, 1, 0)
 	listener.HandleEvent(ev)
 	time.Sleep(waitForBatchSubmit)
-	scores = listener.Get(namespace, "nondyn")
+	scores = listener.Get(namespace, "nondyn", Query{})
 
 	if scores.Hits != 0 || scores.Misses != 0 {
 		t.Fatalf("Non-dynamic bucket score was not accurate: %+v != [Hits=0, Misses=0]", scores)
 	}
 
 	time.Sleep(waitForBatchSubmit)
-	scores = listener.Get(namespace, "nondyn")
+	scores = listener.Get(namespace, "nondyn", Query{})
 
 	if scores.Hits != 0 || scores.Misses != 0 {
 		t.Fatalf("Nonexisting namespace was not accurate: %+v != [Hits=0, Misses=0]", scores)
@@ -85,7 +88,7 @@ func TestRedisHandleNewMissBucket(t *testing.T) {
 	namespace = randomNamespace()
 	listener.HandleEvent(events.NewBucketMissedEvent(namespace, "new-miss-dyn", true))
 	time.Sleep(waitForBatchSubmit)
-	scores := listener.Get(namespace, "new-miss-dyn")
+	scores := listener.Get(namespace, "new-miss-dyn", Query{})
 
 	if scores.Hits != 0 || scores.Misses != 1 {
 		t.Fatalf("Bucket score was not accurate: %+v != [Hits=0, Misses=1]", scores)
@@ -93,7 +96,7 @@ func TestRedisHandleNewMissBucket(t *testing.T) {
 
 	listener.HandleEvent(events.NewBucketMissedEvent(namespace, "nondyn", false))
 	time.Sleep(waitForBatchSubmit)
-	scores = listener.Get(namespace, "nondyn")
+	scores = listener.Get(namespace, "nondyn", Query{})
 
 	if scores.Hits != 0 || scores.Misses != 0 {
 		t.Fatalf("Non-dynamic bucket score was not accurate: %+v != [Hits=0, Misses=0]", scores)
@@ -110,7 +113,7 @@ func TestRedisHandleIncrMissBucket(t *testing.T) {
 	listener.HandleEvent(events.NewBucketMissedEvent(namespace, "incr-miss", true))
 	listener.HandleEvent(events.NewBucketMissedEvent(namespace, "incr-miss", true))
 	time.Sleep(waitForBatchSubmit)
-	scores := listener.Get(namespace, "incr-miss")
+	scores := listener.Get(namespace, "incr-miss", Query{})
 
 	if scores.Hits != 0 || scores.Misses != 3 {
 		t.Fatalf("Bucket score was not accurate: %+v != [Hits=0, Misses=3]", scores)
@@ -127,10 +130,10 @@ func TestRedisHandleIncrHitBucket(t *testing.T) {
 	listener.HandleEvent(events.NewTokensServedEvent(namespace, "incr-hit", true, 3, 0))
 	listener.HandleEvent(events.NewTokensServedEvent(namespace, "incr-hit", true, 1, 0))
 	time.Sleep(waitForBatchSubmit)
-	scores := listener.Get(namespace, "incr-hit")
+	scores := listener.Get(namespace, "incr-hit", Query{})
 
-	if scores.Hits != 5 || scores.Misses != 0 {
-		t.Fatalf("Bucket score was not accurate: %+v != [Hits=5, Misses=0]", scores)
+	if scores.Hits != 3 || scores.Tokens != 5 || scores.Misses != 0 {
+		t.Fatalf("Bucket score was not accurate: %+v != [Hits=3, Tokens=5, Misses=0]", scores)
 	}
 
 	teardown(listener)
@@ -142,7 +145,7 @@ func TestRedisHandleNonEvent(t *testing.T) {
 	namespace = randomNamespace()
 	listener.HandleEvent(events.NewTimedOutEvent(namespace, "nonevent", true, 1))
 	time.Sleep(waitForBatchSubmit)
-	scores := listener.Get(namespace, "nonevent")
+	scores := listener.Get(namespace, "nonevent", Query{})
 
 	if scores.Hits != 0 || scores.Misses != 0 {
 		t.Fatalf("Bucket score was not accurate: %+v != [Hits=0, Misses=0]", scores)
@@ -160,7 +163,7 @@ func TestRedisTop10Hits(t *testing.T) {
 	listener.HandleEvent(events.NewTokensServedEvent(namespace, "hits-dyn-3", true, 1, 0))
 
 	time.Sleep(waitForBatchSubmit)
-	hits := listener.TopHits(namespace)
+	hits := listener.Top(namespace, Query{Metric: TOKENS})
 	correctHits := []*BucketScore{
 		{Bucket: "hits-dyn-2", Score: 10},
 		{Bucket: "hits-dyn-1", Score: 3},
@@ -187,7 +190,7 @@ func TestRedisTop10Misses(t *testing.T) {
 	listener.HandleEvent(events.NewBucketMissedEvent(namespace, "misses-dyn-3", true))
 
 	time.Sleep(waitForBatchSubmit)
-	misses := listener.TopMisses(namespace)
+	misses := listener.Top(namespace, Query{Metric: MISSES})
 	correctMisses := []*BucketScore{
 		{Bucket: "misses-dyn-2", Score: 3},
 		{Bucket: "misses-dyn-3", Score: 2},
@@ -202,20 +205,21 @@ func TestRedisTop10Misses(t *testing.T) {
 
 func TestRedisBatching(t *testing.T) {
 	setUp()
-	listener := NewRedisStatsListener(&redis.Options{Addr: "localhost:6379"}, 128, 5*time.Second)
+	listener, err := NewRedisStatsListener(&redis.Options{Addr: "localhost:6379"}, 128, 5*time.Second)
+	helpers.CheckError(t, err)
 
 	for i := 0; i < 127; i++ {
 		listener.HandleEvent(events.NewBucketMissedEvent(namespace, "misses-dyn-1", true))
 	}
 
-	misses := listener.TopMisses(namespace)
+	misses := listener.Top(namespace, Query{Metric: MISSES})
 	if !reflect.DeepEqual(misses, []*BucketScore{}) {
 		t.Fatalf("Misses top1 was not empty %+v", misses)
 	}
 
 	listener.HandleEvent(events.NewBucketMissedEvent(namespace, "misses-dyn-1", true))
 	time.Sleep(waitForBatchSubmit)
-	misses = listener.TopMisses(namespace)
+	misses = listener.Top(namespace, Query{Metric: MISSES})
 	correctMisses := []*BucketScore{{Bucket: "misses-dyn-1", Score: 128}}
 
 	if !reflect.DeepEqual(misses, correctMisses) {
@@ -5,24 +5,116 @@ package stats
 
 import (
 	"fmt"
+	"sort"
+	"time"
 
 	"github.com/square/quotaservice/events"
 )
 
 // Listener is an interface for consuming
-// and retrieving dynamic bucket hits and misses
+// and retrieving bucket stats within a rolling
+// window, one of Windows
 type Listener interface {
-	TopHits(string) []*BucketScore
-	TopMisses(string) []*BucketScore
-	Get(string, string) *BucketScores
+	// Top returns the buckets of a namespace with the highest query.Metric, highest first.
+	Top(namespace string, query Query) []*BucketScore
+	// Get returns every metric of a bucket. query.Metric and query.Limit are ignored.
+	Get(namespace, bucket string, query Query) *BucketScores
+	// Summary adds up every metric of a namespace's buckets. query.Metric and query.Limit are ignored.
+	Summary(namespace string, query Query) *NamespaceSummary
 	HandleEvent(events.Event)
 }
 
+// Metric is a count kept for each bucket.
+type Metric int
+
+const (
+	// HITS counts requests that were served tokens.
+	HITS Metric = iota
+	// MISSES counts requests for buckets that don't exist.
+	MISSES
+	// TIMEOUTS counts requests that would have had to wait longer than their max wait time for tokens.
+	TIMEOUTS
+	// TOKENS counts tokens served.
+	TOKENS
+	// WAIT_MILLIS adds up the milliseconds that requests served tokens were asked to wait.
+	WAIT_MILLIS
+)
+
+const numMetrics = int(WAIT_MILLIS) + 1
+
+var metricNames = []string{
+	HITS:        "hits",
+	MISSES:      "misses",
+	TIMEOUTS:    "timeouts",
+	TOKENS:      "tokens",
+	WAIT_MILLIS: "waitMillis",
+}
+
+func (m Metric) String() string {
+	if m < 0 || int(m) >= len(metricNames) {
+		return fmt.Sprintf("Metric(%d)", m)
+	}
+
+	return metricNames[m]
+}
+
+// ParseMetric parses the name of a metric, such as "timeouts".
+func ParseMetric(s string) (Metric, error) {
+	for m, name := range metricNames {
+		if name == s {
+			return Metric(m), nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown metric %v; must be one of %v", s, metricNames)
+}
+
+// DefaultLimit is the number of buckets in top lists when no limit is specified.
+const DefaultLimit = 10
+
+// Query selects the stats returned by a Listener. The zero value selects the top DefaultLimit dynamic buckets by
+// hits within DefaultWindow.
+type Query struct {
+	// Window is the rolling window to count within, one of Windows.
+	Window time.Duration
+	// Metric is the metric buckets are ranked by.
+	Metric Metric
+	// Limit is the maximum number of buckets in top lists.
+	Limit int
+	// IncludeStatic counts buckets that aren't dynamic, including requests served by default buckets, as well as
+	// dynamic buckets.
+	IncludeStatic bool
+}
+
+// withDefaults returns the query with defaults in place of unset fields.
+func (q Query) withDefaults() Query {
+	if q.Window == 0 {
+		q.Window = DefaultWindow
+	}
+
+	if q.Limit <= 0 {
+		q.Limit = DefaultLimit
+	}
+
+	return q
+}
+
 // BucketScores stores a specific bucket's
-// stats on hits and misses
+// stats on each metric
 type BucketScores struct {
-	Hits   int64 `json:"hits"`
-	Misses int64 `json:"misses"`
+	Hits       int64 `json:"hits"`
+	Misses     int64 `json:"misses"`
+	Timeouts   int64 `json:"timeouts"`
+	Tokens     int64 `json:"tokens"`
+	WaitMillis int64 `json:"waitMillis"`
+}
+
+// NamespaceSummary adds up the stats of
+// a namespace's buckets
+type NamespaceSummary struct {
+	BucketScores
+	// Buckets is the number of buckets with stats in the window.
+	Buckets int `json:"buckets"`
 }
 
 // BucketScore stores a specific bucket's
@@ -33,17 +125,49 @@ type BucketScore struct {
 }
 
 var emptyArr []*BucketScore
-var emptyBucketScores *BucketScores
 
 func init() {
 	emptyArr = make([]*BucketScore, 0)
-	emptyBucketScores = &BucketScores{0, 0}
 }
 
 func (b *BucketScore) String() string {
 	return fmt.Sprintf("{%s, %d}", b.Bucket, b.Score)
 }
 
+// metric returns a pointer to the field of s holding metric m.
+func (s *BucketScores) metric(m Metric) *int64 {
+	switch m {
+	case HITS:
+		return &s.Hits
+	case MISSES:
+		return &s.Misses
+	case TIMEOUTS:
+		return &s.Timeouts
+	case TOKENS:
+		return &s.Tokens
+	default:
+		return &s.WaitMillis
+	}
+}
+
+// increments returns the amount an event adds to each metric, and whether it's counted at all.
+func increments(event events.Event) (inc [numMetrics]int64, counted bool) {
+	switch event.EventType() {
+	case events.EVENT_TOKENS_SERVED:
+		inc[HITS] = 1
+		inc[TOKENS] = event.NumTokens()
+		inc[WAIT_MILLIS] = event.WaitTime().Milliseconds()
+	case events.EVENT_BUCKET_MISS:
+		inc[MISSES] = 1
+	case events.EVENT_TIMEOUT_SERVING_TOKENS:
+		inc[TIMEOUTS] = 1
+	default:
+		return inc, false
+	}
+
+	return inc, true
+}
+
 // BucketScoreArray mplements a sortable BucketScore array
 type BucketScoreArray []*BucketScore
 
@@ -58,3 +182,14 @@ func (b BucketScoreArray) Less(i, j int) bool {
 func (b BucketScoreArray) Swap(i, j int) {
 	b[i], b[j] = b[j], b[i]
 }
+
+// top sorts b and returns its first limit scores.
+func (b BucketScoreArray) top(limit int) []*BucketScore {
+	sort.Sort(b)
+
+	if len(b) > limit {
+		return b[0:limit]
+	}
+
+	return b
+}
@@ -0,0 +1,96 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package stats
+
+import (
+	"fmt"
+	"time"
+)
+
+// Windows are the rolling windows over which listeners count hits and misses.
+var Windows = []time.Duration{time.Minute, 5 * time.Minute, time.Hour}
+
+// DefaultWindow is the window used when none is specified.
+const DefaultWindow = 5 * time.Minute
+
+// slotsPerWindow is the number of slots each window is divided into. Counts roll out of a window a slot at a
+// time, so a window covers between (slotsPerWindow-1)/slotsPerWindow of its duration and all of it.
+const slotsPerWindow = 12
+
+// ParseWindow parses a window such as "5m", returning an error if it isn't one of Windows.
+func ParseWindow(s string) (time.Duration, error) {
+	window, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+
+	if windowIndex(window) < 0 {
+		return 0, fmt.Errorf("unsupported window %v; must be one of %v", window, Windows)
+	}
+
+	return window, nil
+}
+
+// windowIndex returns the index of window in Windows, or -1 if it isn't one.
+func windowIndex(window time.Duration) int {
+	for i, w := range Windows {
+		if w == window {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// slotWidth returns the duration of each of window's slots.
+func slotWidth(window time.Duration) time.Duration {
+	return window / slotsPerWindow
+}
+
+// slotAt returns the slot of window that t falls in, as the number of slots since the epoch.
+func slotAt(window time.Duration, t time.Time) int64 {
+	return t.UnixNano() / int64(slotWidth(window))
+}
+
+// counter counts events in a rolling window, with a count per slot.
+type counter struct {
+	slots [slotsPerWindow]int64
+	// newest is the most recent slot counted.
+	newest int64
+}
+
+func (c *counter) add(slot, n int64) {
+	if slot <= c.newest-slotsPerWindow {
+		// Too old to be in the window.
+		return
+	}
+
+	if slot-c.newest >= slotsPerWindow {
+		c.slots = [slotsPerWindow]int64{}
+	} else {
+		// Clear slots that have rolled out of the window since the last count.
+		for s := c.newest + 1; s <= slot; s++ {
+			c.slots[s%slotsPerWindow] = 0
+		}
+	}
+
+	if slot > c.newest {
+		c.newest = slot
+	}
+
+	c.slots[slot%slotsPerWindow] += n
+}
+
+// sum returns the count of the window ending with slot.
+func (c *counter) sum(slot int64) int64 {
+	var total int64
+
+	for s := slot - slotsPerWindow + 1; s <= slot; s++ {
+		if s <= c.newest && s > c.newest-slotsPerWindow {
+			total += c.slots[s%slotsPerWindow]
+		}
+	}
+
+	return total
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/square/quotaservice/config"
 	"github.com/square/quotaservice/events"
 	"github.com/square/quotaservice/logging"
+	"github.com/square/quotaservice/metrics/prometheus"
 	"github.com/square/quotaservice/rpc/grpc"
 	"github.com/square/quotaservice/stats"
 	"github.com/square/quotaservice/test/helpers"
@@ -47,6 +48,11 @@ func main() {
 		0,
 		grpc.New(gRPCServer, events.NewNilProducer()))
 	server.SetStatsListener(stats.NewMemoryStatsListener())
+
+	exporter := prometheus.New(server.GetServerAdministrable(), prometheus.Options{DynamicBucketLabels: 100})
+	helpers.PanicError(server.AddListener("prometheus", exporter.HandleEvent, 1000, events.DROP_OLDEST))
+	server.SetMetricsHandler(exporter)
+
 	if _, e := server.Start(); e != nil {
 		panic(e)
 	}
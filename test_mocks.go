@@ -70,8 +70,8 @@ func (bf *MockBucketFactory) bucket(namespace, name string) *MockBucket {
 	return bucket
 }
 
-func (bf *MockBucketFactory) Init(cfg *pbconfig.ServiceConfig) {}
-func (bf *MockBucketFactory) Client() interface{}              { return nil }
+func (bf *MockBucketFactory) Init(cfg *pbconfig.ServiceConfig) error { return nil }
+func (bf *MockBucketFactory) Client() interface{}                    { return nil }
 func (bf *MockBucketFactory) NewBucket(namespace, bucketName string, cfg *pbconfig.BucketConfig, dyn bool) Bucket {
 	b := &MockBucket{
 		WaitTime:        0,
@@ -107,14 +107,16 @@ type MockEndpoint struct {
 func (d *MockEndpoint) Init(qs QuotaService) {
 	d.QuotaService = qs
 }
-func (d *MockEndpoint) Start() {}
-func (d *MockEndpoint) Stop()  {}
+func (d *MockEndpoint) Start() error { return nil }
+func (d *MockEndpoint) Stop()        {}
 
 func NewBucketContainerWithMocks(cfg *pbconfig.ServiceConfig) (*bucketContainer, *MockBucketFactory, *MockEmitter) {
 	bf := &MockBucketFactory{}
 	e := &MockEmitter{}
 	bc := NewBucketContainer(bf, e, NewReaperConfigForTests())
-	bc.Init(cfg)
+	if err := bc.Init(cfg); err != nil {
+		panic(err)
+	}
 
 	return bc, bf, e
 }
@@ -0,0 +1,92 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package quotaservice
+
+import (
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Attributes recorded on the OpenTelemetry spans of the Allow path, by the server as well as by RPC endpoints
+// and bucket implementations.
+const (
+	NamespaceAttribute  = attribute.Key("quotaservice.namespace")
+	BucketAttribute     = attribute.Key("quotaservice.bucket")
+	TokensAttribute     = attribute.Key("quotaservice.tokens")
+	DynamicAttribute    = attribute.Key("quotaservice.dynamic")
+	WaitMillisAttribute = attribute.Key("quotaservice.wait_millis")
+	// OutcomeAttribute is one of the Outcome* values.
+	OutcomeAttribute = attribute.Key("quotaservice.outcome")
+)
+
+// Values of OutcomeAttribute.
+const (
+	OutcomeServed         = "served"
+	OutcomeTimedOut       = "timed_out"
+	OutcomeNoBucket       = "no_bucket"
+	OutcomeTooManyBuckets = "too_many_buckets"
+	OutcomeTooManyTokens  = "too_many_tokens"
+	OutcomeError          = "error"
+)
+
+var tracer = otel.Tracer("github.com/square/quotaservice")
+
+// Outcome describes the result of a call to QuotaService.Allow as one of the Outcome* values.
+func Outcome(err error) string {
+	if err == nil {
+		return OutcomeServed
+	}
+
+	qsErr, ok := err.(QuotaServiceError)
+	if !ok {
+		return OutcomeError
+	}
+
+	switch qsErr.Reason {
+	case ER_TIMEOUT:
+		return OutcomeTimedOut
+	case ER_NO_BUCKET:
+		return OutcomeNoBucket
+	case ER_TOO_MANY_BUCKETS:
+		return OutcomeTooManyBuckets
+	case ER_TOO_MANY_TOKENS_REQUESTED:
+		return OutcomeTooManyTokens
+	default:
+		return OutcomeError
+	}
+}
+
+// EndTakeSpan records the result of Bucket.Take on a span started by the bucket implementation, and ends it.
+func EndTakeSpan(span trace.Span, wait time.Duration, success bool, err error) {
+	switch {
+	case err != nil:
+		span.SetAttributes(OutcomeAttribute.String(OutcomeError))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	case !success:
+		span.SetAttributes(OutcomeAttribute.String(OutcomeTimedOut))
+	default:
+		span.SetAttributes(OutcomeAttribute.String(OutcomeServed), WaitMillisAttribute.Int64(wait.Milliseconds()))
+	}
+
+	span.End()
+}
+
+// endAllowSpan records the result of server.Allow on its span, and ends it.
+func endAllowSpan(span trace.Span, wait time.Duration, dynamic bool, err error) {
+	span.SetAttributes(OutcomeAttribute.String(Outcome(err)), DynamicAttribute.Bool(dynamic))
+
+	if err == nil {
+		span.SetAttributes(WaitMillisAttribute.Int64(wait.Milliseconds()))
+	} else if Outcome(err) == OutcomeError {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
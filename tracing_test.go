@@ -0,0 +1,88 @@
+// Licensed under the Apache License, Version 2.0
+// Details: https://raw.githubusercontent.com/square/quotaservice/master/LICENSE
+
+package quotaservice
+
+import (
+	"context"
+	"testing"
+
+	"github.com/square/quotaservice/config"
+	"github.com/square/quotaservice/test/helpers"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestAllowSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(provider)
+
+	cfg := config.NewDefaultServiceConfig()
+	nsc := config.NewDefaultNamespaceConfig("traced")
+	nsc.MaxDynamicBuckets = 1
+	config.SetDynamicBucketTemplate(nsc, config.NewDefaultBucketConfig(""))
+	helpers.CheckError(t, config.AddNamespace(cfg, nsc))
+
+	s := New(&MockBucketFactory{}, config.NewMemoryConfig(cfg), NewReaperConfigForTests(), 0, &MockEndpoint{}).(*server)
+	_, err := s.Start()
+	helpers.CheckError(t, err)
+	defer stopServer(t, s)
+
+	_, _, err = s.Allow(context.Background(), "traced", "a", 2, 0, false)
+	helpers.CheckError(t, err)
+
+	if _, _, err = s.Allow(context.Background(), "traced", "b", 1, 0, false); err == nil {
+		t.Fatal("Expecting too many dynamic buckets")
+	}
+
+	spans := recorder.Ended()
+	var names []string
+	for _, span := range spans {
+		names = append(names, span.Name())
+	}
+
+	expected := []string{
+		"bucketContainer.createDynamicBucket", "bucketContainer.FindBucket", "server.Allow",
+		"bucketContainer.createDynamicBucket", "bucketContainer.FindBucket", "server.Allow"}
+	if len(names) != len(expected) {
+		t.Fatalf("Expecting spans %v, got %v", expected, names)
+	}
+
+	for i, name := range expected {
+		if names[i] != name {
+			t.Fatalf("Expecting spans %v, got %v", expected, names)
+		}
+	}
+
+	// Child spans belong to the trace of the Allow span.
+	for i := 0; i < 2; i++ {
+		if spans[i].Parent().SpanID() != spans[i+1].SpanContext().SpanID() {
+			t.Errorf("Expecting span %v to be a child of %v", spans[i].Name(), spans[i+1].Name())
+		}
+	}
+
+	expectAttributes(t, spans[2].Attributes(),
+		NamespaceAttribute.String("traced"), BucketAttribute.String("a"), TokensAttribute.Int64(2),
+		OutcomeAttribute.String(OutcomeServed), DynamicAttribute.Bool(true))
+	expectAttributes(t, spans[5].Attributes(), OutcomeAttribute.String(OutcomeTooManyBuckets))
+	expectAttributes(t, spans[3].Attributes(), OutcomeAttribute.String(OutcomeTooManyBuckets))
+}
+
+func expectAttributes(t *testing.T, actual []attribute.KeyValue, expected ...attribute.KeyValue) {
+	t.Helper()
+
+	for _, e := range expected {
+		found := false
+		for _, a := range actual {
+			found = found || a == e
+		}
+
+		if !found {
+			t.Errorf("Expecting attribute %v in %v", e, actual)
+		}
+	}
+}